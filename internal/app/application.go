@@ -2,7 +2,10 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -15,6 +18,7 @@ import (
 	"github.com/Conty111/AlfredoBot/internal/repositories"
 	"github.com/Conty111/AlfredoBot/internal/services/s3"
 	"github.com/Conty111/AlfredoBot/internal/services/telegram"
+	"github.com/Conty111/AlfredoBot/internal/services/trash"
 )
 
 // Application is a main struct for the application that contains general information
@@ -22,6 +26,10 @@ type Application struct {
 	db          *gorm.DB
 	Container   *dependencies.Container
 	telegramBot *telegram.TelegramBotService
+	// trashPurger permanently deletes items kept in the trash longer than the retention
+	trashPurger *trash.Purger
+	// storageServer serves signed URLs of the local storage backend
+	storageServer *http.Server
 }
 
 // InitializeApplication initializes new application
@@ -50,6 +58,11 @@ func InitializeApplication(cfg *configs.Configuration) (*Application, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize migrations: %w", err)
 	}
+	if cfg.Workspace != nil {
+		if err := initializers.InitializeDefaultWorkspace(app.db, cfg.Workspace.Default); err != nil {
+			return nil, fmt.Errorf("failed to initialize default workspace: %w", err)
+		}
+	}
 
 	// Create repositories if they don't exist
 	var photoRepository interfaces.PhotoManager
@@ -79,17 +92,31 @@ func InitializeApplication(cfg *configs.Configuration) (*Application, error) {
 
 	// Initialize Telegram bot service
 	telegramBot, err := telegram.NewTelegramBotService(
-		cfg.Telegram,
-		cfg.S3,
+		cfg,
 		app.Container.TelegramUserRepository,
 		photoRepository,
 		articleRepository,
+		app.Container.InviteCodeRepository,
+		app.Container.WorkspaceRepository,
+		app.Container.ChatRepository,
+		app.Container.SupportTicketRepository,
+		app.Container.AuditLogRepository,
 		s3Client,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize telegram bot: %w", err)
 	}
 	app.telegramBot = telegramBot
+	app.trashPurger = trash.NewPurger(cfg, photoRepository, articleRepository)
+
+	if handler, ok := s3Client.(http.Handler); ok && cfg.Storage != nil &&
+		cfg.Storage.Local != nil && cfg.Storage.Local.ListenAddr != "" {
+		app.storageServer = &http.Server{
+			Addr:              cfg.Storage.Local.ListenAddr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+	}
 
 	log.Info().Msg("Application initialized successfully")
 	return app, nil
@@ -109,6 +136,20 @@ func (a *Application) Start(ctx context.Context, cli bool) {
 			log.Error().Err(err).Msg("Failed to start Telegram bot")
 		}
 	}
+
+	if a.trashPurger != nil {
+		a.trashPurger.Start(ctx)
+	}
+
+	// Start local storage file server if configured
+	if a.storageServer != nil {
+		go func() {
+			log.Info().Str("addr", a.storageServer.Addr).Msg("Starting local storage file server")
+			if err := a.storageServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error().Err(err).Msg("Local storage file server failed")
+			}
+		}()
+	}
 }
 
 // Stop stops application services
@@ -122,9 +163,29 @@ func (a *Application) Stop() (err error) {
 		}
 	}
 
+	if a.trashPurger != nil {
+		a.trashPurger.Stop()
+	}
+
+	if a.storageServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := a.storageServer.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to stop local storage file server")
+		}
+	}
+
 	return nil
 }
 
+// ReloadContent reads the help text, support contacts and menu layout of the bot again
+func (a *Application) ReloadContent() error {
+	if a.telegramBot == nil {
+		return nil
+	}
+	return a.telegramBot.ReloadContent()
+}
+
 // createS3Client creates a new S3 client
 func createS3Client(cfg *configs.S3Config) (interfaces.S3Client, error) {
 	if cfg == nil {
@@ -0,0 +1,244 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+)
+
+// NewArticleCmd renames and merges article numbers, moves photos between them and manages their aliases
+func NewArticleCmd() *cobra.Command {
+	var (
+		configPath string
+		workspace  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "article",
+		Short: "Rename and merge article numbers, move photos between them, manage aliases",
+	}
+	cmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to config file (default searches for config.yaml|json)")
+	cmd.PersistentFlags().StringVar(&workspace, "workspace", "", "Workspace of the article numbers (default is the configured default workspace)")
+
+	rename := &cobra.Command{
+		Use:   "rename <old> <new>",
+		Short: "Change an article number, the new number must not exist in the workspace",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			id, err := store.findArticleNumber(workspace, args[0])
+			if err != nil {
+				return err
+			}
+
+			_, err = store.articles.RenameArticleNumber(id, args[1])
+			switch {
+			case errors.Is(err, models.ErrArticleNumberTaken):
+				return fmt.Errorf("article number %s already exists, merge them with: article merge %s %s", args[1], args[0], args[1])
+			case errors.Is(err, models.ErrArticleNumberInTrash):
+				return fmt.Errorf("article number %s is in the trash, restore and merge it or purge it first", args[1])
+			case err != nil:
+				return fmt.Errorf("failed to rename article number: %w", err)
+			}
+			cmd.Printf("article number %s renamed to %s\n", args[0], args[1])
+			return nil
+		},
+	}
+
+	merge := &cobra.Command{
+		Use:   "merge <from> <into>",
+		Short: "Move all photos of an article number to another one and move the first to the trash",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			sourceID, targetID, err := store.findArticleNumberPair(workspace, args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			moved, err := store.articles.MergeArticleNumbers(sourceID, targetID)
+			if err != nil {
+				return fmt.Errorf("failed to merge article numbers: %w", err)
+			}
+			cmd.Printf("article number %s merged into %s, %d photos moved\n", args[0], args[1], moved)
+			return nil
+		},
+	}
+
+	move := &cobra.Command{
+		Use:   "move <from> <to> <photo>...",
+		Short: "Move photos from an article number to another one",
+		Long: `Move photos from an article number to another one.
+
+Photos are picked by their IDs, the first 8 characters of an ID are enough,
+or by their positions in the list printed by "article photos".`,
+		Args: cobra.MinimumNArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			sourceID, targetID, err := store.findArticleNumberPair(workspace, args[0], args[1])
+			if err != nil {
+				return err
+			}
+			source, err := store.articles.GetArticleNumberWithPhotos(sourceID)
+			if err != nil {
+				return fmt.Errorf("failed to get photos of %s: %w", args[0], err)
+			}
+			photoIDs, err := catalog.SelectPhotos(appliedPhotos(source.Photos), args[2:])
+			if err != nil {
+				return err
+			}
+
+			moved, err := store.articles.MoveArticleNumberPhotos(sourceID, targetID, photoIDs)
+			if err != nil {
+				return fmt.Errorf("failed to move photos: %w", err)
+			}
+			cmd.Printf("%d photos moved from %s to %s\n", moved, args[0], args[1])
+			return nil
+		},
+	}
+
+	photos := &cobra.Command{
+		Use:   "photos <article>",
+		Short: "List photos of an article number with their positions for move",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			id, err := store.findArticleNumber(workspace, args[0])
+			if err != nil {
+				return err
+			}
+			articleNumber, err := store.articles.GetArticleNumberWithPhotos(id)
+			if err != nil {
+				return fmt.Errorf("failed to get photos of %s: %w", args[0], err)
+			}
+			for i, photo := range appliedPhotos(articleNumber.Photos) {
+				numbers := make([]string, 0, len(photo.ArticleNumbers))
+				for _, linked := range photo.ArticleNumbers {
+					numbers = append(numbers, linked.Number)
+				}
+				cmd.Printf("%3d  %s  %s\n", i+1, photo.ID, strings.Join(numbers, ", "))
+			}
+			return nil
+		},
+	}
+
+	alias := &cobra.Command{
+		Use:   "alias <article> [alias]...",
+		Short: "List aliases of an article number or add supplier codes, EANs or old numbers to it",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			id, err := store.findArticleNumber(workspace, args[0])
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 1 {
+				aliases, err := store.articles.GetArticleAliases(id)
+				if err != nil {
+					return fmt.Errorf("failed to get aliases of %s: %w", args[0], err)
+				}
+				for _, alias := range aliases {
+					cmd.Println(alias.Alias)
+				}
+				return nil
+			}
+			_, err = store.articles.AddArticleAliases(id, args[1:])
+			var numberErr *models.ArticleNumberError
+			switch {
+			case errors.As(err, &numberErr) && errors.Is(numberErr, models.ErrArticleNumberTaken):
+				return fmt.Errorf("%s is already an article number or an alias, no aliases added", numberErr.Number)
+			case errors.As(err, &numberErr) && errors.Is(numberErr, models.ErrArticleNumberInTrash):
+				return fmt.Errorf("article number %s is in the trash, restore or purge it first, no aliases added", numberErr.Number)
+			case err != nil:
+				return fmt.Errorf("failed to add aliases: %w", err)
+			}
+			for _, alias := range args[1:] {
+				cmd.Printf("alias %s added to %s\n", alias, args[0])
+			}
+			return nil
+		},
+	}
+
+	unalias := &cobra.Command{
+		Use:   "unalias <alias>...",
+		Short: "Remove aliases of article numbers",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			ws, err := store.findWorkspace(workspace)
+			if err != nil {
+				return err
+			}
+			for _, alias := range args {
+				articleNumber, err := store.articles.RemoveArticleAlias(ws.ID, alias)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("alias %s not found in %s", alias, ws.Name)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to remove alias %s: %w", alias, err)
+				}
+				cmd.Printf("alias %s removed from %s\n", alias, articleNumber.Number)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(rename, merge, move, photos, alias, unalias)
+	return cmd
+}
+
+// findArticleNumberPair resolves two different article numbers of the named workspace
+func (s *storage) findArticleNumberPair(workspaceName, source, target string) (sourceID, targetID uuid.UUID, err error) {
+	if source == target {
+		return sourceID, targetID, fmt.Errorf("pick two different article numbers")
+	}
+	if sourceID, err = s.findArticleNumber(workspaceName, source); err != nil {
+		return sourceID, targetID, err
+	}
+	if targetID, err = s.findArticleNumber(workspaceName, target); err != nil {
+		return sourceID, targetID, err
+	}
+	// Different aliases may name the same article number
+	if sourceID == targetID {
+		return sourceID, targetID, fmt.Errorf("%s and %s are the same article number", source, target)
+	}
+	return sourceID, targetID, nil
+}
+
+// appliedPhotos keeps the photos shown in search results, in the order of the search
+func appliedPhotos(photos []models.Photo) []models.Photo {
+	var applied []models.Photo
+	for _, photo := range photos {
+		if photo.State == models.PhotoApplied {
+			applied = append(applied, photo)
+		}
+	}
+	return applied
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// NewAuditCmd queries the audit log of catalog changes
+func NewAuditCmd() *cobra.Command {
+	var (
+		configPath string
+		actor      string
+		action     string
+		target     string
+		user       string
+		article    string
+		workspace  string
+		since      time.Duration
+		limit      int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show who changed photos, article numbers and roles, the newest changes first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if action != "" && !models.IsValidAuditAction(action) {
+				return fmt.Errorf("unknown action %q, expected one of: %s", action, strings.Join(models.AuditActions, ", "))
+			}
+
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+
+			filter := models.AuditLogFilter{Action: action, Limit: limit}
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+			if actor != "" {
+				found, err := store.lookupUser(actor)
+				if err != nil {
+					return err
+				}
+				filter.ActorTelegramID = found.TelegramID
+			}
+
+			switch {
+			case target != "":
+				if filter.TargetID, err = uuid.Parse(target); err != nil {
+					return fmt.Errorf("invalid target ID %q: %w", target, err)
+				}
+			case user != "":
+				found, err := store.lookupUser(user)
+				if err != nil {
+					return err
+				}
+				filter.TargetID = found.ID
+			case article != "":
+				if filter.TargetID, err = store.findArticleNumber(workspace, article); err != nil {
+					return err
+				}
+			}
+
+			entries, err := store.audit.GetAuditLogs(filter)
+			if err != nil {
+				return fmt.Errorf("failed to query audit log: %w", err)
+			}
+			for _, entry := range entries {
+				printAuditLog(cmd, entry)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file (default searches for config.yaml|json)")
+	cmd.Flags().StringVar(&actor, "actor", "", "Changes made by the user, <telegram_id|@username>")
+	cmd.Flags().StringVar(&action, "action", "", "Changes of one kind: "+strings.Join(models.AuditActions, ", "))
+	cmd.Flags().StringVar(&target, "target", "", "Changes of the photo, article number or user with the ID")
+	cmd.Flags().StringVar(&user, "user", "", "Role changes of the user, <telegram_id|@username>")
+	cmd.Flags().StringVar(&article, "article", "", "Changes of the article number")
+	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace of --article (default is the configured default workspace)")
+	cmd.Flags().DurationVar(&since, "since", 0, "Changes made within the duration, e.g. 24h")
+	cmd.Flags().IntVar(&limit, "limit", 50, "Maximum number of changes, 0 for all")
+
+	return cmd
+}
+
+// lookupUser finds a user by "@username" or numeric Telegram ID
+func (s *storage) lookupUser(reference string) (*models.TelegramUser, error) {
+	user, err := s.findUser(reference)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user %s not found", reference)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return user, nil
+}
+
+// findWorkspace resolves the named workspace, the default one if the name is empty
+func (s *storage) findWorkspace(workspaceName string) (*models.Workspace, error) {
+	if workspaceName == "" && s.cfg.Workspace != nil {
+		workspaceName = s.cfg.Workspace.Default
+	}
+	workspace, err := s.workspaces.GetByName(workspaceName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("workspace %s not found", workspaceName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+// findArticleNumber resolves an article number of the named workspace, the default one if the name is empty
+func (s *storage) findArticleNumber(workspaceName, number string) (uuid.UUID, error) {
+	workspace, err := s.findWorkspace(workspaceName)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	articleNumber, err := s.articles.GetByNumber(workspace.ID, number)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, fmt.Errorf("article number %s not found in %s", number, workspace.Name)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to find article number: %w", err)
+	}
+	return articleNumber.ID, nil
+}
+
+func printAuditLog(cmd *cobra.Command, entry *models.AuditLog) {
+	actor := "cli"
+	if entry.ActorID != uuid.Nil {
+		actor = fmt.Sprintf("%d", entry.ActorTelegramID)
+		if entry.ActorUsername != "" {
+			actor = "@" + entry.ActorUsername
+		}
+	}
+
+	var targets []string
+	if entry.PhotoID != uuid.Nil {
+		targets = append(targets, "photo="+entry.PhotoID.String())
+	}
+	if entry.ArticleNumberID != uuid.Nil {
+		targets = append(targets, "article="+entry.ArticleNumberID.String())
+	}
+	if entry.UserID != uuid.Nil {
+		targets = append(targets, "user="+entry.UserID.String())
+	}
+
+	cmd.Printf("%s  %-14s %-18s %s\n", entry.CreatedAt.Format(time.DateTime), actor, entry.Action, strings.Join(targets, " "))
+	if entry.Before != "" {
+		cmd.Printf("    before: %s\n", entry.Before)
+	}
+	if entry.After != "" {
+		cmd.Printf("    after:  %s\n", entry.After)
+	}
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Conty111/AlfredoBot/internal/app/initializers"
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+)
+
+// NewExportCmd exports photos of a workspace with a manifest of their article numbers
+func NewExportCmd() *cobra.Command {
+	var (
+		configPath string
+		workspace  string
+		user       string
+		since      string
+		until      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export <archive.zip|->",
+		Short: "Export photos of a workspace to a ZIP archive with a manifest",
+		Long: `Export photos of a workspace to a ZIP archive with a manifest.
+
+Photos are saved to the photos folder of the archive and named by their article
+numbers. manifest.csv and manifest.json list the article numbers, photo IDs,
+uploaders and upload times, manifest.csv can be imported back with "import".
+Pass "-" to write the archive to stdout.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			source, err := store.findWorkspace(workspace)
+			if err != nil {
+				return err
+			}
+
+			filter := models.PhotoFilter{WorkspaceID: source.ID}
+			if user != "" {
+				uploader, err := store.lookupUser(user)
+				if err != nil {
+					return err
+				}
+				filter.UserID = uploader.ID
+			}
+			if since != "" {
+				if filter.Since, err = catalog.ParseDate(since); err != nil {
+					return err
+				}
+			}
+			if until != "" {
+				if filter.Until, err = catalog.ParseDate(until); err != nil {
+					return err
+				}
+				// The last day is included
+				filter.Until = filter.Until.AddDate(0, 0, 1)
+			}
+
+			if store.photos.S3Client, err = initializers.InitializeS3Client(store.cfg); err != nil {
+				return fmt.Errorf("failed to create S3 client: %w", err)
+			}
+			if store.photos.S3Client == nil {
+				return fmt.Errorf("object storage is not configured")
+			}
+			var bucket string
+			if store.cfg.S3 != nil {
+				bucket = store.cfg.S3.Bucket
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			if args[0] != "-" {
+				file, err := os.Create(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to create archive: %w", err)
+				}
+				defer file.Close()
+				out = file
+			}
+
+			exporter := catalog.NewExporter(store.photos, store.users, bucket)
+			report, err := exporter.Export(context.Background(), out, filter)
+			if err != nil {
+				if args[0] != "-" {
+					_ = os.Remove(args[0])
+				}
+				return err
+			}
+
+			cmd.PrintErrf("%d photos exported, %d failed\n", len(report.Photos), len(report.Failed))
+			for _, id := range report.Failed {
+				cmd.PrintErrf("failed to read photo %s from the storage\n", id)
+			}
+			if len(report.Failed) > 0 {
+				return fmt.Errorf("%d photos failed to export", len(report.Failed))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file (default searches for config.yaml|json)")
+	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace to export (default is the configured default workspace)")
+	cmd.Flags().StringVar(&user, "user", "", "Only photos of the uploader, <telegram_id|@username>")
+	cmd.Flags().StringVar(&since, "since", "", "Only photos uploaded on the day or later, YYYY-MM-DD")
+	cmd.Flags().StringVar(&until, "until", "", "Only photos uploaded on the day or earlier, YYYY-MM-DD")
+
+	return cmd
+}
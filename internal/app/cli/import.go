@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Conty111/AlfredoBot/internal/app/initializers"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
+)
+
+// NewImportCmd imports an existing catalog of photos with their article numbers
+func NewImportCmd() *cobra.Command {
+	var (
+		configPath string
+		images     string
+		user       string
+		workspace  string
+		reportPath string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import <manifest.csv|manifest.xlsx|folder|archive.zip>",
+		Short: "Import photos of a catalog listed in a manifest with their article numbers",
+		Long: `Import photos of a catalog listed in a manifest with their article numbers.
+
+The manifest is a CSV or XLSX table with a header row. The "file" column names an
+image, the "article_numbers" column lists its article numbers separated by commas.
+A folder or ZIP archive is imported with the manifest.csv or manifest.xlsx inside it.
+Image paths are relative to the manifest unless --images points elsewhere.
+
+Files already in the workspace are recognized by their content, so an import may be
+run again: it only adds missing photos and article numbers.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rows, imagesFS, closeSource, err := openImport(args[0], images)
+			if err != nil {
+				return err
+			}
+			defer closeSource()
+
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			uploader, err := store.lookupUser(user)
+			if err != nil {
+				return err
+			}
+			target, err := store.findWorkspace(workspace)
+			if err != nil {
+				return err
+			}
+			if store.photos.S3Client, err = initializers.InitializeS3Client(store.cfg); err != nil {
+				return fmt.Errorf("failed to create S3 client: %w", err)
+			}
+			if store.photos.S3Client == nil {
+				return fmt.Errorf("object storage is not configured")
+			}
+
+			var bucket string
+			if store.cfg.S3 != nil {
+				bucket = store.cfg.S3.Bucket
+			}
+			importer := catalog.NewImporter(store.photos, store.articles, imaging.NewProcessor(store.cfg.Images), bucket)
+			report := importer.Import(context.Background(), imagesFS, rows, uploader.ID, target.ID)
+
+			out := cmd.OutOrStdout()
+			if reportPath != "" {
+				file, err := os.Create(reportPath)
+				if err != nil {
+					return fmt.Errorf("failed to create report: %w", err)
+				}
+				defer file.Close()
+				out = file
+			}
+			if err := report.WriteCSV(out); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+
+			cmd.PrintErrf("%d rows: %d imported, %d linked, %d unchanged, %d failed\n",
+				len(report.Rows),
+				report.Count(catalog.RowImported),
+				report.Count(catalog.RowLinked),
+				report.Count(catalog.RowUnchanged),
+				report.Count(catalog.RowFailed))
+			if failed := report.Count(catalog.RowFailed); failed > 0 {
+				return fmt.Errorf("%d rows failed to import", failed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file (default searches for config.yaml|json)")
+	cmd.Flags().StringVar(&images, "images", "", "Folder or ZIP archive with the images (default is next to the manifest)")
+	cmd.Flags().StringVar(&user, "user", "", "Uploader of the photos, <telegram_id|@username>")
+	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace to import to (default is the configured default workspace)")
+	cmd.Flags().StringVar(&reportPath, "report", "", "Write the per-row CSV report to the file instead of stdout")
+	_ = cmd.MarkFlagRequired("user")
+
+	return cmd
+}
+
+// openImport reads the manifest of the source and opens the images it names.
+// The returned function closes opened archives.
+func openImport(source, images string) ([]catalog.ManifestRow, fs.FS, func(), error) {
+	var closers []func()
+	closeAll := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+	fail := func(err error) ([]catalog.ManifestRow, fs.FS, func(), error) {
+		closeAll()
+		return nil, nil, nil, err
+	}
+	open := func(name string) (fs.FS, error) {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return os.DirFS(name), nil
+		}
+		if !strings.EqualFold(filepath.Ext(name), ".zip") {
+			return nil, fmt.Errorf("%s is neither a folder nor a ZIP archive", name)
+		}
+		archive, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		closers = append(closers, func() { _ = archive.Close() })
+		return archive, nil
+	}
+
+	var (
+		rows     []catalog.ManifestRow
+		imagesFS fs.FS
+	)
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".csv", ".xlsx":
+		file, err := os.Open(source)
+		if err != nil {
+			return fail(err)
+		}
+		rows, err = catalog.ReadManifest(source, file)
+		file.Close()
+		if err != nil {
+			return fail(err)
+		}
+		if images == "" {
+			images = filepath.Dir(source)
+		}
+	default:
+		sourceFS, err := open(source)
+		if err != nil {
+			return fail(err)
+		}
+		if rows, imagesFS, err = catalog.OpenCatalog(sourceFS); err != nil {
+			return fail(err)
+		}
+	}
+
+	if images != "" {
+		var err error
+		if imagesFS, err = open(images); err != nil {
+			return fail(err)
+		}
+	}
+	return rows, imagesFS, closeAll, nil
+}
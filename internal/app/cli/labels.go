@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/services/labels"
+)
+
+// NewLabelsCmd prints label sheets with QR codes opening article numbers in the bot
+func NewLabelsCmd() *cobra.Command {
+	var (
+		configPath  string
+		workspace   string
+		format      string
+		botUsername string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "labels <sheet.pdf|sheet.png|-> <article>...",
+		Short: "Print labels with QR codes opening article numbers in the bot",
+		Long: `Print labels with QR codes opening article numbers in the bot.
+
+Each label has a QR code of the t.me deep link starting the bot with the article
+number and the number itself, 3 by 8 labels fit an A4 sheet. The format is taken
+from the extension of the file unless --format is given. Pass "-" to write the
+sheet to stdout. The bot username is asked from Telegram unless --bot is given.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sheetFormat, err := labels.ParseFormat(format)
+			if format == "" && args[0] != "-" {
+				sheetFormat, err = labels.ParseFormat(strings.TrimPrefix(filepath.Ext(args[0]), "."))
+			}
+			if err != nil {
+				return err
+			}
+
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			if botUsername == "" {
+				if botUsername, err = getBotUsername(store.cfg); err != nil {
+					return err
+				}
+			}
+
+			var sheet []labels.Label
+			// Aliases of one article number get a single label
+			seen := make(map[uuid.UUID]bool)
+			for _, number := range args[1:] {
+				id, err := store.findArticleNumber(workspace, number)
+				if err != nil {
+					return err
+				}
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				articleNumber, err := store.articles.GetByID(id)
+				if err != nil {
+					return fmt.Errorf("failed to get article number %s: %w", number, err)
+				}
+				sheet = append(sheet, labels.Label{
+					Number: articleNumber.Number,
+					Link:   labels.DeepLink(strings.TrimPrefix(botUsername, "@"), id),
+				})
+			}
+
+			// The sheet is rendered before the file is created, so failures leave no broken files
+			var buf bytes.Buffer
+			if err := labels.Write(&buf, sheetFormat, sheet); err != nil {
+				return fmt.Errorf("failed to print labels: %w", err)
+			}
+			if args[0] == "-" {
+				_, err = cmd.OutOrStdout().Write(buf.Bytes())
+			} else {
+				err = os.WriteFile(args[0], buf.Bytes(), 0o644)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write labels: %w", err)
+			}
+
+			cmd.PrintErrf("%d labels printed\n", len(sheet))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file (default searches for config.yaml|json)")
+	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace of the article numbers (default is the configured default workspace)")
+	cmd.Flags().StringVar(&format, "format", "", "Sheet format, pdf or png (default is the file extension, then pdf)")
+	cmd.Flags().StringVar(&botUsername, "bot", "", "Username of the bot opened by the labels (default is asked from Telegram)")
+
+	return cmd
+}
+
+// getBotUsername asks Telegram for the username of the configured bot
+func getBotUsername(cfg *configs.Configuration) (string, error) {
+	if cfg.Telegram == nil || cfg.Telegram.Token == "" {
+		return "", fmt.Errorf("telegram token is not configured, pass the bot username with --bot")
+	}
+	opts := []bot.Option{bot.WithSkipGetMe()}
+	if cfg.Telegram.APIURL != "" {
+		opts = append(opts, bot.WithServerURL(strings.TrimSuffix(cfg.Telegram.APIURL, "/")))
+	}
+	b, err := bot.New(cfg.Telegram.Token, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Telegram client: %w", err)
+	}
+	me, err := b.GetMe(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("failed to get bot info: %w", err)
+	}
+	return me.Username, nil
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Conty111/AlfredoBot/internal/app/initializers"
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+)
+
+// NewLookbookCmd prints photos of a workspace to a PDF lookbook
+func NewLookbookCmd() *cobra.Command {
+	var (
+		configPath string
+		workspace  string
+		user       string
+		since      string
+		until      string
+		fields     []string
+		title      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lookbook <lookbook.pdf|-> [article]...",
+		Short: "Print photos of a workspace to a PDF lookbook",
+		Long: `Print photos of a workspace to a PDF lookbook.
+
+Each article number gets a heading and a grid of its photos captioned with their
+article numbers. Without article numbers every article number with photos is
+printed, ordered by number. --fields adds the codes of article numbers and the
+uploaders and upload days of photos. Pass "-" to write the lookbook to stdout.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			source, err := store.findWorkspace(workspace)
+			if err != nil {
+				return err
+			}
+
+			options := catalog.LookbookOptions{
+				Filter: models.PhotoFilter{WorkspaceID: source.ID},
+				Title:  title,
+			}
+			if options.Title == "" {
+				options.Title = source.Name
+			}
+			for _, name := range fields {
+				field, err := catalog.ParseLookbookField(name)
+				if err != nil {
+					return err
+				}
+				options.Fields = append(options.Fields, field)
+			}
+			for _, number := range args[1:] {
+				id, err := store.findArticleNumber(workspace, number)
+				if err != nil {
+					return err
+				}
+				options.ArticleNumberIDs = append(options.ArticleNumberIDs, id)
+			}
+			if user != "" {
+				uploader, err := store.lookupUser(user)
+				if err != nil {
+					return err
+				}
+				options.Filter.UserID = uploader.ID
+			}
+			if since != "" {
+				if options.Filter.Since, err = catalog.ParseDate(since); err != nil {
+					return err
+				}
+			}
+			if until != "" {
+				if options.Filter.Until, err = catalog.ParseDate(until); err != nil {
+					return err
+				}
+				// The last day is included
+				options.Filter.Until = options.Filter.Until.AddDate(0, 0, 1)
+			}
+
+			if store.photos.S3Client, err = initializers.InitializeS3Client(store.cfg); err != nil {
+				return fmt.Errorf("failed to create S3 client: %w", err)
+			}
+			if store.photos.S3Client == nil {
+				return fmt.Errorf("object storage is not configured")
+			}
+			var bucket string
+			if store.cfg.S3 != nil {
+				bucket = store.cfg.S3.Bucket
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			if args[0] != "-" {
+				file, err := os.Create(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to create lookbook: %w", err)
+				}
+				defer file.Close()
+				out = file
+			}
+
+			lookbook := catalog.NewLookbook(store.photos, store.articles, store.users, bucket)
+			report, err := lookbook.Generate(context.Background(), out, options)
+			if err == nil && report.ArticleNumbers == 0 {
+				err = fmt.Errorf("no photos for the lookbook")
+			}
+			if err != nil {
+				if args[0] != "-" {
+					_ = os.Remove(args[0])
+				}
+				return err
+			}
+
+			cmd.PrintErrf("%d article numbers with %d photos printed, %d photos failed\n",
+				report.ArticleNumbers, report.Photos, len(report.Failed))
+			for _, id := range report.Failed {
+				cmd.PrintErrf("failed to read photo %s from the storage\n", id)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file (default searches for config.yaml|json)")
+	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace to print (default is the configured default workspace)")
+	cmd.Flags().StringVar(&user, "user", "", "Only photos of the uploader, <telegram_id|@username>")
+	cmd.Flags().StringVar(&since, "since", "", "Only photos uploaded on the day or later, YYYY-MM-DD")
+	cmd.Flags().StringVar(&until, "until", "", "Only photos uploaded on the day or earlier, YYYY-MM-DD")
+	cmd.Flags().StringSliceVar(&fields, "fields", nil, "Optional fields: aliases, uploader, date")
+	cmd.Flags().StringVar(&title, "title", "", "Title of the first page (default is the workspace name)")
+
+	return cmd
+}
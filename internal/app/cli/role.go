@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// NewRoleCmd manages roles of bot users
+func NewRoleCmd() *cobra.Command {
+	var configPath string
+
+	cmd := &cobra.Command{
+		Use:   "role",
+		Short: "Manage user roles (" + strings.Join(models.TelegramUserRoles, ", ") + ")",
+	}
+	cmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to config file (default searches for config.yaml|json)")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "grant <telegram_id|@username> <role>",
+			Short: "Assign a role to a user, unknown Telegram IDs are registered in advance",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if !models.IsValidTelegramUserRole(args[1]) {
+					return fmt.Errorf("unknown role %q, expected one of: %s", args[1], strings.Join(models.TelegramUserRoles, ", "))
+				}
+				return setRole(cmd, configPath, args[0], args[1])
+			},
+		},
+		&cobra.Command{
+			Use:   "revoke <telegram_id|@username>",
+			Short: "Drop a user to the viewer role",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return setRole(cmd, configPath, args[0], models.TelegramUserRoleViewer)
+			},
+		},
+		&cobra.Command{
+			Use:   "list",
+			Short: "List users grouped by role",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				store, err := openStorage(configPath)
+				if err != nil {
+					return err
+				}
+				for _, role := range models.TelegramUserRoles {
+					users, err := store.users.GetUsersByRole(role)
+					if err != nil {
+						return fmt.Errorf("failed to list %s users: %w", role, err)
+					}
+					for _, user := range users {
+						cmd.Printf("%-8s %-12d @%s\n", role, user.TelegramID, user.Username)
+					}
+				}
+				return nil
+			},
+		},
+	)
+
+	return cmd
+}
+
+func setRole(cmd *cobra.Command, configPath, reference, role string) error {
+	store, err := openStorage(configPath)
+	if err != nil {
+		return err
+	}
+
+	user, err := store.findUser(reference)
+	telegramID, idErr := strconv.ParseInt(reference, 10, 64)
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound) && idErr == nil:
+		// Lets the first admin be set up before they ever write to the bot
+		user = &models.TelegramUser{
+			TelegramID: telegramID,
+			State:      models.TelegramUserStateDefault,
+			Role:       role,
+		}
+		if err := store.users.CreateUser(user); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		if err := store.joinDefaultWorkspace(user); err != nil {
+			return err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("user %s not found, use the Telegram ID if they have not written to the bot yet", reference)
+	case err != nil:
+		return fmt.Errorf("failed to find user: %w", err)
+	default:
+		if err := store.users.UpdateByID(user.ID, map[string]interface{}{"role": role}); err != nil {
+			return fmt.Errorf("failed to update role: %w", err)
+		}
+	}
+
+	log.Info().
+		Int64("telegram_id", user.TelegramID).
+		Str("role", role).
+		Msg("User role changed")
+	cmd.Printf("%s is now %s\n", reference, role)
+	return nil
+}
@@ -10,6 +10,15 @@ func ExecuteRootCmd() {
 	c := cobra.Command{}
 
 	c.AddCommand(NewServeCmd())
+	c.AddCommand(NewRoleCmd())
+	c.AddCommand(NewWorkspaceCmd())
+	c.AddCommand(NewAuditCmd())
+	c.AddCommand(NewTrashCmd())
+	c.AddCommand(NewImportCmd())
+	c.AddCommand(NewExportCmd())
+	c.AddCommand(NewLookbookCmd())
+	c.AddCommand(NewArticleCmd())
+	c.AddCommand(NewLabelsCmd())
 
 	if err := c.Execute(); err != nil {
 		log.Fatal().Err(err)
@@ -42,6 +42,18 @@ func NewServeCmd() *cobra.Command {
 			cliMode := false
 			application.Start(ctx, cliMode)
 
+			// SIGHUP reloads the bot content without a restart
+			hupchan := make(chan os.Signal, 1)
+			signal.Notify(hupchan, syscall.SIGHUP)
+
+			go func() {
+				for range hupchan {
+					if err := application.ReloadContent(); err != nil {
+						log.Error().Err(err).Msg("failed to reload content, keeping the previous one")
+					}
+				}
+			}()
+
 			log.Info().Msg("Started")
 			<-sigchan
 
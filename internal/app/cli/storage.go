@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Conty111/AlfredoBot/internal/app/initializers"
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/repositories"
+)
+
+// storage gives maintenance commands access to the database without starting the bot
+type storage struct {
+	cfg        *configs.Configuration
+	users      *repositories.TelegramUserRepository
+	workspaces *repositories.WorkspaceRepository
+	articles   *repositories.ArticleNumberRepository
+	photos     *repositories.PhotoRepository
+	audit      *repositories.AuditLogRepository
+}
+
+func openStorage(configPath string) (*storage, error) {
+	cfg, err := configs.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := initializers.InitializeLogs(*cfg.App); err != nil {
+		return nil, fmt.Errorf("failed to initialize logs: %w", err)
+	}
+
+	db := initializers.InitializeDatabase(cfg)
+	if err := initializers.InitializeMigrations(db); err != nil {
+		return nil, fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+	if cfg.Workspace != nil {
+		if err := initializers.InitializeDefaultWorkspace(db, cfg.Workspace.Default); err != nil {
+			return nil, fmt.Errorf("failed to initialize default workspace: %w", err)
+		}
+	}
+
+	return &storage{
+		cfg:        cfg,
+		users:      repositories.NewTelegramUserRepository(db),
+		workspaces: repositories.NewWorkspaceRepository(db),
+		articles:   repositories.NewArticleNumberRepository(db),
+		photos:     repositories.NewPhotoRepository(db, nil),
+		audit:      repositories.NewAuditLogRepository(db),
+	}, nil
+}
+
+// findUser looks a user up by "@username" or numeric Telegram ID
+func (s *storage) findUser(reference string) (*models.TelegramUser, error) {
+	if telegramID, err := strconv.ParseInt(reference, 10, 64); err == nil {
+		return s.users.GetByTelegramID(telegramID)
+	}
+	return s.users.GetByUsername(strings.TrimPrefix(reference, "@"))
+}
+
+// joinDefaultWorkspace adds a user registered from the command line to the default workspace
+func (s *storage) joinDefaultWorkspace(user *models.TelegramUser) error {
+	if s.cfg.Workspace == nil || s.cfg.Workspace.Default == "" {
+		return nil
+	}
+	workspace, err := s.workspaces.GetOrCreateWorkspace(s.cfg.Workspace.Default)
+	if err != nil {
+		return fmt.Errorf("failed to get default workspace: %w", err)
+	}
+	if err := s.workspaces.AddMember(workspace.ID, user.ID); err != nil {
+		return fmt.Errorf("failed to join default workspace: %w", err)
+	}
+	return s.users.UpdateByID(user.ID, map[string]interface{}{"active_workspace_id": workspace.ID})
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/Conty111/AlfredoBot/internal/app/initializers"
+	"github.com/Conty111/AlfredoBot/internal/services/trash"
+)
+
+// NewTrashCmd lists, restores and purges deleted photos and article numbers
+func NewTrashCmd() *cobra.Command {
+	var (
+		configPath string
+		workspace  string
+		olderThan  time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "List, restore and purge deleted photos and article numbers",
+	}
+	cmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to config file (default searches for config.yaml|json)")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List deleted photos and article numbers of a workspace, the last deleted first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			found, err := store.findWorkspace(workspace)
+			if err != nil {
+				return err
+			}
+
+			photos, err := store.photos.GetDeletedPhotos(found.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get deleted photos: %w", err)
+			}
+			for _, photo := range photos {
+				numbers := make([]string, 0, len(photo.ArticleNumbers))
+				for _, articleNumber := range photo.ArticleNumbers {
+					numbers = append(numbers, articleNumber.Number)
+				}
+				cmd.Printf("%s  photo    %s  %s\n",
+					photo.DeletedAt.Time.Format(time.DateTime), photo.ID, strings.Join(numbers, ", "))
+			}
+
+			articleNumbers, err := store.articles.GetDeletedArticleNumbers(found.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get deleted article numbers: %w", err)
+			}
+			for _, articleNumber := range articleNumbers {
+				cmd.Printf("%s  article  %s  %s\n",
+					articleNumber.DeletedAt.Time.Format(time.DateTime), articleNumber.ID, articleNumber.Number)
+			}
+			return nil
+		},
+	}
+	list.Flags().StringVar(&workspace, "workspace", "", "Workspace to list (default is the configured default workspace)")
+
+	restore := &cobra.Command{
+		Use:   "restore <photo|article> <id>",
+		Short: "Restore a deleted photo or article number",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := uuid.Parse(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid ID %q: %w", args[1], err)
+			}
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+
+			switch args[0] {
+			case "photo":
+				err = store.photos.RestorePhoto(id)
+			case "article":
+				err = store.articles.RestoreArticleNumber(id)
+			default:
+				return fmt.Errorf("unknown kind %q, expected photo or article", args[0])
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%s %s is not in the trash", args[0], id)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to restore %s: %w", args[0], err)
+			}
+			cmd.Printf("%s %s restored\n", args[0], id)
+			return nil
+		},
+	}
+
+	purge := &cobra.Command{
+		Use:   "purge",
+		Short: "Permanently delete items which stayed in the trash longer than the retention",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openStorage(configPath)
+			if err != nil {
+				return err
+			}
+			if olderThan <= 0 && store.cfg.Trash != nil {
+				olderThan = store.cfg.Trash.Retention
+			}
+			if olderThan <= 0 {
+				return fmt.Errorf("the trash is kept forever, pass --older-than to purge it")
+			}
+
+			if store.photos.S3Client, err = initializers.InitializeS3Client(store.cfg); err != nil {
+				return fmt.Errorf("failed to create S3 client: %w", err)
+			}
+			purger := trash.NewPurger(store.cfg, store.photos, store.articles)
+			photos, articleNumbers, err := purger.Purge(time.Now().Add(-olderThan))
+			cmd.Printf("purged %d photos and %d article numbers\n", photos, articleNumbers)
+			if err != nil {
+				return fmt.Errorf("failed to purge some items: %w", err)
+			}
+			return nil
+		},
+	}
+	purge.Flags().DurationVar(&olderThan, "older-than", 0, "Purge items deleted earlier than the duration ago (default is trash.retention)")
+
+	cmd.AddCommand(list, restore, purge)
+	return cmd
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// NewWorkspaceCmd manages workspaces and their members
+func NewWorkspaceCmd() *cobra.Command {
+	var configPath string
+
+	cmd := &cobra.Command{
+		Use:   "workspace",
+		Short: "Manage workspaces and their members",
+	}
+	cmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to config file (default searches for config.yaml|json)")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "create <name>",
+			Short: "Create a workspace",
+			Args:  cobra.MinimumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				store, err := openStorage(configPath)
+				if err != nil {
+					return err
+				}
+				name := strings.Join(args, " ")
+				if _, err := store.workspaces.GetOrCreateWorkspace(name); err != nil {
+					return fmt.Errorf("failed to create workspace: %w", err)
+				}
+				cmd.Printf("workspace %s is ready\n", name)
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "add <telegram_id|@username> <name>",
+			Short: "Add a user to a workspace",
+			Args:  cobra.MinimumNArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				store, err := openStorage(configPath)
+				if err != nil {
+					return err
+				}
+				user, workspace, err := store.membershipArgs(args)
+				if err != nil {
+					return err
+				}
+				if err := store.workspaces.AddMember(workspace, user); err != nil {
+					return fmt.Errorf("failed to add member: %w", err)
+				}
+				cmd.Printf("%s joined %s\n", args[0], strings.Join(args[1:], " "))
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "remove <telegram_id|@username> <name>",
+			Short: "Remove a user from a workspace",
+			Args:  cobra.MinimumNArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				store, err := openStorage(configPath)
+				if err != nil {
+					return err
+				}
+				user, workspace, err := store.membershipArgs(args)
+				if err != nil {
+					return err
+				}
+				if err := store.workspaces.RemoveMember(workspace, user); err != nil {
+					return fmt.Errorf("failed to remove member: %w", err)
+				}
+				// The bot activates another workspace of the user on their next message
+				if err := store.users.UpdateByID(user, map[string]interface{}{"active_workspace_id": uuid.Nil}); err != nil {
+					return fmt.Errorf("failed to reset active workspace: %w", err)
+				}
+				cmd.Printf("%s left %s\n", args[0], strings.Join(args[1:], " "))
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "list",
+			Short: "List workspaces with their members",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				store, err := openStorage(configPath)
+				if err != nil {
+					return err
+				}
+				workspaces, err := store.workspaces.GetAll()
+				if err != nil {
+					return fmt.Errorf("failed to list workspaces: %w", err)
+				}
+				for _, workspace := range workspaces {
+					members, err := store.workspaces.GetMembers(workspace.ID)
+					if err != nil {
+						return fmt.Errorf("failed to list members of %s: %w", workspace.Name, err)
+					}
+					cmd.Printf("%s\n", workspace.Name)
+					for _, member := range members {
+						cmd.Printf("  %-12d @%s\n", member.TelegramID, member.Username)
+					}
+				}
+				return nil
+			},
+		},
+	)
+
+	return cmd
+}
+
+// membershipArgs resolves "<user> <workspace name>" into user and workspace IDs
+func (s *storage) membershipArgs(args []string) (uuid.UUID, uuid.UUID, error) {
+	user, err := s.findUser(args[0])
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("user %s not found", args[0])
+	}
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	name := strings.Join(args[1:], " ")
+	workspace, err := s.workspaces.GetByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("workspace %s not found", name)
+	}
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to find workspace: %w", err)
+	}
+	return user.ID, workspace.ID, nil
+}
@@ -13,5 +13,10 @@ type Container struct {
 	TelegramUserRepository  interfaces.TelegramUserManager
 	PhotoRepository         interfaces.PhotoManager
 	ArticleNumberRepository interfaces.ArticleNumberManager
+	InviteCodeRepository    interfaces.InviteCodeManager
+	WorkspaceRepository     interfaces.WorkspaceManager
+	ChatRepository          interfaces.ChatManager
+	SupportTicketRepository interfaces.SupportTicketManager
+	AuditLogRepository      interfaces.AuditLogManager
 	S3Client                interfaces.S3Client
 }
@@ -1,8 +1,11 @@
 package initializers
 
 import (
+	"fmt"
+
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/rs/zerolog/log"
 
@@ -23,15 +26,108 @@ func InitializeDatabase(cfg *configs.Configuration) *gorm.DB {
 
 func InitializeMigrations(db *gorm.DB) error {
 
+	// Article numbers used to be unique across the whole database,
+	// now they are unique within a workspace
+	if db.Migrator().HasIndex(&models.ArticleNumber{}, "idx_article_numbers_number") {
+		if err := db.Migrator().DropIndex(&models.ArticleNumber{}, "idx_article_numbers_number"); err != nil {
+			log.Fatal().Err(err).Msg("failed to drop global article number index")
+			return err
+		}
+	}
+
 	err := db.AutoMigrate(
 		&models.TelegramUser{},
 		&models.Photo{},
 		&models.ArticleNumber{},
 		&models.ArticleNumberPhoto{},
+		&models.ArticleAlias{},
+		&models.InviteCode{},
+		&models.Workspace{},
+		&models.WorkspaceMember{},
+		&models.ChatState{},
+		&models.GroupChat{},
+		&models.SupportTicket{},
+		&models.SupportMessage{},
+		&models.AuditLog{},
 	)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to run migrations")
 		return err
 	}
+
+	if err := migrateChatStates(db); err != nil {
+		log.Fatal().Err(err).Msg("failed to migrate user states")
+		return err
+	}
+	return nil
+}
+
+// migrateChatStates moves dialog states and pending photos kept per user into
+// their private chats, the ID of a private chat equals the Telegram ID of the user
+func migrateChatStates(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE photos SET chat_id = (
+			SELECT telegram_users.telegram_id FROM telegram_users WHERE telegram_users.id = photos.user_id
+		) WHERE chat_id IS NULL`).Error; err != nil {
+			return err
+		}
+
+		var users []models.TelegramUser
+		if err := tx.Where("state NOT IN ?", []string{"", models.TelegramUserStateDefault}).Find(&users).Error; err != nil {
+			return err
+		}
+		for _, user := range users {
+			state := &models.ChatState{ChatID: user.TelegramID, TelegramID: user.TelegramID, State: user.State}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(state).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&user).Update("state", models.TelegramUserStateDefault).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// InitializeDefaultWorkspace creates the workspace new users join and moves
+// photos and article numbers created before workspaces existed into it
+func InitializeDefaultWorkspace(db *gorm.DB, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	workspace := &models.Workspace{}
+	tx := db.Where(models.Workspace{Name: name}).FirstOrCreate(workspace)
+	if tx.Error != nil {
+		return fmt.Errorf("failed to create default workspace: %w", tx.Error)
+	}
+
+	// Users registered before workspaces existed join the default one once,
+	// later removals from it must stick
+	if tx.RowsAffected > 0 {
+		err := db.Exec(
+			"INSERT INTO workspace_members (workspace_id, telegram_user_id) "+
+				"SELECT ?, id FROM telegram_users WHERE deleted_at IS NULL",
+			workspace.ID,
+		).Error
+		if err != nil {
+			return fmt.Errorf("failed to add users to default workspace: %w", err)
+		}
+	}
+
+	for _, model := range []interface{}{&models.Photo{}, &models.ArticleNumber{}} {
+		tx := db.Model(model).
+			Where("workspace_id IS NULL").
+			Update("workspace_id", workspace.ID)
+		if tx.Error != nil {
+			return fmt.Errorf("failed to move data into default workspace: %w", tx.Error)
+		}
+		if tx.RowsAffected > 0 {
+			log.Info().
+				Str("workspace", name).
+				Int64("rows", tx.RowsAffected).
+				Msgf("Moved %T rows into default workspace", model)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,28 @@
+package initializers
+
+import (
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/services/localstorage"
+	"github.com/Conty111/AlfredoBot/internal/services/s3"
+)
+
+// InitializeS3Client creates a new object storage client for the configured backend
+func InitializeS3Client(cfg *configs.Configuration) (interfaces.S3Client, error) {
+	if cfg.Storage != nil && cfg.Storage.Backend == configs.StorageBackendLocal {
+		return localstorage.NewClient(cfg.Storage.Local)
+	}
+
+	if cfg.S3 == nil {
+		return nil, nil
+	}
+
+	// Create the S3 client
+	s3Client, err := s3.NewClient(cfg.S3)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrap it in the S3Client interface implementation
+	return s3.NewS3Client(s3Client), nil
+}
@@ -10,9 +10,7 @@ import (
 	"github.com/Conty111/AlfredoBot/internal/app/dependencies"
 	"github.com/Conty111/AlfredoBot/internal/app/initializers"
 	"github.com/Conty111/AlfredoBot/internal/configs"
-	"github.com/Conty111/AlfredoBot/internal/interfaces"
 	"github.com/Conty111/AlfredoBot/internal/repositories"
-	"github.com/Conty111/AlfredoBot/internal/services/s3"
 )
 
 // Injectors from wire.go:
@@ -21,7 +19,7 @@ func BuildApplication(cfg *configs.Configuration) (*Application, error) {
 	db := initializers.InitializeDatabase(cfg)
 	info := initializers.InitializeBuildInfo()
 	telegramUserRepository := repositories.NewTelegramUserRepository(db)
-	s3Client, err := provideS3Client(cfg)
+	s3Client, err := initializers.InitializeS3Client(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -30,12 +28,22 @@ func BuildApplication(cfg *configs.Configuration) (*Application, error) {
 		S3Client: s3Client,
 	}
 	articleNumberRepository := repositories.NewArticleNumberRepository(db)
+	inviteCodeRepository := repositories.NewInviteCodeRepository(db)
+	workspaceRepository := repositories.NewWorkspaceRepository(db)
+	chatRepository := repositories.NewChatRepository(db)
+	supportTicketRepository := repositories.NewSupportTicketRepository(db)
+	auditLogRepository := repositories.NewAuditLogRepository(db)
 	container := &dependencies.Container{
 		BuildInfo:               info,
 		Config:                  cfg,
 		TelegramUserRepository:  telegramUserRepository,
 		PhotoRepository:         photoRepository,
 		ArticleNumberRepository: articleNumberRepository,
+		InviteCodeRepository:    inviteCodeRepository,
+		WorkspaceRepository:     workspaceRepository,
+		ChatRepository:          chatRepository,
+		SupportTicketRepository: supportTicketRepository,
+		AuditLogRepository:      auditLogRepository,
 		S3Client:                s3Client,
 	}
 	application := &Application{
@@ -44,19 +52,3 @@ func BuildApplication(cfg *configs.Configuration) (*Application, error) {
 	}
 	return application, nil
 }
-
-// wire.go:
-
-// provideS3Client creates a new S3 client
-func provideS3Client(cfg *configs.Configuration) (interfaces.S3Client, error) {
-	if cfg.S3 == nil {
-		return nil, nil
-	}
-
-	s3Client, err := s3.NewClient(cfg.S3)
-	if err != nil {
-		return nil, err
-	}
-
-	return s3.NewS3Client(s3Client), nil
-}
@@ -1,13 +1,27 @@
 package configs
 
+import "time"
+
 // Configuration contains all application configurations
 type Configuration struct {
-	App      *App            `mapstructure:"app"`
-	DB       *DatabaseConfig `mapstructure:"db"`
-	Telegram *TelegramConfig `mapstructure:"telegram"`
-	S3       *S3Config       `mapstructure:"s3"`
+	App       *App             `mapstructure:"app"`
+	DB        *DatabaseConfig  `mapstructure:"db"`
+	Telegram  *TelegramConfig  `mapstructure:"telegram"`
+	S3        *S3Config        `mapstructure:"s3"`
+	Storage   *StorageConfig   `mapstructure:"storage"`
+	Share     *ShareConfig     `mapstructure:"share"`
+	Access    *AccessConfig    `mapstructure:"access"`
+	Workspace *WorkspaceConfig `mapstructure:"workspace"`
+	Content   *ContentConfig   `mapstructure:"content"`
+	Support   *SupportConfig   `mapstructure:"support"`
+	Trash     *TrashConfig     `mapstructure:"trash"`
+	Images    *ImagesConfig    `mapstructure:"images"`
+	Watermark *WatermarkConfig `mapstructure:"watermark"`
 }
 
+// EnvironmentProduction is the app.environment value of production deployments
+const EnvironmentProduction = "production"
+
 // App contains application configuration
 type App struct {
 	Name        string `mapstructure:"name"`
@@ -33,6 +47,9 @@ type DatabaseConfig struct {
 type TelegramConfig struct {
 	Token      string `mapstructure:"token"`
 	TokenFile  string `mapstructure:"token_file"`
+	APIURL     string `mapstructure:"api_url"`
+	FileURL    string `mapstructure:"file_url"`
+	LocalMode  bool   `mapstructure:"local_mode"`
 	Timeout    int    `mapstructure:"timeout"`
 	WebhookURL string `mapstructure:"webhook_url"`
 	UseWebhook bool   `mapstructure:"use_webhook"`
@@ -49,6 +66,140 @@ type S3Config struct {
 	SecretAccessKeyFile string `mapstructure:"secret_access_key_file"`
 	Bucket              string `mapstructure:"bucket"`
 	UseSSL              bool   `mapstructure:"use_ssl"`
+	// CAFile is a PEM bundle of extra CAs trusted besides the system pool
+	CAFile string `mapstructure:"ca_file"`
+	// CertFile and KeyFile are a client certificate for mutual TLS
+	CertFile   string `mapstructure:"cert_file"`
+	KeyFile    string `mapstructure:"key_file"`
+	ServerName string `mapstructure:"server_name"`
+	// InsecureSkipVerify disables certificate checks, refused in production
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"`
+}
+
+// Storage backends
+const (
+	StorageBackendS3    = "s3"
+	StorageBackendLocal = "local"
+)
+
+// StorageConfig selects the object storage backend
+type StorageConfig struct {
+	Backend string              `mapstructure:"backend"`
+	Local   *LocalStorageConfig `mapstructure:"local"`
+}
+
+// LocalStorageConfig contains filesystem storage configuration
+type LocalStorageConfig struct {
+	Root           string `mapstructure:"root"`
+	ListenAddr     string `mapstructure:"listen_addr"`
+	PublicURL      string `mapstructure:"public_url"`
+	SigningKey     string `mapstructure:"signing_key"`
+	SigningKeyFile string `mapstructure:"signing_key_file"`
+}
+
+// ShareConfig contains settings of photo share links
+type ShareConfig struct {
+	// LinkTTL is how long presigned share links stay valid
+	LinkTTL time.Duration `mapstructure:"link_ttl"`
+}
+
+// Access policies
+const (
+	// AccessPolicyOpen registers everyone who writes to the bot
+	AccessPolicyOpen = "open"
+	// AccessPolicyWhitelist admits only whitelisted and pre-registered Telegram IDs
+	AccessPolicyWhitelist = "whitelist"
+	// AccessPolicyInviteOnly admits whitelisted users and holders of invite codes
+	AccessPolicyInviteOnly = "invite_only"
+)
+
+// AccessConfig contains access control settings
+type AccessConfig struct {
+	Policy string `mapstructure:"policy"`
+	// DefaultRole is assigned to users on their first message
+	DefaultRole string `mapstructure:"default_role"`
+	// Whitelist contains Telegram IDs admitted under restricted policies
+	Whitelist []int64 `mapstructure:"whitelist"`
+}
+
+// WorkspaceConfig contains settings of workspaces
+type WorkspaceConfig struct {
+	// Default is the workspace users without any membership join, empty disables auto-joining
+	Default string `mapstructure:"default"`
+}
+
+// ContentConfig points to the help text, support contacts and menu layout of the bot
+type ContentConfig struct {
+	// File is a YAML file with the content, empty to use the built-in one.
+	// It is read again on SIGHUP and on the /reload_content command.
+	File string `mapstructure:"file"`
+}
+
+// SupportConfig contains settings of the support ticket relay
+type SupportConfig struct {
+	// ChatID is the staff group receiving messages of users, 0 keeps the support button static
+	ChatID int64 `mapstructure:"chat_id"`
+	// ThreadID is the forum topic of the staff group for tickets, 0 for the general one
+	ThreadID int `mapstructure:"thread_id"`
+}
+
+// TrashConfig contains settings of the trash of deleted photos and article numbers
+type TrashConfig struct {
+	// Retention is how long deleted records and their objects are kept, 0 keeps them forever
+	Retention time.Duration `mapstructure:"retention"`
+	// PurgeInterval is how often records older than Retention are deleted permanently
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
+}
+
+// Formats of images.format
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+	// ImageFormatOriginal keeps the format of the uploaded file
+	ImageFormatOriginal = "original"
+)
+
+// ImagesConfig contains settings of the normalization of uploaded images before storage
+type ImagesConfig struct {
+	// Format is the format photos are stored in: jpeg, png or original
+	Format string `mapstructure:"format"`
+	// Quality is the JPEG quality from 1 to 100
+	Quality int `mapstructure:"quality"`
+	// MaxDimension is the longest side in pixels, larger photos are downscaled, 0 keeps their size
+	MaxDimension int `mapstructure:"max_dimension"`
+	// MaxFileSize is the largest accepted file in bytes, 0 accepts any size
+	MaxFileSize int64 `mapstructure:"max_file_size"`
+	// StripMetadata drops EXIF data such as GPS coordinates and camera details
+	StripMetadata bool `mapstructure:"strip_metadata"`
+}
+
+// Positions of watermark.position
+const (
+	WatermarkTopLeft     = "top-left"
+	WatermarkTopRight    = "top-right"
+	WatermarkBottomLeft  = "bottom-left"
+	WatermarkBottomRight = "bottom-right"
+	WatermarkCenter      = "center"
+)
+
+// WatermarkPositions lists all positions of the watermark
+var WatermarkPositions = []string{
+	WatermarkTopLeft, WatermarkTopRight, WatermarkBottomLeft, WatermarkBottomRight, WatermarkCenter,
+}
+
+// WatermarkConfig contains settings of the watermark put on photos sent to users
+type WatermarkConfig struct {
+	// Image is the path of the watermark image, PNG with transparency works best. Empty disables watermarks
+	Image string `mapstructure:"image"`
+	// Position is the corner or the center of the photo the watermark is put at
+	Position string `mapstructure:"position"`
+	// Opacity of the watermark from 0 to 1
+	Opacity float64 `mapstructure:"opacity"`
+	// Scale is the width of the watermark as a share of the photo width, from 0 to 1
+	Scale float64 `mapstructure:"scale"`
+	// Roles get watermarked photos in private chats, other roles get the originals.
+	// Groups get watermarked photos while Roles is not empty
+	Roles []string `mapstructure:"roles"`
 }
 
 // GetConfig loads configuration using default path
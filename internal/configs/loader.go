@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -73,6 +75,14 @@ func LoadConfig(configPath string) (*Configuration, error) {
 		}
 	}
 
+	if cfg.Storage != nil && cfg.Storage.Local != nil && cfg.Storage.Local.SigningKeyFile != "" {
+		data, err := os.ReadFile(cfg.Storage.Local.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local storage signing key file: %w", err)
+		}
+		cfg.Storage.Local.SigningKey = string(data)
+	}
+
 	if cfg.Telegram != nil && cfg.Telegram.TokenFile != "" {
 		data, err := os.ReadFile(cfg.Telegram.TokenFile)
 		if err != nil {
@@ -81,6 +91,10 @@ func LoadConfig(configPath string) (*Configuration, error) {
 		cfg.Telegram.Token = string(data)
 	}
 
+	if err := validate(cfg); err != nil {
+		return nil, err
+	}
+
 	// Generate DSN for database
 	cfg.DB.DSN = fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
@@ -91,6 +105,62 @@ func LoadConfig(configPath string) (*Configuration, error) {
 	return cfg, nil
 }
 
+// validate checks settings which are not safe to combine
+func validate(cfg *Configuration) error {
+	production := cfg.App != nil && cfg.App.Environment == EnvironmentProduction
+
+	if cfg.S3 != nil && cfg.S3.InsecureSkipVerify && production {
+		return fmt.Errorf("s3.insecure_skip_verify is not allowed in production, configure s3.ca_file instead")
+	}
+	if cfg.S3 != nil && (cfg.S3.CertFile == "") != (cfg.S3.KeyFile == "") {
+		return fmt.Errorf("s3.cert_file and s3.key_file must be set together")
+	}
+	if cfg.Access != nil {
+		switch cfg.Access.Policy {
+		case AccessPolicyOpen, AccessPolicyWhitelist, AccessPolicyInviteOnly:
+		default:
+			return fmt.Errorf("unknown access.policy %q, expected one of: %s, %s, %s",
+				cfg.Access.Policy, AccessPolicyOpen, AccessPolicyWhitelist, AccessPolicyInviteOnly)
+		}
+	}
+	if cfg.Storage != nil {
+		switch cfg.Storage.Backend {
+		case StorageBackendS3, StorageBackendLocal:
+		default:
+			return fmt.Errorf("unknown storage.backend %q, expected one of: %s, %s",
+				cfg.Storage.Backend, StorageBackendS3, StorageBackendLocal)
+		}
+	}
+	if cfg.Images != nil {
+		switch cfg.Images.Format {
+		case ImageFormatJPEG, ImageFormatPNG, ImageFormatOriginal:
+		default:
+			return fmt.Errorf("unknown images.format %q, expected one of: %s, %s, %s",
+				cfg.Images.Format, ImageFormatJPEG, ImageFormatPNG, ImageFormatOriginal)
+		}
+		if cfg.Images.Quality < 1 || cfg.Images.Quality > 100 {
+			return fmt.Errorf("images.quality must be from 1 to 100, got %d", cfg.Images.Quality)
+		}
+		if cfg.Images.MaxDimension < 0 || cfg.Images.MaxFileSize < 0 {
+			return fmt.Errorf("images.max_dimension and images.max_file_size must not be negative")
+		}
+	}
+	if cfg.Watermark != nil && cfg.Watermark.Image != "" {
+		if !slices.Contains(WatermarkPositions, cfg.Watermark.Position) {
+			return fmt.Errorf("unknown watermark.position %q, expected one of: %s",
+				cfg.Watermark.Position, strings.Join(WatermarkPositions, ", "))
+		}
+		if cfg.Watermark.Opacity <= 0 || cfg.Watermark.Opacity > 1 {
+			return fmt.Errorf("watermark.opacity must be above 0 and at most 1, got %g", cfg.Watermark.Opacity)
+		}
+		if cfg.Watermark.Scale <= 0 || cfg.Watermark.Scale > 1 {
+			return fmt.Errorf("watermark.scale must be above 0 and at most 1, got %g", cfg.Watermark.Scale)
+		}
+	}
+
+	return nil
+}
+
 func setDefaults(v *viper.Viper) {
 	// App defaults
 	v.SetDefault("app.name", "AlfredoBot")
@@ -107,6 +177,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("db.sslmode", "disable")
 
 	// Telegram defaults
+	v.SetDefault("telegram.api_url", "https://api.telegram.org")
+	v.SetDefault("telegram.local_mode", false)
 	v.SetDefault("telegram.timeout", 60)
 	v.SetDefault("telegram.use_webhook", false)
 
@@ -115,6 +187,41 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("s3.region", "")
 	v.SetDefault("s3.bucket", "")
 	v.SetDefault("s3.use_ssl", false)
+	v.SetDefault("s3.insecure_skip_verify", false)
+
+	// Share defaults
+	v.SetDefault("share.link_ttl", "24h")
+
+	// Access defaults
+	v.SetDefault("access.policy", AccessPolicyOpen)
+	v.SetDefault("access.default_role", "uploader")
+
+	// Workspace defaults
+	v.SetDefault("workspace.default", "default")
+
+	// Storage defaults
+	v.SetDefault("storage.backend", "s3")
+	v.SetDefault("storage.local.root", "data/storage")
+	v.SetDefault("storage.local.listen_addr", ":8081")
+	v.SetDefault("storage.local.public_url", "http://localhost:8081")
+
+	// Trash defaults
+	v.SetDefault("trash.retention", "720h")
+	v.SetDefault("trash.purge_interval", "1h")
+
+	// Images defaults
+	v.SetDefault("images.format", ImageFormatJPEG)
+	v.SetDefault("images.quality", 85)
+	v.SetDefault("images.max_dimension", 2560)
+	v.SetDefault("images.max_file_size", 20<<20)
+	v.SetDefault("images.strip_metadata", true)
+
+	// Watermark defaults
+	v.SetDefault("watermark.image", "")
+	v.SetDefault("watermark.position", WatermarkBottomRight)
+	v.SetDefault("watermark.opacity", 0.5)
+	v.SetDefault("watermark.scale", 0.25)
+	v.SetDefault("watermark.roles", []string{"viewer"})
 }
 
 // bindEnv explicitly binds environment variables to config fields
@@ -146,6 +253,9 @@ func bindEnv(v *viper.Viper) error {
 
 	// Telegram config bindings
 	bind("telegram.token", "TELEGRAM_TOKEN")
+	bind("telegram.api_url", "TELEGRAM_API_URL")
+	bind("telegram.file_url", "TELEGRAM_FILE_URL")
+	bind("telegram.local_mode", "TELEGRAM_LOCAL_MODE")
 	bind("telegram.timeout", "TELEGRAM_TIMEOUT")
 	bind("telegram.webhook_url", "TELEGRAM_WEBHOOK_URL")
 	bind("telegram.use_webhook", "TELEGRAM_USE_WEBHOOK")
@@ -157,6 +267,54 @@ func bindEnv(v *viper.Viper) error {
 	bind("s3.secret_access_key", "S3_SECRET_ACCESS_KEY")
 	bind("s3.bucket", "S3_BUCKET")
 	bind("s3.use_ssl", "S3_USE_SSL")
+	bind("s3.ca_file", "S3_CA_FILE")
+	bind("s3.cert_file", "S3_CERT_FILE")
+	bind("s3.key_file", "S3_KEY_FILE")
+	bind("s3.server_name", "S3_SERVER_NAME")
+	bind("s3.insecure_skip_verify", "S3_INSECURE_SKIP_VERIFY")
+
+	// Share config bindings
+	bind("share.link_ttl", "SHARE_LINK_TTL")
+
+	// Access config bindings
+	bind("access.policy", "ACCESS_POLICY")
+	bind("access.default_role", "ACCESS_DEFAULT_ROLE")
+	bind("access.whitelist", "ACCESS_WHITELIST")
+
+	// Workspace config bindings
+	bind("workspace.default", "WORKSPACE_DEFAULT")
+
+	// Content config bindings
+	bind("content.file", "CONTENT_FILE")
+
+	// Support config bindings
+	bind("support.chat_id", "SUPPORT_CHAT_ID")
+	bind("support.thread_id", "SUPPORT_THREAD_ID")
+
+	// Trash config bindings
+	bind("trash.retention", "TRASH_RETENTION")
+	bind("trash.purge_interval", "TRASH_PURGE_INTERVAL")
+
+	// Images config bindings
+	bind("images.format", "IMAGES_FORMAT")
+	bind("images.quality", "IMAGES_QUALITY")
+	bind("images.max_dimension", "IMAGES_MAX_DIMENSION")
+	bind("images.max_file_size", "IMAGES_MAX_FILE_SIZE")
+	bind("images.strip_metadata", "IMAGES_STRIP_METADATA")
+
+	// Watermark config bindings
+	bind("watermark.image", "WATERMARK_IMAGE")
+	bind("watermark.position", "WATERMARK_POSITION")
+	bind("watermark.opacity", "WATERMARK_OPACITY")
+	bind("watermark.scale", "WATERMARK_SCALE")
+	bind("watermark.roles", "WATERMARK_ROLES")
+
+	// Storage config bindings
+	bind("storage.backend", "STORAGE_BACKEND")
+	bind("storage.local.root", "STORAGE_LOCAL_ROOT")
+	bind("storage.local.listen_addr", "STORAGE_LOCAL_LISTEN_ADDR")
+	bind("storage.local.public_url", "STORAGE_LOCAL_PUBLIC_URL")
+	bind("storage.local.signing_key", "STORAGE_LOCAL_SIGNING_KEY")
 
 	if len(errs) > 0 {
 		return fmt.Errorf("environment binding errors: %v", errs)
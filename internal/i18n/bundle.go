@@ -0,0 +1,240 @@
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLocale is used for users whose language has no bundle
+const DefaultLocale = "ru"
+
+//go:embed locales/*.yaml
+var locales embed.FS
+
+// Bundle holds messages of all locales.
+// A message is either a plain string or a set of plural forms.
+type Bundle struct {
+	defaultLocale string
+	messages      map[string]map[string]message
+}
+
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+var loadDefault = sync.OnceValues(func() (*Bundle, error) {
+	fsys, err := fs.Sub(locales, "locales")
+	if err != nil {
+		return nil, err
+	}
+	return Load(fsys, DefaultLocale)
+})
+
+// Default returns the bundle embedded into the binary
+func Default() *Bundle {
+	bundle, err := loadDefault()
+	if err != nil {
+		panic(fmt.Sprintf("i18n: broken embedded bundle: %v", err))
+	}
+	return bundle
+}
+
+// Load reads <locale>.yaml files from the root of fsys.
+// Nested keys are joined with dots, so "search: {done: ...}" is the "search.done" message.
+func Load(fsys fs.FS, defaultLocale string) (*Bundle, error) {
+	files, err := fs.Glob(fsys, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{
+		defaultLocale: defaultLocale,
+		messages:      map[string]map[string]message{},
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var tree map[string]interface{}
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+
+		messages := map[string]message{}
+		if err := flatten("", tree, messages); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		bundle.messages[strings.TrimSuffix(path.Base(file), ".yaml")] = messages
+	}
+
+	if _, ok := bundle.messages[defaultLocale]; !ok {
+		return nil, fmt.Errorf("no messages for the default locale %q", defaultLocale)
+	}
+	return bundle, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, messages map[string]message) error {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch value := value.(type) {
+		case string:
+			messages[key] = message{text: value}
+		case map[string]interface{}:
+			if forms, ok := pluralForms(value); ok {
+				messages[key] = message{plural: forms}
+				continue
+			}
+			if err := flatten(key, value, messages); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("message %s must be a string, got %T", key, value)
+		}
+	}
+	return nil
+}
+
+// pluralForms recognizes a map of plural categories with at least the "other" form
+func pluralForms(tree map[string]interface{}) (map[string]string, bool) {
+	if _, ok := tree[pluralOther]; !ok {
+		return nil, false
+	}
+	forms := map[string]string{}
+	for category, value := range tree {
+		text, ok := value.(string)
+		if !ok || !isPluralCategory(category) {
+			return nil, false
+		}
+		forms[category] = text
+	}
+	return forms, true
+}
+
+// Locales returns locales of the bundle, the default one first
+func (b *Bundle) Locales() []string {
+	locales := make([]string, 0, len(b.messages))
+	for locale := range b.messages {
+		if locale != b.defaultLocale {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales)
+	return append([]string{b.defaultLocale}, locales...)
+}
+
+// DefaultLocale returns the locale used when no other matches
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// Keys returns keys of all messages of the locale
+func (b *Bundle) Keys(locale string) []string {
+	keys := make([]string, 0, len(b.messages[locale]))
+	for key := range b.messages[locale] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Match picks the locale for a Telegram language code like "en" or "pt-br".
+// The first of the codes with a bundle wins, so an explicit preference may go first.
+func (b *Bundle) Match(languageCodes ...string) string {
+	for _, code := range languageCodes {
+		code = strings.ToLower(code)
+		if _, ok := b.messages[code]; ok {
+			return code
+		}
+		language, _, _ := strings.Cut(code, "-")
+		if _, ok := b.messages[language]; ok {
+			return language
+		}
+	}
+	return b.defaultLocale
+}
+
+// Localizer returns messages of the locale matching the language codes
+func (b *Bundle) Localizer(languageCodes ...string) *Localizer {
+	return &Localizer{bundle: b, locale: b.Match(languageCodes...)}
+}
+
+// All returns distinct translations of a plain message in all locales
+func (b *Bundle) All(key string) []string {
+	var texts []string
+	for _, locale := range b.Locales() {
+		text := b.Localizer(locale).T(key)
+		if !slices.Contains(texts, text) {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// Localizer formats messages of one locale
+type Localizer struct {
+	bundle *Bundle
+	locale string
+}
+
+// Locale returns the locale of the messages
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// T returns the message formatted with fmt verbs.
+// Messages missing in the locale are taken from the default locale, unknown keys are returned as is.
+func (l *Localizer) T(key string, args ...interface{}) string {
+	message, ok := l.lookup(key)
+	if !ok {
+		return key
+	}
+	text := message.text
+	if message.plural != nil {
+		text = message.plural[pluralOther]
+	}
+	return format(text, args)
+}
+
+// N returns the plural form of the message for count formatted with fmt verbs.
+// The count is not added to args, pass it explicitly where the message shows it.
+func (l *Localizer) N(key string, count int, args ...interface{}) string {
+	message, ok := l.lookup(key)
+	if !ok {
+		return key
+	}
+	if message.plural == nil {
+		return format(message.text, args)
+	}
+	text, ok := message.plural[pluralCategory(l.locale, count)]
+	if !ok {
+		text = message.plural[pluralOther]
+	}
+	return format(text, args)
+}
+
+func (l *Localizer) lookup(key string) (message, bool) {
+	if message, ok := l.bundle.messages[l.locale][key]; ok {
+		return message, true
+	}
+	message, ok := l.bundle.messages[l.bundle.defaultLocale][key]
+	return message, ok
+}
+
+func format(text string, args []interface{}) string {
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
@@ -0,0 +1,114 @@
+package i18n_test
+
+import (
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/i18n"
+)
+
+var _ = Describe("Bundle", func() {
+	Describe("embedded bundles", func() {
+		bundle := i18n.Default()
+
+		It("should ship ru and en", func() {
+			Expect(bundle.Locales()).To(Equal([]string{"ru", "en"}))
+		})
+
+		It("should translate every message of the default locale", func() {
+			for _, locale := range bundle.Locales() {
+				Expect(bundle.Keys(locale)).To(Equal(bundle.Keys(i18n.DefaultLocale)), locale)
+			}
+		})
+
+		It("should keep fmt verbs of translations in line", func() {
+			ru := bundle.Localizer("ru")
+			for _, locale := range bundle.Locales() {
+				localizer := bundle.Localizer(locale)
+				for _, key := range bundle.Keys(locale) {
+					Expect(verbs(localizer.T(key))).To(Equal(verbs(ru.T(key))), locale+": "+key)
+				}
+			}
+		})
+	})
+
+	Describe("Localizer", func() {
+		var bundle *i18n.Bundle
+
+		BeforeEach(func() {
+			var err error
+			bundle, err = i18n.Load(fstest.MapFS{
+				"ru.yaml": {Data: []byte(`
+greeting: "Привет, %s!"
+articles:
+  count:
+    one: "%d артикул"
+    few: "%d артикула"
+    many: "%d артикулов"
+    other: "%d артикула"
+only_ru: "Только по-русски"
+`)},
+				"en.yaml": {Data: []byte(`
+greeting: "Hi, %s!"
+articles:
+  count:
+    one: "%d article"
+    other: "%d articles"
+`)},
+			}, "ru")
+			Expect(err).To(BeNil())
+		})
+
+		It("should match Telegram language codes", func() {
+			Expect(bundle.Localizer("en").Locale()).To(Equal("en"))
+			Expect(bundle.Localizer("en-GB").Locale()).To(Equal("en"))
+			Expect(bundle.Localizer("de").Locale()).To(Equal("ru"))
+			Expect(bundle.Localizer("", "en").Locale()).To(Equal("en"))
+			Expect(bundle.Localizer("ru", "en").Locale()).To(Equal("ru"))
+		})
+
+		It("should format messages and fall back to the default locale", func() {
+			en := bundle.Localizer("en")
+			Expect(en.T("greeting", "Alice")).To(Equal("Hi, Alice!"))
+			Expect(en.T("only_ru")).To(Equal("Только по-русски"))
+			Expect(en.T("unknown.key")).To(Equal("unknown.key"))
+		})
+
+		It("should pick plural forms by the rules of the locale", func() {
+			ru := bundle.Localizer("ru")
+			Expect(ru.N("articles.count", 1, 1)).To(Equal("1 артикул"))
+			Expect(ru.N("articles.count", 3, 3)).To(Equal("3 артикула"))
+			Expect(ru.N("articles.count", 11, 11)).To(Equal("11 артикулов"))
+			Expect(ru.N("articles.count", 21, 21)).To(Equal("21 артикул"))
+			Expect(ru.N("articles.count", 112, 112)).To(Equal("112 артикулов"))
+
+			en := bundle.Localizer("en")
+			Expect(en.N("articles.count", 1, 1)).To(Equal("1 article"))
+			Expect(en.N("articles.count", 0, 0)).To(Equal("0 articles"))
+		})
+
+		It("should list distinct translations", func() {
+			Expect(bundle.All("greeting")).To(Equal([]string{"Привет, %s!", "Hi, %s!"}))
+			Expect(bundle.All("only_ru")).To(Equal([]string{"Только по-русски"}))
+		})
+
+		It("should require the default locale", func() {
+			_, err := i18n.Load(fstest.MapFS{"en.yaml": {Data: []byte(`greeting: "Hi"`)}}, "ru")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
+
+// verbs returns the fmt verbs of a message in order
+func verbs(text string) []string {
+	var found []string
+	for i := 0; i < len(text)-1; i++ {
+		if text[i] == '%' {
+			found = append(found, text[i:i+2])
+			i++
+		}
+	}
+	return found
+}
@@ -0,0 +1,58 @@
+package i18n
+
+// Plural categories of the CLDR plural rules
+const (
+	pluralZero  = "zero"
+	pluralOne   = "one"
+	pluralTwo   = "two"
+	pluralFew   = "few"
+	pluralMany  = "many"
+	pluralOther = "other"
+)
+
+func isPluralCategory(category string) bool {
+	switch category {
+	case pluralZero, pluralOne, pluralTwo, pluralFew, pluralMany, pluralOther:
+		return true
+	}
+	return false
+}
+
+// pluralRules map a locale to the CLDR rule for integer counts.
+// Locales without a rule use the English one.
+var pluralRules = map[string]func(n int) string{
+	"en": englishPlural,
+	"ru": slavicPlural,
+	"uk": slavicPlural,
+	"be": slavicPlural,
+}
+
+func pluralCategory(locale string, count int) string {
+	if count < 0 {
+		count = -count
+	}
+	rule, ok := pluralRules[locale]
+	if !ok {
+		rule = englishPlural
+	}
+	return rule(count)
+}
+
+func englishPlural(n int) string {
+	if n == 1 {
+		return pluralOne
+	}
+	return pluralOther
+}
+
+// slavicPlural is the rule of Russian: 1, 21 артикул → one; 2-4, 22-24 артикула → few; 0, 5-20, 25 артикулов → many
+func slavicPlural(n int) string {
+	switch mod10, mod100 := n%10, n%100; {
+	case mod10 == 1 && mod100 != 11:
+		return pluralOne
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return pluralFew
+	default:
+		return pluralMany
+	}
+}
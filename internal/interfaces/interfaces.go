@@ -3,6 +3,7 @@ package interfaces
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -15,6 +16,7 @@ type TelegramUserProvider interface {
 	GetByUsername(username string) (*models.TelegramUser, error)
 	CreateUser(user *models.TelegramUser) error
 	GetUsersByState(state string) ([]*models.TelegramUser, error)
+	GetUsersByRole(role string) ([]*models.TelegramUser, error)
 }
 
 type TelegramUserManager interface {
@@ -27,6 +29,49 @@ type TelegramUserManager interface {
 	DeleteByID(id uuid.UUID) error
 	DeleteByTelegramID(telegramID int64) error
 	GetUsersByState(state string) ([]*models.TelegramUser, error)
+	GetUsersByRole(role string) ([]*models.TelegramUser, error)
+	// WithActor returns the repository recording role changes in the audit log as made by the actor
+	WithActor(actor *models.TelegramUser) TelegramUserManager
+}
+
+type InviteCodeManager interface {
+	CreateInviteCode(invite *models.InviteCode) error
+	GetByCode(code string) (*models.InviteCode, error)
+	UseInviteCode(code string) (*models.InviteCode, error)
+	GetActiveInviteCodes() ([]*models.InviteCode, error)
+	DeleteByCode(code string) error
+}
+
+type WorkspaceManager interface {
+	CreateWorkspace(workspace *models.Workspace) error
+	GetByID(id uuid.UUID) (*models.Workspace, error)
+	GetByName(name string) (*models.Workspace, error)
+	GetAll() ([]*models.Workspace, error)
+	GetOrCreateWorkspace(name string) (*models.Workspace, error)
+	AddMember(workspaceID, userID uuid.UUID) error
+	RemoveMember(workspaceID, userID uuid.UUID) error
+	IsMember(workspaceID, userID uuid.UUID) (bool, error)
+	GetUserWorkspaces(userID uuid.UUID) ([]*models.Workspace, error)
+	GetMembers(workspaceID uuid.UUID) ([]*models.TelegramUser, error)
+}
+
+type ChatManager interface {
+	GetState(chatID, telegramID int64) (string, error)
+	SetState(chatID, telegramID int64, state string) error
+	GetGroupChat(chatID int64) (*models.GroupChat, error)
+	BindGroupChat(group *models.GroupChat) error
+	UnbindGroupChat(chatID int64) error
+}
+
+type SupportTicketManager interface {
+	CreateTicket(ticket *models.SupportTicket) error
+	GetTicket(id uint) (*models.SupportTicket, error)
+	GetOpenTicket(telegramID int64) (*models.SupportTicket, error)
+	GetOpenTickets() ([]*models.SupportTicket, error)
+	CloseTicket(id uint) error
+	AddMessage(message *models.SupportMessage) error
+	GetMessages(ticketID uint) ([]*models.SupportMessage, error)
+	GetTicketByStaffMessage(staffChatID int64, staffMessageID int) (*models.SupportTicket, error)
 }
 
 type PhotoProvider interface {
@@ -39,20 +84,38 @@ type PhotoManager interface {
 	PhotoProvider
 	CreatePhoto(photo *models.Photo) error
 	GetUsersPhotosByState(userID uuid.UUID, state string) ([]*models.Photo, error)
+	// GetPhotos retrieves photos selected by the filter with their article numbers, the oldest first
+	GetPhotos(filter models.PhotoFilter) ([]*models.Photo, error)
+	// GetByContentHash retrieves an applied photo of the workspace with the content hash
+	GetByContentHash(workspaceID uuid.UUID, contentHash string) (*models.Photo, error)
 	UpdatePhoto(photo *models.Photo) error
-	DeletePhoto(id uuid.UUID, bucket string) error
+	// DeletePhoto moves a photo to the trash, its object is kept until the photo is purged
+	DeletePhoto(id uuid.UUID) error
+	RestorePhoto(id uuid.UUID) error
+	GetDeletedPhotos(workspaceID uuid.UUID) ([]*models.Photo, error)
+	// PurgeDeletedPhotos permanently deletes photos deleted before the time together with their objects
+	PurgeDeletedPhotos(deletedBefore time.Time, bucket string) (int, error)
+	// DiscardPhoto permanently deletes a photo which never reached the catalog together with its object
+	DiscardPhoto(id uuid.UUID, bucket string) error
 	AddArticleNumberToPhoto(photoID, articleNumberID uuid.UUID) error
 	RemoveArticleNumberFromPhoto(photoID, articleNumberID uuid.UUID) error
-	UploadPhotoToS3(ctx context.Context, userID uuid.UUID, s3Key uuid.UUID, bucket string, photoData io.Reader) error
-	GetPhotoFromS3(ctx context.Context, userID uuid.UUID, s3Key uuid.UUID, bucket string) (io.ReadCloser, error)
-	GetPhotoURL(ctx context.Context, userID uuid.UUID, s3Key uuid.UUID, bucket string, endpoint string) string
+	// UploadPhotoToS3, GetPhotoFromS3 and GetPhotoURL name objects after the image format,
+	// models.Photo.Format for originals and models.PhotoRenditionFormat for renditions
+	UploadPhotoToS3(ctx context.Context, userID uuid.UUID, s3Key uuid.UUID, format string, bucket string, photoData io.Reader) error
+	GetPhotoFromS3(ctx context.Context, userID uuid.UUID, s3Key uuid.UUID, format string, bucket string) (io.ReadCloser, error)
+	GetPhotoURL(ctx context.Context, userID uuid.UUID, s3Key uuid.UUID, format string, bucket string, expiresIn time.Duration) (string, error)
+	// WithActor returns the repository recording changes in the audit log as made by the actor
+	WithActor(actor *models.TelegramUser) PhotoManager
 }
 
 type ArticleNumberProvider interface {
 	GetByID(id uuid.UUID) (*models.ArticleNumber, error)
-	GetByNumber(number string) (*models.ArticleNumber, error)
+	// GetByNumber finds the article number by its number or one of its aliases
+	GetByNumber(workspaceID uuid.UUID, number string) (*models.ArticleNumber, error)
 	GetArticleNumbersByPhoto(photoID uuid.UUID) ([]*models.ArticleNumber, error)
 	GetArticleNumberWithPhotos(articleNumberID uuid.UUID) (*models.ArticleNumber, error)
+	// GetArticleAliases returns the aliases of the article number in the order they were added
+	GetArticleAliases(articleNumberID uuid.UUID) ([]*models.ArticleAlias, error)
 }
 
 type ArticleNumberManager interface {
@@ -60,7 +123,39 @@ type ArticleNumberManager interface {
 	CreateArticleNumber(articleNumber *models.ArticleNumber) error
 	UpdateArticleNumber(articleNumber *models.ArticleNumber) error
 	DeleteArticleNumber(id uuid.UUID) error
-	GetOrCreateArticleNumber(number string) (*models.ArticleNumber, error)
+	RestoreArticleNumber(id uuid.UUID) error
+	GetDeletedArticleNumbers(workspaceID uuid.UUID) ([]*models.ArticleNumber, error)
+	// PurgeDeletedArticleNumbers permanently deletes article numbers deleted before the time
+	PurgeDeletedArticleNumbers(deletedBefore time.Time) (int, error)
+	// GetOrCreateArticleNumber resolves aliases like GetByNumber and creates missing article numbers,
+	// failing with models.ErrArticleNumberInTrash for numbers in the trash
+	GetOrCreateArticleNumber(workspaceID uuid.UUID, number string) (*models.ArticleNumber, error)
+	// RenameArticleNumber changes the number, failing with models.ErrArticleNumberTaken or
+	// models.ErrArticleNumberInTrash when the workspace already has it
+	RenameArticleNumber(id uuid.UUID, number string) (*models.ArticleNumber, error)
+	// MergeArticleNumbers moves all photos of the source to the target, photos linked with both
+	// keep one link, and moves the source to the trash. Aliases of the source go to the target,
+	// the number of the source becomes one more until the source is restored.
+	// Returns the number of moved photos.
+	MergeArticleNumbers(sourceID, targetID uuid.UUID) (int, error)
+	// MoveArticleNumberPhotos moves the photos from the source to the target, photos not linked
+	// with the source are skipped. Returns the number of moved photos.
+	MoveArticleNumberPhotos(sourceID, targetID uuid.UUID, photoIDs []uuid.UUID) (int, error)
+	// AddArticleAlias adds an alias to the article number, failing with models.ErrArticleNumberTaken or
+	// models.ErrArticleNumberInTrash when the workspace already has it as a number or an alias.
+	// Adding an alias the article number already has returns it.
+	AddArticleAlias(articleNumberID uuid.UUID, alias string) (*models.ArticleAlias, error)
+	// AddArticleAliases adds all the aliases to the article number or none of them. Failures
+	// are *models.ArticleNumberError naming the alias.
+	AddArticleAliases(articleNumberID uuid.UUID, aliases []string) ([]*models.ArticleAlias, error)
+	// RemoveArticleAlias removes the alias of the workspace and returns the article number it belonged to
+	RemoveArticleAlias(workspaceID uuid.UUID, alias string) (*models.ArticleNumber, error)
+	// WithActor returns the repository recording changes in the audit log as made by the actor
+	WithActor(actor *models.TelegramUser) ArticleNumberManager
+}
+
+type AuditLogManager interface {
+	GetAuditLogs(filter models.AuditLogFilter) ([]*models.AuditLog, error)
 }
 
 type S3Client interface {
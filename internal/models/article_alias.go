@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+)
+
+// ArticleAlias is another code of an article number: a supplier code, an EAN or an old number.
+// Aliases share the namespace of numbers, an alias is never a number or another alias of the workspace.
+type ArticleAlias struct {
+	ID              uuid.UUID `gorm:"primaryKey"`
+	WorkspaceID     uuid.UUID `gorm:"column:workspace_id;uniqueIndex:idx_article_aliases_workspace_alias"`
+	Alias           string    `gorm:"column:alias;uniqueIndex:idx_article_aliases_workspace_alias"`
+	ArticleNumberID uuid.UUID `gorm:"column:article_number_id;index"`
+	CreatedAt       time.Time
+}
+
+func (a *ArticleAlias) BeforeCreate(tx *gorm.DB) (err error) {
+	a.ID = uuid.New()
+	return nil
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+
 	"gorm.io/gorm"
 
 	"github.com/google/uuid"
@@ -9,11 +11,35 @@ import (
 // ArticleNumber represents an article number in the database
 type ArticleNumber struct {
 	BaseModel
-	Number string  `gorm:"column:number;uniqueIndex"`
-	Photos []Photo `gorm:"many2many:article_number_photos;"`
+	// Numbers are unique within a workspace only
+	WorkspaceID uuid.UUID `gorm:"column:workspace_id;uniqueIndex:idx_article_numbers_workspace_number"`
+	Number      string    `gorm:"column:number;uniqueIndex:idx_article_numbers_workspace_number"`
+	Photos      []Photo   `gorm:"many2many:article_number_photos;"`
 }
 
 func (a *ArticleNumber) BeforeCreate(tx *gorm.DB) (err error) {
 	a.ID = uuid.New()
 	return nil
 }
+
+// ErrArticleNumberTaken is returned when an article number or alias would repeat a number
+// or an alias of the workspace
+var ErrArticleNumberTaken = errors.New("article number already exists")
+
+// ErrArticleNumberInTrash is returned when an article number is renamed to a number in the trash,
+// the unique index covers deleted article numbers until they are purged
+var ErrArticleNumberInTrash = errors.New("article number is in the trash")
+
+// ArticleNumberError tells which of several article numbers or aliases failed
+type ArticleNumberError struct {
+	Number string
+	Err    error
+}
+
+func (e *ArticleNumberError) Error() string {
+	return e.Number + ": " + e.Err.Error()
+}
+
+func (e *ArticleNumberError) Unwrap() error {
+	return e.Err
+}
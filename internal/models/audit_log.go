@@ -0,0 +1,238 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+)
+
+// AuditLog is an entry of the append-only log of catalog mutations.
+// Entries are written by the repositories together with the change they describe.
+type AuditLog struct {
+	ID uint `gorm:"primaryKey"`
+	// ActorID is the user who made the change, uuid.Nil for changes made from the command line
+	ActorID         uuid.UUID `gorm:"column:actor_id;index"`
+	ActorTelegramID int64     `gorm:"column:actor_telegram_id;index"`
+	ActorUsername   string    `gorm:"column:actor_username"`
+	Action          string    `gorm:"column:action;index"`
+	// Targets of the change, unset when the action does not involve them
+	PhotoID         uuid.UUID `gorm:"column:photo_id;index"`
+	ArticleNumberID uuid.UUID `gorm:"column:article_number_id;index"`
+	UserID          uuid.UUID `gorm:"column:user_id;index"`
+	// Before and After are JSON snapshots of the target, empty when it did not exist
+	Before    string    `gorm:"column:before"`
+	After     string    `gorm:"column:after"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+const (
+	AuditPhotoCreated    = "photo.created"
+	AuditPhotoApplied    = "photo.applied"
+	AuditPhotoUpdated    = "photo.updated"
+	AuditPhotoDeleted    = "photo.deleted"
+	AuditPhotoRestored   = "photo.restored"
+	AuditPhotoPurged     = "photo.purged"
+	AuditArticleCreated  = "article.created"
+	AuditArticleUpdated  = "article.updated"
+	AuditArticleDeleted  = "article.deleted"
+	AuditArticleRestored = "article.restored"
+	AuditArticlePurged   = "article.purged"
+	AuditArticleMerged   = "article.merged"
+	AuditArticleLinked   = "article.linked"
+	AuditArticleUnlinked = "article.unlinked"
+	AuditAliasAdded      = "article.alias_added"
+	AuditAliasRemoved    = "article.alias_removed"
+	AuditUserRoleChanged = "user.role_changed"
+)
+
+// AuditActions lists the actions recorded in the audit log
+var AuditActions = []string{
+	AuditPhotoCreated, AuditPhotoApplied, AuditPhotoUpdated, AuditPhotoDeleted, AuditPhotoRestored, AuditPhotoPurged,
+	AuditArticleCreated, AuditArticleUpdated, AuditArticleDeleted, AuditArticleRestored, AuditArticlePurged,
+	AuditArticleMerged, AuditArticleLinked, AuditArticleUnlinked, AuditAliasAdded, AuditAliasRemoved,
+	AuditUserRoleChanged,
+}
+
+// IsValidAuditAction reports whether the action is one of AuditActions
+func IsValidAuditAction(action string) bool {
+	for _, a := range AuditActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrAuditLogAppendOnly is returned on attempts to change or delete audit entries
+var ErrAuditLogAppendOnly = errors.New("audit log is append-only")
+
+func (l *AuditLog) BeforeUpdate(_ *gorm.DB) error {
+	return ErrAuditLogAppendOnly
+}
+
+func (l *AuditLog) BeforeDelete(_ *gorm.DB) error {
+	return ErrAuditLogAppendOnly
+}
+
+// NewAuditLog creates an entry for a change made by the actor, nil for the command line.
+// Snapshots are stored as JSON, nil snapshots stay empty.
+func NewAuditLog(actor *TelegramUser, action string, before, after interface{}) *AuditLog {
+	entry := &AuditLog{
+		Action: action,
+		Before: auditSnapshot(before),
+		After:  auditSnapshot(after),
+	}
+	if actor != nil {
+		entry.ActorID = actor.ID
+		entry.ActorTelegramID = actor.TelegramID
+		entry.ActorUsername = actor.Username
+	}
+	return entry
+}
+
+func auditSnapshot(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// PhotoSnapshot is the state of a photo kept in the audit log
+type PhotoSnapshot struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	S3Key       uuid.UUID `json:"s3_key"`
+	State       string    `json:"state"`
+	ChatID      int64     `json:"chat_id"`
+}
+
+// NewPhotoSnapshot takes the snapshot of a photo
+func NewPhotoSnapshot(photo *Photo) *PhotoSnapshot {
+	return &PhotoSnapshot{
+		ID:          photo.ID,
+		UserID:      photo.UserID,
+		WorkspaceID: photo.WorkspaceID,
+		S3Key:       photo.S3Key,
+		State:       photo.State,
+		ChatID:      photo.ChatID,
+	}
+}
+
+// PhotoChangeAction names the change of a saved photo, before is nil for new photos
+func PhotoChangeAction(before *PhotoSnapshot, after *Photo) string {
+	switch {
+	case before == nil:
+		return AuditPhotoCreated
+	case before.State != PhotoApplied && after.State == PhotoApplied:
+		return AuditPhotoApplied
+	}
+	return AuditPhotoUpdated
+}
+
+// ArticleNumberSnapshot is the state of an article number kept in the audit log
+type ArticleNumberSnapshot struct {
+	ID          uuid.UUID `json:"id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	Number      string    `json:"number"`
+}
+
+// NewArticleNumberSnapshot takes the snapshot of an article number
+func NewArticleNumberSnapshot(articleNumber *ArticleNumber) *ArticleNumberSnapshot {
+	return &ArticleNumberSnapshot{
+		ID:          articleNumber.ID,
+		WorkspaceID: articleNumber.WorkspaceID,
+		Number:      articleNumber.Number,
+	}
+}
+
+// LinkSnapshot is a link between a photo and an article number kept in the audit log
+type LinkSnapshot struct {
+	PhotoID         uuid.UUID `json:"photo_id"`
+	ArticleNumberID uuid.UUID `json:"article_number_id"`
+	Number          string    `json:"number"`
+}
+
+// NewLinkAuditLog creates an entry for linking a photo with an article number or unlinking them
+func NewLinkAuditLog(actor *TelegramUser, action string, photoID uuid.UUID, articleNumber *ArticleNumber) *AuditLog {
+	link := &LinkSnapshot{
+		PhotoID:         photoID,
+		ArticleNumberID: articleNumber.ID,
+		Number:          articleNumber.Number,
+	}
+	var entry *AuditLog
+	if action == AuditArticleLinked {
+		entry = NewAuditLog(actor, action, nil, link)
+	} else {
+		entry = NewAuditLog(actor, action, link, nil)
+	}
+	entry.PhotoID = photoID
+	entry.ArticleNumberID = articleNumber.ID
+	return entry
+}
+
+// AliasSnapshot is an alias of an article number kept in the audit log
+type AliasSnapshot struct {
+	ID              uuid.UUID `json:"id"`
+	ArticleNumberID uuid.UUID `json:"article_number_id"`
+	Number          string    `json:"number"`
+	Alias           string    `json:"alias"`
+}
+
+// NewAliasAuditLog creates an entry for adding an alias to an article number or removing it
+func NewAliasAuditLog(actor *TelegramUser, action string, alias *ArticleAlias, articleNumber *ArticleNumber) *AuditLog {
+	snapshot := &AliasSnapshot{
+		ID:              alias.ID,
+		ArticleNumberID: articleNumber.ID,
+		Number:          articleNumber.Number,
+		Alias:           alias.Alias,
+	}
+	var entry *AuditLog
+	if action == AuditAliasAdded {
+		entry = NewAuditLog(actor, action, nil, snapshot)
+	} else {
+		entry = NewAuditLog(actor, action, snapshot, nil)
+	}
+	entry.ArticleNumberID = articleNumber.ID
+	return entry
+}
+
+// RoleSnapshot is the role of a user kept in the audit log
+type RoleSnapshot struct {
+	Role string `json:"role"`
+}
+
+// AuditLogFilter selects audit entries, zero fields match everything
+type AuditLogFilter struct {
+	ActorTelegramID int64
+	Action          string
+	// TargetID matches the photo, article number or user of an entry
+	TargetID uuid.UUID
+	Since    time.Time
+	// Limit caps the number of entries, the newest are returned first
+	Limit int
+}
+
+// Matches reports whether the entry is selected by the filter, Limit is not checked
+func (f AuditLogFilter) Matches(entry *AuditLog) bool {
+	switch {
+	case f.ActorTelegramID != 0 && entry.ActorTelegramID != f.ActorTelegramID:
+		return false
+	case f.Action != "" && entry.Action != f.Action:
+		return false
+	case f.TargetID != uuid.Nil && entry.PhotoID != f.TargetID &&
+		entry.ArticleNumberID != f.TargetID && entry.UserID != f.TargetID:
+		return false
+	case !f.Since.IsZero() && entry.CreatedAt.Before(f.Since):
+		return false
+	}
+	return true
+}
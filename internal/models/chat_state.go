@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ChatState is the dialog state of a user in one chat, so a user may search
+// in a group while uploading photos in a private chat with the bot
+type ChatState struct {
+	ChatID     int64  `gorm:"column:chat_id;primaryKey;autoIncrement:false"`
+	TelegramID int64  `gorm:"column:telegram_id;primaryKey;autoIncrement:false"`
+	State      string `gorm:"column:state"`
+	UpdatedAt  time.Time
+}
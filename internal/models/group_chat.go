@@ -0,0 +1,21 @@
+package models
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+)
+
+// GroupChat is a group or forum bound by an admin to a workspace.
+// Everyone searching or uploading in the group works with that workspace.
+type GroupChat struct {
+	BaseModel
+	ChatID      int64     `gorm:"column:chat_id;uniqueIndex"`
+	Title       string    `gorm:"column:title"`
+	WorkspaceID uuid.UUID `gorm:"column:workspace_id"`
+}
+
+func (g *GroupChat) BeforeCreate(_ *gorm.DB) (err error) {
+	g.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+)
+
+// InviteCode lets new users join a restricted bot with the given role
+type InviteCode struct {
+	BaseModel
+	Code string `gorm:"column:code;uniqueIndex"`
+	Role string `gorm:"column:role"`
+	// MaxUses limits how many users may join with the code, 0 means unlimited
+	MaxUses     int       `gorm:"column:max_uses"`
+	Uses        int       `gorm:"column:uses"`
+	CreatedByID uuid.UUID `gorm:"column:created_by_id"`
+	// WorkspaceID is joined by users of the code, if set
+	WorkspaceID uuid.UUID `gorm:"column:workspace_id"`
+}
+
+func (c *InviteCode) BeforeCreate(_ *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	return nil
+}
+
+// IsExhausted reports whether the code has no uses left
+func (c *InviteCode) IsExhausted() bool {
+	return c.MaxUses > 0 && c.Uses >= c.MaxUses
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
 	"gorm.io/gorm"
 
 	"github.com/google/uuid"
@@ -14,6 +18,14 @@ type Photo struct {
 	TelegramUser   TelegramUser    `gorm:"foreignKey:UserID"`
 	UserID         uuid.UUID       `gorm:"column:user_id"`
 	State          string          `gorm:"column:state"`
+	WorkspaceID    uuid.UUID       `gorm:"column:workspace_id;index"`
+	// ChatID is the chat the photo was sent to, pending photos are applied per chat
+	ChatID int64 `gorm:"column:chat_id"`
+	// ContentHash identifies the uploaded file, imports skip files already in the catalog
+	ContentHash string `gorm:"column:content_hash;index"`
+	// Format is the image format of the stored object as image.DecodeConfig names it,
+	// photos stored before formats were recorded are JPEG
+	Format string `gorm:"column:format"`
 }
 
 func (i *Photo) BeforeCreate(tx *gorm.DB) (err error) {
@@ -21,6 +33,71 @@ func (i *Photo) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// PhotoContentHash computes the ContentHash of photo data
+func PhotoContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// PhotoObjectKey returns the S3 object key of a photo or a rendition stored in the format
+func PhotoObjectKey(userID, s3Key uuid.UUID, format string) string {
+	return userID.String() + "/" + s3Key.String() + PhotoExtension(format)
+}
+
+// PhotoExtension returns the file extension of an image format, an empty format is JPEG
+func PhotoExtension(format string) string {
+	if format == "" || format == "jpeg" {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+// Renditions are photos derived from the originals at delivery. They are cached
+// in S3 next to the originals, stored as PhotoRenditionFormat and deleted together with them.
+const (
+	// PhotoRenditionWatermarked is the photo with the configured watermark
+	PhotoRenditionWatermarked = "watermarked"
+)
+
+// PhotoRenditionFormat is the image format of all renditions
+const PhotoRenditionFormat = "jpeg"
+
+// PhotoRenditions lists all renditions of photos
+var PhotoRenditions = []string{PhotoRenditionWatermarked}
+
+// RenditionKey returns the S3Key of a rendition of the photo
+func (i *Photo) RenditionKey(rendition string) uuid.UUID {
+	return uuid.NewSHA1(i.S3Key, []byte(rendition))
+}
+
+// PhotoFilter selects photos, zero fields match everything
+type PhotoFilter struct {
+	WorkspaceID uuid.UUID
+	// UserID matches photos of the uploader
+	UserID uuid.UUID
+	State  string
+	// Since and Until bound the upload time, Until is excluded
+	Since time.Time
+	Until time.Time
+}
+
+// Matches reports whether the photo is selected by the filter
+func (f PhotoFilter) Matches(photo *Photo) bool {
+	switch {
+	case f.WorkspaceID != uuid.Nil && photo.WorkspaceID != f.WorkspaceID:
+		return false
+	case f.UserID != uuid.Nil && photo.UserID != f.UserID:
+		return false
+	case f.State != "" && photo.State != f.State:
+		return false
+	case !f.Since.IsZero() && photo.CreatedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !photo.CreatedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
 const (
 	PhotoNotApplied = "not_applied"
 	PhotoApplied    = "applied"
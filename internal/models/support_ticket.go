@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// SupportTicket is a conversation of a user with the staff through the bot.
+// Tickets have sequential IDs, so the staff can refer to them as #42.
+type SupportTicket struct {
+	ID uint `gorm:"primaryKey"`
+	// TelegramID is the user who opened the ticket, replies go to the private chat with the user
+	TelegramID int64  `gorm:"column:telegram_id;index"`
+	Status     string `gorm:"column:status;index"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ClosedAt   *time.Time       `gorm:"column:closed_at"`
+	Messages   []SupportMessage `gorm:"foreignKey:TicketID"`
+}
+
+// IsOpen reports whether the ticket still accepts messages
+func (t *SupportTicket) IsOpen() bool {
+	return t.Status == SupportTicketOpen
+}
+
+const (
+	SupportTicketOpen   = "open"
+	SupportTicketClosed = "closed"
+)
+
+// SupportMessage is one message of the ticket history
+type SupportMessage struct {
+	ID       uint `gorm:"primaryKey"`
+	TicketID uint `gorm:"column:ticket_id;index"`
+	// FromStaff is set for replies of the staff, unset for messages of the user
+	FromStaff bool `gorm:"column:from_staff"`
+	// TelegramID is the author of the message
+	TelegramID  int64  `gorm:"column:telegram_id"`
+	Text        string `gorm:"column:text"`
+	PhotoFileID string `gorm:"column:photo_file_id"`
+	// StaffChatID and StaffMessageID locate the message in the staff chat,
+	// so replies to it find the ticket
+	StaffChatID    int64 `gorm:"column:staff_chat_id;index:idx_support_messages_staff_message"`
+	StaffMessageID int   `gorm:"column:staff_message_id;index:idx_support_messages_staff_message"`
+	CreatedAt      time.Time
+}
@@ -16,14 +16,45 @@ type TelegramUser struct {
 	LanguageCode string  `gorm:"column:language_code"`
 	IsBot        bool    `gorm:"column:is_bot"`
 	Photos       []Photo `gorm:"foreignKey:UserID"`
-	State        string  `gorm:"column:state"`
+	// Locale is the language picked with /language, empty to follow LanguageCode
+	Locale string `gorm:"column:locale"`
+	// State is the dialog state in the chat of the current update, it is
+	// stored per chat in ChatState and only kept here for old databases
+	State string `gorm:"column:state"`
+	Role  string `gorm:"column:role;default:uploader;index"`
+	// ActiveWorkspaceID is the workspace the user currently uploads to and searches in
+	ActiveWorkspaceID uuid.UUID   `gorm:"column:active_workspace_id"`
+	Workspaces        []Workspace `gorm:"many2many:workspace_members;"`
 }
 
 func (u *TelegramUser) BeforeCreate(_ *gorm.DB) (err error) {
 	u.ID = uuid.New()
+	if u.Role == "" {
+		u.Role = TelegramUserRoleUploader
+	}
 	return nil
 }
 
+// IsAdmin reports whether the user may manage roles of other users
+func (u *TelegramUser) IsAdmin() bool {
+	return u.Role == TelegramUserRoleAdmin
+}
+
+// IsBanned reports whether the user is denied any access to the bot
+func (u *TelegramUser) IsBanned() bool {
+	return u.Role == TelegramUserRoleBanned
+}
+
+// CanUpload reports whether the user may add photos
+func (u *TelegramUser) CanUpload() bool {
+	return u.Role == TelegramUserRoleAdmin || u.Role == TelegramUserRoleUploader
+}
+
+// CanSearch reports whether the user may search and share photos
+func (u *TelegramUser) CanSearch() bool {
+	return u.CanUpload() || u.Role == TelegramUserRoleViewer
+}
+
 // TelegramUserFilter for filtering telegram users
 type TelegramUserFilter struct {
 	TelegramID int64
@@ -35,4 +66,48 @@ const (
 	TelegramUserStateUploading = "uploading"
 	TelegramUserStateSearching = "searching"
 	TelegramUserStateDefault   = "default"
+	// TelegramUserStateSupport relays messages of the user to the staff chat
+	TelegramUserStateSupport = "support"
+	// TelegramUserStateImporting waits for an archive with a catalog to import
+	TelegramUserStateImporting = "importing"
 )
+
+// Roles in descending order of privileges
+const (
+	TelegramUserRoleAdmin    = "admin"
+	TelegramUserRoleUploader = "uploader"
+	TelegramUserRoleViewer   = "viewer"
+	TelegramUserRoleBanned   = "banned"
+)
+
+// TelegramUserRoles lists all known roles
+var TelegramUserRoles = []string{
+	TelegramUserRoleAdmin,
+	TelegramUserRoleUploader,
+	TelegramUserRoleViewer,
+	TelegramUserRoleBanned,
+}
+
+// IsRoleHigher reports whether role grants more privileges than other
+func IsRoleHigher(role, other string) bool {
+	return roleRank(role) < roleRank(other)
+}
+
+func roleRank(role string) int {
+	for rank, known := range TelegramUserRoles {
+		if role == known {
+			return rank
+		}
+	}
+	return len(TelegramUserRoles)
+}
+
+// IsValidTelegramUserRole checks that role is one of TelegramUserRoles
+func IsValidTelegramUserRole(role string) bool {
+	for _, known := range TelegramUserRoles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
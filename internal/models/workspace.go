@@ -0,0 +1,19 @@
+package models
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+)
+
+// Workspace is an isolated catalog of photos and article numbers, e.g. one shop
+type Workspace struct {
+	BaseModel
+	Name    string         `gorm:"column:name;uniqueIndex"`
+	Members []TelegramUser `gorm:"many2many:workspace_members;"`
+}
+
+func (w *Workspace) BeforeCreate(_ *gorm.DB) (err error) {
+	w.ID = uuid.New()
+	return nil
+}
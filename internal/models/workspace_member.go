@@ -0,0 +1,12 @@
+package models
+
+import "github.com/google/uuid"
+
+// WorkspaceMember is the join table for Workspace and TelegramUser
+type WorkspaceMember struct {
+	WorkspaceID    uuid.UUID `gorm:"primaryKey"`
+	TelegramUserID uuid.UUID `gorm:"primaryKey"`
+
+	Workspace    Workspace    `gorm:"foreignKey:WorkspaceID"`
+	TelegramUser TelegramUser `gorm:"foreignKey:TelegramUserID"`
+}
@@ -1,16 +1,23 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"gorm.io/gorm"
 
 	"github.com/google/uuid"
 
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
 	"github.com/Conty111/AlfredoBot/internal/models"
 )
 
 // ArticleNumberRepository handles database operations for ArticleNumbers
 type ArticleNumberRepository struct {
 	db *gorm.DB
+	// actor is recorded in the audit log as the author of changes
+	actor *models.TelegramUser
 }
 
 // NewArticleNumberRepository creates a new ArticleNumberRepository
@@ -18,6 +25,13 @@ func NewArticleNumberRepository(db *gorm.DB) *ArticleNumberRepository {
 	return &ArticleNumberRepository{db: db}
 }
 
+// WithActor returns a copy of the repository recording changes as made by the actor
+func (r *ArticleNumberRepository) WithActor(actor *models.TelegramUser) interfaces.ArticleNumberManager {
+	c := *r
+	c.actor = actor
+	return &c
+}
+
 // GetByID retrieves an ArticleNumber by UUID
 func (r *ArticleNumberRepository) GetByID(id uuid.UUID) (*models.ArticleNumber, error) {
 	articleNumber := &models.ArticleNumber{}
@@ -28,21 +42,26 @@ func (r *ArticleNumberRepository) GetByID(id uuid.UUID) (*models.ArticleNumber,
 	return articleNumber, nil
 }
 
-// GetByNumber retrieves an ArticleNumber by its number string
-func (r *ArticleNumberRepository) GetByNumber(number string) (*models.ArticleNumber, error) {
-	articleNumber := &models.ArticleNumber{}
-	tx := r.db.Where("number = ?", number).First(articleNumber)
+// GetByNumber retrieves an ArticleNumber by its number string or an alias within a workspace
+func (r *ArticleNumberRepository) GetByNumber(workspaceID uuid.UUID, number string) (*models.ArticleNumber, error) {
+	return findByNumber(r.db, workspaceID, number)
+}
+
+// GetArticleAliases retrieves the aliases of an ArticleNumber in the order they were added
+func (r *ArticleNumberRepository) GetArticleAliases(articleNumberID uuid.UUID) ([]*models.ArticleAlias, error) {
+	var aliases []*models.ArticleAlias
+	tx := r.db.Where("article_number_id = ?", articleNumberID).Order("created_at").Find(&aliases)
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
-	return articleNumber, nil
+	return aliases, nil
 }
 
 // GetArticleNumbersByPhoto retrieves all ArticleNumbers associated with a photo
 func (r *ArticleNumberRepository) GetArticleNumbersByPhoto(photoID uuid.UUID) ([]*models.ArticleNumber, error) {
 	var articleNumbers []*models.ArticleNumber
-	tx := r.db.Joins("JOIN photo_article_number ON photo_article_number.article_number_id = article_numbers.id").
-		Where("photo_article_number.photo_id = ?", photoID).
+	tx := r.db.Joins("JOIN article_number_photos ON article_number_photos.article_number_id = article_numbers.id").
+		Where("article_number_photos.photo_id = ?", photoID).
 		Find(&articleNumbers)
 	if tx.Error != nil {
 		return nil, tx.Error
@@ -52,48 +71,413 @@ func (r *ArticleNumberRepository) GetArticleNumbersByPhoto(photoID uuid.UUID) ([
 
 // CreateArticleNumber creates a new ArticleNumber
 func (r *ArticleNumberRepository) CreateArticleNumber(articleNumber *models.ArticleNumber) error {
-	return r.db.Create(articleNumber).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return r.create(tx, articleNumber)
+	})
 }
 
 // UpdateArticleNumber updates an ArticleNumber
 func (r *ArticleNumberRepository) UpdateArticleNumber(articleNumber *models.ArticleNumber) error {
-	return r.db.Save(articleNumber).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var before *models.ArticleNumberSnapshot
+		if articleNumber.ID != uuid.Nil {
+			stored := &models.ArticleNumber{}
+			err := tx.Where("id = ?", articleNumber.ID).First(stored).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err == nil {
+				before = models.NewArticleNumberSnapshot(stored)
+			}
+		}
+
+		if err := tx.Save(articleNumber).Error; err != nil {
+			return err
+		}
+		action := models.AuditArticleUpdated
+		if before == nil {
+			action = models.AuditArticleCreated
+		}
+		entry := models.NewAuditLog(r.actor, action, before, models.NewArticleNumberSnapshot(articleNumber))
+		entry.ArticleNumberID = articleNumber.ID
+		return audit(tx, entry)
+	})
 }
 
 // DeleteArticleNumber deletes an ArticleNumber
 func (r *ArticleNumberRepository) DeleteArticleNumber(id uuid.UUID) error {
-	return r.db.Delete(&models.ArticleNumber{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		articleNumber := &models.ArticleNumber{}
+		err := tx.Where("id = ?", id).First(articleNumber).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&models.ArticleNumber{}, id).Error; err != nil {
+			return err
+		}
+		entry := models.NewAuditLog(r.actor, models.AuditArticleDeleted, models.NewArticleNumberSnapshot(articleNumber), nil)
+		entry.ArticleNumberID = id
+		return audit(tx, entry)
+	})
 }
 
-// GetOrCreateArticleNumber gets an existing article number by number string or creates a new one
-func (r *ArticleNumberRepository) GetOrCreateArticleNumber(number string) (*models.ArticleNumber, error) {
-	articleNumber := &models.ArticleNumber{}
+// RestoreArticleNumber takes an ArticleNumber out of the trash
+func (r *ArticleNumberRepository) RestoreArticleNumber(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		articleNumber := &models.ArticleNumber{}
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(articleNumber).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(articleNumber).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := r.takeBackMergedNumber(tx, articleNumber); err != nil {
+			return err
+		}
+		entry := models.NewAuditLog(r.actor, models.AuditArticleRestored, nil, models.NewArticleNumberSnapshot(articleNumber))
+		entry.ArticleNumberID = id
+		return audit(tx, entry)
+	})
+}
+
+// GetDeletedArticleNumbers retrieves ArticleNumbers of a workspace in the trash, the last deleted first
+func (r *ArticleNumberRepository) GetDeletedArticleNumbers(workspaceID uuid.UUID) ([]*models.ArticleNumber, error) {
+	var articleNumbers []*models.ArticleNumber
+	tx := r.db.Unscoped().
+		Where("workspace_id = ? AND deleted_at IS NOT NULL", workspaceID).
+		Order("deleted_at DESC").
+		Find(&articleNumbers)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return articleNumbers, nil
+}
+
+// PurgeDeletedArticleNumbers permanently deletes ArticleNumbers deleted before the time with their links to photos
+func (r *ArticleNumberRepository) PurgeDeletedArticleNumbers(deletedBefore time.Time) (int, error) {
+	var articleNumbers []*models.ArticleNumber
+	tx := r.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).Find(&articleNumbers)
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
 
+	for i, articleNumber := range articleNumbers {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("article_number_id = ?", articleNumber.ID).Delete(&models.ArticleNumberPhoto{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("article_number_id = ?", articleNumber.ID).Delete(&models.ArticleAlias{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Delete(&models.ArticleNumber{}, articleNumber.ID).Error; err != nil {
+				return err
+			}
+			entry := models.NewAuditLog(r.actor, models.AuditArticlePurged, models.NewArticleNumberSnapshot(articleNumber), nil)
+			entry.ArticleNumberID = articleNumber.ID
+			return audit(tx, entry)
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(articleNumbers), nil
+}
+
+// GetOrCreateArticleNumber gets an existing article number of the workspace by number string or alias
+// or creates a new one
+func (r *ArticleNumberRepository) GetOrCreateArticleNumber(workspaceID uuid.UUID, number string) (*models.ArticleNumber, error) {
 	// Try to find existing article number
-	tx := r.db.Where("number = ?", number).First(articleNumber)
-	if tx.Error == nil {
+	articleNumber, err := findByNumber(r.db, workspaceID, number)
+	if err == nil {
 		return articleNumber, nil
 	}
 
 	// If not found, create a new one
-	if tx.Error == gorm.ErrRecordNotFound {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		articleNumber = &models.ArticleNumber{
-			Number: number,
+			WorkspaceID: workspaceID,
+			Number:      number,
 		}
-		if err := r.db.Create(articleNumber).Error; err != nil {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			return r.create(tx, articleNumber)
+		})
+		if err != nil {
 			return nil, err
 		}
 		return articleNumber, nil
 	}
 
 	// Return any other error
-	return nil, tx.Error
+	return nil, err
+}
+
+// RenameArticleNumber changes the number of an ArticleNumber, numbers stay unique within the workspace
+func (r *ArticleNumberRepository) RenameArticleNumber(id uuid.UUID, number string) (*models.ArticleNumber, error) {
+	articleNumber := &models.ArticleNumber{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(articleNumber).Error; err != nil {
+			return err
+		}
+		if articleNumber.Number == number {
+			return nil
+		}
+
+		if err := checkNumberFree(tx, articleNumber.WorkspaceID, number); err != nil {
+			return err
+		}
+
+		before := models.NewArticleNumberSnapshot(articleNumber)
+		if err := tx.Model(articleNumber).Update("number", number).Error; err != nil {
+			return err
+		}
+		articleNumber.Number = number
+		entry := models.NewAuditLog(r.actor, models.AuditArticleUpdated, before, models.NewArticleNumberSnapshot(articleNumber))
+		entry.ArticleNumberID = id
+		return audit(tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return articleNumber, nil
+}
+
+// MergeArticleNumbers moves all photos of the source ArticleNumber to the target one
+// and moves the source to the trash, its number becomes an alias of the target
+func (r *ArticleNumberRepository) MergeArticleNumbers(sourceID, targetID uuid.UUID) (int, error) {
+	moved := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		source, target, err := findArticleNumberPair(tx, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+
+		var photoIDs []uuid.UUID
+		err = tx.Model(&models.ArticleNumberPhoto{}).
+			Where("article_number_id = ?", sourceID).
+			Pluck("photo_id", &photoIDs).
+			Error
+		if err != nil {
+			return err
+		}
+		if moved, err = r.movePhotos(tx, source, target, photoIDs); err != nil {
+			return err
+		}
+		err = tx.Model(&models.ArticleAlias{}).
+			Where("article_number_id = ?", sourceID).
+			Update("article_number_id", targetID).
+			Error
+		if err != nil {
+			return err
+		}
+		// The number of the source keeps finding the target until the source is restored
+		alias := &models.ArticleAlias{WorkspaceID: target.WorkspaceID, Alias: source.Number, ArticleNumberID: targetID}
+		if err := tx.Create(alias).Error; err != nil {
+			return err
+		}
+		if err := audit(tx, models.NewAliasAuditLog(r.actor, models.AuditAliasAdded, alias, target)); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&models.ArticleNumber{}, sourceID).Error; err != nil {
+			return err
+		}
+		entry := models.NewAuditLog(r.actor, models.AuditArticleMerged,
+			models.NewArticleNumberSnapshot(source), models.NewArticleNumberSnapshot(target))
+		entry.ArticleNumberID = sourceID
+		return audit(tx, entry)
+	})
+	return moved, err
+}
+
+// MoveArticleNumberPhotos moves the photos from the source ArticleNumber to the target one
+func (r *ArticleNumberRepository) MoveArticleNumberPhotos(sourceID, targetID uuid.UUID, photoIDs []uuid.UUID) (int, error) {
+	moved := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		source, target, err := findArticleNumberPair(tx, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+		moved, err = r.movePhotos(tx, source, target, photoIDs)
+		return err
+	})
+	return moved, err
+}
+
+// AddArticleAlias adds an alias to an ArticleNumber, aliases are unique among numbers and aliases of the workspace
+func (r *ArticleNumberRepository) AddArticleAlias(articleNumberID uuid.UUID, alias string) (*models.ArticleAlias, error) {
+	articleAliases, err := r.AddArticleAliases(articleNumberID, []string{alias})
+	if err != nil {
+		return nil, err
+	}
+	return articleAliases[0], nil
+}
+
+// AddArticleAliases adds aliases to an ArticleNumber in one transaction, a failing alias adds none of them
+func (r *ArticleNumberRepository) AddArticleAliases(articleNumberID uuid.UUID, aliases []string) ([]*models.ArticleAlias, error) {
+	var articleAliases []*models.ArticleAlias
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		articleNumber := &models.ArticleNumber{}
+		if err := tx.Where("id = ?", articleNumberID).First(articleNumber).Error; err != nil {
+			return err
+		}
+
+		for _, alias := range aliases {
+			articleAlias, err := r.addAlias(tx, articleNumber, alias)
+			if err != nil {
+				return &models.ArticleNumberError{Number: alias, Err: err}
+			}
+			articleAliases = append(articleAliases, articleAlias)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return articleAliases, nil
+}
+
+// addAlias adds an alias to the ArticleNumber unless it already has it
+func (r *ArticleNumberRepository) addAlias(tx *gorm.DB, articleNumber *models.ArticleNumber, alias string) (*models.ArticleAlias, error) {
+	articleAlias := &models.ArticleAlias{}
+	err := tx.Where("workspace_id = ? AND alias = ? AND article_number_id = ?",
+		articleNumber.WorkspaceID, alias, articleNumber.ID).First(articleAlias).Error
+	if err == nil {
+		return articleAlias, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err := checkNumberFree(tx, articleNumber.WorkspaceID, alias); err != nil {
+		return nil, err
+	}
+
+	articleAlias = &models.ArticleAlias{
+		WorkspaceID:     articleNumber.WorkspaceID,
+		Alias:           alias,
+		ArticleNumberID: articleNumber.ID,
+	}
+	if err := tx.Create(articleAlias).Error; err != nil {
+		return nil, err
+	}
+	if err := audit(tx, models.NewAliasAuditLog(r.actor, models.AuditAliasAdded, articleAlias, articleNumber)); err != nil {
+		return nil, err
+	}
+	return articleAlias, nil
+}
+
+// RemoveArticleAlias removes an alias of the workspace and returns the ArticleNumber it belonged to
+func (r *ArticleNumberRepository) RemoveArticleAlias(workspaceID uuid.UUID, alias string) (*models.ArticleNumber, error) {
+	articleNumber := &models.ArticleNumber{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		articleAlias := &models.ArticleAlias{}
+		if err := tx.Where("workspace_id = ? AND alias = ?", workspaceID, alias).First(articleAlias).Error; err != nil {
+			return err
+		}
+		// Aliases of article numbers in the trash are removed as well
+		if err := tx.Unscoped().Where("id = ?", articleAlias.ArticleNumberID).First(articleNumber).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(articleAlias).Error; err != nil {
+			return err
+		}
+		return audit(tx, models.NewAliasAuditLog(r.actor, models.AuditAliasRemoved, articleAlias, articleNumber))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return articleNumber, nil
+}
+
+// takeBackMergedNumber removes the alias a merge left for the number of a restored ArticleNumber,
+// so the number finds the restored one again
+func (r *ArticleNumberRepository) takeBackMergedNumber(tx *gorm.DB, articleNumber *models.ArticleNumber) error {
+	alias := &models.ArticleAlias{}
+	err := tx.Where("workspace_id = ? AND alias = ?", articleNumber.WorkspaceID, articleNumber.Number).First(alias).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	owner := &models.ArticleNumber{}
+	if err := tx.Unscoped().Where("id = ?", alias.ArticleNumberID).First(owner).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Delete(alias).Error; err != nil {
+		return err
+	}
+	return audit(tx, models.NewAliasAuditLog(r.actor, models.AuditAliasRemoved, alias, owner))
+}
+
+// movePhotos replaces links of the photos with the source by links with the target,
+// photos already linked with the target just lose the source
+func (r *ArticleNumberRepository) movePhotos(tx *gorm.DB, source, target *models.ArticleNumber, photoIDs []uuid.UUID) (int, error) {
+	moved := 0
+	for _, photoID := range photoIDs {
+		result := tx.Where("article_number_id = ? AND photo_id = ?", source.ID, photoID).Delete(&models.ArticleNumberPhoto{})
+		if result.Error != nil {
+			return moved, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if err := audit(tx, models.NewLinkAuditLog(r.actor, models.AuditArticleUnlinked, photoID, source)); err != nil {
+			return moved, err
+		}
+
+		var linked int64
+		err := tx.Model(&models.ArticleNumberPhoto{}).
+			Where("article_number_id = ? AND photo_id = ?", target.ID, photoID).
+			Count(&linked).
+			Error
+		if err != nil {
+			return moved, err
+		}
+		if linked == 0 {
+			relation := models.ArticleNumberPhoto{ArticleNumberID: target.ID, PhotoID: photoID}
+			if err := tx.Create(&relation).Error; err != nil {
+				return moved, err
+			}
+			if err := audit(tx, models.NewLinkAuditLog(r.actor, models.AuditArticleLinked, photoID, target)); err != nil {
+				return moved, err
+			}
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// findArticleNumberPair loads two different ArticleNumbers of the same workspace
+func findArticleNumberPair(tx *gorm.DB, sourceID, targetID uuid.UUID) (*models.ArticleNumber, *models.ArticleNumber, error) {
+	if sourceID == targetID {
+		return nil, nil, fmt.Errorf("cannot move photos of an article number to itself")
+	}
+	source := &models.ArticleNumber{}
+	if err := tx.Where("id = ?", sourceID).First(source).Error; err != nil {
+		return nil, nil, err
+	}
+	target := &models.ArticleNumber{}
+	if err := tx.Where("id = ?", targetID).First(target).Error; err != nil {
+		return nil, nil, err
+	}
+	if source.WorkspaceID != target.WorkspaceID {
+		return nil, nil, fmt.Errorf("article numbers %s and %s belong to different workspaces", source.Number, target.Number)
+	}
+	return source, target, nil
 }
 
 // GetArticleNumberWithPhotos retrieves an article number with its associated photos
 func (r *ArticleNumberRepository) GetArticleNumberWithPhotos(articleNumberID uuid.UUID) (*models.ArticleNumber, error) {
 	articleNumber := &models.ArticleNumber{}
+	// Photos are ordered by upload, bot and command line users pick them by position
 	tx := r.db.
+		Preload("Photos", func(db *gorm.DB) *gorm.DB { return db.Order("photos.created_at") }).
 		Preload("Photos.ArticleNumbers").
 		Where("id = ?", articleNumberID).
 		First(articleNumber)
@@ -102,3 +486,61 @@ func (r *ArticleNumberRepository) GetArticleNumberWithPhotos(articleNumberID uui
 	}
 	return articleNumber, nil
 }
+
+func (r *ArticleNumberRepository) create(tx *gorm.DB, articleNumber *models.ArticleNumber) error {
+	if err := checkNumberFree(tx, articleNumber.WorkspaceID, articleNumber.Number); err != nil {
+		return err
+	}
+	if err := tx.Create(articleNumber).Error; err != nil {
+		return err
+	}
+	entry := models.NewAuditLog(r.actor, models.AuditArticleCreated, nil, models.NewArticleNumberSnapshot(articleNumber))
+	entry.ArticleNumberID = articleNumber.ID
+	return audit(tx, entry)
+}
+
+// findByNumber looks up a live ArticleNumber of the workspace by its number, then by its aliases
+func findByNumber(tx *gorm.DB, workspaceID uuid.UUID, number string) (*models.ArticleNumber, error) {
+	articleNumber := &models.ArticleNumber{}
+	err := tx.Where("workspace_id = ? AND number = ?", workspaceID, number).First(articleNumber).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		if err != nil {
+			return nil, err
+		}
+		return articleNumber, nil
+	}
+
+	err = tx.Joins("JOIN article_aliases ON article_aliases.article_number_id = article_numbers.id").
+		Where("article_aliases.workspace_id = ? AND article_aliases.alias = ?", workspaceID, number).
+		First(articleNumber).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return articleNumber, nil
+}
+
+// checkNumberFree fails when the number is already a number or an alias of the workspace.
+// The unique index of numbers covers article numbers in the trash as well.
+func checkNumberFree(tx *gorm.DB, workspaceID uuid.UUID, number string) error {
+	existing := &models.ArticleNumber{}
+	err := tx.Unscoped().Where("workspace_id = ? AND number = ?", workspaceID, number).First(existing).Error
+	switch {
+	case err == nil && existing.DeletedAt.Valid:
+		return models.ErrArticleNumberInTrash
+	case err == nil:
+		return models.ErrArticleNumberTaken
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+
+	var aliases int64
+	err = tx.Model(&models.ArticleAlias{}).Where("workspace_id = ? AND alias = ?", workspaceID, number).Count(&aliases).Error
+	if err != nil {
+		return err
+	}
+	if aliases > 0 {
+		return models.ErrArticleNumberTaken
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// AuditLogRepository queries the audit log. Entries are written by the
+// repositories of the changed models in the transaction of the change.
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository creates a new AuditLogRepository
+func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// GetAuditLogs retrieves entries selected by the filter, the newest first
+func (r *AuditLogRepository) GetAuditLogs(filter models.AuditLogFilter) ([]*models.AuditLog, error) {
+	query := r.db.Model(&models.AuditLog{})
+	if filter.ActorTelegramID != 0 {
+		query = query.Where("actor_telegram_id = ?", filter.ActorTelegramID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetID != uuid.Nil {
+		query = query.Where("photo_id = ? OR article_number_id = ? OR user_id = ?",
+			filter.TargetID, filter.TargetID, filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var entries []*models.AuditLog
+	if err := query.Order("id DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// audit writes an entry in the transaction of the change it describes
+func audit(tx *gorm.DB, entry *models.AuditLog) error {
+	return tx.Create(entry).Error
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// ChatRepository handles database operations for dialog states and group chats
+type ChatRepository struct {
+	db *gorm.DB
+}
+
+// NewChatRepository creates a new ChatRepository
+func NewChatRepository(db *gorm.DB) *ChatRepository {
+	return &ChatRepository{db: db}
+}
+
+// GetState retrieves the dialog state of a user in a chat, it is default when none was set
+func (r *ChatRepository) GetState(chatID, telegramID int64) (string, error) {
+	state := &models.ChatState{}
+	tx := r.db.Where("chat_id = ? AND telegram_id = ?", chatID, telegramID).First(state)
+	if tx.Error == gorm.ErrRecordNotFound || (tx.Error == nil && state.State == "") {
+		return models.TelegramUserStateDefault, nil
+	}
+	if tx.Error != nil {
+		return "", tx.Error
+	}
+	return state.State, nil
+}
+
+// SetState stores the dialog state of a user in a chat
+func (r *ChatRepository) SetState(chatID, telegramID int64, state string) error {
+	return r.db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "chat_id"}, {Name: "telegram_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"state", "updated_at"}),
+		}).
+		Create(&models.ChatState{ChatID: chatID, TelegramID: telegramID, State: state, UpdatedAt: time.Now()}).
+		Error
+}
+
+// GetGroupChat retrieves the binding of a group chat
+func (r *ChatRepository) GetGroupChat(chatID int64) (*models.GroupChat, error) {
+	group := &models.GroupChat{}
+	tx := r.db.Where("chat_id = ?", chatID).First(group)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return group, nil
+}
+
+// BindGroupChat binds a group chat to a workspace, replacing any previous binding
+func (r *ChatRepository) BindGroupChat(group *models.GroupChat) error {
+	existing, err := r.GetGroupChat(group.ChatID)
+	if err == gorm.ErrRecordNotFound {
+		return r.db.Create(group).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	group.ID = existing.ID
+	group.CreatedAt = existing.CreatedAt
+	return r.db.Model(existing).Updates(map[string]interface{}{
+		"title":        group.Title,
+		"workspace_id": group.WorkspaceID,
+	}).Error
+}
+
+// UnbindGroupChat removes the binding of a group chat
+func (r *ChatRepository) UnbindGroupChat(chatID int64) error {
+	// Unscoped, so the chat can be bound again despite the unique index
+	return r.db.Unscoped().Where("chat_id = ?", chatID).Delete(&models.GroupChat{}).Error
+}
@@ -0,0 +1,1019 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/repositories"
+	"github.com/Conty111/AlfredoBot/internal/repositories/memory"
+	"github.com/Conty111/AlfredoBot/internal/services/localstorage"
+)
+
+const bucket = "test-bucket"
+
+// backend is a full set of storage implementations under contract test
+type backend struct {
+	users      interfaces.TelegramUserManager
+	photos     interfaces.PhotoManager
+	articles   interfaces.ArticleNumberManager
+	invites    interfaces.InviteCodeManager
+	workspaces interfaces.WorkspaceManager
+	chats      interfaces.ChatManager
+	support    interfaces.SupportTicketManager
+	audit      interfaces.AuditLogManager
+	s3         interfaces.S3Client
+}
+
+// newGormBackend opens an isolated database for the gorm repositories.
+// TEST_DB_DSN switches from in-memory SQLite to a PostgreSQL database.
+func newGormBackend() backend {
+	var dialector gorm.Dialector
+	if dsn := os.Getenv("TEST_DB_DSN"); dsn != "" {
+		dialector = postgres.Open(dsn)
+	} else {
+		dialector = sqlite.Open("file:" + uuid.NewString() + "?mode=memory&cache=shared")
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+	Expect(err).To(BeNil())
+	Expect(db.AutoMigrate(
+		&models.TelegramUser{},
+		&models.Photo{},
+		&models.ArticleNumber{},
+		&models.ArticleNumberPhoto{},
+		&models.ArticleAlias{},
+		&models.InviteCode{},
+		&models.Workspace{},
+		&models.WorkspaceMember{},
+		&models.ChatState{},
+		&models.GroupChat{},
+		&models.SupportTicket{},
+		&models.SupportMessage{},
+		&models.AuditLog{},
+	)).To(Succeed())
+
+	if os.Getenv("TEST_DB_DSN") != "" {
+		DeferCleanup(func() {
+			db.Exec("TRUNCATE article_number_photos, article_aliases, photos, article_numbers, telegram_users, invite_codes, workspace_members, workspaces, chat_states, group_chats, support_tickets, support_messages, audit_logs")
+		})
+	}
+
+	s3Client, err := localstorage.NewClient(&configs.LocalStorageConfig{
+		Root:       GinkgoT().TempDir(),
+		SigningKey: "test",
+	})
+	Expect(err).To(BeNil())
+
+	return backend{
+		users:      repositories.NewTelegramUserRepository(db),
+		photos:     repositories.NewPhotoRepository(db, s3Client),
+		articles:   repositories.NewArticleNumberRepository(db),
+		invites:    repositories.NewInviteCodeRepository(db),
+		workspaces: repositories.NewWorkspaceRepository(db),
+		chats:      repositories.NewChatRepository(db),
+		support:    repositories.NewSupportTicketRepository(db),
+		audit:      repositories.NewAuditLogRepository(db),
+		s3:         s3Client,
+	}
+}
+
+func newMemoryBackend() backend {
+	db := memory.NewDatabase()
+	s3Client := memory.NewS3Client()
+
+	return backend{
+		users:      memory.NewTelegramUserRepository(db),
+		photos:     memory.NewPhotoRepository(db, s3Client),
+		articles:   memory.NewArticleNumberRepository(db),
+		invites:    memory.NewInviteCodeRepository(db),
+		workspaces: memory.NewWorkspaceRepository(db),
+		chats:      memory.NewChatRepository(db),
+		support:    memory.NewSupportTicketRepository(db),
+		audit:      memory.NewAuditLogRepository(db),
+		s3:         s3Client,
+	}
+}
+
+var _ = Describe("Repository contract", func() {
+	for name, newBackend := range map[string]func() backend{
+		"gorm":   newGormBackend,
+		"memory": newMemoryBackend,
+	} {
+		Describe(name, func() {
+			var b backend
+
+			BeforeEach(func() {
+				b = newBackend()
+			})
+
+			Describe("TelegramUserManager", func() {
+				var user *models.TelegramUser
+
+				BeforeEach(func() {
+					user = &models.TelegramUser{TelegramID: 42, Username: "alfredo", State: models.TelegramUserStateDefault}
+					Expect(b.users.CreateUser(user)).To(Succeed())
+				})
+
+				It("should assign an ID on create", func() {
+					Expect(user.ID).NotTo(Equal(uuid.Nil))
+				})
+
+				It("should find users by every key", func() {
+					byID, err := b.users.GetByID(user.ID)
+					Expect(err).To(BeNil())
+					Expect(byID.TelegramID).To(Equal(int64(42)))
+
+					byTelegramID, err := b.users.GetByTelegramID(42)
+					Expect(err).To(BeNil())
+					Expect(byTelegramID.ID).To(Equal(user.ID))
+
+					byUsername, err := b.users.GetByUsername("alfredo")
+					Expect(err).To(BeNil())
+					Expect(byUsername.ID).To(Equal(user.ID))
+				})
+
+				It("should return gorm.ErrRecordNotFound for missing users", func() {
+					_, err := b.users.GetByTelegramID(7)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+
+					_, err = b.users.GetByID(uuid.New())
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+				})
+
+				It("should refuse duplicate Telegram IDs", func() {
+					Expect(b.users.CreateUser(&models.TelegramUser{TelegramID: 42})).NotTo(Succeed())
+				})
+
+				It("should apply map updates by column name", func() {
+					Expect(b.users.UpdateByTelegramID(42, map[string]interface{}{
+						"state":      models.TelegramUserStateUploading,
+						"first_name": "Alfredo",
+					})).To(Succeed())
+
+					updated, err := b.users.GetByID(user.ID)
+					Expect(err).To(BeNil())
+					Expect(updated.State).To(Equal(models.TelegramUserStateUploading))
+					Expect(updated.FirstName).To(Equal("Alfredo"))
+					Expect(updated.Username).To(Equal("alfredo"))
+				})
+
+				It("should apply only non-zero fields of struct updates", func() {
+					Expect(b.users.UpdateByID(user.ID, models.TelegramUser{LastName: "Linguini"})).To(Succeed())
+
+					updated, err := b.users.GetByID(user.ID)
+					Expect(err).To(BeNil())
+					Expect(updated.LastName).To(Equal("Linguini"))
+					Expect(updated.Username).To(Equal("alfredo"))
+				})
+
+				It("should list users by state", func() {
+					Expect(b.users.CreateUser(&models.TelegramUser{TelegramID: 43, State: models.TelegramUserStateSearching})).To(Succeed())
+
+					users, err := b.users.GetUsersByState(models.TelegramUserStateSearching)
+					Expect(err).To(BeNil())
+					Expect(users).To(HaveLen(1))
+					Expect(users[0].TelegramID).To(Equal(int64(43)))
+				})
+
+				It("should default to the uploader role and list users by role", func() {
+					Expect(user.Role).To(Equal(models.TelegramUserRoleUploader))
+					Expect(b.users.CreateUser(&models.TelegramUser{TelegramID: 43, Role: models.TelegramUserRoleAdmin})).To(Succeed())
+
+					admins, err := b.users.GetUsersByRole(models.TelegramUserRoleAdmin)
+					Expect(err).To(BeNil())
+					Expect(admins).To(HaveLen(1))
+					Expect(admins[0].TelegramID).To(Equal(int64(43)))
+
+					stored, err := b.users.GetByTelegramID(42)
+					Expect(err).To(BeNil())
+					Expect(stored.Role).To(Equal(models.TelegramUserRoleUploader))
+				})
+
+				It("should hide deleted users", func() {
+					Expect(b.users.DeleteByTelegramID(42)).To(Succeed())
+
+					_, err := b.users.GetByID(user.ID)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+				})
+			})
+
+			Describe("ArticleNumberManager", func() {
+				workspaceID := uuid.New()
+
+				It("should get or create article numbers idempotently", func() {
+					first, err := b.articles.GetOrCreateArticleNumber(workspaceID, "1.2345")
+					Expect(err).To(BeNil())
+					second, err := b.articles.GetOrCreateArticleNumber(workspaceID, "1.2345")
+					Expect(err).To(BeNil())
+
+					Expect(second.ID).To(Equal(first.ID))
+					Expect(second.WorkspaceID).To(Equal(workspaceID))
+				})
+
+				It("should refuse duplicate numbers within a workspace", func() {
+					Expect(b.articles.CreateArticleNumber(&models.ArticleNumber{WorkspaceID: workspaceID, Number: "1.2345"})).To(Succeed())
+					Expect(b.articles.CreateArticleNumber(&models.ArticleNumber{WorkspaceID: workspaceID, Number: "1.2345"})).NotTo(Succeed())
+				})
+
+				It("should keep the same number apart in different workspaces", func() {
+					otherID := uuid.New()
+					first, err := b.articles.GetOrCreateArticleNumber(workspaceID, "1.2345")
+					Expect(err).To(BeNil())
+					second, err := b.articles.GetOrCreateArticleNumber(otherID, "1.2345")
+					Expect(err).To(BeNil())
+					Expect(second.ID).NotTo(Equal(first.ID))
+
+					found, err := b.articles.GetByNumber(otherID, "1.2345")
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(second.ID))
+					_, err = b.articles.GetByNumber(uuid.New(), "1.2345")
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+				})
+
+				It("should find, update and delete article numbers", func() {
+					articleNumber := &models.ArticleNumber{WorkspaceID: workspaceID, Number: "1.2345"}
+					Expect(b.articles.CreateArticleNumber(articleNumber)).To(Succeed())
+
+					articleNumber.Number = "6.7890"
+					Expect(b.articles.UpdateArticleNumber(articleNumber)).To(Succeed())
+
+					found, err := b.articles.GetByNumber(workspaceID, "6.7890")
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(articleNumber.ID))
+
+					Expect(b.articles.DeleteArticleNumber(articleNumber.ID)).To(Succeed())
+					_, err = b.articles.GetByID(articleNumber.ID)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+				})
+
+				It("should keep deleted article numbers in the trash until purged", func() {
+					articleNumber := &models.ArticleNumber{WorkspaceID: workspaceID, Number: "1.2345"}
+					Expect(b.articles.CreateArticleNumber(articleNumber)).To(Succeed())
+					Expect(b.articles.DeleteArticleNumber(articleNumber.ID)).To(Succeed())
+
+					deleted, err := b.articles.GetDeletedArticleNumbers(workspaceID)
+					Expect(err).To(BeNil())
+					Expect(deleted).To(HaveLen(1))
+					Expect(deleted[0].Number).To(Equal("1.2345"))
+
+					Expect(b.articles.RestoreArticleNumber(articleNumber.ID)).To(Succeed())
+					Expect(errors.Is(b.articles.RestoreArticleNumber(articleNumber.ID), gorm.ErrRecordNotFound)).To(BeTrue())
+					found, err := b.articles.GetByNumber(workspaceID, "1.2345")
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(articleNumber.ID))
+
+					Expect(b.articles.DeleteArticleNumber(articleNumber.ID)).To(Succeed())
+					purged, err := b.articles.PurgeDeletedArticleNumbers(time.Now().Add(-time.Hour))
+					Expect(err).To(BeNil())
+					Expect(purged).To(BeZero())
+					purged, err = b.articles.PurgeDeletedArticleNumbers(time.Now().Add(time.Hour))
+					Expect(err).To(BeNil())
+					Expect(purged).To(Equal(1))
+
+					deleted, err = b.articles.GetDeletedArticleNumbers(workspaceID)
+					Expect(err).To(BeNil())
+					Expect(deleted).To(BeEmpty())
+					Expect(errors.Is(b.articles.RestoreArticleNumber(articleNumber.ID), gorm.ErrRecordNotFound)).To(BeTrue())
+				})
+
+				It("should rename article numbers to free numbers only", func() {
+					articleNumber, err := b.articles.GetOrCreateArticleNumber(workspaceID, "1.2354")
+					Expect(err).To(BeNil())
+					taken, err := b.articles.GetOrCreateArticleNumber(workspaceID, "6.7890")
+					Expect(err).To(BeNil())
+					trashed, err := b.articles.GetOrCreateArticleNumber(workspaceID, "9.9999")
+					Expect(err).To(BeNil())
+					Expect(b.articles.DeleteArticleNumber(trashed.ID)).To(Succeed())
+
+					_, err = b.articles.RenameArticleNumber(articleNumber.ID, taken.Number)
+					Expect(err).To(MatchError(models.ErrArticleNumberTaken))
+					_, err = b.articles.RenameArticleNumber(articleNumber.ID, trashed.Number)
+					Expect(err).To(MatchError(models.ErrArticleNumberInTrash))
+					_, err = b.articles.RenameArticleNumber(uuid.New(), "1.2345")
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+
+					renamed, err := b.articles.RenameArticleNumber(articleNumber.ID, "1.2345")
+					Expect(err).To(BeNil())
+					Expect(renamed.ID).To(Equal(articleNumber.ID))
+					Expect(renamed.Number).To(Equal("1.2345"))
+					found, err := b.articles.GetByNumber(workspaceID, "1.2345")
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(articleNumber.ID))
+
+					entries, err := b.audit.GetAuditLogs(models.AuditLogFilter{TargetID: articleNumber.ID, Limit: 1})
+					Expect(err).To(BeNil())
+					Expect(entries[0].Action).To(Equal(models.AuditArticleUpdated))
+					Expect(entries[0].Before).To(ContainSubstring(`"1.2354"`))
+					Expect(entries[0].After).To(ContainSubstring(`"1.2345"`))
+				})
+
+				It("should merge and move photos between article numbers", func() {
+					source, err := b.articles.GetOrCreateArticleNumber(workspaceID, "1.2354")
+					Expect(err).To(BeNil())
+					target, err := b.articles.GetOrCreateArticleNumber(workspaceID, "1.2345")
+					Expect(err).To(BeNil())
+					var photos []*models.Photo
+					for i := 0; i < 3; i++ {
+						photo := &models.Photo{UserID: uuid.New(), S3Key: uuid.New(), State: models.PhotoApplied, WorkspaceID: workspaceID}
+						Expect(b.photos.CreatePhoto(photo)).To(Succeed())
+						Expect(b.photos.AddArticleNumberToPhoto(photo.ID, source.ID)).To(Succeed())
+						photos = append(photos, photo)
+					}
+					Expect(b.photos.AddArticleNumberToPhoto(photos[1].ID, target.ID)).To(Succeed())
+
+					moved, err := b.articles.MoveArticleNumberPhotos(source.ID, target.ID, []uuid.UUID{photos[0].ID, uuid.New()})
+					Expect(err).To(BeNil())
+					Expect(moved).To(Equal(1))
+					numbers, err := b.articles.GetArticleNumbersByPhoto(photos[0].ID)
+					Expect(err).To(BeNil())
+					Expect(numbers).To(HaveLen(1))
+					Expect(numbers[0].ID).To(Equal(target.ID))
+
+					_, err = b.articles.MoveArticleNumberPhotos(source.ID, source.ID, []uuid.UUID{photos[1].ID})
+					Expect(err).NotTo(BeNil())
+					other, err := b.articles.GetOrCreateArticleNumber(uuid.New(), "1.2345")
+					Expect(err).To(BeNil())
+					_, err = b.articles.MergeArticleNumbers(source.ID, other.ID)
+					Expect(err).NotTo(BeNil())
+
+					moved, err = b.articles.MergeArticleNumbers(source.ID, target.ID)
+					Expect(err).To(BeNil())
+					Expect(moved).To(Equal(2))
+					merged, err := b.articles.GetArticleNumberWithPhotos(target.ID)
+					Expect(err).To(BeNil())
+					Expect(merged.Photos).To(HaveLen(3))
+					for _, photo := range photos {
+						numbers, err := b.articles.GetArticleNumbersByPhoto(photo.ID)
+						Expect(err).To(BeNil())
+						Expect(numbers).To(HaveLen(1))
+					}
+
+					_, err = b.articles.GetByID(source.ID)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+					deleted, err := b.articles.GetDeletedArticleNumbers(workspaceID)
+					Expect(err).To(BeNil())
+					Expect(deleted).To(HaveLen(1))
+					Expect(deleted[0].ID).To(Equal(source.ID))
+
+					entries, err := b.audit.GetAuditLogs(models.AuditLogFilter{Action: models.AuditArticleMerged})
+					Expect(err).To(BeNil())
+					Expect(entries).To(HaveLen(1))
+					Expect(entries[0].ArticleNumberID).To(Equal(source.ID))
+					Expect(entries[0].After).To(ContainSubstring(target.ID.String()))
+
+					// The merged-away number finds the target for searches and new uploads
+					found, err := b.articles.GetByNumber(workspaceID, "1.2354")
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(target.ID))
+					found, err = b.articles.GetOrCreateArticleNumber(workspaceID, "1.2354")
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(target.ID))
+					_, err = b.articles.AddArticleAlias(target.ID, "1.2354")
+					Expect(err).To(BeNil())
+
+					Expect(b.articles.RestoreArticleNumber(source.ID)).To(Succeed())
+					found, err = b.articles.GetByNumber(workspaceID, "1.2354")
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(source.ID))
+					aliases, err := b.articles.GetArticleAliases(target.ID)
+					Expect(err).To(BeNil())
+					Expect(aliases).To(BeEmpty())
+				})
+
+				It("should resolve aliases unique across aliases and numbers", func() {
+					articleNumber, err := b.articles.GetOrCreateArticleNumber(workspaceID, "1.2345")
+					Expect(err).To(BeNil())
+					other, err := b.articles.GetOrCreateArticleNumber(workspaceID, "6.7890")
+					Expect(err).To(BeNil())
+
+					alias, err := b.articles.AddArticleAlias(articleNumber.ID, "4006381333931")
+					Expect(err).To(BeNil())
+					Expect(alias.ArticleNumberID).To(Equal(articleNumber.ID))
+					again, err := b.articles.AddArticleAlias(articleNumber.ID, "4006381333931")
+					Expect(err).To(BeNil())
+					Expect(again.ID).To(Equal(alias.ID))
+					_, err = b.articles.AddArticleAlias(other.ID, "4006381333931")
+					Expect(err).To(MatchError(models.ErrArticleNumberTaken))
+					_, err = b.articles.AddArticleAlias(other.ID, "1.2345")
+					Expect(err).To(MatchError(models.ErrArticleNumberTaken))
+					_, err = b.articles.RenameArticleNumber(other.ID, "4006381333931")
+					Expect(err).To(MatchError(models.ErrArticleNumberTaken))
+					Expect(b.articles.CreateArticleNumber(&models.ArticleNumber{WorkspaceID: workspaceID, Number: "4006381333931"})).
+						To(MatchError(models.ErrArticleNumberTaken))
+
+					found, err := b.articles.GetByNumber(workspaceID, "4006381333931")
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(articleNumber.ID))
+					found, err = b.articles.GetOrCreateArticleNumber(workspaceID, "4006381333931")
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(articleNumber.ID))
+					_, err = b.articles.GetByNumber(uuid.New(), "4006381333931")
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+
+					_, err = b.articles.MergeArticleNumbers(articleNumber.ID, other.ID)
+					Expect(err).To(BeNil())
+					found, err = b.articles.GetByNumber(workspaceID, "4006381333931")
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(other.ID))
+					aliases, err := b.articles.GetArticleAliases(other.ID)
+					Expect(err).To(BeNil())
+					Expect(aliases).To(HaveLen(2))
+					Expect(aliases[1].Alias).To(Equal("1.2345"))
+
+					owner, err := b.articles.RemoveArticleAlias(workspaceID, "4006381333931")
+					Expect(err).To(BeNil())
+					Expect(owner.ID).To(Equal(other.ID))
+					_, err = b.articles.GetByNumber(workspaceID, "4006381333931")
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+					_, err = b.articles.RemoveArticleAlias(workspaceID, "4006381333931")
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+
+					entries, err := b.audit.GetAuditLogs(models.AuditLogFilter{TargetID: other.ID, Limit: 1})
+					Expect(err).To(BeNil())
+					Expect(entries[0].Action).To(Equal(models.AuditAliasRemoved))
+					Expect(entries[0].Before).To(ContainSubstring(`"4006381333931"`))
+				})
+
+				It("should add all aliases or none of them", func() {
+					articleNumber, err := b.articles.GetOrCreateArticleNumber(workspaceID, "1.2345")
+					Expect(err).To(BeNil())
+					_, err = b.articles.GetOrCreateArticleNumber(workspaceID, "6.7890")
+					Expect(err).To(BeNil())
+
+					_, err = b.articles.AddArticleAliases(articleNumber.ID, []string{"SUP-77", "6.7890", "4006381333931"})
+					var numberErr *models.ArticleNumberError
+					Expect(errors.As(err, &numberErr)).To(BeTrue())
+					Expect(numberErr.Number).To(Equal("6.7890"))
+					Expect(err).To(MatchError(models.ErrArticleNumberTaken))
+					aliases, err := b.articles.GetArticleAliases(articleNumber.ID)
+					Expect(err).To(BeNil())
+					Expect(aliases).To(BeEmpty())
+
+					added, err := b.articles.AddArticleAliases(articleNumber.ID, []string{"SUP-77", "4006381333931", "SUP-77"})
+					Expect(err).To(BeNil())
+					Expect(added).To(HaveLen(3))
+					Expect(added[2].ID).To(Equal(added[0].ID))
+					aliases, err = b.articles.GetArticleAliases(articleNumber.ID)
+					Expect(err).To(BeNil())
+					Expect(aliases).To(HaveLen(2))
+				})
+			})
+
+			Describe("PhotoManager", func() {
+				var (
+					user          *models.TelegramUser
+					photo         *models.Photo
+					articleNumber *models.ArticleNumber
+				)
+
+				BeforeEach(func() {
+					user = &models.TelegramUser{TelegramID: 42}
+					Expect(b.users.CreateUser(user)).To(Succeed())
+
+					photo = &models.Photo{UserID: user.ID, S3Key: uuid.New(), State: models.PhotoNotApplied}
+					Expect(b.photos.CreatePhoto(photo)).To(Succeed())
+
+					var err error
+					articleNumber, err = b.articles.GetOrCreateArticleNumber(uuid.New(), "1.2345")
+					Expect(err).To(BeNil())
+				})
+
+				It("should list user's photos by state", func() {
+					photos, err := b.photos.GetUsersPhotosByState(user.ID, models.PhotoNotApplied)
+					Expect(err).To(BeNil())
+					Expect(photos).To(HaveLen(1))
+					Expect(photos[0].ID).To(Equal(photo.ID))
+
+					photo.State = models.PhotoApplied
+					Expect(b.photos.UpdatePhoto(photo)).To(Succeed())
+
+					photos, err = b.photos.GetUsersPhotosByState(user.ID, models.PhotoNotApplied)
+					Expect(err).To(BeNil())
+					Expect(photos).To(BeEmpty())
+				})
+
+				It("should link and unlink article numbers", func() {
+					Expect(b.photos.AddArticleNumberToPhoto(photo.ID, articleNumber.ID)).To(Succeed())
+
+					withArticles, err := b.photos.GetPhotoWithArticleNumbers(photo.ID)
+					Expect(err).To(BeNil())
+					Expect(withArticles.ArticleNumbers).To(HaveLen(1))
+					Expect(withArticles.ArticleNumbers[0].Number).To(Equal("1.2345"))
+
+					photos, err := b.photos.GetPhotosByArticleNumber(articleNumber.ID)
+					Expect(err).To(BeNil())
+					Expect(photos).To(HaveLen(1))
+					Expect(photos[0].ID).To(Equal(photo.ID))
+
+					articleNumbers, err := b.articles.GetArticleNumbersByPhoto(photo.ID)
+					Expect(err).To(BeNil())
+					Expect(articleNumbers).To(HaveLen(1))
+
+					withPhotos, err := b.articles.GetArticleNumberWithPhotos(articleNumber.ID)
+					Expect(err).To(BeNil())
+					Expect(withPhotos.Photos).To(HaveLen(1))
+					Expect(withPhotos.Photos[0].ArticleNumbers).To(HaveLen(1))
+
+					Expect(b.photos.RemoveArticleNumberFromPhoto(photo.ID, articleNumber.ID)).To(Succeed())
+
+					photos, err = b.photos.GetPhotosByArticleNumber(articleNumber.ID)
+					Expect(err).To(BeNil())
+					Expect(photos).To(BeEmpty())
+				})
+
+				It("should generate presigned photo URLs named after the image format", func() {
+					url, err := b.photos.GetPhotoURL(context.Background(), user.ID, photo.S3Key, photo.Format, bucket, time.Hour)
+					Expect(err).To(BeNil())
+					Expect(url).To(ContainSubstring(photo.S3Key.String() + ".jpg"))
+					url, err = b.photos.GetPhotoURL(context.Background(), user.ID, photo.S3Key, "png", bucket, time.Hour)
+					Expect(err).To(BeNil())
+					Expect(url).To(ContainSubstring(photo.S3Key.String() + ".png"))
+				})
+
+				It("should refuse linking missing article numbers", func() {
+					Expect(b.photos.AddArticleNumberToPhoto(photo.ID, uuid.New())).NotTo(Succeed())
+				})
+
+				It("should refuse linking the same article number twice", func() {
+					Expect(b.photos.AddArticleNumberToPhoto(photo.ID, articleNumber.ID)).To(Succeed())
+					Expect(b.photos.AddArticleNumberToPhoto(photo.ID, articleNumber.ID)).NotTo(Succeed())
+				})
+
+				It("should keep photo data in the trash and remove it on purge", func() {
+					ctx := context.Background()
+					photo.Format = "png"
+					Expect(b.photos.UpdatePhoto(photo)).To(Succeed())
+					Expect(b.photos.UploadPhotoToS3(ctx, user.ID, photo.S3Key, photo.Format, bucket, strings.NewReader("png"))).To(Succeed())
+					rendition := photo.RenditionKey(models.PhotoRenditionWatermarked)
+					Expect(b.photos.UploadPhotoToS3(ctx, user.ID, rendition, models.PhotoRenditionFormat, bucket, strings.NewReader("watermarked"))).
+						To(Succeed())
+
+					reader, err := b.photos.GetPhotoFromS3(ctx, user.ID, photo.S3Key, photo.Format, bucket)
+					Expect(err).To(BeNil())
+					data, err := io.ReadAll(reader)
+					Expect(err).To(BeNil())
+					Expect(reader.Close()).To(Succeed())
+					Expect(string(data)).To(Equal("png"))
+					_, err = b.photos.GetPhotoFromS3(ctx, user.ID, photo.S3Key, "", bucket)
+					Expect(err).NotTo(BeNil())
+
+					Expect(b.photos.AddArticleNumberToPhoto(photo.ID, articleNumber.ID)).To(Succeed())
+					Expect(b.photos.DeletePhoto(photo.ID)).To(Succeed())
+
+					_, err = b.photos.GetByID(photo.ID)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+					reader, err = b.photos.GetPhotoFromS3(ctx, user.ID, photo.S3Key, photo.Format, bucket)
+					Expect(err).To(BeNil())
+					Expect(reader.Close()).To(Succeed())
+
+					deleted, err := b.photos.GetDeletedPhotos(photo.WorkspaceID)
+					Expect(err).To(BeNil())
+					Expect(deleted).To(HaveLen(1))
+					Expect(deleted[0].ID).To(Equal(photo.ID))
+					Expect(deleted[0].ArticleNumbers).To(HaveLen(1))
+
+					purged, err := b.photos.PurgeDeletedPhotos(time.Now().Add(time.Hour), bucket)
+					Expect(err).To(BeNil())
+					Expect(purged).To(Equal(1))
+
+					deleted, err = b.photos.GetDeletedPhotos(photo.WorkspaceID)
+					Expect(err).To(BeNil())
+					Expect(deleted).To(BeEmpty())
+					_, err = b.photos.GetPhotoFromS3(ctx, user.ID, photo.S3Key, photo.Format, bucket)
+					Expect(err).NotTo(BeNil())
+					_, err = b.photos.GetPhotoFromS3(ctx, user.ID, rendition, models.PhotoRenditionFormat, bucket)
+					Expect(err).NotTo(BeNil())
+					found, err := b.articles.GetArticleNumberWithPhotos(articleNumber.ID)
+					Expect(err).To(BeNil())
+					Expect(found.Photos).To(BeEmpty())
+				})
+
+				It("should discard photos with their data", func() {
+					ctx := context.Background()
+					Expect(b.photos.UploadPhotoToS3(ctx, user.ID, photo.S3Key, photo.Format, bucket, strings.NewReader("jpeg"))).To(Succeed())
+					Expect(b.photos.DiscardPhoto(photo.ID, bucket)).To(Succeed())
+
+					_, err := b.photos.GetPhotoFromS3(ctx, user.ID, photo.S3Key, photo.Format, bucket)
+					Expect(err).NotTo(BeNil())
+					deleted, err := b.photos.GetDeletedPhotos(photo.WorkspaceID)
+					Expect(err).To(BeNil())
+					Expect(deleted).To(BeEmpty())
+				})
+
+				It("should delete data of photos stored without a bucket", func() {
+					ctx := context.Background()
+					Expect(b.photos.UploadPhotoToS3(ctx, user.ID, photo.S3Key, photo.Format, "", strings.NewReader("jpeg"))).To(Succeed())
+					Expect(b.photos.DiscardPhoto(photo.ID, "")).To(Succeed())
+
+					_, err := b.photos.GetPhotoFromS3(ctx, user.ID, photo.S3Key, photo.Format, "")
+					Expect(err).NotTo(BeNil())
+				})
+
+				It("should restore deleted photos", func() {
+					Expect(b.photos.DeletePhoto(photo.ID)).To(Succeed())
+					Expect(errors.Is(b.photos.DeletePhoto(photo.ID), gorm.ErrRecordNotFound)).To(BeTrue())
+
+					Expect(b.photos.RestorePhoto(photo.ID)).To(Succeed())
+					Expect(errors.Is(b.photos.RestorePhoto(photo.ID), gorm.ErrRecordNotFound)).To(BeTrue())
+
+					found, err := b.photos.GetByID(photo.ID)
+					Expect(err).To(BeNil())
+					Expect(found.S3Key).To(Equal(photo.S3Key))
+					deleted, err := b.photos.GetDeletedPhotos(photo.WorkspaceID)
+					Expect(err).To(BeNil())
+					Expect(deleted).To(BeEmpty())
+				})
+
+				It("should list photos selected by the filter", func() {
+					other := &models.Photo{UserID: uuid.New(), S3Key: uuid.New(), State: models.PhotoApplied, WorkspaceID: photo.WorkspaceID}
+					Expect(b.photos.CreatePhoto(other)).To(Succeed())
+					Expect(b.photos.AddArticleNumberToPhoto(other.ID, articleNumber.ID)).To(Succeed())
+
+					found, err := b.photos.GetPhotos(models.PhotoFilter{WorkspaceID: photo.WorkspaceID})
+					Expect(err).To(BeNil())
+					Expect(found).To(HaveLen(2))
+					Expect(found[0].ID).To(Equal(photo.ID))
+					Expect(found[1].ArticleNumbers).To(HaveLen(1))
+
+					found, err = b.photos.GetPhotos(models.PhotoFilter{UserID: user.ID, State: models.PhotoNotApplied})
+					Expect(err).To(BeNil())
+					Expect(found).To(HaveLen(1))
+					Expect(found[0].ID).To(Equal(photo.ID))
+
+					found, err = b.photos.GetPhotos(models.PhotoFilter{
+						Since: other.CreatedAt.Add(-time.Minute),
+						Until: other.CreatedAt.Add(time.Minute),
+						State: models.PhotoApplied,
+					})
+					Expect(err).To(BeNil())
+					Expect(found).To(HaveLen(1))
+					Expect(found[0].ID).To(Equal(other.ID))
+
+					found, err = b.photos.GetPhotos(models.PhotoFilter{Until: photo.CreatedAt})
+					Expect(err).To(BeNil())
+					Expect(found).To(BeEmpty())
+				})
+
+				It("should find applied photos by content hash in their workspace", func() {
+					hash := models.PhotoContentHash([]byte("jpeg-data"))
+					photo.ContentHash = hash
+					Expect(b.photos.UpdatePhoto(photo)).To(Succeed())
+
+					_, err := b.photos.GetByContentHash(photo.WorkspaceID, hash)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+
+					photo.State = models.PhotoApplied
+					Expect(b.photos.UpdatePhoto(photo)).To(Succeed())
+					Expect(b.photos.AddArticleNumberToPhoto(photo.ID, articleNumber.ID)).To(Succeed())
+
+					found, err := b.photos.GetByContentHash(photo.WorkspaceID, hash)
+					Expect(err).To(BeNil())
+					Expect(found.ID).To(Equal(photo.ID))
+					Expect(found.ArticleNumbers).To(HaveLen(1))
+
+					_, err = b.photos.GetByContentHash(uuid.New(), hash)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+				})
+			})
+
+			Describe("InviteCodeManager", func() {
+				BeforeEach(func() {
+					Expect(b.invites.CreateInviteCode(&models.InviteCode{
+						Code:    "single",
+						Role:    models.TelegramUserRoleViewer,
+						MaxUses: 1,
+					})).To(Succeed())
+					Expect(b.invites.CreateInviteCode(&models.InviteCode{
+						Code: "unlimited",
+						Role: models.TelegramUserRoleUploader,
+					})).To(Succeed())
+				})
+
+				It("should spend single-use codes once", func() {
+					invite, err := b.invites.UseInviteCode("single")
+					Expect(err).To(BeNil())
+					Expect(invite.Role).To(Equal(models.TelegramUserRoleViewer))
+					Expect(invite.Uses).To(Equal(1))
+
+					_, err = b.invites.UseInviteCode("single")
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+
+					exhausted, err := b.invites.GetByCode("single")
+					Expect(err).To(BeNil())
+					Expect(exhausted.IsExhausted()).To(BeTrue())
+				})
+
+				It("should keep unlimited codes active", func() {
+					for i := 0; i < 3; i++ {
+						_, err := b.invites.UseInviteCode("unlimited")
+						Expect(err).To(BeNil())
+					}
+					_, err := b.invites.UseInviteCode("single")
+					Expect(err).To(BeNil())
+
+					active, err := b.invites.GetActiveInviteCodes()
+					Expect(err).To(BeNil())
+					Expect(active).To(HaveLen(1))
+					Expect(active[0].Code).To(Equal("unlimited"))
+					Expect(active[0].Uses).To(Equal(3))
+				})
+
+				It("should not use deleted or unknown codes", func() {
+					Expect(b.invites.DeleteByCode("unlimited")).To(Succeed())
+
+					_, err := b.invites.UseInviteCode("unlimited")
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+					_, err = b.invites.UseInviteCode("missing")
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+				})
+			})
+
+			Describe("WorkspaceManager", func() {
+				var (
+					user      *models.TelegramUser
+					workspace *models.Workspace
+				)
+
+				BeforeEach(func() {
+					user = &models.TelegramUser{TelegramID: 42}
+					Expect(b.users.CreateUser(user)).To(Succeed())
+
+					var err error
+					workspace, err = b.workspaces.GetOrCreateWorkspace("north")
+					Expect(err).To(BeNil())
+				})
+
+				It("should get or create workspaces idempotently", func() {
+					again, err := b.workspaces.GetOrCreateWorkspace("north")
+					Expect(err).To(BeNil())
+					Expect(again.ID).To(Equal(workspace.ID))
+
+					Expect(b.workspaces.CreateWorkspace(&models.Workspace{Name: "north"})).NotTo(Succeed())
+				})
+
+				It("should list workspaces by name", func() {
+					_, err := b.workspaces.GetOrCreateWorkspace("east")
+					Expect(err).To(BeNil())
+
+					workspaces, err := b.workspaces.GetAll()
+					Expect(err).To(BeNil())
+					Expect(workspaces).To(HaveLen(2))
+					Expect(workspaces[0].Name).To(Equal("east"))
+					Expect(workspaces[1].Name).To(Equal("north"))
+				})
+
+				It("should add and remove members", func() {
+					Expect(b.workspaces.AddMember(workspace.ID, user.ID)).To(Succeed())
+					Expect(b.workspaces.AddMember(workspace.ID, user.ID)).To(Succeed())
+
+					isMember, err := b.workspaces.IsMember(workspace.ID, user.ID)
+					Expect(err).To(BeNil())
+					Expect(isMember).To(BeTrue())
+
+					members, err := b.workspaces.GetMembers(workspace.ID)
+					Expect(err).To(BeNil())
+					Expect(members).To(HaveLen(1))
+					Expect(members[0].TelegramID).To(Equal(int64(42)))
+
+					userWorkspaces, err := b.workspaces.GetUserWorkspaces(user.ID)
+					Expect(err).To(BeNil())
+					Expect(userWorkspaces).To(HaveLen(1))
+					Expect(userWorkspaces[0].ID).To(Equal(workspace.ID))
+
+					Expect(b.workspaces.RemoveMember(workspace.ID, user.ID)).To(Succeed())
+					isMember, err = b.workspaces.IsMember(workspace.ID, user.ID)
+					Expect(err).To(BeNil())
+					Expect(isMember).To(BeFalse())
+				})
+			})
+
+			Describe("ChatManager", func() {
+				It("should keep dialog states per chat and user", func() {
+					state, err := b.chats.GetState(-100, 42)
+					Expect(err).To(BeNil())
+					Expect(state).To(Equal(models.TelegramUserStateDefault))
+
+					Expect(b.chats.SetState(-100, 42, models.TelegramUserStateSearching)).To(Succeed())
+					Expect(b.chats.SetState(42, 42, models.TelegramUserStateUploading)).To(Succeed())
+					Expect(b.chats.SetState(-100, 42, models.TelegramUserStateUploading)).To(Succeed())
+
+					state, err = b.chats.GetState(-100, 42)
+					Expect(err).To(BeNil())
+					Expect(state).To(Equal(models.TelegramUserStateUploading))
+					state, err = b.chats.GetState(-100, 43)
+					Expect(err).To(BeNil())
+					Expect(state).To(Equal(models.TelegramUserStateDefault))
+				})
+
+				It("should bind, rebind and unbind group chats", func() {
+					_, err := b.chats.GetGroupChat(-100)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+
+					first, second := uuid.New(), uuid.New()
+					Expect(b.chats.BindGroupChat(&models.GroupChat{ChatID: -100, Title: "Shop", WorkspaceID: first})).To(Succeed())
+					Expect(b.chats.BindGroupChat(&models.GroupChat{ChatID: -100, Title: "Shop", WorkspaceID: second})).To(Succeed())
+
+					group, err := b.chats.GetGroupChat(-100)
+					Expect(err).To(BeNil())
+					Expect(group.WorkspaceID).To(Equal(second))
+					Expect(group.Title).To(Equal("Shop"))
+
+					Expect(b.chats.UnbindGroupChat(-100)).To(Succeed())
+					_, err = b.chats.GetGroupChat(-100)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+					Expect(b.chats.BindGroupChat(&models.GroupChat{ChatID: -100, WorkspaceID: first})).To(Succeed())
+				})
+			})
+
+			Describe("SupportTicketManager", func() {
+				It("should keep tickets with their history", func() {
+					_, err := b.support.GetOpenTicket(42)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+
+					first := &models.SupportTicket{TelegramID: 42}
+					Expect(b.support.CreateTicket(first)).To(Succeed())
+					second := &models.SupportTicket{TelegramID: 43}
+					Expect(b.support.CreateTicket(second)).To(Succeed())
+					Expect(first.Status).To(Equal(models.SupportTicketOpen))
+					Expect(second.ID).To(BeNumerically(">", first.ID))
+
+					Expect(b.support.AddMessage(&models.SupportMessage{
+						TicketID: first.ID, TelegramID: 42, Text: "help", StaffChatID: -100, StaffMessageID: 7,
+					})).To(Succeed())
+					Expect(b.support.AddMessage(&models.SupportMessage{
+						TicketID: first.ID, TelegramID: 1, FromStaff: true, Text: "sure", StaffChatID: -100, StaffMessageID: 8,
+					})).To(Succeed())
+
+					open, err := b.support.GetOpenTicket(42)
+					Expect(err).To(BeNil())
+					Expect(open.ID).To(Equal(first.ID))
+
+					byStaffMessage, err := b.support.GetTicketByStaffMessage(-100, 7)
+					Expect(err).To(BeNil())
+					Expect(byStaffMessage.ID).To(Equal(first.ID))
+					_, err = b.support.GetTicketByStaffMessage(-200, 7)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+
+					history, err := b.support.GetMessages(first.ID)
+					Expect(err).To(BeNil())
+					Expect(history).To(HaveLen(2))
+					Expect(history[0].Text).To(Equal("help"))
+					Expect(history[1].FromStaff).To(BeTrue())
+
+					Expect(b.support.CloseTicket(first.ID)).To(Succeed())
+					closed, err := b.support.GetTicket(first.ID)
+					Expect(err).To(BeNil())
+					Expect(closed.Status).To(Equal(models.SupportTicketClosed))
+					Expect(closed.ClosedAt).NotTo(BeNil())
+					_, err = b.support.GetOpenTicket(42)
+					Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+
+					tickets, err := b.support.GetOpenTickets()
+					Expect(err).To(BeNil())
+					Expect(tickets).To(HaveLen(1))
+					Expect(tickets[0].ID).To(Equal(second.ID))
+				})
+			})
+
+			Describe("AuditLogManager", func() {
+				var (
+					admin *models.TelegramUser
+					user  *models.TelegramUser
+				)
+
+				BeforeEach(func() {
+					admin = &models.TelegramUser{TelegramID: 1, Username: "admin", Role: models.TelegramUserRoleAdmin}
+					Expect(b.users.CreateUser(admin)).To(Succeed())
+					user = &models.TelegramUser{TelegramID: 42}
+					Expect(b.users.CreateUser(user)).To(Succeed())
+				})
+
+				It("should record catalog changes with their actor", func() {
+					photos := b.photos.WithActor(user)
+					photo := &models.Photo{UserID: user.ID, S3Key: uuid.New(), State: models.PhotoNotApplied}
+					Expect(photos.CreatePhoto(photo)).To(Succeed())
+					articleNumber, err := b.articles.WithActor(user).GetOrCreateArticleNumber(uuid.New(), "1.2345")
+					Expect(err).To(BeNil())
+
+					photo.State = models.PhotoApplied
+					Expect(photos.UpdatePhoto(photo)).To(Succeed())
+					Expect(photos.AddArticleNumberToPhoto(photo.ID, articleNumber.ID)).To(Succeed())
+					Expect(b.photos.WithActor(admin).RemoveArticleNumberFromPhoto(photo.ID, articleNumber.ID)).To(Succeed())
+					Expect(b.photos.WithActor(admin).DeletePhoto(photo.ID)).To(Succeed())
+
+					entries, err := b.audit.GetAuditLogs(models.AuditLogFilter{TargetID: photo.ID})
+					Expect(err).To(BeNil())
+					actions := make([]string, 0, len(entries))
+					for _, entry := range entries {
+						actions = append(actions, entry.Action)
+					}
+					Expect(actions).To(Equal([]string{
+						models.AuditPhotoDeleted, models.AuditArticleUnlinked, models.AuditArticleLinked,
+						models.AuditPhotoApplied, models.AuditPhotoCreated,
+					}))
+
+					Expect(entries[0].ActorTelegramID).To(Equal(int64(1)))
+					Expect(entries[0].ActorUsername).To(Equal("admin"))
+					Expect(entries[0].Before).To(ContainSubstring(`"state":"applied"`))
+					Expect(entries[0].After).To(BeEmpty())
+					Expect(entries[1].ArticleNumberID).To(Equal(articleNumber.ID))
+					Expect(entries[1].Before).To(ContainSubstring(`"number":"1.2345"`))
+					Expect(entries[3].Before).To(ContainSubstring(`"state":"not_applied"`))
+					Expect(entries[3].After).To(ContainSubstring(`"state":"applied"`))
+
+					byUser, err := b.audit.GetAuditLogs(models.AuditLogFilter{ActorTelegramID: 42, Action: models.AuditArticleCreated})
+					Expect(err).To(BeNil())
+					Expect(byUser).To(HaveLen(1))
+					Expect(byUser[0].ArticleNumberID).To(Equal(articleNumber.ID))
+
+					latest, err := b.audit.GetAuditLogs(models.AuditLogFilter{Limit: 2})
+					Expect(err).To(BeNil())
+					Expect(latest).To(HaveLen(2))
+					Expect(latest[0].Action).To(Equal(models.AuditPhotoDeleted))
+				})
+
+				It("should record role changes only", func() {
+					users := b.users.WithActor(admin)
+					Expect(users.UpdateByID(user.ID, map[string]interface{}{"locale": "en"})).To(Succeed())
+					Expect(users.UpdateByID(user.ID, map[string]interface{}{"role": models.TelegramUserRoleUploader})).To(Succeed())
+					Expect(users.UpdateByTelegramID(42, map[string]interface{}{"role": models.TelegramUserRoleViewer})).To(Succeed())
+					// Changes from the command line have no actor
+					Expect(b.users.UpdateByID(user.ID, map[string]interface{}{"role": models.TelegramUserRoleAdmin})).To(Succeed())
+
+					entries, err := b.audit.GetAuditLogs(models.AuditLogFilter{Action: models.AuditUserRoleChanged})
+					Expect(err).To(BeNil())
+					Expect(entries).To(HaveLen(2))
+					Expect(entries[0].ActorID).To(Equal(uuid.Nil))
+					Expect(entries[0].After).To(Equal(`{"role":"admin"}`))
+					Expect(entries[1].ActorID).To(Equal(admin.ID))
+					Expect(entries[1].UserID).To(Equal(user.ID))
+					Expect(entries[1].Before).To(Equal(`{"role":"uploader"}`))
+					Expect(entries[1].After).To(Equal(`{"role":"viewer"}`))
+				})
+
+				It("should filter entries by time", func() {
+					Expect(b.articles.CreateArticleNumber(&models.ArticleNumber{WorkspaceID: uuid.New(), Number: "1"})).To(Succeed())
+
+					entries, err := b.audit.GetAuditLogs(models.AuditLogFilter{Since: time.Now().Add(-time.Minute)})
+					Expect(err).To(BeNil())
+					Expect(entries).To(HaveLen(1))
+					entries, err = b.audit.GetAuditLogs(models.AuditLogFilter{Since: time.Now().Add(time.Minute)})
+					Expect(err).To(BeNil())
+					Expect(entries).To(BeEmpty())
+				})
+			})
+
+			Describe("S3Client", func() {
+				var ctx context.Context
+
+				BeforeEach(func() {
+					ctx = context.Background()
+				})
+
+				It("should round-trip objects", func() {
+					Expect(b.s3.UploadFile(ctx, bucket, "a/b.jpg", strings.NewReader("data"))).To(Succeed())
+
+					reader, err := b.s3.DownloadFile(ctx, bucket, "a/b.jpg")
+					Expect(err).To(BeNil())
+					data, err := io.ReadAll(reader)
+					Expect(err).To(BeNil())
+					Expect(reader.Close()).To(Succeed())
+					Expect(string(data)).To(Equal("data"))
+				})
+
+				It("should fail to download missing objects", func() {
+					_, err := b.s3.DownloadFile(ctx, bucket, "missing.jpg")
+					Expect(err).NotTo(BeNil())
+				})
+
+				It("should delete objects idempotently", func() {
+					Expect(b.s3.UploadFile(ctx, bucket, "a/b.jpg", strings.NewReader("data"))).To(Succeed())
+					Expect(b.s3.DeleteFile(ctx, bucket, "a/b.jpg")).To(Succeed())
+					Expect(b.s3.DeleteFile(ctx, bucket, "a/b.jpg")).To(Succeed())
+				})
+
+				It("should generate presigned URLs", func() {
+					url, err := b.s3.GeneratePresignedURL(ctx, bucket, "a/b.jpg", 60)
+					Expect(err).To(BeNil())
+					Expect(url).To(ContainSubstring("b.jpg"))
+				})
+			})
+		})
+	}
+})
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// InviteCodeRepository handles database operations for invite codes
+type InviteCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewInviteCodeRepository creates a new InviteCodeRepository
+func NewInviteCodeRepository(db *gorm.DB) *InviteCodeRepository {
+	return &InviteCodeRepository{db: db}
+}
+
+// CreateInviteCode creates a new invite code
+func (r *InviteCodeRepository) CreateInviteCode(invite *models.InviteCode) error {
+	return r.db.Create(invite).Error
+}
+
+// GetByCode retrieves an invite code, exhausted codes included
+func (r *InviteCodeRepository) GetByCode(code string) (*models.InviteCode, error) {
+	invite := &models.InviteCode{}
+	tx := r.db.Where("code = ?", code).First(invite)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return invite, nil
+}
+
+// UseInviteCode atomically spends one use of the code.
+// Missing and exhausted codes both return gorm.ErrRecordNotFound.
+func (r *InviteCodeRepository) UseInviteCode(code string) (*models.InviteCode, error) {
+	tx := r.db.
+		Model(&models.InviteCode{}).
+		Where("code = ? AND (max_uses = 0 OR uses < max_uses)", code).
+		Update("uses", gorm.Expr("uses + 1"))
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.GetByCode(code)
+}
+
+// GetActiveInviteCodes retrieves all codes which still have uses left
+func (r *InviteCodeRepository) GetActiveInviteCodes() ([]*models.InviteCode, error) {
+	var invites []*models.InviteCode
+	tx := r.db.
+		Where("max_uses = 0 OR uses < max_uses").
+		Order("created_at").
+		Find(&invites)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return invites, nil
+}
+
+// DeleteByCode deletes an invite code
+func (r *InviteCodeRepository) DeleteByCode(code string) error {
+	return r.db.
+		Where("code = ?", code).
+		Delete(&models.InviteCode{}).
+		Error
+}
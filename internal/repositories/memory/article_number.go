@@ -0,0 +1,531 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// ArticleNumberRepository is an in-memory implementation of interfaces.ArticleNumberManager
+type ArticleNumberRepository struct {
+	db    *Database
+	actor *models.TelegramUser
+}
+
+var _ interfaces.ArticleNumberManager = (*ArticleNumberRepository)(nil)
+
+// NewArticleNumberRepository creates a new in-memory ArticleNumberRepository
+func NewArticleNumberRepository(db *Database) *ArticleNumberRepository {
+	return &ArticleNumberRepository{db: db}
+}
+
+// WithActor returns a copy of the repository recording changes as made by the actor
+func (r *ArticleNumberRepository) WithActor(actor *models.TelegramUser) interfaces.ArticleNumberManager {
+	c := *r
+	c.actor = actor
+	return &c
+}
+
+// GetByID retrieves an ArticleNumber by UUID
+func (r *ArticleNumberRepository) GetByID(id uuid.UUID) (*models.ArticleNumber, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findOne(func(a *models.ArticleNumber) bool { return a.ID == id })
+}
+
+// GetByNumber retrieves an ArticleNumber by its number string or an alias within a workspace
+func (r *ArticleNumberRepository) GetByNumber(workspaceID uuid.UUID, number string) (*models.ArticleNumber, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findByNumber(workspaceID, number)
+}
+
+// GetArticleAliases retrieves the aliases of an ArticleNumber in the order they were added
+func (r *ArticleNumberRepository) GetArticleAliases(articleNumberID uuid.UUID) ([]*models.ArticleAlias, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var aliases []*models.ArticleAlias
+	for _, alias := range r.db.articleAliases {
+		if alias.ArticleNumberID == articleNumberID {
+			c := *alias
+			aliases = append(aliases, &c)
+		}
+	}
+	sortByCreation(aliases, func(a *models.ArticleAlias) time.Time { return a.CreatedAt })
+	return aliases, nil
+}
+
+// GetArticleNumbersByPhoto retrieves all ArticleNumbers associated with a photo
+func (r *ArticleNumberRepository) GetArticleNumbersByPhoto(photoID uuid.UUID) ([]*models.ArticleNumber, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	articleNumbers := r.db.photoArticleNumbers(photoID)
+	result := make([]*models.ArticleNumber, 0, len(articleNumbers))
+	for i := range articleNumbers {
+		result = append(result, &articleNumbers[i])
+	}
+	return result, nil
+}
+
+// CreateArticleNumber creates a new ArticleNumber
+func (r *ArticleNumberRepository) CreateArticleNumber(articleNumber *models.ArticleNumber) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.create(articleNumber)
+}
+
+// UpdateArticleNumber updates an ArticleNumber
+func (r *ArticleNumberRepository) UpdateArticleNumber(articleNumber *models.ArticleNumber) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, existing := range r.db.articleNumbers {
+		if id != articleNumber.ID && existing.WorkspaceID == articleNumber.WorkspaceID &&
+			existing.Number == articleNumber.Number {
+			return fmt.Errorf("duplicate article number: %s", articleNumber.Number)
+		}
+	}
+
+	// Save without a primary key inserts a new row
+	if articleNumber.ID == uuid.Nil {
+		return r.create(articleNumber)
+	}
+
+	var before *models.ArticleNumberSnapshot
+	if existing, ok := r.db.articleNumbers[articleNumber.ID]; ok && !existing.DeletedAt.Valid {
+		before = models.NewArticleNumberSnapshot(existing)
+	}
+	r.db.touch(&articleNumber.BaseModel)
+	stored := copyArticleNumber(articleNumber)
+	r.db.articleNumbers[articleNumber.ID] = &stored
+
+	action := models.AuditArticleUpdated
+	if before == nil {
+		action = models.AuditArticleCreated
+	}
+	entry := models.NewAuditLog(r.actor, action, before, models.NewArticleNumberSnapshot(articleNumber))
+	entry.ArticleNumberID = articleNumber.ID
+	r.db.audit(entry)
+	return nil
+}
+
+// DeleteArticleNumber deletes an ArticleNumber
+func (r *ArticleNumberRepository) DeleteArticleNumber(id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if articleNumber, ok := r.db.articleNumbers[id]; ok && !articleNumber.DeletedAt.Valid {
+		r.db.softDelete(&articleNumber.BaseModel)
+
+		entry := models.NewAuditLog(r.actor, models.AuditArticleDeleted, models.NewArticleNumberSnapshot(articleNumber), nil)
+		entry.ArticleNumberID = id
+		r.db.audit(entry)
+	}
+	return nil
+}
+
+// RestoreArticleNumber takes an ArticleNumber out of the trash
+func (r *ArticleNumberRepository) RestoreArticleNumber(id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	articleNumber, ok := r.db.articleNumbers[id]
+	if !ok || !articleNumber.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+	articleNumber.DeletedAt = gorm.DeletedAt{}
+	r.db.touch(&articleNumber.BaseModel)
+	r.takeBackMergedNumber(articleNumber)
+
+	entry := models.NewAuditLog(r.actor, models.AuditArticleRestored, nil, models.NewArticleNumberSnapshot(articleNumber))
+	entry.ArticleNumberID = id
+	r.db.audit(entry)
+	return nil
+}
+
+// GetDeletedArticleNumbers retrieves ArticleNumbers of a workspace in the trash, the last deleted first
+func (r *ArticleNumberRepository) GetDeletedArticleNumbers(workspaceID uuid.UUID) ([]*models.ArticleNumber, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var articleNumbers []*models.ArticleNumber
+	for _, articleNumber := range r.db.articleNumbers {
+		if articleNumber.DeletedAt.Valid && articleNumber.WorkspaceID == workspaceID {
+			c := copyArticleNumber(articleNumber)
+			articleNumbers = append(articleNumbers, &c)
+		}
+	}
+	sort.Slice(articleNumbers, func(i, j int) bool {
+		return articleNumbers[i].DeletedAt.Time.After(articleNumbers[j].DeletedAt.Time)
+	})
+	return articleNumbers, nil
+}
+
+// PurgeDeletedArticleNumbers permanently deletes ArticleNumbers deleted before the time with their links to photos
+func (r *ArticleNumberRepository) PurgeDeletedArticleNumbers(deletedBefore time.Time) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	purged := 0
+	for id, articleNumber := range r.db.articleNumbers {
+		if !articleNumber.DeletedAt.Valid || !articleNumber.DeletedAt.Time.Before(deletedBefore) {
+			continue
+		}
+		for relation := range r.db.articlePhotos {
+			if relation.ArticleNumberID == id {
+				delete(r.db.articlePhotos, relation)
+			}
+		}
+		for aliasID, alias := range r.db.articleAliases {
+			if alias.ArticleNumberID == id {
+				delete(r.db.articleAliases, aliasID)
+			}
+		}
+		delete(r.db.articleNumbers, id)
+
+		entry := models.NewAuditLog(r.actor, models.AuditArticlePurged, models.NewArticleNumberSnapshot(articleNumber), nil)
+		entry.ArticleNumberID = id
+		r.db.audit(entry)
+		purged++
+	}
+	return purged, nil
+}
+
+// GetOrCreateArticleNumber gets an existing article number of the workspace by number string or alias
+// or creates a new one
+func (r *ArticleNumberRepository) GetOrCreateArticleNumber(workspaceID uuid.UUID, number string) (*models.ArticleNumber, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	articleNumber, err := r.findByNumber(workspaceID, number)
+	if err == nil {
+		return articleNumber, nil
+	}
+
+	articleNumber = &models.ArticleNumber{
+		WorkspaceID: workspaceID,
+		Number:      number,
+	}
+	if err := r.create(articleNumber); err != nil {
+		return nil, err
+	}
+	return articleNumber, nil
+}
+
+// RenameArticleNumber changes the number of an ArticleNumber, numbers stay unique within the workspace
+func (r *ArticleNumberRepository) RenameArticleNumber(id uuid.UUID, number string) (*models.ArticleNumber, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	articleNumber, ok := r.db.articleNumbers[id]
+	if !ok || articleNumber.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if articleNumber.Number != number {
+		if err := r.checkNumberFree(articleNumber.WorkspaceID, number); err != nil {
+			return nil, err
+		}
+
+		before := models.NewArticleNumberSnapshot(articleNumber)
+		articleNumber.Number = number
+		r.db.touch(&articleNumber.BaseModel)
+		entry := models.NewAuditLog(r.actor, models.AuditArticleUpdated, before, models.NewArticleNumberSnapshot(articleNumber))
+		entry.ArticleNumberID = id
+		r.db.audit(entry)
+	}
+
+	c := copyArticleNumber(articleNumber)
+	return &c, nil
+}
+
+// MergeArticleNumbers moves all photos of the source ArticleNumber to the target one
+// and moves the source to the trash, its number becomes an alias of the target
+func (r *ArticleNumberRepository) MergeArticleNumbers(sourceID, targetID uuid.UUID) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	source, target, err := r.findPair(sourceID, targetID)
+	if err != nil {
+		return 0, err
+	}
+
+	var photoIDs []uuid.UUID
+	for relation := range r.db.articlePhotos {
+		if relation.ArticleNumberID == sourceID {
+			photoIDs = append(photoIDs, relation.PhotoID)
+		}
+	}
+	moved := r.movePhotos(source, target, photoIDs)
+	for _, alias := range r.db.articleAliases {
+		if alias.ArticleNumberID == sourceID {
+			alias.ArticleNumberID = targetID
+		}
+	}
+	// The number of the source keeps finding the target until the source is restored
+	if _, err := r.addAlias(target, source.Number); err != nil {
+		return 0, err
+	}
+
+	r.db.softDelete(&source.BaseModel)
+	entry := models.NewAuditLog(r.actor, models.AuditArticleMerged,
+		models.NewArticleNumberSnapshot(source), models.NewArticleNumberSnapshot(target))
+	entry.ArticleNumberID = sourceID
+	r.db.audit(entry)
+	return moved, nil
+}
+
+// MoveArticleNumberPhotos moves the photos from the source ArticleNumber to the target one
+func (r *ArticleNumberRepository) MoveArticleNumberPhotos(sourceID, targetID uuid.UUID, photoIDs []uuid.UUID) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	source, target, err := r.findPair(sourceID, targetID)
+	if err != nil {
+		return 0, err
+	}
+	return r.movePhotos(source, target, photoIDs), nil
+}
+
+// AddArticleAlias adds an alias to an ArticleNumber, aliases are unique among numbers and aliases of the workspace
+func (r *ArticleNumberRepository) AddArticleAlias(articleNumberID uuid.UUID, alias string) (*models.ArticleAlias, error) {
+	articleAliases, err := r.AddArticleAliases(articleNumberID, []string{alias})
+	if err != nil {
+		return nil, err
+	}
+	return articleAliases[0], nil
+}
+
+// AddArticleAliases adds aliases to an ArticleNumber, a failing alias adds none of them
+func (r *ArticleNumberRepository) AddArticleAliases(articleNumberID uuid.UUID, aliases []string) ([]*models.ArticleAlias, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	articleNumber, ok := r.db.articleNumbers[articleNumberID]
+	if !ok || articleNumber.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	// All aliases are checked before adding any
+	existing := make(map[string]*models.ArticleAlias)
+	for _, alias := range r.db.articleAliases {
+		if alias.ArticleNumberID == articleNumberID {
+			existing[alias.Alias] = alias
+		}
+	}
+	for _, alias := range aliases {
+		if _, ok := existing[alias]; ok {
+			continue
+		}
+		if err := r.checkNumberFree(articleNumber.WorkspaceID, alias); err != nil {
+			return nil, &models.ArticleNumberError{Number: alias, Err: err}
+		}
+	}
+
+	articleAliases := make([]*models.ArticleAlias, 0, len(aliases))
+	for _, alias := range aliases {
+		if stored, ok := existing[alias]; ok {
+			c := *stored
+			articleAliases = append(articleAliases, &c)
+			continue
+		}
+		articleAlias, err := r.addAlias(articleNumber, alias)
+		if err != nil {
+			return nil, err
+		}
+		existing[alias] = articleAlias
+		articleAliases = append(articleAliases, articleAlias)
+	}
+	return articleAliases, nil
+}
+
+// addAlias stores an alias of the ArticleNumber without checking whether it is free
+func (r *ArticleNumberRepository) addAlias(articleNumber *models.ArticleNumber, alias string) (*models.ArticleAlias, error) {
+	articleAlias := &models.ArticleAlias{
+		WorkspaceID:     articleNumber.WorkspaceID,
+		Alias:           alias,
+		ArticleNumberID: articleNumber.ID,
+		CreatedAt:       r.db.now(),
+	}
+	if err := articleAlias.BeforeCreate(nil); err != nil {
+		return nil, err
+	}
+	stored := *articleAlias
+	r.db.articleAliases[articleAlias.ID] = &stored
+
+	r.db.audit(models.NewAliasAuditLog(r.actor, models.AuditAliasAdded, articleAlias, articleNumber))
+	return articleAlias, nil
+}
+
+// takeBackMergedNumber removes the alias a merge left for the number of a restored ArticleNumber,
+// so the number finds the restored one again
+func (r *ArticleNumberRepository) takeBackMergedNumber(articleNumber *models.ArticleNumber) {
+	for id, alias := range r.db.articleAliases {
+		if alias.WorkspaceID != articleNumber.WorkspaceID || alias.Alias != articleNumber.Number {
+			continue
+		}
+		delete(r.db.articleAliases, id)
+		if owner, ok := r.db.articleNumbers[alias.ArticleNumberID]; ok {
+			r.db.audit(models.NewAliasAuditLog(r.actor, models.AuditAliasRemoved, alias, owner))
+		}
+		return
+	}
+}
+
+// RemoveArticleAlias removes an alias of the workspace and returns the ArticleNumber it belonged to
+func (r *ArticleNumberRepository) RemoveArticleAlias(workspaceID uuid.UUID, alias string) (*models.ArticleNumber, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, existing := range r.db.articleAliases {
+		if existing.WorkspaceID != workspaceID || existing.Alias != alias {
+			continue
+		}
+		// Aliases of article numbers in the trash are removed as well
+		articleNumber, ok := r.db.articleNumbers[existing.ArticleNumberID]
+		if !ok {
+			return nil, gorm.ErrRecordNotFound
+		}
+		delete(r.db.articleAliases, id)
+
+		r.db.audit(models.NewAliasAuditLog(r.actor, models.AuditAliasRemoved, existing, articleNumber))
+		c := copyArticleNumber(articleNumber)
+		return &c, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// movePhotos replaces links of the photos with the source by links with the target,
+// photos already linked with the target just lose the source
+func (r *ArticleNumberRepository) movePhotos(source, target *models.ArticleNumber, photoIDs []uuid.UUID) int {
+	moved := 0
+	for _, photoID := range photoIDs {
+		relation := articlePhoto{ArticleNumberID: source.ID, PhotoID: photoID}
+		if _, ok := r.db.articlePhotos[relation]; !ok {
+			continue
+		}
+		delete(r.db.articlePhotos, relation)
+		r.db.audit(models.NewLinkAuditLog(r.actor, models.AuditArticleUnlinked, photoID, source))
+
+		relation.ArticleNumberID = target.ID
+		if _, ok := r.db.articlePhotos[relation]; !ok {
+			r.db.articlePhotos[relation] = struct{}{}
+			r.db.audit(models.NewLinkAuditLog(r.actor, models.AuditArticleLinked, photoID, target))
+		}
+		moved++
+	}
+	return moved
+}
+
+// findPair looks up two different ArticleNumbers of the same workspace
+func (r *ArticleNumberRepository) findPair(sourceID, targetID uuid.UUID) (*models.ArticleNumber, *models.ArticleNumber, error) {
+	if sourceID == targetID {
+		return nil, nil, fmt.Errorf("cannot move photos of an article number to itself")
+	}
+	source, ok := r.db.articleNumbers[sourceID]
+	if !ok || source.DeletedAt.Valid {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	target, ok := r.db.articleNumbers[targetID]
+	if !ok || target.DeletedAt.Valid {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	if source.WorkspaceID != target.WorkspaceID {
+		return nil, nil, fmt.Errorf("article numbers %s and %s belong to different workspaces", source.Number, target.Number)
+	}
+	return source, target, nil
+}
+
+// GetArticleNumberWithPhotos retrieves an article number with its associated photos
+func (r *ArticleNumberRepository) GetArticleNumberWithPhotos(articleNumberID uuid.UUID) (*models.ArticleNumber, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	articleNumber, err := r.findOne(func(a *models.ArticleNumber) bool { return a.ID == articleNumberID })
+	if err != nil {
+		return nil, err
+	}
+
+	articleNumber.Photos = r.db.articleNumberPhotos(articleNumberID)
+	for i := range articleNumber.Photos {
+		articleNumber.Photos[i].ArticleNumbers = r.db.photoArticleNumbers(articleNumber.Photos[i].ID)
+	}
+	return articleNumber, nil
+}
+
+func (r *ArticleNumberRepository) create(articleNumber *models.ArticleNumber) error {
+	if err := r.checkNumberFree(articleNumber.WorkspaceID, articleNumber.Number); err != nil {
+		return err
+	}
+
+	if err := articleNumber.BeforeCreate(nil); err != nil {
+		return err
+	}
+	r.db.touch(&articleNumber.BaseModel)
+
+	stored := copyArticleNumber(articleNumber)
+	r.db.articleNumbers[articleNumber.ID] = &stored
+
+	entry := models.NewAuditLog(r.actor, models.AuditArticleCreated, nil, models.NewArticleNumberSnapshot(articleNumber))
+	entry.ArticleNumberID = articleNumber.ID
+	r.db.audit(entry)
+	return nil
+}
+
+func (r *ArticleNumberRepository) findOne(match func(*models.ArticleNumber) bool) (*models.ArticleNumber, error) {
+	var found []models.ArticleNumber
+	for _, articleNumber := range r.db.articleNumbers {
+		if !articleNumber.DeletedAt.Valid && match(articleNumber) {
+			found = append(found, copyArticleNumber(articleNumber))
+		}
+	}
+	if len(found) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	sortByCreation(found, func(a models.ArticleNumber) time.Time { return a.CreatedAt })
+	return &found[0], nil
+}
+
+// findByNumber looks up a live ArticleNumber of the workspace by its number, then by its aliases
+func (r *ArticleNumberRepository) findByNumber(workspaceID uuid.UUID, number string) (*models.ArticleNumber, error) {
+	articleNumber, err := r.findOne(func(a *models.ArticleNumber) bool { return a.WorkspaceID == workspaceID && a.Number == number })
+	if err == nil {
+		return articleNumber, nil
+	}
+	for _, alias := range r.db.articleAliases {
+		if alias.WorkspaceID == workspaceID && alias.Alias == number {
+			return r.findOne(func(a *models.ArticleNumber) bool { return a.ID == alias.ArticleNumberID })
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// checkNumberFree fails when the number is already a number or an alias of the workspace.
+// (workspace_id, number) has a unique index which also covers soft-deleted rows.
+func (r *ArticleNumberRepository) checkNumberFree(workspaceID uuid.UUID, number string) error {
+	for _, existing := range r.db.articleNumbers {
+		if existing.WorkspaceID != workspaceID || existing.Number != number {
+			continue
+		}
+		if existing.DeletedAt.Valid {
+			return models.ErrArticleNumberInTrash
+		}
+		return models.ErrArticleNumberTaken
+	}
+	for _, alias := range r.db.articleAliases {
+		if alias.WorkspaceID == workspaceID && alias.Alias == number {
+			return models.ErrArticleNumberTaken
+		}
+	}
+	return nil
+}
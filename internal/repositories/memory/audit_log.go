@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// AuditLogRepository is an in-memory implementation of interfaces.AuditLogManager
+type AuditLogRepository struct {
+	db *Database
+}
+
+var _ interfaces.AuditLogManager = (*AuditLogRepository)(nil)
+
+// NewAuditLogRepository creates a new in-memory AuditLogRepository
+func NewAuditLogRepository(db *Database) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// GetAuditLogs retrieves entries selected by the filter, the newest first
+func (r *AuditLogRepository) GetAuditLogs(filter models.AuditLogFilter) ([]*models.AuditLog, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var entries []*models.AuditLog
+	for i := len(r.db.auditLogs) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+		if entry := r.db.auditLogs[i]; filter.Matches(entry) {
+			c := *entry
+			entries = append(entries, &c)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// ChatRepository is an in-memory implementation of interfaces.ChatManager
+type ChatRepository struct {
+	db *Database
+}
+
+var _ interfaces.ChatManager = (*ChatRepository)(nil)
+
+// NewChatRepository creates a new in-memory ChatRepository
+func NewChatRepository(db *Database) *ChatRepository {
+	return &ChatRepository{db: db}
+}
+
+// GetState retrieves the dialog state of a user in a chat, it is default when none was set
+func (r *ChatRepository) GetState(chatID, telegramID int64) (string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	state, ok := r.db.chatStates[chatUser{ChatID: chatID, TelegramID: telegramID}]
+	if !ok || state.State == "" {
+		return models.TelegramUserStateDefault, nil
+	}
+	return state.State, nil
+}
+
+// SetState stores the dialog state of a user in a chat
+func (r *ChatRepository) SetState(chatID, telegramID int64, state string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.chatStates[chatUser{ChatID: chatID, TelegramID: telegramID}] = &models.ChatState{
+		ChatID:     chatID,
+		TelegramID: telegramID,
+		State:      state,
+		UpdatedAt:  r.db.now(),
+	}
+	return nil
+}
+
+// GetGroupChat retrieves the binding of a group chat
+func (r *ChatRepository) GetGroupChat(chatID int64) (*models.GroupChat, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	group, ok := r.db.groupChats[chatID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	c := *group
+	return &c, nil
+}
+
+// BindGroupChat binds a group chat to a workspace, replacing any previous binding
+func (r *ChatRepository) BindGroupChat(group *models.GroupChat) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if existing, ok := r.db.groupChats[group.ChatID]; ok {
+		group.ID = existing.ID
+		group.CreatedAt = existing.CreatedAt
+	} else if err := group.BeforeCreate(nil); err != nil {
+		return err
+	}
+	r.db.touch(&group.BaseModel)
+
+	stored := *group
+	r.db.groupChats[group.ChatID] = &stored
+	return nil
+}
+
+// UnbindGroupChat removes the binding of a group chat
+func (r *ChatRepository) UnbindGroupChat(chatID int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.groupChats, chatID)
+	return nil
+}
@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/google/uuid"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// Database is an in-memory replacement of the relational storage shared by
+// the in-memory repositories. Rows are stored as copies, so callers can not
+// change persisted data without going through a repository.
+type Database struct {
+	mu sync.RWMutex
+
+	users            map[uuid.UUID]*models.TelegramUser
+	photos           map[uuid.UUID]*models.Photo
+	articleNumbers   map[uuid.UUID]*models.ArticleNumber
+	articlePhotos    map[articlePhoto]struct{}
+	articleAliases   map[uuid.UUID]*models.ArticleAlias
+	inviteCodes      map[uuid.UUID]*models.InviteCode
+	workspaces       map[uuid.UUID]*models.Workspace
+	workspaceMembers map[workspaceMember]struct{}
+	chatStates       map[chatUser]*models.ChatState
+	groupChats       map[int64]*models.GroupChat
+	supportTickets   map[uint]*models.SupportTicket
+	supportMessages  map[uint]*models.SupportMessage
+	// lastSupportID holds the last IDs of tickets and messages, they are auto-incremented
+	lastSupportID struct{ ticket, message uint }
+	// auditLogs is append-only, the position of an entry is its ID
+	auditLogs []*models.AuditLog
+
+	schemas sync.Map
+	now     func() time.Time
+}
+
+// articlePhoto is a row of the article_number_photos join table
+type articlePhoto struct {
+	ArticleNumberID uuid.UUID
+	PhotoID         uuid.UUID
+}
+
+// workspaceMember is a row of the workspace_members join table
+type workspaceMember struct {
+	WorkspaceID    uuid.UUID
+	TelegramUserID uuid.UUID
+}
+
+// chatUser is the primary key of the chat_states table
+type chatUser struct {
+	ChatID     int64
+	TelegramID int64
+}
+
+// NewDatabase creates a new empty in-memory database
+func NewDatabase() *Database {
+	return &Database{
+		users:            map[uuid.UUID]*models.TelegramUser{},
+		photos:           map[uuid.UUID]*models.Photo{},
+		articleNumbers:   map[uuid.UUID]*models.ArticleNumber{},
+		articlePhotos:    map[articlePhoto]struct{}{},
+		articleAliases:   map[uuid.UUID]*models.ArticleAlias{},
+		inviteCodes:      map[uuid.UUID]*models.InviteCode{},
+		workspaces:       map[uuid.UUID]*models.Workspace{},
+		workspaceMembers: map[workspaceMember]struct{}{},
+		chatStates:       map[chatUser]*models.ChatState{},
+		groupChats:       map[int64]*models.GroupChat{},
+		supportTickets:   map[uint]*models.SupportTicket{},
+		supportMessages:  map[uint]*models.SupportMessage{},
+		now:              time.Now,
+	}
+}
+
+// touch fills base model timestamps the same way gorm does on save
+func (d *Database) touch(base *models.BaseModel) {
+	now := d.now()
+	if base.CreatedAt.IsZero() {
+		base.CreatedAt = now
+	}
+	base.UpdatedAt = now
+}
+
+// audit appends an entry to the audit log, the caller holds the write lock
+func (d *Database) audit(entry *models.AuditLog) {
+	entry.ID = uint(len(d.auditLogs) + 1)
+	entry.CreatedAt = d.now()
+	stored := *entry
+	d.auditLogs = append(d.auditLogs, &stored)
+}
+
+// softDelete marks a row as deleted the same way gorm does for models with DeletedAt
+func (d *Database) softDelete(base *models.BaseModel) {
+	base.DeletedAt = gorm.DeletedAt{Time: d.now(), Valid: true}
+}
+
+// applyUpdates mimics gorm's Updates: maps are applied by column name,
+// structs only update their non-zero fields
+func (d *Database) applyUpdates(dest interface{}, updates interface{}) error {
+	s, err := schema.Parse(dest, &d.schemas, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	destValue := reflect.ValueOf(dest)
+
+	switch values := updates.(type) {
+	case map[string]interface{}:
+		for column, value := range values {
+			field := s.LookUpField(column)
+			if field == nil {
+				return fmt.Errorf("unknown column: %s", column)
+			}
+			if err := field.Set(ctx, destValue, value); err != nil {
+				return err
+			}
+		}
+	default:
+		updatesValue := reflect.Indirect(reflect.ValueOf(updates))
+		if updatesValue.Type() != destValue.Elem().Type() {
+			return fmt.Errorf("unsupported updates type: %T", updates)
+		}
+		for _, field := range s.Fields {
+			if field.DBName == "" || field.PrimaryKey {
+				continue
+			}
+			value, isZero := field.ValueOf(ctx, updatesValue)
+			if isZero {
+				continue
+			}
+			if err := field.Set(ctx, destValue, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.touch(baseModelOf(dest))
+	return nil
+}
+
+func baseModelOf(model interface{}) *models.BaseModel {
+	switch m := model.(type) {
+	case *models.TelegramUser:
+		return &m.BaseModel
+	case *models.Photo:
+		return &m.BaseModel
+	case *models.ArticleNumber:
+		return &m.BaseModel
+	}
+	panic(fmt.Sprintf("unsupported model: %T", model))
+}
+
+// photoArticleNumbers returns live article numbers linked with a photo
+func (d *Database) photoArticleNumbers(photoID uuid.UUID) []models.ArticleNumber {
+	var articleNumbers []models.ArticleNumber
+	for relation := range d.articlePhotos {
+		if relation.PhotoID != photoID {
+			continue
+		}
+		articleNumber, ok := d.articleNumbers[relation.ArticleNumberID]
+		if !ok || articleNumber.DeletedAt.Valid {
+			continue
+		}
+		articleNumbers = append(articleNumbers, copyArticleNumber(articleNumber))
+	}
+	sortByCreation(articleNumbers, func(a models.ArticleNumber) time.Time { return a.CreatedAt })
+	return articleNumbers
+}
+
+// articleNumberPhotos returns live photos linked with an article number
+func (d *Database) articleNumberPhotos(articleNumberID uuid.UUID) []models.Photo {
+	var photos []models.Photo
+	for relation := range d.articlePhotos {
+		if relation.ArticleNumberID != articleNumberID {
+			continue
+		}
+		photo, ok := d.photos[relation.PhotoID]
+		if !ok || photo.DeletedAt.Valid {
+			continue
+		}
+		photos = append(photos, copyPhoto(photo))
+	}
+	sortByCreation(photos, func(p models.Photo) time.Time { return p.CreatedAt })
+	return photos
+}
+
+func copyUser(user *models.TelegramUser) models.TelegramUser {
+	c := *user
+	c.Photos = nil
+	c.Workspaces = nil
+	return c
+}
+
+func copyWorkspace(workspace *models.Workspace) models.Workspace {
+	c := *workspace
+	c.Members = nil
+	return c
+}
+
+func copyPhoto(photo *models.Photo) models.Photo {
+	c := *photo
+	c.ArticleNumbers = nil
+	c.TelegramUser = models.TelegramUser{}
+	return c
+}
+
+func copyArticleNumber(articleNumber *models.ArticleNumber) models.ArticleNumber {
+	c := *articleNumber
+	c.Photos = nil
+	return c
+}
+
+// sortByCreation orders rows by creation time to keep results deterministic
+func sortByCreation[T any](items []T, createdAt func(T) time.Time) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return createdAt(items[i]).Before(createdAt(items[j]))
+	})
+}
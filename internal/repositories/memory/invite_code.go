@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// InviteCodeRepository is an in-memory implementation of interfaces.InviteCodeManager
+type InviteCodeRepository struct {
+	db *Database
+}
+
+var _ interfaces.InviteCodeManager = (*InviteCodeRepository)(nil)
+
+// NewInviteCodeRepository creates a new in-memory InviteCodeRepository
+func NewInviteCodeRepository(db *Database) *InviteCodeRepository {
+	return &InviteCodeRepository{db: db}
+}
+
+// CreateInviteCode creates a new invite code
+func (r *InviteCodeRepository) CreateInviteCode(invite *models.InviteCode) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// code has a unique index which also covers soft-deleted rows
+	for _, existing := range r.db.inviteCodes {
+		if existing.Code == invite.Code {
+			return fmt.Errorf("duplicate invite code: %s", invite.Code)
+		}
+	}
+
+	if err := invite.BeforeCreate(nil); err != nil {
+		return err
+	}
+	r.db.touch(&invite.BaseModel)
+
+	stored := *invite
+	r.db.inviteCodes[invite.ID] = &stored
+	return nil
+}
+
+// GetByCode retrieves an invite code, exhausted codes included
+func (r *InviteCodeRepository) GetByCode(code string) (*models.InviteCode, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	invites := r.findAll(func(c *models.InviteCode) bool { return c.Code == code })
+	if len(invites) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return invites[0], nil
+}
+
+// UseInviteCode atomically spends one use of the code.
+// Missing and exhausted codes both return gorm.ErrRecordNotFound.
+func (r *InviteCodeRepository) UseInviteCode(code string) (*models.InviteCode, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, invite := range r.db.inviteCodes {
+		if invite.DeletedAt.Valid || invite.Code != code || invite.IsExhausted() {
+			continue
+		}
+		invite.Uses++
+		r.db.touch(&invite.BaseModel)
+
+		used := *invite
+		return &used, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// GetActiveInviteCodes retrieves all codes which still have uses left
+func (r *InviteCodeRepository) GetActiveInviteCodes() ([]*models.InviteCode, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findAll(func(c *models.InviteCode) bool { return !c.IsExhausted() }), nil
+}
+
+// DeleteByCode deletes an invite code
+func (r *InviteCodeRepository) DeleteByCode(code string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, invite := range r.db.inviteCodes {
+		if !invite.DeletedAt.Valid && invite.Code == code {
+			r.db.softDelete(&invite.BaseModel)
+		}
+	}
+	return nil
+}
+
+func (r *InviteCodeRepository) findAll(match func(*models.InviteCode) bool) []*models.InviteCode {
+	var invites []models.InviteCode
+	for _, invite := range r.db.inviteCodes {
+		if !invite.DeletedAt.Valid && match(invite) {
+			invites = append(invites, *invite)
+		}
+	}
+	sortByCreation(invites, func(c models.InviteCode) time.Time { return c.CreatedAt })
+
+	result := make([]*models.InviteCode, 0, len(invites))
+	for i := range invites {
+		result = append(result, &invites[i])
+	}
+	return result
+}
@@ -0,0 +1,368 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// PhotoRepository is an in-memory implementation of interfaces.PhotoManager
+type PhotoRepository struct {
+	db       *Database
+	s3Client interfaces.S3Client
+	actor    *models.TelegramUser
+}
+
+var _ interfaces.PhotoManager = (*PhotoRepository)(nil)
+
+// NewPhotoRepository creates a new in-memory PhotoRepository
+func NewPhotoRepository(db *Database, s3Client interfaces.S3Client) *PhotoRepository {
+	return &PhotoRepository{
+		db:       db,
+		s3Client: s3Client,
+	}
+}
+
+// WithActor returns a copy of the repository recording changes as made by the actor
+func (r *PhotoRepository) WithActor(actor *models.TelegramUser) interfaces.PhotoManager {
+	c := *r
+	c.actor = actor
+	return &c
+}
+
+// GetByID retrieves a Photo by UUID
+func (r *PhotoRepository) GetByID(id uuid.UUID) (*models.Photo, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	photos := r.findAll(func(p *models.Photo) bool { return p.ID == id })
+	if len(photos) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return photos[0], nil
+}
+
+// GetUsersPhotosByState retrieves photos for a user filtered by state
+func (r *PhotoRepository) GetUsersPhotosByState(userID uuid.UUID, state string) ([]*models.Photo, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	// The gorm implementation joins telegram_users, so photos of deleted users are skipped
+	user, ok := r.db.users[userID]
+	if !ok || user.DeletedAt.Valid {
+		return nil, nil
+	}
+
+	return r.findAll(func(p *models.Photo) bool {
+		return p.UserID == userID && p.State == state
+	}), nil
+}
+
+// GetPhotos retrieves Photos selected by the filter with their article numbers, the oldest first
+func (r *PhotoRepository) GetPhotos(filter models.PhotoFilter) ([]*models.Photo, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findAll(filter.Matches), nil
+}
+
+// GetByContentHash retrieves an applied Photo of the workspace by the hash of its file
+func (r *PhotoRepository) GetByContentHash(workspaceID uuid.UUID, contentHash string) (*models.Photo, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	photos := r.findAll(func(p *models.Photo) bool {
+		return p.WorkspaceID == workspaceID && p.ContentHash == contentHash && p.State == models.PhotoApplied
+	})
+	if len(photos) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return photos[0], nil
+}
+
+// GetPhotosByArticleNumber retrieves all Photos associated with an article number
+func (r *PhotoRepository) GetPhotosByArticleNumber(articleNumberID uuid.UUID) ([]*models.Photo, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findAll(func(p *models.Photo) bool {
+		_, linked := r.db.articlePhotos[articlePhoto{
+			ArticleNumberID: articleNumberID,
+			PhotoID:         p.ID,
+		}]
+		return linked
+	}), nil
+}
+
+// CreatePhoto creates a new Photo
+func (r *PhotoRepository) CreatePhoto(photo *models.Photo) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.create(photo)
+}
+
+// UpdatePhoto updates a Photo
+func (r *PhotoRepository) UpdatePhoto(photo *models.Photo) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// Save without a primary key inserts a new row
+	if photo.ID == uuid.Nil {
+		return r.create(photo)
+	}
+
+	var before *models.PhotoSnapshot
+	if existing, ok := r.db.photos[photo.ID]; ok && !existing.DeletedAt.Valid {
+		before = models.NewPhotoSnapshot(existing)
+	}
+	r.db.touch(&photo.BaseModel)
+	stored := copyPhoto(photo)
+	r.db.photos[photo.ID] = &stored
+
+	entry := models.NewAuditLog(r.actor, models.PhotoChangeAction(before, photo), before, models.NewPhotoSnapshot(photo))
+	entry.PhotoID = photo.ID
+	r.db.audit(entry)
+	return nil
+}
+
+// DeletePhoto moves a Photo to the trash, its object is kept until the photo is purged
+func (r *PhotoRepository) DeletePhoto(id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	photo, ok := r.db.photos[id]
+	if !ok || photo.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+	r.db.softDelete(&photo.BaseModel)
+
+	entry := models.NewAuditLog(r.actor, models.AuditPhotoDeleted, models.NewPhotoSnapshot(photo), nil)
+	entry.PhotoID = id
+	r.db.audit(entry)
+	return nil
+}
+
+// RestorePhoto takes a Photo out of the trash
+func (r *PhotoRepository) RestorePhoto(id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	photo, ok := r.db.photos[id]
+	if !ok || !photo.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+	photo.DeletedAt = gorm.DeletedAt{}
+	r.db.touch(&photo.BaseModel)
+
+	entry := models.NewAuditLog(r.actor, models.AuditPhotoRestored, nil, models.NewPhotoSnapshot(photo))
+	entry.PhotoID = id
+	r.db.audit(entry)
+	return nil
+}
+
+// GetDeletedPhotos retrieves Photos of a workspace in the trash, the last deleted first
+func (r *PhotoRepository) GetDeletedPhotos(workspaceID uuid.UUID) ([]*models.Photo, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var photos []*models.Photo
+	for _, photo := range r.db.photos {
+		if photo.DeletedAt.Valid && photo.WorkspaceID == workspaceID {
+			c := copyPhoto(photo)
+			c.ArticleNumbers = r.db.photoArticleNumbers(photo.ID)
+			photos = append(photos, &c)
+		}
+	}
+	sort.Slice(photos, func(i, j int) bool {
+		return photos[i].DeletedAt.Time.After(photos[j].DeletedAt.Time)
+	})
+	return photos, nil
+}
+
+// PurgeDeletedPhotos permanently deletes Photos deleted before the time, their links
+// and their objects in the storage. Photos failing to purge are kept for the next time.
+func (r *PhotoRepository) PurgeDeletedPhotos(deletedBefore time.Time, bucket string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	purged := 0
+	var errs []error
+	for id, photo := range r.db.photos {
+		if !photo.DeletedAt.Valid || !photo.DeletedAt.Time.Before(deletedBefore) {
+			continue
+		}
+		if err := r.purge(photo, bucket, models.AuditPhotoPurged); err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge photo %s: %w", id, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
+
+// DiscardPhoto permanently deletes a Photo which never reached the catalog, such as
+// a cancelled upload, and its object in the storage
+func (r *PhotoRepository) DiscardPhoto(id uuid.UUID, bucket string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	photo, ok := r.db.photos[id]
+	if !ok || photo.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+	return r.purge(photo, bucket, models.AuditPhotoDeleted)
+}
+
+// purge deletes the object of a Photo, its links and the Photo itself, recording the action.
+// The caller holds the write lock.
+func (r *PhotoRepository) purge(photo *models.Photo, bucket string, action string) error {
+	// The local storage has no buckets, so an empty bucket is valid
+	if r.s3Client != nil {
+		keys := []string{models.PhotoObjectKey(photo.UserID, photo.S3Key, photo.Format)}
+		for _, rendition := range models.PhotoRenditions {
+			keys = append(keys, models.PhotoObjectKey(photo.UserID, photo.RenditionKey(rendition), models.PhotoRenditionFormat))
+		}
+		for _, s3ObjectKey := range keys {
+			if err := r.s3Client.DeleteFile(context.Background(), bucket, s3ObjectKey); err != nil {
+				return fmt.Errorf("failed to delete from S3: %w", err)
+			}
+		}
+	}
+
+	for relation := range r.db.articlePhotos {
+		if relation.PhotoID == photo.ID {
+			delete(r.db.articlePhotos, relation)
+		}
+	}
+	delete(r.db.photos, photo.ID)
+
+	entry := models.NewAuditLog(r.actor, action, models.NewPhotoSnapshot(photo), nil)
+	entry.PhotoID = photo.ID
+	r.db.audit(entry)
+	return nil
+}
+
+// AddArticleNumberToPhoto associates an article number with a photo
+func (r *PhotoRepository) AddArticleNumberToPhoto(photoID, articleNumberID uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	articleNumber, ok := r.db.articleNumbers[articleNumberID]
+	if !ok || articleNumber.DeletedAt.Valid {
+		return fmt.Errorf("article number not found")
+	}
+
+	relation := articlePhoto{
+		PhotoID:         photoID,
+		ArticleNumberID: articleNumberID,
+	}
+	if _, exists := r.db.articlePhotos[relation]; exists {
+		return fmt.Errorf("article number is already linked with photo")
+	}
+	r.db.articlePhotos[relation] = struct{}{}
+	r.db.audit(models.NewLinkAuditLog(r.actor, models.AuditArticleLinked, photoID, articleNumber))
+	return nil
+}
+
+// RemoveArticleNumberFromPhoto removes an association between an article number and a photo
+func (r *PhotoRepository) RemoveArticleNumberFromPhoto(photoID, articleNumberID uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	relation := articlePhoto{
+		PhotoID:         photoID,
+		ArticleNumberID: articleNumberID,
+	}
+	if _, exists := r.db.articlePhotos[relation]; !exists {
+		return nil
+	}
+	delete(r.db.articlePhotos, relation)
+
+	// The number is kept for the log even if the article number is already deleted
+	articleNumber := &models.ArticleNumber{}
+	articleNumber.ID = articleNumberID
+	if stored, ok := r.db.articleNumbers[articleNumberID]; ok {
+		articleNumber = stored
+	}
+	r.db.audit(models.NewLinkAuditLog(r.actor, models.AuditArticleUnlinked, photoID, articleNumber))
+	return nil
+}
+
+// UploadPhotoToS3 uploads a photo to S3 storage
+func (r *PhotoRepository) UploadPhotoToS3(
+	ctx context.Context,
+	userID uuid.UUID,
+	s3Key uuid.UUID,
+	format string,
+	bucket string,
+	photoData io.Reader) error {
+	s3ObjectKey := models.PhotoObjectKey(userID, s3Key, format)
+	return r.s3Client.UploadFile(ctx, bucket, s3ObjectKey, photoData)
+}
+
+// GetPhotoFromS3 downloads a photo from S3 storage
+func (r *PhotoRepository) GetPhotoFromS3(ctx context.Context, userID uuid.UUID, s3Key uuid.UUID, format string, bucket string) (io.ReadCloser, error) {
+	s3ObjectKey := models.PhotoObjectKey(userID, s3Key, format)
+	return r.s3Client.DownloadFile(ctx, bucket, s3ObjectKey)
+}
+
+// GetPhotoURL generates an expiring presigned URL for a photo in S3
+func (r *PhotoRepository) GetPhotoURL(
+	ctx context.Context,
+	userID uuid.UUID,
+	s3Key uuid.UUID,
+	format string,
+	bucket string,
+	expiresIn time.Duration) (string, error) {
+	s3ObjectKey := models.PhotoObjectKey(userID, s3Key, format)
+	return r.s3Client.GeneratePresignedURL(ctx, bucket, s3ObjectKey, int64(expiresIn.Seconds()))
+}
+
+// GetPhotoWithArticleNumbers retrieves a photo with its associated article numbers
+func (r *PhotoRepository) GetPhotoWithArticleNumbers(photoID uuid.UUID) (*models.Photo, error) {
+	return r.GetByID(photoID)
+}
+
+func (r *PhotoRepository) create(photo *models.Photo) error {
+	if err := photo.BeforeCreate(nil); err != nil {
+		return err
+	}
+	r.db.touch(&photo.BaseModel)
+
+	stored := copyPhoto(photo)
+	r.db.photos[photo.ID] = &stored
+
+	entry := models.NewAuditLog(r.actor, models.AuditPhotoCreated, nil, models.NewPhotoSnapshot(photo))
+	entry.PhotoID = photo.ID
+	r.db.audit(entry)
+	return nil
+}
+
+// findAll returns live photos with preloaded article numbers
+func (r *PhotoRepository) findAll(match func(*models.Photo) bool) []*models.Photo {
+	var photos []models.Photo
+	for _, photo := range r.db.photos {
+		if !photo.DeletedAt.Valid && match(photo) {
+			photos = append(photos, copyPhoto(photo))
+		}
+	}
+	sortByCreation(photos, func(p models.Photo) time.Time { return p.CreatedAt })
+
+	result := make([]*models.Photo, 0, len(photos))
+	for i := range photos {
+		photos[i].ArticleNumbers = r.db.photoArticleNumbers(photos[i].ID)
+		result = append(result, &photos[i])
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+)
+
+// S3Client is an in-memory implementation of interfaces.S3Client
+type S3Client struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+var _ interfaces.S3Client = (*S3Client)(nil)
+
+// NewS3Client creates a new empty in-memory S3Client
+func NewS3Client() *S3Client {
+	return &S3Client{objects: map[string][]byte{}}
+}
+
+// UploadFile stores a file in memory
+func (c *S3Client) UploadFile(_ context.Context, bucket, key string, file io.Reader) error {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.objects[objectName(bucket, key)] = data
+	return nil
+}
+
+// DownloadFile returns a stored file
+func (c *S3Client) DownloadFile(_ context.Context, bucket, key string) (io.ReadCloser, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.objects[objectName(bucket, key)]
+	if !ok {
+		return nil, fmt.Errorf("object %s not found", objectName(bucket, key))
+	}
+	return &objectReader{Reader: bytes.NewReader(data)}, nil
+}
+
+// DeleteFile removes a stored file, missing objects are not an error as in S3
+func (c *S3Client) DeleteFile(_ context.Context, bucket, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.objects, objectName(bucket, key))
+	return nil
+}
+
+// GeneratePresignedURL returns a fake URL that encodes object name and expiration
+func (c *S3Client) GeneratePresignedURL(_ context.Context, bucket, key string, expiresIn int64) (string, error) {
+	query := url.Values{}
+	query.Set("expires", time.Now().Add(time.Duration(expiresIn)*time.Second).UTC().Format(time.RFC3339))
+	return "memory://" + objectName(bucket, key) + "?" + query.Encode(), nil
+}
+
+// Objects returns names of all stored objects as bucket/key
+func (c *S3Client) Objects() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.objects))
+	for name := range c.objects {
+		names = append(names, name)
+	}
+	return names
+}
+
+// objectReader is a pointer-typed ReadCloser like the body returned by the AWS SDK
+type objectReader struct {
+	*bytes.Reader
+}
+
+func (r *objectReader) Close() error {
+	return nil
+}
+
+func objectName(bucket, key string) string {
+	return bucket + "/" + key
+}
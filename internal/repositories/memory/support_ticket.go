@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// SupportTicketRepository is an in-memory implementation of interfaces.SupportTicketManager
+type SupportTicketRepository struct {
+	db *Database
+}
+
+var _ interfaces.SupportTicketManager = (*SupportTicketRepository)(nil)
+
+// NewSupportTicketRepository creates a new in-memory SupportTicketRepository
+func NewSupportTicketRepository(db *Database) *SupportTicketRepository {
+	return &SupportTicketRepository{db: db}
+}
+
+// CreateTicket creates a new ticket, it is open unless the status is set
+func (r *SupportTicketRepository) CreateTicket(ticket *models.SupportTicket) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if ticket.Status == "" {
+		ticket.Status = models.SupportTicketOpen
+	}
+	r.db.lastSupportID.ticket++
+	ticket.ID = r.db.lastSupportID.ticket
+	ticket.CreatedAt = r.db.now()
+	ticket.UpdatedAt = ticket.CreatedAt
+
+	stored := *ticket
+	stored.Messages = nil
+	r.db.supportTickets[ticket.ID] = &stored
+	return nil
+}
+
+// GetTicket retrieves a ticket by its number, without the history
+func (r *SupportTicketRepository) GetTicket(id uint) (*models.SupportTicket, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.get(id)
+}
+
+// GetOpenTicket retrieves the open ticket of a user
+func (r *SupportTicketRepository) GetOpenTicket(telegramID int64) (*models.SupportTicket, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	tickets := r.findAll(func(t *models.SupportTicket) bool {
+		return t.TelegramID == telegramID && t.IsOpen()
+	})
+	if len(tickets) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return tickets[len(tickets)-1], nil
+}
+
+// GetOpenTickets retrieves all open tickets, the oldest first
+func (r *SupportTicketRepository) GetOpenTickets() ([]*models.SupportTicket, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findAll(func(t *models.SupportTicket) bool { return t.IsOpen() }), nil
+}
+
+// CloseTicket marks a ticket closed, closing a closed ticket keeps its closing time
+func (r *SupportTicketRepository) CloseTicket(id uint) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	ticket, ok := r.db.supportTickets[id]
+	if !ok || !ticket.IsOpen() {
+		return nil
+	}
+	now := r.db.now()
+	ticket.Status = models.SupportTicketClosed
+	ticket.ClosedAt = &now
+	ticket.UpdatedAt = now
+	return nil
+}
+
+// AddMessage appends a message to the history of its ticket
+func (r *SupportTicketRepository) AddMessage(message *models.SupportMessage) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.lastSupportID.message++
+	message.ID = r.db.lastSupportID.message
+	message.CreatedAt = r.db.now()
+
+	stored := *message
+	r.db.supportMessages[message.ID] = &stored
+	if ticket, ok := r.db.supportTickets[message.TicketID]; ok {
+		ticket.UpdatedAt = message.CreatedAt
+	}
+	return nil
+}
+
+// GetMessages retrieves the history of a ticket in the order of messages
+func (r *SupportTicketRepository) GetMessages(ticketID uint) ([]*models.SupportMessage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var messages []*models.SupportMessage
+	for _, message := range r.db.supportMessages {
+		if message.TicketID == ticketID {
+			c := *message
+			messages = append(messages, &c)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+// GetTicketByStaffMessage finds the ticket of a message shown in the staff chat
+func (r *SupportTicketRepository) GetTicketByStaffMessage(staffChatID int64, staffMessageID int) (*models.SupportTicket, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, message := range r.db.supportMessages {
+		if message.StaffChatID == staffChatID && message.StaffMessageID == staffMessageID {
+			return r.get(message.TicketID)
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *SupportTicketRepository) get(id uint) (*models.SupportTicket, error) {
+	ticket, ok := r.db.supportTickets[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	c := *ticket
+	return &c, nil
+}
+
+// findAll returns copies of matching tickets ordered by their numbers
+func (r *SupportTicketRepository) findAll(match func(*models.SupportTicket) bool) []*models.SupportTicket {
+	var tickets []*models.SupportTicket
+	for _, ticket := range r.db.supportTickets {
+		if match(ticket) {
+			c := *ticket
+			tickets = append(tickets, &c)
+		}
+	}
+	sort.Slice(tickets, func(i, j int) bool { return tickets[i].ID < tickets[j].ID })
+	return tickets
+}
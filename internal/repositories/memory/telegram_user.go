@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// TelegramUserRepository is an in-memory implementation of interfaces.TelegramUserManager
+type TelegramUserRepository struct {
+	db    *Database
+	actor *models.TelegramUser
+}
+
+var _ interfaces.TelegramUserManager = (*TelegramUserRepository)(nil)
+
+// NewTelegramUserRepository creates a new in-memory TelegramUserRepository
+func NewTelegramUserRepository(db *Database) *TelegramUserRepository {
+	return &TelegramUserRepository{db: db}
+}
+
+// WithActor returns a copy of the repository recording role changes as made by the actor
+func (r *TelegramUserRepository) WithActor(actor *models.TelegramUser) interfaces.TelegramUserManager {
+	c := *r
+	c.actor = actor
+	return &c
+}
+
+// GetByID retrieves a Telegram user by UUID
+func (r *TelegramUserRepository) GetByID(id uuid.UUID) (*models.TelegramUser, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findOne(func(u *models.TelegramUser) bool { return u.ID == id })
+}
+
+// GetByTelegramID retrieves a Telegram user by their Telegram ID
+func (r *TelegramUserRepository) GetByTelegramID(telegramID int64) (*models.TelegramUser, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findOne(func(u *models.TelegramUser) bool { return u.TelegramID == telegramID })
+}
+
+// GetByUsername retrieves a Telegram user by their username
+func (r *TelegramUserRepository) GetByUsername(username string) (*models.TelegramUser, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findOne(func(u *models.TelegramUser) bool { return u.Username == username })
+}
+
+// CreateUser creates a new Telegram user
+func (r *TelegramUserRepository) CreateUser(user *models.TelegramUser) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// telegram_id has a unique index which also covers soft-deleted rows
+	for _, existing := range r.db.users {
+		if existing.TelegramID == user.TelegramID {
+			return fmt.Errorf("duplicate telegram_id: %d", user.TelegramID)
+		}
+	}
+
+	if err := user.BeforeCreate(nil); err != nil {
+		return err
+	}
+	r.db.touch(&user.BaseModel)
+
+	stored := copyUser(user)
+	r.db.users[user.ID] = &stored
+	return nil
+}
+
+// UpdateByID updates a Telegram user by ID
+func (r *TelegramUserRepository) UpdateByID(id uuid.UUID, updates interface{}) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.update(func(u *models.TelegramUser) bool { return u.ID == id }, updates)
+}
+
+// UpdateByTelegramID updates a Telegram user by Telegram ID
+func (r *TelegramUserRepository) UpdateByTelegramID(telegramID int64, updates interface{}) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.update(func(u *models.TelegramUser) bool { return u.TelegramID == telegramID }, updates)
+}
+
+// DeleteByID deletes a Telegram user by ID
+func (r *TelegramUserRepository) DeleteByID(id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.delete(func(u *models.TelegramUser) bool { return u.ID == id })
+	return nil
+}
+
+// DeleteByTelegramID deletes a Telegram user by Telegram ID
+func (r *TelegramUserRepository) DeleteByTelegramID(telegramID int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.delete(func(u *models.TelegramUser) bool { return u.TelegramID == telegramID })
+	return nil
+}
+
+// GetUsersByState retrieves all users with a specific state
+func (r *TelegramUserRepository) GetUsersByState(state string) ([]*models.TelegramUser, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findAll(func(u *models.TelegramUser) bool { return u.State == state }), nil
+}
+
+// GetUsersByRole retrieves all users with a specific role
+func (r *TelegramUserRepository) GetUsersByRole(role string) ([]*models.TelegramUser, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findAll(func(u *models.TelegramUser) bool { return u.Role == role }), nil
+}
+
+func (r *TelegramUserRepository) findOne(match func(*models.TelegramUser) bool) (*models.TelegramUser, error) {
+	users := r.findAll(match)
+	if len(users) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return users[0], nil
+}
+
+func (r *TelegramUserRepository) findAll(match func(*models.TelegramUser) bool) []*models.TelegramUser {
+	var users []models.TelegramUser
+	for _, user := range r.db.users {
+		if !user.DeletedAt.Valid && match(user) {
+			users = append(users, copyUser(user))
+		}
+	}
+	sortByCreation(users, func(u models.TelegramUser) time.Time { return u.CreatedAt })
+
+	result := make([]*models.TelegramUser, 0, len(users))
+	for i := range users {
+		result = append(result, &users[i])
+	}
+	return result
+}
+
+func (r *TelegramUserRepository) update(match func(*models.TelegramUser) bool, updates interface{}) error {
+	for _, user := range r.db.users {
+		if user.DeletedAt.Valid || !match(user) {
+			continue
+		}
+		role := user.Role
+		if err := r.db.applyUpdates(user, updates); err != nil {
+			return err
+		}
+		if user.Role != role {
+			entry := models.NewAuditLog(r.actor, models.AuditUserRoleChanged,
+				&models.RoleSnapshot{Role: role}, &models.RoleSnapshot{Role: user.Role})
+			entry.UserID = user.ID
+			r.db.audit(entry)
+		}
+	}
+	return nil
+}
+
+func (r *TelegramUserRepository) delete(match func(*models.TelegramUser) bool) {
+	for _, user := range r.db.users {
+		if !user.DeletedAt.Valid && match(user) {
+			r.db.softDelete(&user.BaseModel)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// WorkspaceRepository is an in-memory implementation of interfaces.WorkspaceManager
+type WorkspaceRepository struct {
+	db *Database
+}
+
+var _ interfaces.WorkspaceManager = (*WorkspaceRepository)(nil)
+
+// NewWorkspaceRepository creates a new in-memory WorkspaceRepository
+func NewWorkspaceRepository(db *Database) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// CreateWorkspace creates a new workspace
+func (r *WorkspaceRepository) CreateWorkspace(workspace *models.Workspace) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.create(workspace)
+}
+
+// GetByID retrieves a workspace by UUID
+func (r *WorkspaceRepository) GetByID(id uuid.UUID) (*models.Workspace, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findOne(func(w *models.Workspace) bool { return w.ID == id })
+}
+
+// GetByName retrieves a workspace by its name
+func (r *WorkspaceRepository) GetByName(name string) (*models.Workspace, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findOne(func(w *models.Workspace) bool { return w.Name == name })
+}
+
+// GetAll retrieves all workspaces ordered by name
+func (r *WorkspaceRepository) GetAll() ([]*models.Workspace, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findAll(func(*models.Workspace) bool { return true }), nil
+}
+
+// GetOrCreateWorkspace gets an existing workspace by name or creates a new one
+func (r *WorkspaceRepository) GetOrCreateWorkspace(name string) (*models.Workspace, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	workspace, err := r.findOne(func(w *models.Workspace) bool { return w.Name == name })
+	if err == nil {
+		return workspace, nil
+	}
+
+	workspace = &models.Workspace{Name: name}
+	if err := r.create(workspace); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+// AddMember adds a user to a workspace, adding an existing member is a no-op
+func (r *WorkspaceRepository) AddMember(workspaceID, userID uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.workspaceMembers[workspaceMember{WorkspaceID: workspaceID, TelegramUserID: userID}] = struct{}{}
+	return nil
+}
+
+// RemoveMember removes a user from a workspace
+func (r *WorkspaceRepository) RemoveMember(workspaceID, userID uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.workspaceMembers, workspaceMember{WorkspaceID: workspaceID, TelegramUserID: userID})
+	return nil
+}
+
+// IsMember checks whether a user belongs to a workspace
+func (r *WorkspaceRepository) IsMember(workspaceID, userID uuid.UUID) (bool, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	_, ok := r.db.workspaceMembers[workspaceMember{WorkspaceID: workspaceID, TelegramUserID: userID}]
+	return ok, nil
+}
+
+// GetUserWorkspaces retrieves workspaces of a user ordered by name
+func (r *WorkspaceRepository) GetUserWorkspaces(userID uuid.UUID) ([]*models.Workspace, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.findAll(func(w *models.Workspace) bool {
+		_, ok := r.db.workspaceMembers[workspaceMember{WorkspaceID: w.ID, TelegramUserID: userID}]
+		return ok
+	}), nil
+}
+
+// GetMembers retrieves users of a workspace
+func (r *WorkspaceRepository) GetMembers(workspaceID uuid.UUID) ([]*models.TelegramUser, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var users []models.TelegramUser
+	for membership := range r.db.workspaceMembers {
+		if membership.WorkspaceID != workspaceID {
+			continue
+		}
+		user, ok := r.db.users[membership.TelegramUserID]
+		if !ok || user.DeletedAt.Valid {
+			continue
+		}
+		users = append(users, copyUser(user))
+	}
+	sortByCreation(users, func(u models.TelegramUser) time.Time { return u.CreatedAt })
+
+	result := make([]*models.TelegramUser, 0, len(users))
+	for i := range users {
+		result = append(result, &users[i])
+	}
+	return result, nil
+}
+
+func (r *WorkspaceRepository) create(workspace *models.Workspace) error {
+	// name has a unique index which also covers soft-deleted rows
+	for _, existing := range r.db.workspaces {
+		if existing.Name == workspace.Name {
+			return fmt.Errorf("duplicate workspace name: %s", workspace.Name)
+		}
+	}
+
+	if err := workspace.BeforeCreate(nil); err != nil {
+		return err
+	}
+	r.db.touch(&workspace.BaseModel)
+
+	stored := copyWorkspace(workspace)
+	r.db.workspaces[workspace.ID] = &stored
+	return nil
+}
+
+func (r *WorkspaceRepository) findOne(match func(*models.Workspace) bool) (*models.Workspace, error) {
+	workspaces := r.findAll(match)
+	if len(workspaces) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return workspaces[0], nil
+}
+
+func (r *WorkspaceRepository) findAll(match func(*models.Workspace) bool) []*models.Workspace {
+	var workspaces []models.Workspace
+	for _, workspace := range r.db.workspaces {
+		if !workspace.DeletedAt.Valid && match(workspace) {
+			workspaces = append(workspaces, copyWorkspace(workspace))
+		}
+	}
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Name < workspaces[j].Name })
+
+	result := make([]*models.Workspace, 0, len(workspaces))
+	for i := range workspaces {
+		result = append(result, &workspaces[i])
+	}
+	return result
+}
@@ -2,8 +2,10 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -17,6 +19,8 @@ import (
 type PhotoRepository struct {
 	DB       *gorm.DB
 	S3Client interfaces.S3Client
+	// actor is recorded in the audit log as the author of changes
+	actor *models.TelegramUser
 }
 
 // NewPhotoRepository creates a new PhotoRepository
@@ -27,6 +31,13 @@ func NewPhotoRepository(db *gorm.DB, s3Client interfaces.S3Client) *PhotoReposit
 	}
 }
 
+// WithActor returns a copy of the repository recording changes as made by the actor
+func (r *PhotoRepository) WithActor(actor *models.TelegramUser) interfaces.PhotoManager {
+	c := *r
+	c.actor = actor
+	return &c
+}
+
 // GetByID retrieves a Photo by UUID
 func (r *PhotoRepository) GetByID(id uuid.UUID) (*models.Photo, error) {
 	photo := &models.Photo{}
@@ -57,12 +68,51 @@ func (r *PhotoRepository) GetUsersPhotosByState(
 	return photos, nil
 }
 
+// GetPhotos retrieves Photos selected by the filter with their article numbers, the oldest first
+func (r *PhotoRepository) GetPhotos(filter models.PhotoFilter) ([]*models.Photo, error) {
+	tx := r.DB.Preload("ArticleNumbers")
+	if filter.WorkspaceID != uuid.Nil {
+		tx = tx.Where("workspace_id = ?", filter.WorkspaceID)
+	}
+	if filter.UserID != uuid.Nil {
+		tx = tx.Where("user_id = ?", filter.UserID)
+	}
+	if filter.State != "" {
+		tx = tx.Where("state = ?", filter.State)
+	}
+	if !filter.Since.IsZero() {
+		tx = tx.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		tx = tx.Where("created_at < ?", filter.Until)
+	}
+
+	var photos []*models.Photo
+	if err := tx.Order("created_at").Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// GetByContentHash retrieves an applied Photo of the workspace by the hash of its file
+func (r *PhotoRepository) GetByContentHash(workspaceID uuid.UUID, contentHash string) (*models.Photo, error) {
+	photo := &models.Photo{}
+	tx := r.DB.Preload("ArticleNumbers").
+		Where("workspace_id = ? AND content_hash = ? AND state = ?", workspaceID, contentHash, models.PhotoApplied).
+		Order("created_at").
+		First(photo)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return photo, nil
+}
+
 // GetPhotosByArticleNumber retrieves all Photos associated with an article number
 func (r *PhotoRepository) GetPhotosByArticleNumber(articleNumberID uuid.UUID) ([]*models.Photo, error) {
 	var photos []*models.Photo
 	tx := r.DB.Preload("ArticleNumbers").
-		Joins("JOIN photo_article_number ON photo_article_number.photo_id = photo.id").
-		Where("photo_article_number.article_number_id = ?", articleNumberID).
+		Joins("JOIN article_number_photos ON article_number_photos.photo_id = photos.id").
+		Where("article_number_photos.article_number_id = ?", articleNumberID).
 		Find(&photos)
 	if tx.Error != nil {
 		return nil, tx.Error
@@ -72,57 +122,186 @@ func (r *PhotoRepository) GetPhotosByArticleNumber(articleNumberID uuid.UUID) ([
 
 // CreatePhoto creates a new Photo
 func (r *PhotoRepository) CreatePhoto(photo *models.Photo) error {
-	return r.DB.Create(photo).Error
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(photo).Error; err != nil {
+			return err
+		}
+		entry := models.NewAuditLog(r.actor, models.AuditPhotoCreated, nil, models.NewPhotoSnapshot(photo))
+		entry.PhotoID = photo.ID
+		return audit(tx, entry)
+	})
 }
 
 // UpdatePhoto updates a Photo
 func (r *PhotoRepository) UpdatePhoto(photo *models.Photo) error {
-	return r.DB.Save(photo).Error
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var before *models.PhotoSnapshot
+		if photo.ID != uuid.Nil {
+			stored := &models.Photo{}
+			err := tx.Where("id = ?", photo.ID).First(stored).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err == nil {
+				before = models.NewPhotoSnapshot(stored)
+			}
+		}
+
+		if err := tx.Save(photo).Error; err != nil {
+			return err
+		}
+		entry := models.NewAuditLog(r.actor, models.PhotoChangeAction(before, photo), before, models.NewPhotoSnapshot(photo))
+		entry.PhotoID = photo.ID
+		return audit(tx, entry)
+	})
 }
 
-func (r *PhotoRepository) DeletePhoto(
-	id uuid.UUID,
-	bucket string) error {
+// DeletePhoto moves a Photo to the trash, its object stays in S3 until the photo is purged
+func (r *PhotoRepository) DeletePhoto(id uuid.UUID) error {
+	photo, err := r.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.Photo{}, id).Error; err != nil {
+			return err
+		}
+		entry := models.NewAuditLog(r.actor, models.AuditPhotoDeleted, models.NewPhotoSnapshot(photo), nil)
+		entry.PhotoID = photo.ID
+		return audit(tx, entry)
+	})
+}
+
+// RestorePhoto takes a Photo out of the trash
+func (r *PhotoRepository) RestorePhoto(id uuid.UUID) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		photo := &models.Photo{}
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(photo).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(photo).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		entry := models.NewAuditLog(r.actor, models.AuditPhotoRestored, nil, models.NewPhotoSnapshot(photo))
+		entry.PhotoID = photo.ID
+		return audit(tx, entry)
+	})
+}
+
+// GetDeletedPhotos retrieves Photos of a workspace in the trash, the last deleted first
+func (r *PhotoRepository) GetDeletedPhotos(workspaceID uuid.UUID) ([]*models.Photo, error) {
+	var photos []*models.Photo
+	tx := r.DB.Unscoped().
+		Preload("ArticleNumbers").
+		Where("workspace_id = ? AND deleted_at IS NOT NULL", workspaceID).
+		Order("deleted_at DESC").
+		Find(&photos)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return photos, nil
+}
+
+// PurgeDeletedPhotos permanently deletes Photos deleted before the time, their links
+// and their objects in the storage. Photos failing to purge are kept for the next time.
+func (r *PhotoRepository) PurgeDeletedPhotos(deletedBefore time.Time, bucket string) (int, error) {
+	var photos []*models.Photo
+	tx := r.DB.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).Find(&photos)
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+
+	purged := 0
+	var errs []error
+	for _, photo := range photos {
+		if err := r.purge(photo, bucket, models.AuditPhotoPurged); err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge photo %s: %w", photo.ID, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
 
+// DiscardPhoto permanently deletes a Photo which never reached the catalog, such as
+// a cancelled upload, and its object in the storage
+func (r *PhotoRepository) DiscardPhoto(id uuid.UUID, bucket string) error {
 	photo, err := r.GetByID(id)
 	if err != nil {
 		return err
 	}
+	return r.purge(photo, bucket, models.AuditPhotoDeleted)
+}
 
-	if bucket != "" {
-		s3ObjectKey := photo.UserID.String() + "/" + photo.S3Key.String() + ".jpg"
-		if err := r.S3Client.DeleteFile(context.Background(), bucket, s3ObjectKey); err != nil {
-			return fmt.Errorf("failed to delete from S3: %w", err)
+// purge deletes the object of a Photo, its links and the row itself, recording the action
+func (r *PhotoRepository) purge(photo *models.Photo, bucket string, action string) error {
+	// The local storage has no buckets, so an empty bucket is valid
+	if r.S3Client != nil {
+		keys := []string{models.PhotoObjectKey(photo.UserID, photo.S3Key, photo.Format)}
+		for _, rendition := range models.PhotoRenditions {
+			keys = append(keys, models.PhotoObjectKey(photo.UserID, photo.RenditionKey(rendition), models.PhotoRenditionFormat))
+		}
+		for _, s3ObjectKey := range keys {
+			if err := r.S3Client.DeleteFile(context.Background(), bucket, s3ObjectKey); err != nil {
+				return fmt.Errorf("failed to delete from S3: %w", err)
+			}
 		}
 	}
 
-	return r.DB.Delete(&models.Photo{}, id).Error
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("photo_id = ?", photo.ID).Delete(&models.ArticleNumberPhoto{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&models.Photo{}, photo.ID).Error; err != nil {
+			return err
+		}
+		entry := models.NewAuditLog(r.actor, action, models.NewPhotoSnapshot(photo), nil)
+		entry.PhotoID = photo.ID
+		return audit(tx, entry)
+	})
 }
 
 // AddArticleNumberToPhoto associates an article number with a photo
 func (r *PhotoRepository) AddArticleNumberToPhoto(photoID, articleNumberID uuid.UUID) error {
 	// Check if article number exists
-	var count int64
-	if err := r.DB.Model(&models.ArticleNumber{}).
-		Where("id = ?", articleNumberID).
-		Count(&count).Error; err != nil {
-		return err
-	}
-	if count == 0 {
+	articleNumber := &models.ArticleNumber{}
+	err := r.DB.Where("id = ?", articleNumberID).First(articleNumber).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return fmt.Errorf("article number not found")
 	}
+	if err != nil {
+		return err
+	}
 
 	relation := models.ArticleNumberPhoto{
 		PhotoID:         photoID,
 		ArticleNumberID: articleNumberID,
 	}
-	return r.DB.Create(&relation).Error
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&relation).Error; err != nil {
+			return err
+		}
+		return audit(tx, models.NewLinkAuditLog(r.actor, models.AuditArticleLinked, photoID, articleNumber))
+	})
 }
 
 // RemoveArticleNumberFromPhoto removes an association between an article number and a photo
 func (r *PhotoRepository) RemoveArticleNumberFromPhoto(photoID, articleNumberID uuid.UUID) error {
-	return r.DB.Where("photo_id = ? AND article_number_id = ?", photoID, articleNumberID).
-		Delete(&models.ArticleNumberPhoto{}).Error
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("photo_id = ? AND article_number_id = ?", photoID, articleNumberID).
+			Delete(&models.ArticleNumberPhoto{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		// The number is kept for the log even if the article number is already deleted
+		articleNumber := &models.ArticleNumber{}
+		if err := tx.Unscoped().Where("id = ?", articleNumberID).First(articleNumber).Error; err != nil {
+			articleNumber.ID = articleNumberID
+		}
+		return audit(tx, models.NewLinkAuditLog(r.actor, models.AuditArticleUnlinked, photoID, articleNumber))
+	})
 }
 
 // UploadPhotoToS3 uploads a photo to S3 storage and associates it with article numbers
@@ -130,10 +309,11 @@ func (r *PhotoRepository) UploadPhotoToS3(
 	ctx context.Context,
 	userID uuid.UUID,
 	s3Key uuid.UUID,
+	format string,
 	bucket string,
 	photoData io.Reader) error {
 	// Generate S3 object key
-	s3ObjectKey := userID.String() + "/" + s3Key.String() + ".jpg"
+	s3ObjectKey := models.PhotoObjectKey(userID, s3Key, format)
 
 	// Upload the file to S3
 	if err := r.S3Client.UploadFile(ctx, bucket, s3ObjectKey, photoData); err != nil {
@@ -144,15 +324,21 @@ func (r *PhotoRepository) UploadPhotoToS3(
 }
 
 // GetPhotoFromS3 downloads a photo from S3 storage
-func (r *PhotoRepository) GetPhotoFromS3(ctx context.Context, userID uuid.UUID, s3Key uuid.UUID, bucket string) (io.ReadCloser, error) {
-	s3ObjectKey := userID.String() + "/" + s3Key.String() + ".jpg"
+func (r *PhotoRepository) GetPhotoFromS3(ctx context.Context, userID uuid.UUID, s3Key uuid.UUID, format string, bucket string) (io.ReadCloser, error) {
+	s3ObjectKey := models.PhotoObjectKey(userID, s3Key, format)
 	return r.S3Client.DownloadFile(ctx, bucket, s3ObjectKey)
 }
 
-// GetPhotoURL generates a URL for a photo in S3
-func (r *PhotoRepository) GetPhotoURL(ctx context.Context, userID uuid.UUID, s3Key uuid.UUID, bucket string, endpoint string) string {
-	s3ObjectKey := userID.String() + "/" + s3Key.String() + ".jpg"
-	return endpoint + "/" + bucket + "/" + s3ObjectKey
+// GetPhotoURL generates an expiring presigned URL for a photo in S3
+func (r *PhotoRepository) GetPhotoURL(
+	ctx context.Context,
+	userID uuid.UUID,
+	s3Key uuid.UUID,
+	format string,
+	bucket string,
+	expiresIn time.Duration) (string, error) {
+	s3ObjectKey := models.PhotoObjectKey(userID, s3Key, format)
+	return r.S3Client.GeneratePresignedURL(ctx, bucket, s3ObjectKey, int64(expiresIn.Seconds()))
 }
 
 // GetPhotoWithArticleNumbers retrieves a photo with its associated article numbers
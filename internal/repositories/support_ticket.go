@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// SupportTicketRepository handles database operations for support tickets and their history
+type SupportTicketRepository struct {
+	db *gorm.DB
+}
+
+// NewSupportTicketRepository creates a new SupportTicketRepository
+func NewSupportTicketRepository(db *gorm.DB) *SupportTicketRepository {
+	return &SupportTicketRepository{db: db}
+}
+
+// CreateTicket creates a new ticket, it is open unless the status is set
+func (r *SupportTicketRepository) CreateTicket(ticket *models.SupportTicket) error {
+	if ticket.Status == "" {
+		ticket.Status = models.SupportTicketOpen
+	}
+	return r.db.Create(ticket).Error
+}
+
+// GetTicket retrieves a ticket by its number, without the history
+func (r *SupportTicketRepository) GetTicket(id uint) (*models.SupportTicket, error) {
+	ticket := &models.SupportTicket{}
+	tx := r.db.Where("id = ?", id).First(ticket)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return ticket, nil
+}
+
+// GetOpenTicket retrieves the open ticket of a user
+func (r *SupportTicketRepository) GetOpenTicket(telegramID int64) (*models.SupportTicket, error) {
+	ticket := &models.SupportTicket{}
+	tx := r.db.
+		Where("telegram_id = ? AND status = ?", telegramID, models.SupportTicketOpen).
+		Order("id DESC").
+		First(ticket)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return ticket, nil
+}
+
+// GetOpenTickets retrieves all open tickets, the oldest first
+func (r *SupportTicketRepository) GetOpenTickets() ([]*models.SupportTicket, error) {
+	var tickets []*models.SupportTicket
+	tx := r.db.Where("status = ?", models.SupportTicketOpen).Order("id").Find(&tickets)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return tickets, nil
+}
+
+// CloseTicket marks a ticket closed, closing a closed ticket keeps its closing time
+func (r *SupportTicketRepository) CloseTicket(id uint) error {
+	return r.db.
+		Model(&models.SupportTicket{}).
+		Where("id = ? AND status = ?", id, models.SupportTicketOpen).
+		Updates(map[string]interface{}{
+			"status":    models.SupportTicketClosed,
+			"closed_at": time.Now(),
+		}).
+		Error
+}
+
+// AddMessage appends a message to the history of its ticket
+func (r *SupportTicketRepository) AddMessage(message *models.SupportMessage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		// The ticket shows when it was last active
+		return tx.Model(&models.SupportTicket{}).
+			Where("id = ?", message.TicketID).
+			Update("updated_at", message.CreatedAt).
+			Error
+	})
+}
+
+// GetMessages retrieves the history of a ticket in the order of messages
+func (r *SupportTicketRepository) GetMessages(ticketID uint) ([]*models.SupportMessage, error) {
+	var messages []*models.SupportMessage
+	tx := r.db.Where("ticket_id = ?", ticketID).Order("id").Find(&messages)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return messages, nil
+}
+
+// GetTicketByStaffMessage finds the ticket of a message shown in the staff chat
+func (r *SupportTicketRepository) GetTicketByStaffMessage(staffChatID int64, staffMessageID int) (*models.SupportTicket, error) {
+	message := &models.SupportMessage{}
+	tx := r.db.
+		Where("staff_chat_id = ? AND staff_message_id = ?", staffChatID, staffMessageID).
+		First(message)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return r.GetTicket(message.TicketID)
+}
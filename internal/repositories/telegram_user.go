@@ -5,12 +5,15 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
 	"github.com/Conty111/AlfredoBot/internal/models"
 )
 
 // TelegramUserRepository handles database operations for Telegram users
 type TelegramUserRepository struct {
 	db *gorm.DB
+	// actor is recorded in the audit log as the author of role changes
+	actor *models.TelegramUser
 }
 
 // NewTelegramUserRepository creates a new TelegramUserRepository
@@ -18,6 +21,13 @@ func NewTelegramUserRepository(db *gorm.DB) *TelegramUserRepository {
 	return &TelegramUserRepository{db: db}
 }
 
+// WithActor returns a copy of the repository recording role changes as made by the actor
+func (r *TelegramUserRepository) WithActor(actor *models.TelegramUser) interfaces.TelegramUserManager {
+	c := *r
+	c.actor = actor
+	return &c
+}
+
 // GetByID retrieves a Telegram user by UUID
 func (r *TelegramUserRepository) GetByID(id uuid.UUID) (*models.TelegramUser, error) {
 	user := &models.TelegramUser{}
@@ -64,20 +74,12 @@ func (r *TelegramUserRepository) CreateUser(user *models.TelegramUser) error {
 
 // UpdateByID updates a Telegram user by ID
 func (r *TelegramUserRepository) UpdateByID(id uuid.UUID, updates interface{}) error {
-	return r.db.
-		Model(&models.TelegramUser{}).
-		Where("id = ?", id).
-		Updates(updates).
-		Error
+	return r.update("id = ?", id, updates)
 }
 
 // UpdateByTelegramID updates a Telegram user by Telegram ID
 func (r *TelegramUserRepository) UpdateByTelegramID(telegramID int64, updates interface{}) error {
-	return r.db.
-		Model(&models.TelegramUser{}).
-		Where("telegram_id = ?", telegramID).
-		Updates(updates).
-		Error
+	return r.update("telegram_id = ?", telegramID, updates)
 }
 
 // DeleteByID deletes a Telegram user by ID
@@ -108,3 +110,61 @@ func (r *TelegramUserRepository) GetUsersByState(state string) ([]*models.Telegr
 	}
 	return users, nil
 }
+
+// GetUsersByRole retrieves all users with a specific role
+func (r *TelegramUserRepository) GetUsersByRole(role string) ([]*models.TelegramUser, error) {
+	var users []*models.TelegramUser
+	tx := r.db.Where("role = ?", role).Order("created_at").Find(&users)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return users, nil
+}
+
+// update applies updates to the users selected by the condition, role changes are recorded in the audit log
+func (r *TelegramUserRepository) update(condition string, value interface{}, updates interface{}) error {
+	role, changesRole := roleUpdate(updates)
+	if !changesRole {
+		return r.db.
+			Model(&models.TelegramUser{}).
+			Where(condition, value).
+			Updates(updates).
+			Error
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var users []*models.TelegramUser
+		if err := tx.Where(condition, value).Find(&users).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TelegramUser{}).Where(condition, value).Updates(updates).Error; err != nil {
+			return err
+		}
+		for _, user := range users {
+			if user.Role == role {
+				continue
+			}
+			entry := models.NewAuditLog(r.actor, models.AuditUserRoleChanged,
+				&models.RoleSnapshot{Role: user.Role}, &models.RoleSnapshot{Role: role})
+			entry.UserID = user.ID
+			if err := audit(tx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// roleUpdate returns the role set by the updates
+func roleUpdate(updates interface{}) (string, bool) {
+	switch values := updates.(type) {
+	case map[string]interface{}:
+		role, ok := values["role"].(string)
+		return role, ok
+	case *models.TelegramUser:
+		return values.Role, values.Role != ""
+	case models.TelegramUser:
+		return values.Role, values.Role != ""
+	}
+	return "", false
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/google/uuid"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// WorkspaceRepository handles database operations for workspaces and their members
+type WorkspaceRepository struct {
+	db *gorm.DB
+}
+
+// NewWorkspaceRepository creates a new WorkspaceRepository
+func NewWorkspaceRepository(db *gorm.DB) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// CreateWorkspace creates a new workspace
+func (r *WorkspaceRepository) CreateWorkspace(workspace *models.Workspace) error {
+	return r.db.Create(workspace).Error
+}
+
+// GetByID retrieves a workspace by UUID
+func (r *WorkspaceRepository) GetByID(id uuid.UUID) (*models.Workspace, error) {
+	workspace := &models.Workspace{}
+	tx := r.db.Where("id = ?", id).First(workspace)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return workspace, nil
+}
+
+// GetByName retrieves a workspace by its name
+func (r *WorkspaceRepository) GetByName(name string) (*models.Workspace, error) {
+	workspace := &models.Workspace{}
+	tx := r.db.Where("name = ?", name).First(workspace)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return workspace, nil
+}
+
+// GetAll retrieves all workspaces ordered by name
+func (r *WorkspaceRepository) GetAll() ([]*models.Workspace, error) {
+	var workspaces []*models.Workspace
+	tx := r.db.Order("name").Find(&workspaces)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return workspaces, nil
+}
+
+// GetOrCreateWorkspace gets an existing workspace by name or creates a new one
+func (r *WorkspaceRepository) GetOrCreateWorkspace(name string) (*models.Workspace, error) {
+	workspace, err := r.GetByName(name)
+	if err == nil {
+		return workspace, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	workspace = &models.Workspace{Name: name}
+	if err := r.db.Create(workspace).Error; err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+// AddMember adds a user to a workspace, adding an existing member is a no-op
+func (r *WorkspaceRepository) AddMember(workspaceID, userID uuid.UUID) error {
+	return r.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.WorkspaceMember{WorkspaceID: workspaceID, TelegramUserID: userID}).
+		Error
+}
+
+// RemoveMember removes a user from a workspace
+func (r *WorkspaceRepository) RemoveMember(workspaceID, userID uuid.UUID) error {
+	return r.db.
+		Where("workspace_id = ? AND telegram_user_id = ?", workspaceID, userID).
+		Delete(&models.WorkspaceMember{}).
+		Error
+}
+
+// IsMember checks whether a user belongs to a workspace
+func (r *WorkspaceRepository) IsMember(workspaceID, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.
+		Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND telegram_user_id = ?", workspaceID, userID).
+		Count(&count).
+		Error
+	return count > 0, err
+}
+
+// GetUserWorkspaces retrieves workspaces of a user ordered by name
+func (r *WorkspaceRepository) GetUserWorkspaces(userID uuid.UUID) ([]*models.Workspace, error) {
+	var workspaces []*models.Workspace
+	tx := r.db.
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id").
+		Where("workspace_members.telegram_user_id = ?", userID).
+		Order("workspaces.name").
+		Find(&workspaces)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return workspaces, nil
+}
+
+// GetMembers retrieves users of a workspace
+func (r *WorkspaceRepository) GetMembers(workspaceID uuid.UUID) ([]*models.TelegramUser, error) {
+	var users []*models.TelegramUser
+	tx := r.db.
+		Joins("JOIN workspace_members ON workspace_members.telegram_user_id = telegram_users.id").
+		Where("workspace_members.workspace_id = ?", workspaceID).
+		Order("telegram_users.created_at").
+		Find(&users)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return users, nil
+}
@@ -0,0 +1,61 @@
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	// Telegram sends photos as JPEG, documents may be PNG or GIF
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+	"github.com/makiuchi-d/gozxing/qrcode"
+
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
+)
+
+// Decode finds EAN-13, Code 128 and QR codes in an image and returns their texts,
+// each text once, in the order of the formats. An image without codes is not an error.
+// Images with more pixels than imaging.MaxPixels are refused with imaging.ErrTooManyPixels.
+func Decode(data []byte) ([]string, error) {
+	if _, _, err := imaging.DecodeConfig(data); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	// Trying harder also scans 1D codes turned by 90 degrees
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+	readers := []gozxing.Reader{
+		oned.NewEAN13Reader(),
+		oned.NewCode128Reader(),
+		qrcode.NewQRCodeReader(),
+	}
+
+	var texts []string
+	seen := make(map[string]bool)
+	for _, reader := range readers {
+		result, err := reader.Decode(bitmap, hints)
+		if err != nil {
+			// Readers fail when their format is not in the image
+			continue
+		}
+		text := strings.TrimSpace(result.GetText())
+		if text != "" && !seen[text] {
+			seen[text] = true
+			texts = append(texts, text)
+		}
+	}
+	return texts, nil
+}
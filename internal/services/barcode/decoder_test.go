@@ -0,0 +1,95 @@
+package barcode_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/services/barcode"
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
+)
+
+// label renders a code on a white photo the way it appears on a warehouse label
+func label(writer gozxing.Writer, format gozxing.BarcodeFormat, text string, width, height int) image.Image {
+	GinkgoHelper()
+	matrix, err := writer.Encode(text, format, width, height, nil)
+	Expect(err).To(BeNil())
+
+	photo := image.NewGray(image.Rect(0, 0, width+200, height+200))
+	draw.Draw(photo, photo.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(photo, image.Rect(100, 100, width+100, height+100), matrix, image.Point{}, draw.Src)
+	return photo
+}
+
+// rotate turns an image by 90 degrees clockwise
+func rotate(img image.Image) image.Image {
+	bounds := img.Bounds()
+	rotated := image.NewGray(image.Rect(0, 0, bounds.Dy(), bounds.Dx()))
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			rotated.Set(bounds.Max.Y-1-y, x, img.At(x, y))
+		}
+	}
+	return rotated
+}
+
+func encodeJPEG(img image.Image) []byte {
+	GinkgoHelper()
+	var buf bytes.Buffer
+	Expect(jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})).To(Succeed())
+	return buf.Bytes()
+}
+
+var _ = Describe("Decode", func() {
+	It("should decode EAN-13 codes", func() {
+		photo := label(oned.NewEAN13Writer(), gozxing.BarcodeFormat_EAN_13, "4006381333931", 400, 150)
+		Expect(barcode.Decode(encodeJPEG(photo))).To(Equal([]string{"4006381333931"}))
+	})
+
+	It("should decode Code 128 codes turned sideways", func() {
+		photo := label(oned.NewCode128Writer(), gozxing.BarcodeFormat_CODE_128, "SUP-77/1.2345", 500, 150)
+		Expect(barcode.Decode(encodeJPEG(rotate(photo)))).To(Equal([]string{"SUP-77/1.2345"}))
+	})
+
+	It("should decode QR codes in PNG images", func() {
+		photo := label(qrcode.NewQRCodeWriter(), gozxing.BarcodeFormat_QR_CODE, "1.2345", 300, 300)
+		var buf bytes.Buffer
+		Expect(png.Encode(&buf, photo)).To(Succeed())
+		Expect(barcode.Decode(buf.Bytes())).To(Equal([]string{"1.2345"}))
+	})
+
+	It("should find nothing in photos without codes", func() {
+		photo := image.NewGray(image.Rect(0, 0, 300, 200))
+		Expect(barcode.Decode(encodeJPEG(photo))).To(BeEmpty())
+	})
+
+	It("should fail on data which is not an image", func() {
+		_, err := barcode.Decode([]byte("not an image"))
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should refuse images with more pixels than the limit before decoding them", func() {
+		// A PNG header of a 100000x100000 image, decoding it would take 40 GB
+		header := binary.BigEndian.AppendUint32(nil, 100_000)
+		header = binary.BigEndian.AppendUint32(header, 100_000)
+		header = append(header, 8, 0, 0, 0, 0)
+		chunk := append([]byte("IHDR"), header...)
+		data := append([]byte("\x89PNG\r\n\x1a\n"), binary.BigEndian.AppendUint32(nil, uint32(len(header)))...)
+		data = append(data, chunk...)
+		data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+
+		_, err := barcode.Decode(data)
+		Expect(err).To(MatchError(imaging.ErrTooManyPixels))
+	})
+})
@@ -0,0 +1,273 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// Names of the manifests in export archives, the CSV one can be imported back
+const (
+	ExportManifestCSV  = "manifest.csv"
+	ExportManifestJSON = "manifest.json"
+)
+
+// exportPhotosDir is the folder of the photos in export archives
+const exportPhotosDir = "photos"
+
+// ExportedPhoto is a photo listed in the export manifest
+type ExportedPhoto struct {
+	// File is the path of the photo in the archive
+	File               string    `json:"file"`
+	ArticleNumbers     []string  `json:"article_numbers"`
+	PhotoID            uuid.UUID `json:"photo_id"`
+	UploaderID         uuid.UUID `json:"uploader_id"`
+	UploaderTelegramID int64     `json:"uploader_telegram_id,omitempty"`
+	UploaderUsername   string    `json:"uploader_username,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// ExportReport lists the photos written to an export archive
+type ExportReport struct {
+	Photos []ExportedPhoto
+	// Failed lists photos left out because their files could not be read from the storage
+	Failed []uuid.UUID
+}
+
+// Exporter writes applied photos of the catalog to ZIP archives named by their article numbers
+type Exporter struct {
+	photoRepository interfaces.PhotoManager
+	userRepository  interfaces.TelegramUserProvider
+	bucket          string
+}
+
+// NewExporter creates a new Exporter
+func NewExporter(
+	photoRepository interfaces.PhotoManager,
+	userRepository interfaces.TelegramUserProvider,
+	bucket string,
+) *Exporter {
+	return &Exporter{
+		photoRepository: photoRepository,
+		userRepository:  userRepository,
+		bucket:          bucket,
+	}
+}
+
+// Export writes applied photos selected by the filter to w as a ZIP archive with
+// manifest.csv and manifest.json at its root. Photos are copied from the storage one
+// by one, so the archive is never held in memory.
+func (e *Exporter) Export(ctx context.Context, w io.Writer, filter models.PhotoFilter) (*ExportReport, error) {
+	filter.State = models.PhotoApplied
+	photos, err := e.photoRepository.GetPhotos(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photos: %w", err)
+	}
+
+	archive := zip.NewWriter(w)
+	report := &ExportReport{}
+	names := make(map[string]int)
+	uploaders := make(map[uuid.UUID]*models.TelegramUser)
+	for _, photo := range photos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		numbers := make([]string, 0, len(photo.ArticleNumbers))
+		for _, articleNumber := range photo.ArticleNumbers {
+			numbers = append(numbers, articleNumber.Number)
+		}
+		sort.Strings(numbers)
+
+		file, err := e.writePhoto(ctx, archive, photo, photoBaseName(photo, numbers), names)
+		if err != nil {
+			return nil, err
+		}
+		if file == "" {
+			report.Failed = append(report.Failed, photo.ID)
+			continue
+		}
+
+		exported := ExportedPhoto{
+			File:           file,
+			ArticleNumbers: numbers,
+			PhotoID:        photo.ID,
+			UploaderID:     photo.UserID,
+			CreatedAt:      photo.CreatedAt.UTC(),
+		}
+		if uploader := lookupUploader(e.userRepository, uploaders, photo.UserID); uploader != nil {
+			exported.UploaderTelegramID = uploader.TelegramID
+			exported.UploaderUsername = uploader.Username
+		}
+		report.Photos = append(report.Photos, exported)
+	}
+
+	if err := writeManifestCSV(archive, report.Photos); err != nil {
+		return nil, err
+	}
+	if err := writeManifestJSON(archive, report.Photos); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return report, nil
+}
+
+// writePhoto copies the file of the photo to the archive and returns its path there.
+// Photos missing in the storage are skipped with an empty path, failures in the
+// middle of a copy break the archive and are returned as errors.
+func (e *Exporter) writePhoto(
+	ctx context.Context,
+	archive *zip.Writer,
+	photo *models.Photo,
+	baseName string,
+	names map[string]int,
+) (string, error) {
+	object, err := e.photoRepository.GetPhotoFromS3(ctx, photo.UserID, photo.S3Key, photo.Format, e.bucket)
+	if err != nil {
+		log.Error().Err(err).Str("photo_id", photo.ID.String()).Msg("Failed to download photo for export")
+		return "", nil
+	}
+	defer object.Close()
+
+	// Peeking catches failed downloads before their entries are written
+	data := bufio.NewReader(object)
+	if _, err := data.Peek(1); err != nil && err != io.EOF {
+		log.Error().Err(err).Str("photo_id", photo.ID.String()).Msg("Failed to download photo for export")
+		return "", nil
+	}
+
+	names[baseName]++
+	name := baseName
+	if n := names[baseName]; n > 1 {
+		name += "_" + strconv.Itoa(n)
+	}
+	name = path.Join(exportPhotosDir, name+models.PhotoExtension(photo.Format))
+
+	// Images are compressed already
+	entry, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: photo.CreatedAt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := io.Copy(entry, data); err != nil {
+		return "", fmt.Errorf("failed to copy photo %s: %w", photo.ID, err)
+	}
+	return name, nil
+}
+
+// lookupUploader looks up the uploader of a photo once per export or lookbook, unknown users are nil
+func lookupUploader(
+	userRepository interfaces.TelegramUserProvider,
+	cache map[uuid.UUID]*models.TelegramUser,
+	userID uuid.UUID,
+) *models.TelegramUser {
+	if user, ok := cache[userID]; ok {
+		return user
+	}
+	user, err := userRepository.GetByID(userID)
+	if err != nil {
+		log.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to find uploader")
+		user = nil
+	}
+	cache[userID] = user
+	return user
+}
+
+// photoBaseName names a photo by its first article number, or by its ID without any
+func photoBaseName(photo *models.Photo, numbers []string) string {
+	if len(numbers) == 0 {
+		return photo.ID.String()
+	}
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(".-_", r) {
+			return r
+		}
+		return '_'
+	}, numbers[0])
+	if strings.Trim(name, ".") == "" {
+		return photo.ID.String()
+	}
+	return name
+}
+
+func writeManifestCSV(archive *zip.Writer, photos []ExportedPhoto) error {
+	file, err := archive.Create(ExportManifestCSV)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	writer := csv.NewWriter(file)
+	header := []string{"file", "article_numbers", "photo_id", "uploader_id", "uploader_telegram_id", "uploader_username", "created_at"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	for _, photo := range photos {
+		var telegramID string
+		if photo.UploaderTelegramID != 0 {
+			telegramID = strconv.FormatInt(photo.UploaderTelegramID, 10)
+		}
+		record := []string{
+			photo.File,
+			strings.Join(photo.ArticleNumbers, ", "),
+			photo.PhotoID.String(),
+			photo.UploaderID.String(),
+			telegramID,
+			photo.UploaderUsername,
+			photo.CreatedAt.Format(time.RFC3339),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+func writeManifestJSON(archive *zip.Writer, photos []ExportedPhoto) error {
+	file, err := archive.Create(ExportManifestJSON)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if photos == nil {
+		photos = []ExportedPhoto{}
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(photos); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// ParseDate reads a day given as 2006-01-02 or 02.01.2006 in the local time zone
+func ParseDate(value string) (time.Time, error) {
+	for _, layout := range []string{time.DateOnly, "02.01.2006"} {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or DD.MM.YYYY", value)
+}
@@ -0,0 +1,140 @@
+package catalog_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/repositories/memory"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+)
+
+var _ = Describe("Exporter", func() {
+	const bucket = "test-bucket"
+
+	var (
+		s3Client    *memory.S3Client
+		photos      *memory.PhotoRepository
+		articles    *memory.ArticleNumberRepository
+		exporter    *catalog.Exporter
+		alice       *models.TelegramUser
+		bob         *models.TelegramUser
+		workspaceID uuid.UUID
+	)
+
+	BeforeEach(func() {
+		db := memory.NewDatabase()
+		s3Client = memory.NewS3Client()
+		photos = memory.NewPhotoRepository(db, s3Client)
+		articles = memory.NewArticleNumberRepository(db)
+		users := memory.NewTelegramUserRepository(db)
+		exporter = catalog.NewExporter(photos, users, bucket)
+		workspaceID = uuid.New()
+
+		alice = &models.TelegramUser{TelegramID: 1001, Username: "alice"}
+		bob = &models.TelegramUser{TelegramID: 1002}
+		Expect(users.CreateUser(alice)).To(Succeed())
+		Expect(users.CreateUser(bob)).To(Succeed())
+
+		importer := catalog.NewImporter(photos, articles, nil, bucket)
+		images := fstest.MapFS{
+			"red.jpg":  {Data: jpeg("red")},
+			"blue.jpg": {Data: jpeg("blue")},
+			"pink.png": {Data: append([]byte("\x89PNG\r\n\x1a\n"), "pink"...)},
+		}
+		report := importer.Import(context.Background(), images, []catalog.ManifestRow{
+			{File: "red.jpg", ArticleNumbers: []string{"6.7890", "1/2345"}},
+			{File: "blue.jpg", ArticleNumbers: []string{"1/2345"}},
+		}, alice.ID, workspaceID)
+		Expect(report.Count(catalog.RowImported)).To(Equal(2))
+		report = importer.Import(context.Background(), images, []catalog.ManifestRow{
+			{File: "pink.png", ArticleNumbers: []string{"9.9999"}},
+		}, bob.ID, workspaceID)
+		Expect(report.Count(catalog.RowImported)).To(Equal(1))
+	})
+
+	export := func(filter models.PhotoFilter) (*catalog.ExportReport, *zip.Reader) {
+		GinkgoHelper()
+		var archive bytes.Buffer
+		report, err := exporter.Export(context.Background(), &archive, filter)
+		Expect(err).To(BeNil())
+		reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+		Expect(err).To(BeNil())
+		return report, reader
+	}
+
+	readFile := func(archive *zip.Reader, name string) string {
+		GinkgoHelper()
+		file, err := archive.Open(name)
+		Expect(err).To(BeNil())
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		Expect(err).To(BeNil())
+		return string(data)
+	}
+
+	It("should name photos by article numbers and list them in the manifests", func() {
+		report, archive := export(models.PhotoFilter{WorkspaceID: workspaceID})
+		Expect(report.Failed).To(BeEmpty())
+
+		var files []string
+		for _, file := range archive.File {
+			files = append(files, file.Name)
+		}
+		Expect(files).To(Equal([]string{
+			"photos/1_2345.jpg", "photos/1_2345_2.jpg", "photos/9.9999.png", "manifest.csv", "manifest.json",
+		}))
+		Expect(readFile(archive, "photos/1_2345_2.jpg")).To(Equal(string(jpeg("blue"))))
+
+		var manifest []catalog.ExportedPhoto
+		Expect(json.Unmarshal([]byte(readFile(archive, catalog.ExportManifestJSON)), &manifest)).To(Succeed())
+		Expect(manifest).To(HaveLen(3))
+		Expect(manifest[0].ArticleNumbers).To(Equal([]string{"1/2345", "6.7890"}))
+		Expect(manifest[0].UploaderUsername).To(Equal("alice"))
+		Expect(manifest[2].UploaderTelegramID).To(Equal(int64(1002)))
+
+		Expect(readFile(archive, catalog.ExportManifestCSV)).To(HavePrefix(
+			"file,article_numbers,photo_id,uploader_id,uploader_telegram_id,uploader_username,created_at\n" +
+				"photos/1_2345.jpg,\"1/2345, 6.7890\"," + manifest[0].PhotoID.String() + "," + alice.ID.String() + ",1001,alice,"))
+	})
+
+	It("should filter photos by uploader and upload time", func() {
+		report, _ := export(models.PhotoFilter{WorkspaceID: workspaceID, UserID: bob.ID})
+		Expect(report.Photos).To(HaveLen(1))
+		Expect(report.Photos[0].File).To(Equal("photos/9.9999.png"))
+
+		report, _ = export(models.PhotoFilter{WorkspaceID: workspaceID, Since: time.Now().Add(time.Hour)})
+		Expect(report.Photos).To(BeEmpty())
+		report, _ = export(models.PhotoFilter{WorkspaceID: uuid.New()})
+		Expect(report.Photos).To(BeEmpty())
+	})
+
+	It("should leave out photos missing in the storage", func() {
+		for _, name := range s3Client.Objects() {
+			Expect(s3Client.DeleteFile(context.Background(), bucket, strings.TrimPrefix(name, bucket+"/"))).To(Succeed())
+		}
+		report, archive := export(models.PhotoFilter{WorkspaceID: workspaceID})
+		Expect(report.Photos).To(BeEmpty())
+		Expect(report.Failed).To(HaveLen(3))
+		Expect(readFile(archive, catalog.ExportManifestJSON)).To(Equal("[]\n"))
+	})
+
+	It("should export archives which can be imported back", func() {
+		_, archive := export(models.PhotoFilter{WorkspaceID: workspaceID})
+
+		rows, images, err := catalog.OpenCatalog(archive)
+		Expect(err).To(BeNil())
+		report := catalog.NewImporter(photos, articles, nil, bucket).Import(context.Background(), images, rows, alice.ID, workspaceID)
+		Expect(report.Count(catalog.RowUnchanged)).To(Equal(3))
+	})
+})
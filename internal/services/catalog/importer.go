@@ -0,0 +1,277 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
+)
+
+// maxImageSize caps the size of an imported image file
+const maxImageSize = 20 << 20
+
+// Statuses of imported manifest rows
+const (
+	// RowImported is a new photo linked with its article numbers
+	RowImported = "imported"
+	// RowLinked is a photo already in the catalog which got more article numbers
+	RowLinked = "linked"
+	// RowUnchanged is a photo already in the catalog with all its article numbers
+	RowUnchanged = "unchanged"
+	RowFailed    = "failed"
+)
+
+// RowResult is the outcome of importing a manifest row
+type RowResult struct {
+	ManifestRow
+	Status  string
+	PhotoID uuid.UUID
+	// Error explains why the row failed
+	Error error
+}
+
+// Report lists the outcome of every manifest row in the order of the manifest
+type Report struct {
+	Rows []RowResult
+}
+
+// Count returns the number of rows with the status
+func (r *Report) Count(status string) int {
+	count := 0
+	for _, row := range r.Rows {
+		if row.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteCSV writes the report as CSV with a header row
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"line", "file", "article_numbers", "status", "photo_id", "error"}); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		var photoID, message string
+		if row.PhotoID != uuid.Nil {
+			photoID = row.PhotoID.String()
+		}
+		if row.Error != nil {
+			message = row.Error.Error()
+		}
+		record := []string{
+			strconv.Itoa(row.Line),
+			row.File,
+			strings.Join(row.ArticleNumbers, ", "),
+			row.Status,
+			photoID,
+			message,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Importer adds photos of an existing catalog. Imports may be run again: files already
+// in the workspace are recognized by their content hash and only get missing article numbers.
+type Importer struct {
+	photoRepository   interfaces.PhotoManager
+	articleRepository interfaces.ArticleNumberManager
+	processor         *imaging.Processor
+	bucket            string
+}
+
+// NewImporter creates a new Importer, changes are recorded with the actor of the repositories.
+// Images are normalized by the processor like uploads in the bot, a nil one stores them as they are.
+func NewImporter(
+	photoRepository interfaces.PhotoManager,
+	articleRepository interfaces.ArticleNumberManager,
+	processor *imaging.Processor,
+	bucket string,
+) *Importer {
+	return &Importer{
+		photoRepository:   photoRepository,
+		articleRepository: articleRepository,
+		processor:         processor,
+		bucket:            bucket,
+	}
+}
+
+// Import uploads the images of the rows to the workspace as photos of the uploader.
+// A failed row does not stop the import, the report tells which rows failed and why.
+func (i *Importer) Import(
+	ctx context.Context,
+	images fs.FS,
+	rows []ManifestRow,
+	uploaderID uuid.UUID,
+	workspaceID uuid.UUID,
+) *Report {
+	report := &Report{}
+	for _, row := range rows {
+		result := RowResult{ManifestRow: row}
+		result.Status, result.PhotoID, result.Error = i.importRow(ctx, images, row, uploaderID, workspaceID)
+		if result.Error != nil {
+			result.Status = RowFailed
+		}
+		report.Rows = append(report.Rows, result)
+	}
+	return report
+}
+
+func (i *Importer) importRow(
+	ctx context.Context,
+	images fs.FS,
+	row ManifestRow,
+	uploaderID uuid.UUID,
+	workspaceID uuid.UUID,
+) (string, uuid.UUID, error) {
+	if err := ctx.Err(); err != nil {
+		return "", uuid.Nil, err
+	}
+	if row.File == "" {
+		return "", uuid.Nil, errors.New("no file")
+	}
+	if len(row.ArticleNumbers) == 0 {
+		return "", uuid.Nil, errors.New("no article numbers")
+	}
+	data, err := readImage(images, row.File)
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+
+	// Without a processor files are stored as they are
+	format := strings.TrimPrefix(http.DetectContentType(data), "image/")
+	contentHash := models.PhotoContentHash(data)
+	photo, err := i.photoRepository.GetByContentHash(workspaceID, contentHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) && i.processor != nil {
+		// Photos are stored normalized, so files imported before are found by the hash of
+		// their normalized content and exported photos by the hash of the file itself
+		if data, format, err = i.processor.Process(data); err != nil {
+			return "", uuid.Nil, err
+		}
+		contentHash = models.PhotoContentHash(data)
+		photo, err = i.photoRepository.GetByContentHash(workspaceID, contentHash)
+	}
+	if err == nil {
+		linked, err := i.link(photo, row.ArticleNumbers)
+		switch {
+		case err != nil:
+			return "", photo.ID, err
+		case linked == 0:
+			return RowUnchanged, photo.ID, nil
+		}
+		return RowLinked, photo.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", uuid.Nil, fmt.Errorf("failed to look up photo: %w", err)
+	}
+
+	// The photo is saved like an upload in the bot: pending until it has its article numbers
+	photo = &models.Photo{
+		UserID:      uploaderID,
+		S3Key:       uuid.New(),
+		State:       models.PhotoNotApplied,
+		WorkspaceID: workspaceID,
+		ContentHash: contentHash,
+		Format:      format,
+	}
+	if err := i.photoRepository.CreatePhoto(photo); err != nil {
+		return "", uuid.Nil, fmt.Errorf("failed to save photo: %w", err)
+	}
+	err = i.photoRepository.UploadPhotoToS3(ctx, uploaderID, photo.S3Key, photo.Format, i.bucket, bytes.NewReader(data))
+	if err != nil {
+		i.discard(photo)
+		return "", uuid.Nil, fmt.Errorf("failed to upload photo: %w", err)
+	}
+	if _, err := i.link(photo, row.ArticleNumbers); err != nil {
+		i.discard(photo)
+		return "", uuid.Nil, err
+	}
+	photo.State = models.PhotoApplied
+	if err := i.photoRepository.UpdatePhoto(photo); err != nil {
+		i.discard(photo)
+		return "", uuid.Nil, fmt.Errorf("failed to save photo: %w", err)
+	}
+	return RowImported, photo.ID, nil
+}
+
+// link adds the article numbers the photo is not linked with yet and returns their count
+func (i *Importer) link(photo *models.Photo, numbers []string) (int, error) {
+	linked := make(map[string]bool, len(photo.ArticleNumbers))
+	for _, articleNumber := range photo.ArticleNumbers {
+		linked[articleNumber.Number] = true
+	}
+
+	added := 0
+	for _, number := range numbers {
+		if linked[number] {
+			continue
+		}
+		articleNumber, err := i.articleRepository.GetOrCreateArticleNumber(photo.WorkspaceID, number)
+		if errors.Is(err, models.ErrArticleNumberInTrash) {
+			return added, fmt.Errorf("article number %s is in the trash, restore it before importing: %w", number, err)
+		}
+		if err != nil {
+			return added, fmt.Errorf("failed to save article number %s: %w", number, err)
+		}
+		if err := i.photoRepository.AddArticleNumberToPhoto(photo.ID, articleNumber.ID); err != nil {
+			return added, fmt.Errorf("failed to link article number %s: %w", number, err)
+		}
+		linked[number] = true
+		added++
+	}
+	return added, nil
+}
+
+// discard removes a photo which failed to import, so running the import again retries it
+func (i *Importer) discard(photo *models.Photo) {
+	if err := i.photoRepository.DiscardPhoto(photo.ID, i.bucket); err != nil {
+		log.Error().Err(err).Str("photo_id", photo.ID.String()).Msg("Failed to discard imported photo")
+	}
+}
+
+// readImage reads an image named in the manifest. Names use slashes or backslashes
+// and cannot leave the root of the images.
+func readImage(images fs.FS, name string) ([]byte, error) {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, `\`, "/")), "/")
+	file, err := images.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.New("file not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("file is larger than %d MB", maxImageSize>>20)
+	}
+	if !strings.HasPrefix(http.DetectContentType(data), "image/") {
+		return nil, errors.New("not an image")
+	}
+	return data, nil
+}
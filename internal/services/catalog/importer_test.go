@@ -0,0 +1,216 @@
+package catalog_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"testing/fstest"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/repositories/memory"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
+)
+
+// jpeg makes file contents detected as a JPEG image
+func jpeg(content string) []byte {
+	return append([]byte("\xff\xd8\xff\xe0"), content...)
+}
+
+var _ = Describe("ReadManifest", func() {
+	It("should read CSV exported by spreadsheets with semicolons", func() {
+		manifest := "\ufeffFile;Articles;Comment\n" +
+			"red.jpg;\"1.2345, 6.7890\";red box\n" +
+			";;\n" +
+			"blue.jpg;;\n"
+		rows, err := catalog.ReadManifest("manifest.csv", strings.NewReader(manifest))
+		Expect(err).To(BeNil())
+		Expect(rows).To(Equal([]catalog.ManifestRow{
+			{Line: 2, File: "red.jpg", ArticleNumbers: []string{"1.2345", "6.7890"}},
+			{Line: 4, File: "blue.jpg"},
+		}))
+	})
+
+	It("should read the first sheet of XLSX", func() {
+		book := excelize.NewFile()
+		Expect(book.SetSheetRow("Sheet1", "A1", &[]string{"article_numbers", "file"})).To(Succeed())
+		Expect(book.SetSheetRow("Sheet1", "A2", &[]string{"1.2345; 6.7890", "photos/red.jpg"})).To(Succeed())
+		var data bytes.Buffer
+		Expect(book.Write(&data)).To(Succeed())
+
+		rows, err := catalog.ReadManifest("Manifest.XLSX", &data)
+		Expect(err).To(BeNil())
+		Expect(rows).To(Equal([]catalog.ManifestRow{
+			{Line: 2, File: "photos/red.jpg", ArticleNumbers: []string{"1.2345", "6.7890"}},
+		}))
+	})
+
+	It("should refuse manifests without the file and article number columns", func() {
+		_, err := catalog.ReadManifest("manifest.csv", strings.NewReader("name,sku\nred.jpg,1.2345\n"))
+		Expect(err).To(MatchError(ContainSubstring("needs the file and article_numbers columns")))
+		_, err = catalog.ReadManifest("manifest.txt", strings.NewReader(""))
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("OpenCatalog", func() {
+	It("should find the manifest in the only folder of an archive", func() {
+		rows, images, err := catalog.OpenCatalog(fstest.MapFS{
+			"__MACOSX/catalog/._manifest.csv": {},
+			"catalog/manifest.csv":            {Data: []byte("file,article_numbers\nred.jpg,1.2345\n")},
+			"catalog/red.jpg":                 {Data: jpeg("red")},
+		})
+		Expect(err).To(BeNil())
+		Expect(rows).To(HaveLen(1))
+		Expect(fstest.TestFS(images, "red.jpg", "manifest.csv")).To(Succeed())
+	})
+
+	It("should report archives without a manifest", func() {
+		_, _, err := catalog.OpenCatalog(fstest.MapFS{"red.jpg": {Data: jpeg("red")}})
+		Expect(err).To(MatchError(catalog.ErrNoManifest))
+	})
+})
+
+var _ = Describe("Importer", func() {
+	const bucket = "test-bucket"
+
+	var (
+		s3Client    *memory.S3Client
+		photos      *memory.PhotoRepository
+		articles    *memory.ArticleNumberRepository
+		importer    *catalog.Importer
+		uploaderID  uuid.UUID
+		workspaceID uuid.UUID
+		images      fstest.MapFS
+	)
+
+	BeforeEach(func() {
+		db := memory.NewDatabase()
+		s3Client = memory.NewS3Client()
+		photos = memory.NewPhotoRepository(db, s3Client)
+		articles = memory.NewArticleNumberRepository(db)
+		importer = catalog.NewImporter(photos, articles, nil, bucket)
+		uploaderID = uuid.New()
+		workspaceID = uuid.New()
+		images = fstest.MapFS{
+			"red.jpg":      {Data: jpeg("red")},
+			"dir/blue.jpg": {Data: jpeg("blue")},
+			"notes.txt":    {Data: []byte("not an image")},
+		}
+	})
+
+	statuses := func(report *catalog.Report) []string {
+		var result []string
+		for _, row := range report.Rows {
+			result = append(result, row.Status)
+		}
+		return result
+	}
+
+	It("should import new photos and only add what is missing on re-run", func() {
+		rows := []catalog.ManifestRow{
+			{Line: 2, File: "red.jpg", ArticleNumbers: []string{"1.2345"}},
+			{Line: 3, File: `dir\blue.jpg`, ArticleNumbers: []string{"6.7890"}},
+			{Line: 4, File: "missing.jpg", ArticleNumbers: []string{"1.2345"}},
+			{Line: 5, File: "notes.txt", ArticleNumbers: []string{"1.2345"}},
+			{Line: 6, File: "../red.jpg"},
+		}
+		report := importer.Import(context.Background(), images, rows, uploaderID, workspaceID)
+		Expect(statuses(report)).To(Equal([]string{
+			catalog.RowImported, catalog.RowImported, catalog.RowFailed, catalog.RowFailed, catalog.RowFailed,
+		}))
+		Expect(report.Rows[2].Error).To(MatchError("file not found"))
+		Expect(report.Rows[3].Error).To(MatchError("not an image"))
+		Expect(report.Rows[4].Error).To(MatchError("no article numbers"))
+		Expect(s3Client.Objects()).To(HaveLen(2))
+
+		articleNumber, err := articles.GetByNumber(workspaceID, "1.2345")
+		Expect(err).To(BeNil())
+		found, err := articles.GetArticleNumberWithPhotos(articleNumber.ID)
+		Expect(err).To(BeNil())
+		Expect(found.Photos).To(HaveLen(1))
+		Expect(found.Photos[0].State).To(Equal(models.PhotoApplied))
+		Expect(found.Photos[0].UserID).To(Equal(uploaderID))
+
+		rows[0].ArticleNumbers = append(rows[0].ArticleNumbers, "9.9999")
+		report = importer.Import(context.Background(), images, rows[:2], uploaderID, workspaceID)
+		Expect(statuses(report)).To(Equal([]string{catalog.RowLinked, catalog.RowUnchanged}))
+		Expect(report.Rows[0].PhotoID).To(Equal(found.Photos[0].ID))
+		Expect(s3Client.Objects()).To(HaveLen(2))
+
+		// Another workspace gets its own copies
+		report = importer.Import(context.Background(), images, rows[:1], uploaderID, uuid.New())
+		Expect(statuses(report)).To(Equal([]string{catalog.RowImported}))
+	})
+
+	It("should refuse article numbers in the trash and keep no photos of the failed rows", func() {
+		articleNumber, err := articles.GetOrCreateArticleNumber(workspaceID, "1.2345")
+		Expect(err).To(BeNil())
+		Expect(articles.DeleteArticleNumber(articleNumber.ID)).To(Succeed())
+
+		rows := []catalog.ManifestRow{{Line: 2, File: "red.jpg", ArticleNumbers: []string{"1.2345"}}}
+		report := importer.Import(context.Background(), images, rows, uploaderID, workspaceID)
+		Expect(statuses(report)).To(Equal([]string{catalog.RowFailed}))
+		Expect(report.Rows[0].Error).To(MatchError(models.ErrArticleNumberInTrash))
+		Expect(report.Rows[0].Error.Error()).To(HavePrefix("article number 1.2345 is in the trash, restore it"))
+		Expect(s3Client.Objects()).To(BeEmpty())
+
+		Expect(articles.RestoreArticleNumber(articleNumber.ID)).To(Succeed())
+		report = importer.Import(context.Background(), images, rows, uploaderID, workspaceID)
+		Expect(statuses(report)).To(Equal([]string{catalog.RowImported}))
+	})
+
+	It("should store normalized images and recognize them on re-run and in exports", func() {
+		processor := imaging.NewProcessor(&configs.ImagesConfig{
+			Format:        configs.ImageFormatJPEG,
+			Quality:       85,
+			MaxDimension:  100,
+			StripMetadata: true,
+		})
+		importer = catalog.NewImporter(photos, articles, processor, bucket)
+		images["wide.png"] = &fstest.MapFile{Data: photo(color.RGBA{G: 255, A: 255}, 400, 200)}
+		rows := []catalog.ManifestRow{{File: "wide.png", ArticleNumbers: []string{"1.2345"}}}
+		report := importer.Import(context.Background(), images, rows, uploaderID, workspaceID)
+		Expect(statuses(report)).To(Equal([]string{catalog.RowImported}))
+		Expect(s3Client.Objects()).To(HaveLen(1))
+
+		stored, err := s3Client.DownloadFile(context.Background(), bucket, strings.TrimPrefix(s3Client.Objects()[0], bucket+"/"))
+		Expect(err).To(BeNil())
+		data, err := io.ReadAll(stored)
+		Expect(err).To(BeNil())
+		size, format, err := image.DecodeConfig(bytes.NewReader(data))
+		Expect(err).To(BeNil())
+		Expect(format).To(Equal("jpeg"))
+		Expect([]int{size.Width, size.Height}).To(Equal([]int{100, 50}))
+
+		images["exported.jpg"] = &fstest.MapFile{Data: data}
+		rows = append(rows, catalog.ManifestRow{File: "exported.jpg", ArticleNumbers: []string{"1.2345"}})
+		report = importer.Import(context.Background(), images, rows, uploaderID, workspaceID)
+		Expect(statuses(report)).To(Equal([]string{catalog.RowUnchanged, catalog.RowUnchanged}))
+		Expect(s3Client.Objects()).To(HaveLen(1))
+	})
+
+	It("should write the report as CSV", func() {
+		report := importer.Import(context.Background(), images, []catalog.ManifestRow{
+			{Line: 2, File: "red.jpg", ArticleNumbers: []string{"1.2345", "6.7890"}},
+			{Line: 3, File: "missing.jpg", ArticleNumbers: []string{"1.2345"}},
+		}, uploaderID, workspaceID)
+
+		var out strings.Builder
+		Expect(report.WriteCSV(&out)).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		Expect(lines).To(HaveLen(3))
+		Expect(lines[0]).To(Equal("line,file,article_numbers,status,photo_id,error"))
+		Expect(lines[1]).To(MatchRegexp(`^2,red\.jpg,"1\.2345, 6\.7890",imported,[0-9a-f-]{36},$`))
+		Expect(lines[2]).To(Equal("3,missing.jpg,1.2345,failed,,file not found"))
+	})
+})
@@ -0,0 +1,413 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	// Photos are JPEG, imported files may be PNG or GIF
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
+)
+
+// LookbookField is an optional field of the lookbook
+type LookbookField string
+
+const (
+	// LookbookAliases lists the codes of an article number under its heading
+	LookbookAliases LookbookField = "aliases"
+	// LookbookUploader adds the uploader to photo captions
+	LookbookUploader LookbookField = "uploader"
+	// LookbookDate adds the upload day to photo captions
+	LookbookDate LookbookField = "date"
+)
+
+// LookbookFields are the optional fields in the order they are printed
+var LookbookFields = []LookbookField{LookbookAliases, LookbookUploader, LookbookDate}
+
+var ErrUnknownLookbookField = errors.New("unknown lookbook field")
+
+// ParseLookbookField parses a field name case-insensitively
+func ParseLookbookField(name string) (LookbookField, error) {
+	for _, field := range LookbookFields {
+		if strings.EqualFold(name, string(field)) {
+			return field, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownLookbookField, name)
+}
+
+// LookbookOptions select the photos of a lookbook and the way they are printed
+type LookbookOptions struct {
+	Filter models.PhotoFilter
+	// ArticleNumberIDs narrows the lookbook down to the article numbers, in their order.
+	// Without them every article number with photos is printed, ordered by number.
+	ArticleNumberIDs []uuid.UUID
+	Fields           []LookbookField
+	// Title is printed at the top of the first page
+	Title string
+	// AliasesLabel names the codes of article numbers, "Codes" by default
+	AliasesLabel string
+}
+
+func (o LookbookOptions) has(field LookbookField) bool {
+	for _, f := range o.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// LookbookReport counts what was printed to a lookbook
+type LookbookReport struct {
+	ArticleNumbers int
+	Photos         int
+	// Failed lists photos left out because their files could not be read from the storage
+	Failed []uuid.UUID
+}
+
+// Lookbook prints applied photos of the catalog to a PDF with a grid of photos per article number
+type Lookbook struct {
+	photoRepository   interfaces.PhotoManager
+	articleRepository interfaces.ArticleNumberProvider
+	userRepository    interfaces.TelegramUserProvider
+	bucket            string
+}
+
+// NewLookbook creates a new Lookbook
+func NewLookbook(
+	photoRepository interfaces.PhotoManager,
+	articleRepository interfaces.ArticleNumberProvider,
+	userRepository interfaces.TelegramUserProvider,
+	bucket string,
+) *Lookbook {
+	return &Lookbook{
+		photoRepository:   photoRepository,
+		articleRepository: articleRepository,
+		userRepository:    userRepository,
+		bucket:            bucket,
+	}
+}
+
+// Page layout in millimeters
+const (
+	lookbookPageWidth    = 210.0
+	lookbookPageHeight   = 297.0
+	lookbookMargin       = 12.0
+	lookbookColumns      = 3
+	lookbookGap          = 6.0
+	lookbookCellWidth    = (lookbookPageWidth - 2*lookbookMargin - (lookbookColumns-1)*lookbookGap) / lookbookColumns
+	lookbookCaptionLine  = 4.2
+	lookbookRowHeight    = lookbookCellWidth + 2 + 2*lookbookCaptionLine + 4
+	lookbookHeading      = 9.0
+	lookbookFieldLine    = 5.0
+	lookbookSectionGap   = 4.0
+	lookbookContentLimit = lookbookPageHeight - 16
+	// lookbookImageSide bounds the pixels of photos, enough for print in a grid cell
+	lookbookImageSide = 1200
+)
+
+const (
+	lookbookFont     = "goregular"
+	lookbookBoldFont = "gobold"
+)
+
+// lookbookSection is an article number with its photos
+type lookbookSection struct {
+	articleNumber *models.ArticleNumber
+	photos        []*models.Photo
+}
+
+// Generate writes the lookbook of applied photos selected by the options to w.
+// Nothing is written when no article number has photos, the report tells it.
+func (l *Lookbook) Generate(ctx context.Context, w io.Writer, options LookbookOptions) (*LookbookReport, error) {
+	options.Filter.State = models.PhotoApplied
+	photos, err := l.photoRepository.GetPhotos(options.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photos: %w", err)
+	}
+	sections := lookbookSections(photos, options.ArticleNumberIDs)
+	report := &LookbookReport{}
+	if len(sections) == 0 {
+		return report, nil
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(lookbookMargin, lookbookMargin, lookbookMargin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddUTF8FontFromBytes(lookbookFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(lookbookBoldFont, "", gobold.TTF)
+	pdf.SetFooterFunc(func() {
+		pdf.SetFont(lookbookFont, "", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.SetXY(lookbookMargin, lookbookPageHeight-10)
+		pdf.CellFormat(lookbookPageWidth-2*lookbookMargin, 4, strconv.Itoa(pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	y := lookbookMargin
+	if options.Title != "" {
+		pdf.SetFont(lookbookBoldFont, "", 18)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetXY(lookbookMargin, y)
+		pdf.CellFormat(0, 10, fitPDFText(pdf, options.Title, lookbookPageWidth-2*lookbookMargin), "", 0, "L", false, 0, "")
+		y += 14
+	}
+
+	// Photos of several article numbers are downloaded and embedded once
+	placed := make(map[uuid.UUID]image.Point)
+	failed := make(map[uuid.UUID]bool)
+	uploaders := make(map[uuid.UUID]*models.TelegramUser)
+	for _, section := range sections {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var sectionPhotos []*models.Photo
+		for _, photo := range section.photos {
+			if _, ok := placed[photo.ID]; !ok && !failed[photo.ID] {
+				size, ok := l.embedPhoto(ctx, pdf, photo)
+				if !ok {
+					failed[photo.ID] = true
+					report.Failed = append(report.Failed, photo.ID)
+					continue
+				}
+				placed[photo.ID] = size
+			}
+			if !failed[photo.ID] {
+				sectionPhotos = append(sectionPhotos, photo)
+			}
+		}
+		if len(sectionPhotos) == 0 {
+			continue
+		}
+
+		var aliases []string
+		if options.has(LookbookAliases) {
+			aliases = l.aliases(section.articleNumber)
+		}
+		headingHeight := lookbookHeading
+		if len(aliases) > 0 {
+			headingHeight += lookbookFieldLine
+		}
+		if y+headingHeight+lookbookRowHeight > lookbookContentLimit {
+			pdf.AddPage()
+			y = lookbookMargin
+		}
+		y = printHeading(pdf, y, section.articleNumber.Number, aliases, options.AliasesLabel)
+
+		for i, photo := range sectionPhotos {
+			column := i % lookbookColumns
+			if column == 0 && i > 0 {
+				y += lookbookRowHeight
+				if y+lookbookRowHeight > lookbookContentLimit {
+					pdf.AddPage()
+					y = lookbookMargin
+				}
+			}
+			x := lookbookMargin + float64(column)*(lookbookCellWidth+lookbookGap)
+			printPhoto(pdf, x, y, photo, placed[photo.ID])
+			printCaption(pdf, x, y+lookbookCellWidth+2, l.caption(photo, options, uploaders))
+		}
+		y += lookbookRowHeight + lookbookSectionGap
+		report.ArticleNumbers++
+	}
+	report.Photos = len(placed)
+	// Photos of every article number may fail to download
+	if report.ArticleNumbers == 0 {
+		return report, nil
+	}
+
+	if err := pdf.Output(w); err != nil {
+		return nil, fmt.Errorf("failed to write lookbook: %w", err)
+	}
+	return report, nil
+}
+
+// lookbookSections groups photos by their article numbers
+func lookbookSections(photos []*models.Photo, articleNumberIDs []uuid.UUID) []lookbookSection {
+	byID := make(map[uuid.UUID]*lookbookSection)
+	for _, photo := range photos {
+		for i := range photo.ArticleNumbers {
+			articleNumber := &photo.ArticleNumbers[i]
+			section, ok := byID[articleNumber.ID]
+			if !ok {
+				section = &lookbookSection{articleNumber: articleNumber}
+				byID[articleNumber.ID] = section
+			}
+			section.photos = append(section.photos, photo)
+		}
+	}
+
+	var sections []lookbookSection
+	if len(articleNumberIDs) > 0 {
+		for _, id := range articleNumberIDs {
+			if section, ok := byID[id]; ok {
+				sections = append(sections, *section)
+				// Repeated article numbers are printed once
+				delete(byID, id)
+			}
+		}
+		return sections
+	}
+	for _, section := range byID {
+		sections = append(sections, *section)
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		return sections[i].articleNumber.Number < sections[j].articleNumber.Number
+	})
+	return sections
+}
+
+func (l *Lookbook) aliases(articleNumber *models.ArticleNumber) []string {
+	aliases, err := l.articleRepository.GetArticleAliases(articleNumber.ID)
+	if err != nil {
+		log.Warn().Err(err).Str("article_number_id", articleNumber.ID.String()).Msg("Failed to get aliases for lookbook")
+		return nil
+	}
+	codes := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		codes = append(codes, alias.Alias)
+	}
+	return codes
+}
+
+// printHeading prints the article number with its codes and returns the top of its photos
+func printHeading(pdf *fpdf.Fpdf, y float64, number string, aliases []string, aliasesLabel string) float64 {
+	width := lookbookPageWidth - 2*lookbookMargin
+	pdf.SetFont(lookbookBoldFont, "", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(lookbookMargin, y)
+	pdf.CellFormat(width, 7, fitPDFText(pdf, number, width), "", 0, "L", false, 0, "")
+	y += lookbookHeading
+	if len(aliases) == 0 {
+		return y
+	}
+
+	if aliasesLabel == "" {
+		aliasesLabel = "Codes"
+	}
+	pdf.SetFont(lookbookFont, "", 9)
+	pdf.SetTextColor(96, 96, 96)
+	pdf.SetXY(lookbookMargin, y-2)
+	text := aliasesLabel + ": " + strings.Join(aliases, ", ")
+	pdf.CellFormat(width, 4, fitPDFText(pdf, text, width), "", 0, "L", false, 0, "")
+	return y + lookbookFieldLine
+}
+
+// embedPhoto adds a downscaled JPEG of the photo to the PDF and returns its size in pixels.
+// Photos missing in the storage or in unknown formats are skipped.
+func (l *Lookbook) embedPhoto(ctx context.Context, pdf *fpdf.Fpdf, photo *models.Photo) (image.Point, bool) {
+	object, err := l.photoRepository.GetPhotoFromS3(ctx, photo.UserID, photo.S3Key, photo.Format, l.bucket)
+	if err != nil {
+		log.Error().Err(err).Str("photo_id", photo.ID.String()).Msg("Failed to download photo for lookbook")
+		return image.Point{}, false
+	}
+	defer object.Close()
+
+	img, _, err := image.Decode(object)
+	if err != nil {
+		log.Error().Err(err).Str("photo_id", photo.ID.String()).Msg("Failed to decode photo for lookbook")
+		return image.Point{}, false
+	}
+	img = imaging.Downscale(img, lookbookImageSide)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		log.Error().Err(err).Str("photo_id", photo.ID.String()).Msg("Failed to encode photo for lookbook")
+		return image.Point{}, false
+	}
+	pdf.RegisterImageOptionsReader(photo.ID.String(), fpdf.ImageOptions{ImageType: "JPG"}, &buf)
+	return img.Bounds().Size(), true
+}
+
+// printPhoto fits the photo into its square cell, centered
+func printPhoto(pdf *fpdf.Fpdf, x, y float64, photo *models.Photo, size image.Point) {
+	width, height := lookbookCellWidth, lookbookCellWidth
+	if size.X > size.Y {
+		height = lookbookCellWidth * float64(size.Y) / float64(size.X)
+	} else {
+		width = lookbookCellWidth * float64(size.X) / float64(size.Y)
+	}
+	pdf.ImageOptions(photo.ID.String(), x+(lookbookCellWidth-width)/2, y+(lookbookCellWidth-height)/2,
+		width, height, false, fpdf.ImageOptions{ImageType: "JPG"}, 0, "")
+}
+
+// caption lists the article numbers of the photo, then its optional fields
+func (l *Lookbook) caption(photo *models.Photo, options LookbookOptions, uploaders map[uuid.UUID]*models.TelegramUser) []string {
+	numbers := make([]string, 0, len(photo.ArticleNumbers))
+	for _, articleNumber := range photo.ArticleNumbers {
+		numbers = append(numbers, articleNumber.Number)
+	}
+	sort.Strings(numbers)
+	lines := []string{strings.Join(numbers, ", ")}
+
+	var details []string
+	if options.has(LookbookUploader) {
+		if uploader := lookupUploader(l.userRepository, uploaders, photo.UserID); uploader != nil {
+			details = append(details, uploaderName(uploader))
+		}
+	}
+	if options.has(LookbookDate) {
+		details = append(details, photo.CreatedAt.Local().Format(time.DateOnly))
+	}
+	if len(details) > 0 {
+		lines = append(lines, strings.Join(details, " · "))
+	}
+	return lines
+}
+
+func uploaderName(user *models.TelegramUser) string {
+	if user.Username != "" {
+		return "@" + user.Username
+	}
+	return strconv.FormatInt(user.TelegramID, 10)
+}
+
+func printCaption(pdf *fpdf.Fpdf, x, y float64, lines []string) {
+	for i, line := range lines {
+		if i == 0 {
+			pdf.SetFont(lookbookBoldFont, "", 8)
+			pdf.SetTextColor(0, 0, 0)
+		} else {
+			pdf.SetFont(lookbookFont, "", 8)
+			pdf.SetTextColor(96, 96, 96)
+		}
+		pdf.SetXY(x, y+float64(i)*lookbookCaptionLine)
+		pdf.CellFormat(lookbookCellWidth, lookbookCaptionLine, fitPDFText(pdf, line, lookbookCellWidth),
+			"", 0, "C", false, 0, "")
+	}
+}
+
+// fitPDFText cuts the text with an ellipsis to fit the width in the current font
+func fitPDFText(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 1 {
+		runes = runes[:len(runes)-1]
+		if cut := string(runes) + "…"; pdf.GetStringWidth(cut) <= width {
+			return cut
+		}
+	}
+	return string(runes)
+}
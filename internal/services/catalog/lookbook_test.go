@@ -0,0 +1,148 @@
+package catalog_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"regexp"
+	"strings"
+	"testing/fstest"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/repositories/memory"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+)
+
+// photo renders a plain photo of the size
+func photo(c color.Color, width, height int) []byte {
+	GinkgoHelper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	Expect(png.Encode(&buf, img)).To(Succeed())
+	return buf.Bytes()
+}
+
+var pdfPage = regexp.MustCompile(`/Type /Page\b`)
+
+var _ = Describe("Lookbook", func() {
+	const bucket = "test-bucket"
+
+	var (
+		s3Client    *memory.S3Client
+		articles    *memory.ArticleNumberRepository
+		lookbook    *catalog.Lookbook
+		importer    *catalog.Importer
+		alice       *models.TelegramUser
+		workspaceID uuid.UUID
+	)
+
+	BeforeEach(func() {
+		db := memory.NewDatabase()
+		s3Client = memory.NewS3Client()
+		photos := memory.NewPhotoRepository(db, s3Client)
+		articles = memory.NewArticleNumberRepository(db)
+		users := memory.NewTelegramUserRepository(db)
+		lookbook = catalog.NewLookbook(photos, articles, users, bucket)
+		importer = catalog.NewImporter(photos, articles, nil, bucket)
+		workspaceID = uuid.New()
+
+		alice = &models.TelegramUser{TelegramID: 1001, Username: "alice"}
+		Expect(users.CreateUser(alice)).To(Succeed())
+
+		images := fstest.MapFS{
+			"red.png":  {Data: photo(color.RGBA{R: 255, A: 255}, 1600, 900)},
+			"blue.png": {Data: photo(color.RGBA{B: 255, A: 255}, 300, 400)},
+			"pink.png": {Data: photo(color.RGBA{R: 255, G: 192, B: 203, A: 255}, 200, 200)},
+		}
+		report := importer.Import(context.Background(), images, []catalog.ManifestRow{
+			{File: "red.png", ArticleNumbers: []string{"6.7890", "1/2345"}},
+			{File: "blue.png", ArticleNumbers: []string{"1/2345"}},
+			{File: "pink.png", ArticleNumbers: []string{"9.9999"}},
+		}, alice.ID, workspaceID)
+		Expect(report.Count(catalog.RowImported)).To(Equal(3))
+	})
+
+	generate := func(options catalog.LookbookOptions) (*catalog.LookbookReport, []byte) {
+		GinkgoHelper()
+		var buf bytes.Buffer
+		report, err := lookbook.Generate(context.Background(), &buf, options)
+		Expect(err).To(BeNil())
+		return report, buf.Bytes()
+	}
+
+	articleID := func(number string) uuid.UUID {
+		GinkgoHelper()
+		articleNumber, err := articles.GetByNumber(workspaceID, number)
+		Expect(err).To(BeNil())
+		return articleNumber.ID
+	}
+
+	It("should print a section per article number with its photos and fields", func() {
+		_, err := articles.AddArticleAlias(articleID("1/2345"), "SUP-77")
+		Expect(err).To(BeNil())
+
+		report, data := generate(catalog.LookbookOptions{
+			Filter: models.PhotoFilter{WorkspaceID: workspaceID},
+			Fields: catalog.LookbookFields,
+			Title:  "north",
+		})
+		Expect(report.ArticleNumbers).To(Equal(3))
+		Expect(report.Photos).To(Equal(3))
+		Expect(report.Failed).To(BeEmpty())
+		Expect(string(data)).To(HavePrefix("%PDF"))
+
+		field, err := catalog.ParseLookbookField("Aliases")
+		Expect(err).To(BeNil())
+		Expect(field).To(Equal(catalog.LookbookAliases))
+		_, err = catalog.ParseLookbookField("price")
+		Expect(err).To(MatchError(catalog.ErrUnknownLookbookField))
+	})
+
+	It("should print selected article numbers in their order and break pages", func() {
+		report, _ := generate(catalog.LookbookOptions{
+			Filter:           models.PhotoFilter{WorkspaceID: workspaceID},
+			ArticleNumberIDs: []uuid.UUID{articleID("9.9999"), articleID("1/2345"), articleID("9.9999")},
+		})
+		Expect(report.ArticleNumbers).To(Equal(2))
+		Expect(report.Photos).To(Equal(3))
+
+		images := fstest.MapFS{}
+		var rows []catalog.ManifestRow
+		for i := 0; i < 12; i++ {
+			name := fmt.Sprintf("shade-%d.png", i)
+			images[name] = &fstest.MapFile{Data: photo(color.Gray{Y: uint8(i * 20)}, 100, 100)}
+			rows = append(rows, catalog.ManifestRow{File: name, ArticleNumbers: []string{"5.5555"}})
+		}
+		importer.Import(context.Background(), images, rows, alice.ID, workspaceID)
+
+		report, data := generate(catalog.LookbookOptions{
+			Filter:           models.PhotoFilter{WorkspaceID: workspaceID},
+			ArticleNumberIDs: []uuid.UUID{articleID("5.5555")},
+		})
+		Expect(report.Photos).To(Equal(12))
+		Expect(pdfPage.FindAll(data, -1)).To(HaveLen(2))
+	})
+
+	It("should leave out photos missing in the storage and print nothing without photos", func() {
+		for _, name := range s3Client.Objects() {
+			Expect(s3Client.DeleteFile(context.Background(), bucket, strings.TrimPrefix(name, bucket+"/"))).To(Succeed())
+		}
+		report, data := generate(catalog.LookbookOptions{Filter: models.PhotoFilter{WorkspaceID: workspaceID}})
+		Expect(report.ArticleNumbers).To(BeZero())
+		Expect(report.Failed).To(HaveLen(3))
+		Expect(data).To(BeEmpty())
+
+		report, data = generate(catalog.LookbookOptions{Filter: models.PhotoFilter{WorkspaceID: uuid.New()}})
+		Expect(report.ArticleNumbers).To(BeZero())
+		Expect(data).To(BeEmpty())
+	})
+})
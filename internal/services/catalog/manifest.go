@@ -0,0 +1,199 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ManifestNames are the manifests looked up in import archives and folders
+var ManifestNames = []string{"manifest.csv", "manifest.xlsx"}
+
+// Column names of the manifest header, matched case-insensitively
+var (
+	fileColumns    = []string{"file", "image", "photo"}
+	articleColumns = []string{"article_numbers", "articles", "article_number", "article"}
+)
+
+// ManifestRow maps an image file to the article numbers of its item
+type ManifestRow struct {
+	// Line is the number of the row in the manifest, the header is line 1
+	Line           int
+	File           string
+	ArticleNumbers []string
+}
+
+// ReadManifest reads a CSV or XLSX manifest, the format is chosen by the extension of the name.
+// The first row is a header naming the file and article number columns, other columns are ignored.
+// Article numbers of a row are separated by commas or semicolons.
+func ReadManifest(name string, r io.Reader) ([]ManifestRow, error) {
+	var (
+		records [][]string
+		err     error
+	)
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		records, err = readCSV(r)
+	case ".xlsx":
+		records, err = readXLSX(r)
+	default:
+		return nil, fmt.Errorf("unsupported manifest %s, expected a .csv or .xlsx file", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", name, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("manifest %s is empty", name)
+	}
+
+	fileColumn := findColumn(records[0], fileColumns)
+	articleColumn := findColumn(records[0], articleColumns)
+	if fileColumn < 0 || articleColumn < 0 {
+		return nil, fmt.Errorf("manifest %s needs the %s and %s columns", name, fileColumns[0], articleColumns[0])
+	}
+
+	var rows []ManifestRow
+	for i, record := range records[1:] {
+		row := ManifestRow{
+			Line:           i + 2,
+			File:           cell(record, fileColumn),
+			ArticleNumbers: splitArticleNumbers(cell(record, articleColumn)),
+		}
+		if row.File == "" && len(row.ArticleNumbers) == 0 {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// Spreadsheets save CSV with a byte order mark, and with semicolons in some locales
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader.ReadAll()
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, nil
+	}
+	return file.GetRows(sheets[0])
+}
+
+func findColumn(header []string, names []string) int {
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		for _, name := range names {
+			if column == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func cell(record []string, column int) string {
+	if column >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[column])
+}
+
+func splitArticleNumbers(value string) []string {
+	var articleNumbers []string
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			articleNumbers = append(articleNumbers, trimmed)
+		}
+	}
+	return articleNumbers
+}
+
+// ErrNoManifest is returned when an import archive or folder has no manifest
+var ErrNoManifest = errors.New("no manifest found")
+
+// OpenCatalog finds the manifest at the root of fsys or in its only top folder, as archives
+// of a folder have it, and reads it. Image paths of the manifest are relative to the returned
+// file system.
+func OpenCatalog(fsys fs.FS) ([]ManifestRow, fs.FS, error) {
+	dir, name, err := findManifest(fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dir != "." {
+		if fsys, err = fs.Sub(fsys, dir); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	rows, err := ReadManifest(name, file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, fsys, nil
+}
+
+func findManifest(fsys fs.FS) (dir, name string, err error) {
+	if name, ok := manifestIn(fsys, "."); ok {
+		return ".", name, nil
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", "", err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		// Archives made on macOS carry resource forks in __MACOSX
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), "__") {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	if len(dirs) == 1 {
+		if name, ok := manifestIn(fsys, dirs[0]); ok {
+			return dirs[0], name, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w, expected one of: %s", ErrNoManifest, strings.Join(ManifestNames, ", "))
+}
+
+func manifestIn(fsys fs.FS, dir string) (string, bool) {
+	for _, name := range ManifestNames {
+		if _, err := fs.Stat(fsys, path.Join(dir, name)); err == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
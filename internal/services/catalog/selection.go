@@ -0,0 +1,57 @@
+package catalog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// minPhotoIDPrefix is the shortest beginning of a photo ID accepted as a selector,
+// the length of the short IDs shown by the bot
+const minPhotoIDPrefix = 8
+
+// SelectPhotos picks photos by selectors: positions in the list starting at 1,
+// or IDs and their beginnings of at least 8 characters
+func SelectPhotos(photos []models.Photo, selectors []string) ([]uuid.UUID, error) {
+	var selected []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, selector := range selectors {
+		id, err := selectPhoto(photos, strings.ToLower(selector))
+		if err != nil {
+			return nil, err
+		}
+		if !seen[id] {
+			seen[id] = true
+			selected = append(selected, id)
+		}
+	}
+	return selected, nil
+}
+
+func selectPhoto(photos []models.Photo, selector string) (uuid.UUID, error) {
+	if len(selector) < minPhotoIDPrefix {
+		position, err := strconv.Atoi(selector)
+		if err != nil || position < 1 || position > len(photos) {
+			return uuid.Nil, fmt.Errorf("no photo %s", selector)
+		}
+		return photos[position-1].ID, nil
+	}
+
+	var found []uuid.UUID
+	for _, photo := range photos {
+		if strings.HasPrefix(photo.ID.String(), selector) {
+			found = append(found, photo.ID)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return uuid.Nil, fmt.Errorf("no photo %s", selector)
+	case 1:
+		return found[0], nil
+	}
+	return uuid.Nil, fmt.Errorf("photo %s is ambiguous", selector)
+}
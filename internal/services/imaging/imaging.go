@@ -0,0 +1,168 @@
+// Package imaging normalizes uploaded images before they are stored: photos are turned
+// upright by their EXIF orientation, stripped of metadata, downscaled and re-encoded.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+)
+
+// MaxPixels guards against images which are small files but take gigabytes once decoded
+const MaxPixels = 100_000_000
+
+var (
+	// ErrFileTooLarge is returned for files larger than the configured limit
+	ErrFileTooLarge = errors.New("file is too large")
+	// ErrTooManyPixels is returned for images with more than MaxPixels pixels
+	ErrTooManyPixels = errors.New("image has too many pixels")
+	// ErrNotImage is returned for files which are not images of a supported format
+	ErrNotImage = errors.New("not an image")
+)
+
+// Processor normalizes images according to the images config
+type Processor struct {
+	config configs.ImagesConfig
+}
+
+// NewProcessor creates a new Processor, a nil config keeps images as they are
+// apart from the EXIF orientation
+func NewProcessor(cfg *configs.ImagesConfig) *Processor {
+	config := configs.ImagesConfig{Format: configs.ImageFormatOriginal}
+	if cfg != nil {
+		config = *cfg
+	}
+	if config.Format == "" {
+		config.Format = configs.ImageFormatOriginal
+	}
+	if config.Quality <= 0 {
+		config.Quality = jpeg.DefaultQuality
+	}
+	return &Processor{config: config}
+}
+
+// MaxFileSize returns the largest accepted file in bytes, 0 for any size
+func (p *Processor) MaxFileSize() int64 {
+	return p.config.MaxFileSize
+}
+
+// Process returns the normalized image and its format as image.DecodeConfig names it.
+// Files which need no changes are returned as they are, so processing a stored photo
+// again gives the same bytes.
+func (p *Processor) Process(data []byte) ([]byte, string, error) {
+	if p.config.MaxFileSize > 0 && int64(len(data)) > p.config.MaxFileSize {
+		return nil, "", ErrFileTooLarge
+	}
+	cfg, format, err := DecodeConfig(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	target := p.config.Format
+	if target == configs.ImageFormatOriginal {
+		target = format
+		// Other formats can only be decoded
+		if target != configs.ImageFormatJPEG && target != configs.ImageFormatPNG {
+			target = configs.ImageFormatJPEG
+		}
+	}
+
+	// Segments kept in re-encoded JPEG files
+	var segments [][]byte
+	orientation, metadata := 1, false
+	switch format {
+	case "jpeg":
+		all := metadataSegments(data)
+		orientation = exifOrientation(all)
+		for _, segment := range all {
+			if !isColorProfile(segment) {
+				metadata = true
+			}
+			if !p.config.StripMetadata || isColorProfile(segment) {
+				segments = append(segments, segment)
+			}
+		}
+	case "png":
+		metadata = hasPNGMetadata(data)
+	}
+	resize := p.config.MaxDimension > 0 && max(cfg.Width, cfg.Height) > p.config.MaxDimension
+	if target == format && orientation == 1 && !resize && !(metadata && p.config.StripMetadata) {
+		return data, format, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrNotImage
+	}
+	if resize {
+		img = Downscale(img, p.config.MaxDimension)
+	}
+	img = orient(img, orientation)
+
+	var buf bytes.Buffer
+	switch target {
+	case configs.ImageFormatPNG:
+		err = png.Encode(&buf, img)
+	default:
+		err = jpeg.Encode(&buf, opaque(img), &jpeg.Options{Quality: p.config.Quality})
+		if err == nil && len(segments) > 0 {
+			return insertSegments(buf.Bytes(), segments), target, nil
+		}
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), target, nil
+}
+
+// DecodeConfig reads the size and format of an image from its header. Images with more
+// than MaxPixels pixels are refused before anything decodes them.
+func DecodeConfig(data []byte) (image.Config, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Config{}, "", ErrNotImage
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return image.Config{}, "", ErrTooManyPixels
+	}
+	return cfg, format, nil
+}
+
+// Downscale shrinks images with a side longer than the limit, keeping the aspect ratio
+func Downscale(img image.Image, limit int) image.Image {
+	size := img.Bounds().Size()
+	if size.X <= limit && size.Y <= limit {
+		return img
+	}
+	width, height := limit, size.Y*limit/size.X
+	if size.Y > size.X {
+		width, height = size.X*limit/size.Y, limit
+	}
+	scaled := image.NewRGBA(image.Rect(0, 0, max(width, 1), max(height, 1)))
+	xdraw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return scaled
+}
+
+// opaque puts images with transparency on a white background, JPEG would turn it black
+func opaque(img image.Image) image.Image {
+	switch img.(type) {
+	case *image.YCbCr, *image.Gray, *image.CMYK:
+		return img
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,13 @@
+package imaging_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestImaging(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Imaging Suite")
+}
@@ -0,0 +1,204 @@
+package imaging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
+)
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// halves draws an image with a red left half and a blue right half
+func halves(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, image.Rect(0, 0, width/2, height), image.NewUniform(red), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(width/2, 0, width, height), image.NewUniform(blue), image.Point{}, draw.Src)
+	return img
+}
+
+// photoWithOrientation encodes a JPEG with an EXIF segment holding the orientation
+func photoWithOrientation(img image.Image, orientation uint16) []byte {
+	GinkgoHelper()
+	var buf bytes.Buffer
+	Expect(jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})).To(Succeed())
+
+	tiff := []byte("MM\x00*\x00\x00\x00\x08\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	segment := binary.BigEndian.AppendUint16([]byte{0xff, 0xe1}, uint16(len(payload)+2))
+	data := append([]byte{0xff, 0xd8}, segment...)
+	data = append(data, payload...)
+	return append(data, buf.Bytes()[2:]...)
+}
+
+func decode(data []byte) (image.Image, string) {
+	GinkgoHelper()
+	img, format, err := image.Decode(bytes.NewReader(data))
+	Expect(err).To(BeNil())
+	return img, format
+}
+
+func isRed(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > 0xc000 && b < 0x4000
+}
+
+var _ = Describe("Processor", func() {
+	var cfg *configs.ImagesConfig
+
+	BeforeEach(func() {
+		cfg = &configs.ImagesConfig{
+			Format:        configs.ImageFormatJPEG,
+			Quality:       85,
+			MaxDimension:  100,
+			MaxFileSize:   1 << 20,
+			StripMetadata: true,
+		}
+	})
+
+	It("should turn photos upright and strip their EXIF data", func() {
+		data, _, err := imaging.NewProcessor(cfg).Process(photoWithOrientation(halves(40, 20), 6))
+		Expect(err).To(BeNil())
+		Expect(data).NotTo(ContainSubstring("Exif"))
+
+		// Turned clockwise, the left half is on top
+		img, format := decode(data)
+		Expect(format).To(Equal("jpeg"))
+		Expect(img.Bounds().Size()).To(Equal(image.Pt(20, 40)))
+		Expect(isRed(img.At(10, 5))).To(BeTrue())
+		Expect(isRed(img.At(10, 35))).To(BeFalse())
+
+		data, _, err = imaging.NewProcessor(cfg).Process(photoWithOrientation(halves(40, 20), 8))
+		Expect(err).To(BeNil())
+		img, _ = decode(data)
+		Expect(isRed(img.At(10, 35))).To(BeTrue())
+	})
+
+	It("should keep EXIF data reset to upright when configured", func() {
+		cfg.StripMetadata = false
+		processor := imaging.NewProcessor(cfg)
+		data, _, err := processor.Process(photoWithOrientation(halves(40, 20), 3))
+		Expect(err).To(BeNil())
+		Expect(data).To(ContainSubstring("Exif"))
+
+		img, _ := decode(data)
+		Expect(img.Bounds().Size()).To(Equal(image.Pt(40, 20)))
+		Expect(isRed(img.At(35, 10))).To(BeTrue())
+
+		// Stored photos need no more changes
+		again, _, err := processor.Process(data)
+		Expect(err).To(BeNil())
+		Expect(again).To(Equal(data))
+	})
+
+	It("should downscale and re-encode to the configured format", func() {
+		var buf bytes.Buffer
+		Expect(png.Encode(&buf, halves(400, 100))).To(Succeed())
+
+		data, format, err := imaging.NewProcessor(cfg).Process(buf.Bytes())
+		Expect(err).To(BeNil())
+		Expect(format).To(Equal("jpeg"))
+		img, decoded := decode(data)
+		Expect(decoded).To(Equal("jpeg"))
+		Expect(img.Bounds().Size()).To(Equal(image.Pt(100, 25)))
+
+		cfg.Format = configs.ImageFormatPNG
+		cfg.MaxDimension = 0
+		data, format, err = imaging.NewProcessor(cfg).Process(buf.Bytes())
+		Expect(err).To(BeNil())
+		Expect(format).To(Equal("png"))
+		Expect(data).To(Equal(buf.Bytes()))
+
+		cfg.Format = configs.ImageFormatOriginal
+		data, format, err = imaging.NewProcessor(cfg).Process(photoWithOrientation(halves(40, 20), 1))
+		Expect(err).To(BeNil())
+		Expect(format).To(Equal("jpeg"))
+		_, decoded = decode(data)
+		Expect(decoded).To(Equal("jpeg"))
+	})
+
+	It("should refuse large files and files which are not images", func() {
+		cfg.MaxFileSize = 16
+		_, _, err := imaging.NewProcessor(cfg).Process(photoWithOrientation(halves(40, 20), 1))
+		Expect(err).To(MatchError(imaging.ErrFileTooLarge))
+
+		_, _, err = imaging.NewProcessor(nil).Process([]byte("not a photo"))
+		Expect(err).To(MatchError(imaging.ErrNotImage))
+	})
+})
+
+var _ = Describe("Watermark", func() {
+	var cfg *configs.WatermarkConfig
+
+	BeforeEach(func() {
+		logo := image.NewRGBA(image.Rect(0, 0, 20, 10))
+		draw.Draw(logo, logo.Bounds(), image.NewUniform(blue), image.Point{}, draw.Src)
+		var buf bytes.Buffer
+		Expect(png.Encode(&buf, logo)).To(Succeed())
+		path := filepath.Join(GinkgoT().TempDir(), "logo.png")
+		Expect(os.WriteFile(path, buf.Bytes(), 0o644)).To(Succeed())
+
+		cfg = &configs.WatermarkConfig{
+			Image:    path,
+			Position: configs.WatermarkBottomRight,
+			Opacity:  1,
+			Scale:    0.5,
+		}
+	})
+
+	It("should put the watermark at its position and mark the photo with its settings", func() {
+		var buf bytes.Buffer
+		Expect(jpeg.Encode(&buf, halves(200, 100), nil)).To(Succeed())
+
+		watermark, err := imaging.NewWatermark(cfg)
+		Expect(err).To(BeNil())
+		data, err := watermark.Apply(buf.Bytes())
+		Expect(err).To(BeNil())
+		Expect(watermark.IsCurrent(data)).To(BeTrue())
+		Expect(watermark.IsCurrent(buf.Bytes())).To(BeFalse())
+
+		// A 100x50 logo in the bottom right corner, the red half is left as it was
+		img, _ := decode(data)
+		Expect(img.Bounds().Size()).To(Equal(image.Pt(200, 100)))
+		Expect(isRed(img.At(50, 50))).To(BeTrue())
+		Expect(isRed(img.At(60, 90))).To(BeTrue())
+
+		cfg.Position = configs.WatermarkTopLeft
+		moved, err := imaging.NewWatermark(cfg)
+		Expect(err).To(BeNil())
+		Expect(moved.IsCurrent(data)).To(BeFalse())
+		data, err = moved.Apply(buf.Bytes())
+		Expect(err).To(BeNil())
+		img, _ = decode(data)
+		Expect(isRed(img.At(50, 20))).To(BeFalse())
+	})
+
+	It("should be disabled without an image", func() {
+		Expect(imaging.NewWatermark(nil)).To(BeNil())
+		Expect(imaging.NewWatermark(&configs.WatermarkConfig{})).To(BeNil())
+
+		cfg.Image = filepath.Join(GinkgoT().TempDir(), "missing.png")
+		_, err := imaging.NewWatermark(cfg)
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,144 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	markerSOS = 0xda
+	markerEOI = 0xd9
+	markerCOM = 0xfe
+
+	tagOrientation = 0x0112
+	typeShort      = 3
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	iccHeader  = []byte("ICC_PROFILE\x00")
+)
+
+// metadataSegments returns the APP1 to APP15 and comment segments of a JPEG file with their
+// markers, they hold EXIF, XMP, ICC profiles and the like
+func metadataSegments(data []byte) [][]byte {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil
+	}
+	var segments [][]byte
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			break
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xff:
+			// Fill byte
+			i++
+			continue
+		case marker == markerSOS || marker == markerEOI:
+			return segments
+		case marker >= 0xd0 && marker <= 0xd7 || marker == 0x01:
+			// Markers without a payload
+			i += 2
+			continue
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) {
+			break
+		}
+		if marker >= 0xe1 && marker <= 0xef || marker == markerCOM {
+			segments = append(segments, data[i:end])
+		}
+		i = end
+	}
+	return segments
+}
+
+// isColorProfile tells whether the segment is an ICC profile, which is kept even when
+// metadata is stripped since colors of wide gamut photos depend on it
+func isColorProfile(segment []byte) bool {
+	return segment[1] == 0xe2 && bytes.HasPrefix(segment[4:], iccHeader)
+}
+
+// exifOrientation returns the EXIF orientation from 1 to 8 of the segments, 1 is upright
+func exifOrientation(segments [][]byte) int {
+	for _, segment := range segments {
+		if offset := orientationOffset(segment); offset > 0 {
+			order := byteOrder(segment[4+len(exifHeader):])
+			if orientation := int(order.Uint16(segment[offset:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+		}
+	}
+	return 1
+}
+
+// orientationOffset returns the offset of the orientation value in an EXIF segment, 0 without one
+func orientationOffset(segment []byte) int {
+	if segment[1] != 0xe1 || !bytes.HasPrefix(segment[4:], exifHeader) {
+		return 0
+	}
+	start := 4 + len(exifHeader)
+	tiff := segment[start:]
+	order := byteOrder(tiff)
+	if order == nil || len(tiff) < 8 {
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == tagOrientation && order.Uint16(tiff[entry+2:]) == typeShort {
+			return start + entry + 8
+		}
+	}
+	return 0
+}
+
+// byteOrder returns the byte order of a TIFF header, nil for an invalid one
+func byteOrder(tiff []byte) binary.ByteOrder {
+	switch {
+	case bytes.HasPrefix(tiff, []byte("II*\x00")):
+		return binary.LittleEndian
+	case bytes.HasPrefix(tiff, []byte("MM\x00*")):
+		return binary.BigEndian
+	}
+	return nil
+}
+
+// insertSegments adds the segments after the start of a JPEG file. The EXIF orientation
+// is reset to upright, the pixels have already been turned.
+func insertSegments(data []byte, segments [][]byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	for _, segment := range segments {
+		if offset := orientationOffset(segment); offset > 0 {
+			segment = bytes.Clone(segment)
+			byteOrder(segment[4+len(exifHeader):]).PutUint16(segment[offset:], 1)
+		}
+		out = append(out, segment...)
+	}
+	return append(out, data[2:]...)
+}
+
+// hasPNGMetadata tells whether a PNG file has text, EXIF or time chunks
+func hasPNGMetadata(data []byte) bool {
+	for i := 8; i+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		switch string(data[i+4 : i+8]) {
+		case "tEXt", "zTXt", "iTXt", "eXIf", "tIME":
+			return true
+		case "IEND":
+			return false
+		}
+		i += 12 + length
+	}
+	return false
+}
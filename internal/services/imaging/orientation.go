@@ -0,0 +1,47 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// orient turns the image upright according to its EXIF orientation
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	width, height := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if orientation >= 5 {
+		// Orientations from 5 to 8 swap the sides
+		dst = image.NewRGBA(image.Rect(0, 0, height, width))
+	}
+	dstBounds := dst.Bounds()
+	for y := 0; y < dstBounds.Dy(); y++ {
+		for x := 0; x < dstBounds.Dx(); x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				sx, sy = width-1-x, y
+			case 3: // Rotated by 180°
+				sx, sy = width-1-x, height-1-y
+			case 4: // Mirrored vertically
+				sx, sy = x, height-1-y
+			case 5: // Transposed
+				sx, sy = y, x
+			case 6: // Rotated by 90° clockwise to be upright
+				sx, sy = y, height-1-x
+			case 7: // Transversed
+				sx, sy = width-1-y, height-1-x
+			case 8: // Rotated by 90° counterclockwise to be upright
+				sx, sy = width-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,133 @@
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"os"
+
+	xdraw "golang.org/x/image/draw"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+)
+
+// watermarkQuality is the JPEG quality of watermarked photos
+const watermarkQuality = 90
+
+// watermarkComment prefixes the version of the watermark in the comment of watermarked photos
+const watermarkComment = "alfredo-watermark:"
+
+// Watermark puts a logo on photos
+type Watermark struct {
+	logo     image.Image
+	position string
+	opacity  float64
+	scale    float64
+	version  string
+}
+
+// NewWatermark loads the watermark image of the config, it returns nil when no image is configured
+func NewWatermark(cfg *configs.WatermarkConfig) (*Watermark, error) {
+	if cfg == nil || cfg.Image == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(cfg.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watermark image: %w", err)
+	}
+	logo, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode watermark image: %w", err)
+	}
+
+	// Watermarked photos made with other settings are made again
+	hash := sha256.New()
+	hash.Write(data)
+	fmt.Fprintf(hash, "|%s|%g|%g", cfg.Position, cfg.Opacity, cfg.Scale)
+	return &Watermark{
+		logo:     logo,
+		position: cfg.Position,
+		opacity:  cfg.Opacity,
+		scale:    cfg.Scale,
+		version:  hex.EncodeToString(hash.Sum(nil))[:16],
+	}, nil
+}
+
+// Apply returns the photo with the watermark as JPEG
+func (w *Watermark) Apply(data []byte) ([]byte, error) {
+	if _, _, err := DecodeConfig(data); err != nil {
+		return nil, err
+	}
+	photo, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrNotImage
+	}
+
+	bounds := photo.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), photo, bounds.Min, draw.Over)
+
+	area := w.area(dst.Bounds().Size())
+	logo := image.NewRGBA(image.Rect(0, 0, area.Dx(), area.Dy()))
+	xdraw.CatmullRom.Scale(logo, logo.Bounds(), w.logo, w.logo.Bounds(), xdraw.Src, nil)
+	opacity := image.NewUniform(color.Alpha{A: uint8(w.opacity * 0xff)})
+	draw.DrawMask(dst, area, logo, image.Point{}, opacity, image.Point{}, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: watermarkQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return insertSegments(buf.Bytes(), [][]byte{w.comment()}), nil
+}
+
+// IsCurrent tells whether the photo was watermarked with the current image and settings
+func (w *Watermark) IsCurrent(data []byte) bool {
+	comment := w.comment()
+	for _, segment := range metadataSegments(data) {
+		if bytes.Equal(segment, comment) {
+			return true
+		}
+	}
+	return false
+}
+
+// area returns where the watermark goes on a photo of the size
+func (w *Watermark) area(size image.Point) image.Rectangle {
+	logoSize := w.logo.Bounds().Size()
+	width := max(1, int(float64(size.X)*w.scale))
+	height := max(1, logoSize.Y*width/max(1, logoSize.X))
+	// Tall watermarks are fitted into the scale of the photo height too
+	if limit := max(1, int(float64(size.Y)*w.scale)); height > limit {
+		width, height = max(1, width*limit/height), limit
+	}
+	margin := min(size.X, size.Y) / 40
+
+	var x, y int
+	switch w.position {
+	case configs.WatermarkTopLeft:
+		x, y = margin, margin
+	case configs.WatermarkTopRight:
+		x, y = size.X-width-margin, margin
+	case configs.WatermarkBottomLeft:
+		x, y = margin, size.Y-height-margin
+	case configs.WatermarkCenter:
+		x, y = (size.X-width)/2, (size.Y-height)/2
+	default:
+		x, y = size.X-width-margin, size.Y-height-margin
+	}
+	return image.Rect(x, y, x+width, y+height)
+}
+
+// comment returns the JPEG comment segment marking photos watermarked with the current version
+func (w *Watermark) comment() []byte {
+	payload := watermarkComment + w.version
+	segment := binary.BigEndian.AppendUint16([]byte{0xff, markerCOM}, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
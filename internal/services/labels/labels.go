@@ -0,0 +1,148 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// StartPrefix marks start parameters of deep links opening an article number,
+// other start parameters are invite codes
+const StartPrefix = "art_"
+
+// StartParameter is the start parameter of the deep link opening an article number
+func StartParameter(articleNumberID uuid.UUID) string {
+	return StartPrefix + articleNumberID.String()
+}
+
+// ParseStartParameter returns the article number opened by a start parameter,
+// false when the parameter is not an article deep link
+func ParseStartParameter(parameter string) (uuid.UUID, bool) {
+	value, ok := strings.CutPrefix(parameter, StartPrefix)
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// DeepLink is the link starting the bot with an article number
+func DeepLink(botUsername string, articleNumberID uuid.UUID) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s", botUsername, StartParameter(articleNumberID))
+}
+
+// Label is a printed label: a QR code of the link next to the human-readable number
+type Label struct {
+	Number string
+	Link   string
+}
+
+// Format is the file format of a label sheet
+type Format string
+
+const (
+	FormatPDF Format = "pdf"
+	FormatPNG Format = "png"
+)
+
+// Formats are the supported formats, the first one is the default
+var Formats = []Format{FormatPDF, FormatPNG}
+
+var (
+	ErrUnknownFormat = errors.New("unknown label format")
+	ErrNoLabels      = errors.New("no labels to print")
+)
+
+// ParseFormat parses a format name case-insensitively, an empty name is the default format
+func ParseFormat(name string) (Format, error) {
+	if name == "" {
+		return Formats[0], nil
+	}
+	for _, format := range Formats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+}
+
+// Write renders labels on A4 sheets of 3 by 8 labels.
+// A PDF has a page per sheet, a PNG stacks all labels on one image.
+func Write(w io.Writer, format Format, labels []Label) error {
+	if len(labels) == 0 {
+		return ErrNoLabels
+	}
+	switch format {
+	case FormatPDF:
+		return writePDF(w, labels)
+	case FormatPNG:
+		return writePNG(w, labels)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// Sheet layout in millimeters, it matches common 70x37 A4 label stock
+const (
+	pageWidth    = 210.0
+	pageHeight   = 297.0
+	columns      = 3
+	rows         = 8
+	labelWidth   = pageWidth / columns
+	labelHeight  = pageHeight / rows
+	labelPadding = 3.0
+	qrSize       = labelHeight - 2*labelPadding
+	textLeft     = labelPadding + qrSize + labelPadding
+	textWidth    = labelWidth - textLeft - labelPadding
+)
+
+// Font sizes of numbers in points, long numbers shrink to fit the label
+const (
+	maxFontSize = 14.0
+	minFontSize = 6.0
+)
+
+// labelPosition is the top left corner of the i-th label on its sheet
+func labelPosition(i int) (x, y float64) {
+	i %= columns * rows
+	return float64(i%columns) * labelWidth, float64(i/columns) * labelHeight
+}
+
+// encodeQR encodes a link without a quiet zone, one pixel per module
+func encodeQR(link string) (*gozxing.BitMatrix, error) {
+	hints := map[gozxing.EncodeHintType]interface{}{
+		gozxing.EncodeHintType_MARGIN: 0,
+	}
+	matrix, err := qrcode.NewQRCodeWriter().Encode(link, gozxing.BarcodeFormat_QR_CODE, 0, 0, hints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return matrix, nil
+}
+
+// fitText returns the largest font size the text fits the text width with,
+// the text is cut with an ellipsis when it does not fit even the smallest size
+func fitText(text string, width func(text string, size float64) float64) (string, float64) {
+	for size := maxFontSize; size >= minFontSize; size-- {
+		if width(text, size) <= textWidth {
+			return text, size
+		}
+	}
+	runes := []rune(text)
+	for len(runes) > 1 {
+		runes = runes[:len(runes)-1]
+		cut := string(runes) + "…"
+		if width(cut, minFontSize) <= textWidth {
+			return cut, minFontSize
+		}
+	}
+	return string(runes), minFontSize
+}
@@ -0,0 +1,80 @@
+package labels_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"regexp"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/services/barcode"
+	"github.com/Conty111/AlfredoBot/internal/services/labels"
+)
+
+var _ = Describe("Labels", func() {
+	It("should link article numbers with start parameters", func() {
+		id := uuid.New()
+		link := labels.DeepLink("alfredo_bot", id)
+		Expect(link).To(Equal("https://t.me/alfredo_bot?start=art_" + id.String()))
+
+		parsed, ok := labels.ParseStartParameter(labels.StartParameter(id))
+		Expect(ok).To(BeTrue())
+		Expect(parsed).To(Equal(id))
+
+		_, ok = labels.ParseStartParameter("invite-code")
+		Expect(ok).To(BeFalse())
+		_, ok = labels.ParseStartParameter("art_not-an-id")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should parse formats", func() {
+		Expect(labels.ParseFormat("")).To(Equal(labels.FormatPDF))
+		Expect(labels.ParseFormat("PNG")).To(Equal(labels.FormatPNG))
+		_, err := labels.ParseFormat("svg")
+		Expect(err).To(MatchError(labels.ErrUnknownFormat))
+	})
+
+	It("should print scannable QR codes on PNG sheets", func() {
+		link := labels.DeepLink("alfredo_bot", uuid.New())
+		var buf bytes.Buffer
+		Expect(labels.Write(&buf, labels.FormatPNG, []labels.Label{{Number: "A-100", Link: link}})).To(Succeed())
+
+		sheet, err := png.Decode(bytes.NewReader(buf.Bytes()))
+		Expect(err).To(BeNil())
+		Expect(sheet.Bounds().Dx()).To(BeNumerically(">", sheet.Bounds().Dy()))
+		Expect(barcode.Decode(buf.Bytes())).To(Equal([]string{link}))
+	})
+
+	It("should stack PNG sheets and cut numbers too long for a label", func() {
+		many := make([]labels.Label, 25)
+		for i := range many {
+			many[i] = labels.Label{Number: "A-100", Link: labels.DeepLink("alfredo_bot", uuid.New())}
+		}
+		many[0].Number = "SUPPLIER-CODE-WITH-A-VERY-LONG-NAME-0000000001"
+		var buf bytes.Buffer
+		Expect(labels.Write(&buf, labels.FormatPNG, many)).To(Succeed())
+
+		config, _, err := image.DecodeConfig(bytes.NewReader(buf.Bytes()))
+		Expect(err).To(BeNil())
+		// 25 labels take 9 rows of 3
+		Expect(config.Height).To(BeNumerically("~", config.Width*297*9/8/210, 2))
+	})
+
+	It("should print a PDF page per sheet", func() {
+		many := make([]labels.Label, 25)
+		for i := range many {
+			many[i] = labels.Label{Number: "Артикул-1", Link: labels.DeepLink("alfredo_bot", uuid.New())}
+		}
+		var buf bytes.Buffer
+		Expect(labels.Write(&buf, labels.FormatPDF, many)).To(Succeed())
+		Expect(buf.String()).To(HavePrefix("%PDF"))
+		Expect(regexp.MustCompile(`/Type /Page\b`).FindAll(buf.Bytes(), -1)).To(HaveLen(2))
+	})
+
+	It("should refuse empty sheets", func() {
+		Expect(labels.Write(&bytes.Buffer{}, labels.FormatPDF, nil)).To(MatchError(labels.ErrNoLabels))
+	})
+})
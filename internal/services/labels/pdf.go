@@ -0,0 +1,85 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/makiuchi-d/gozxing"
+	"golang.org/x/image/font/gofont/gobold"
+)
+
+const (
+	pdfFont = "gobold"
+	// pdfModulePixels keeps QR codes sharp when viewers smooth scaled images
+	pdfModulePixels = 8
+	// capHeight is the height of uppercase letters of the font, relative to its size
+	capHeight = 0.72
+	pointMM   = 25.4 / 72
+)
+
+func writePDF(w io.Writer, labels []Label) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", gobold.TTF)
+	pdf.SetLineWidth(0.1)
+	pdf.SetDrawColor(int(cutLineColor.Y), int(cutLineColor.Y), int(cutLineColor.Y))
+
+	width := func(text string, size float64) float64 {
+		pdf.SetFont(pdfFont, "", size)
+		return pdf.GetStringWidth(text)
+	}
+
+	for i, label := range labels {
+		if i%(columns*rows) == 0 {
+			pdf.AddPage()
+		}
+		x, y := labelPosition(i)
+		pdf.Rect(x, y, labelWidth, labelHeight, "D")
+
+		matrix, err := encodeQR(label.Link)
+		if err != nil {
+			return err
+		}
+		qr, err := qrPNG(matrix)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("qr%d", i)
+		options := fpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(qr))
+		pdf.ImageOptions(name, x+labelPadding, y+labelPadding, qrSize, qrSize, false, options, 0, "")
+
+		text, size := fitText(label.Number, width)
+		pdf.SetFont(pdfFont, "", size)
+		// The text is centered vertically on the label
+		pdf.Text(x+textLeft, y+labelHeight/2+size*pointMM*capHeight/2, text)
+	}
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return nil
+}
+
+// qrPNG renders a QR code as a PNG image for embedding into PDF
+func qrPNG(matrix *gozxing.BitMatrix) ([]byte, error) {
+	img := image.NewGray(image.Rect(0, 0, matrix.GetWidth()*pdfModulePixels, matrix.GetHeight()*pdfModulePixels))
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if !matrix.Get(x/pdfModulePixels, y/pdfModulePixels) {
+				img.SetGray(x, y, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,115 @@
+package labels
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/makiuchi-d/gozxing"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// pngDPI is the resolution of PNG sheets, enough for phones to scan printed codes
+const pngDPI = 200
+
+// cutLineColor marks label borders for cutting plain paper
+var cutLineColor = color.Gray{Y: 0xdd}
+
+func pixels(mm float64) int {
+	return int(math.Round(mm * pngDPI / 25.4))
+}
+
+func writePNG(w io.Writer, labels []Label) error {
+	typeface, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return fmt.Errorf("failed to parse font: %w", err)
+	}
+
+	sheetRows := (len(labels) + columns - 1) / columns
+	sheet := image.NewGray(image.Rect(0, 0, pixels(pageWidth), pixels(float64(sheetRows)*labelHeight)))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	faces := make(map[float64]font.Face)
+	defer func() {
+		for _, face := range faces {
+			face.Close()
+		}
+	}()
+	for size := maxFontSize; size >= minFontSize; size-- {
+		face, err := opentype.NewFace(typeface, &opentype.FaceOptions{Size: size, DPI: pngDPI, Hinting: font.HintingFull})
+		if err != nil {
+			return fmt.Errorf("failed to load font: %w", err)
+		}
+		faces[size] = face
+	}
+	width := func(text string, size float64) float64 {
+		advance := font.MeasureString(faces[size], text)
+		return float64(advance) / 64 * 25.4 / pngDPI
+	}
+
+	for i, label := range labels {
+		// Sheets are stacked, so positions continue past the first sheet
+		x, y := float64(i%columns)*labelWidth, float64(i/columns)*labelHeight
+		left, top := pixels(x), pixels(y)
+
+		drawBorder(sheet, image.Rect(left, top, pixels(x+labelWidth), pixels(y+labelHeight)))
+
+		matrix, err := encodeQR(label.Link)
+		if err != nil {
+			return err
+		}
+		drawQR(sheet, image.Rect(
+			pixels(x+labelPadding), pixels(y+labelPadding),
+			pixels(x+labelPadding+qrSize), pixels(y+labelPadding+qrSize),
+		), matrix)
+
+		text, size := fitText(label.Number, width)
+		face := faces[size]
+		metrics := face.Metrics()
+		// The text is centered vertically on the label
+		baseline := pixels(y+labelHeight/2) + metrics.CapHeight.Round()/2
+		drawer := &font.Drawer{
+			Dst:  sheet,
+			Src:  image.NewUniform(color.Black),
+			Face: face,
+			Dot:  fixed.P(pixels(x+textLeft), baseline),
+		}
+		drawer.DrawString(text)
+	}
+
+	if err := png.Encode(w, sheet); err != nil {
+		return fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return nil
+}
+
+func drawBorder(dst *image.Gray, rect image.Rectangle) {
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		dst.SetGray(x, rect.Min.Y, cutLineColor)
+		dst.SetGray(x, rect.Max.Y-1, cutLineColor)
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		dst.SetGray(rect.Min.X, y, cutLineColor)
+		dst.SetGray(rect.Max.X-1, y, cutLineColor)
+	}
+}
+
+// drawQR scales a QR code to a rectangle, module by module
+func drawQR(dst *image.Gray, rect image.Rectangle, matrix *gozxing.BitMatrix) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		row := (y - rect.Min.Y) * matrix.GetHeight() / rect.Dy()
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			column := (x - rect.Min.X) * matrix.GetWidth() / rect.Dx()
+			if matrix.Get(column, row) {
+				dst.SetGray(x, y, color.Gray{})
+			}
+		}
+	}
+}
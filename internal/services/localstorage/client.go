@@ -0,0 +1,229 @@
+package localstorage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+)
+
+// Client implements the S3Client interface on top of the local filesystem.
+// Buckets are directories under the configured root and object keys are
+// relative file paths inside them. Presigned URLs are emulated with an
+// HMAC signature which is verified by ServeHTTP.
+type Client struct {
+	root       string
+	publicURL  string
+	signingKey []byte
+}
+
+var _ interfaces.S3Client = (*Client)(nil)
+
+// NewClient creates a new filesystem storage client
+func NewClient(cfg *configs.LocalStorageConfig) (*Client, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("local storage config is nil")
+	}
+	if cfg.Root == "" {
+		return nil, fmt.Errorf("local storage root is required")
+	}
+
+	root, err := filepath.Abs(cfg.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage root: %w", err)
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage root: %w", err)
+	}
+
+	signingKey := []byte(cfg.SigningKey)
+	if len(signingKey) == 0 {
+		signingKey = make([]byte, 32)
+		if _, err := rand.Read(signingKey); err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		log.Warn().Msg("Local storage signing key is not set, signed URLs will not survive a restart")
+	}
+
+	return &Client{
+		root:       root,
+		publicURL:  strings.TrimSuffix(cfg.PublicURL, "/"),
+		signingKey: signingKey,
+	}, nil
+}
+
+// UploadFile writes a file to the local storage
+func (c *Client) UploadFile(_ context.Context, bucket, key string, file io.Reader) error {
+	filePath, err := c.objectPath(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see partial objects
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := io.Copy(tmp, file); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// DownloadFile opens a file from the local storage
+func (c *Client) DownloadFile(_ context.Context, bucket, key string) (io.ReadCloser, error) {
+	filePath, err := c.objectPath(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filePath)
+}
+
+// DeleteFile removes a file from the local storage
+func (c *Client) DeleteFile(_ context.Context, bucket, key string) error {
+	filePath, err := c.objectPath(bucket, key)
+	if err != nil {
+		return err
+	}
+	// S3 treats deleting a missing object as success
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// GeneratePresignedURL generates a signed URL served by ServeHTTP
+func (c *Client) GeneratePresignedURL(_ context.Context, bucket, key string, expiresIn int64) (string, error) {
+	if _, err := c.objectPath(bucket, key); err != nil {
+		return "", err
+	}
+
+	// The signature covers the decoded path ServeHTTP checks, the URL carries it escaped
+	objectPath := objectURLPath(bucket, key)
+	expires := time.Now().Add(time.Duration(expiresIn) * time.Second).Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", c.sign(objectPath, expires))
+
+	return c.publicURL + escapeURLPath(objectPath) + "?" + query.Encode(), nil
+}
+
+// ServeHTTP serves files referenced by signed URLs
+func (c *Client) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid expiration", http.StatusBadRequest)
+		return
+	}
+	if time.Now().Unix() > expires {
+		http.Error(w, "link expired", http.StatusForbidden)
+		return
+	}
+
+	signature := r.URL.Query().Get("signature")
+	if !hmac.Equal([]byte(signature), []byte(c.sign(r.URL.Path, expires))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	filePath, err := c.objectPath(bucket, key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close served file")
+		}
+	}()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// objectPath maps a bucket and key to a path inside the storage root,
+// rejecting keys that would escape it
+func (c *Client) objectPath(bucket, key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("object key is required")
+	}
+	if strings.Contains(bucket, "/") || bucket == ".." {
+		return "", fmt.Errorf("invalid bucket name: %q", bucket)
+	}
+
+	cleanKey := path.Clean("/" + key)
+	if cleanKey == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid object key: %q", key)
+	}
+
+	return filepath.Join(c.root, bucket, filepath.FromSlash(cleanKey)), nil
+}
+
+func (c *Client) sign(objectPath string, expires int64) string {
+	mac := hmac.New(sha256.New, c.signingKey)
+	mac.Write([]byte(objectPath + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func objectURLPath(bucket, key string) string {
+	return "/" + bucket + "/" + strings.TrimPrefix(key, "/")
+}
+
+// escapeURLPath escapes every segment of the path, keeping the slashes between them
+func escapeURLPath(objectPath string) string {
+	segments := strings.Split(objectPath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
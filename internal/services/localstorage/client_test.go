@@ -0,0 +1,128 @@
+package localstorage_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/services/localstorage"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		ctx    context.Context
+		client *localstorage.Client
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = httptest.NewServer(http.NotFoundHandler())
+
+		var err error
+		client, err = localstorage.NewClient(&configs.LocalStorageConfig{
+			Root:       GinkgoT().TempDir(),
+			PublicURL:  server.URL,
+			SigningKey: "test-key",
+		})
+		Expect(err).To(BeNil())
+		server.Config.Handler = client
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("UploadFile() and DownloadFile()", func() {
+		It("should store and read back an object", func() {
+			Expect(client.UploadFile(ctx, "bucket", "user/photo.jpg", strings.NewReader("data"))).To(Succeed())
+
+			reader, err := client.DownloadFile(ctx, "bucket", "user/photo.jpg")
+			Expect(err).To(BeNil())
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal("data"))
+		})
+
+		It("should reject keys escaping the storage root", func() {
+			err := client.UploadFile(ctx, "bucket", "../../etc/passwd", strings.NewReader("data"))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Describe("DeleteFile()", func() {
+		It("should remove an object and ignore missing ones", func() {
+			Expect(client.UploadFile(ctx, "bucket", "photo.jpg", strings.NewReader("data"))).To(Succeed())
+			Expect(client.DeleteFile(ctx, "bucket", "photo.jpg")).To(Succeed())
+			Expect(client.DeleteFile(ctx, "bucket", "photo.jpg")).To(Succeed())
+
+			_, err := client.DownloadFile(ctx, "bucket", "photo.jpg")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Describe("GeneratePresignedURL()", func() {
+		BeforeEach(func() {
+			Expect(client.UploadFile(ctx, "bucket", "user/photo.jpg", strings.NewReader("data"))).To(Succeed())
+		})
+
+		It("should produce a URL served by the built-in handler", func() {
+			url, err := client.GeneratePresignedURL(ctx, "bucket", "user/photo.jpg", 60)
+			Expect(err).To(BeNil())
+
+			resp, err := http.Get(url)
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			data, _ := io.ReadAll(resp.Body)
+			Expect(string(data)).To(Equal("data"))
+		})
+
+		It("should escape keys with characters special in URLs", func() {
+			const key = "user/50% off?#1.jpg"
+			Expect(client.UploadFile(ctx, "bucket", key, strings.NewReader("sale"))).To(Succeed())
+			url, err := client.GeneratePresignedURL(ctx, "bucket", key, 60)
+			Expect(err).To(BeNil())
+			Expect(url).To(ContainSubstring("/bucket/user/50%25%20off%3F%231.jpg?"))
+
+			resp, err := http.Get(url)
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			data, _ := io.ReadAll(resp.Body)
+			Expect(string(data)).To(Equal("sale"))
+		})
+
+		It("should refuse tampered signatures", func() {
+			url, err := client.GeneratePresignedURL(ctx, "bucket", "user/photo.jpg", 60)
+			Expect(err).To(BeNil())
+
+			resp, err := http.Get(strings.Replace(url, "photo.jpg", "other.jpg", 1))
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		})
+
+		It("should refuse expired links", func() {
+			url, err := client.GeneratePresignedURL(ctx, "bucket", "user/photo.jpg", -60)
+			Expect(err).To(BeNil())
+
+			resp, err := http.Get(url)
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package localstorage_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLocalStorage(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Local Storage Suite")
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"os"
+	"path"
 	"strings"
 	"time"
 
@@ -43,24 +47,63 @@ func NewClient(cfg *configs.S3Config) (*s3.Client, error) {
 		return nil, err
 	}
 
+	var httpClient *http.Client
+	if cfg.UseSSL {
+		httpClient, err = NewHTTPClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
 		if endpoint != "" {
 			o.BaseEndpoint = aws.String(endpoint)
 		}
 		o.UsePathStyle = true
-		// Skip TLS verification for development environments
-		if cfg.UseSSL {
-			o.HTTPClient = &http.Client{
-				Transport: &http.Transport{
-					TLSClientConfig: &tls.Config{
-						InsecureSkipVerify: true, // For development only
-					},
-				},
-			}
+		if httpClient != nil {
+			o.HTTPClient = httpClient
 		}
 	}), nil
 }
 
+// NewHTTPClient creates an HTTP client with TLS settings from the S3 config
+func NewHTTPClient(cfg *configs.S3Config) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify, // refused in production by config validation
+	}
+
+	if cfg.CAFile != "" {
+		caBundle, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
+
 // NewS3Client creates a new S3Client implementation
 func NewS3Client(client *s3.Client) interfaces.S3Client {
 	return &S3ClientImpl{
@@ -80,12 +123,17 @@ func (c *S3ClientImpl) UploadFile(ctx context.Context, bucket, key string, file
 	fileReader := bytes.NewReader(fileBytes)
 
 	// Upload with content length
-	_, err = c.client.PutObject(ctx, &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket:        aws.String(bucket),
 		Key:           aws.String(key),
 		Body:          fileReader,
 		ContentLength: aws.Int64(int64(len(fileBytes))),
-	})
+	}
+	// Presigned links open in browsers, which rely on the content type of the object
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	_, err = c.client.PutObject(ctx, input)
 	return err
 }
 
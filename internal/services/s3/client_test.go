@@ -0,0 +1,96 @@
+package s3_test
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/services/s3"
+)
+
+var _ = Describe("NewHTTPClient()", func() {
+	var (
+		server *httptest.Server
+		caFile string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		DeferCleanup(server.Close)
+
+		caFile = filepath.Join(GinkgoT().TempDir(), "ca.crt")
+		certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		Expect(os.WriteFile(caFile, certificate, 0o600)).To(Succeed())
+	})
+
+	get := func(cfg *configs.S3Config) error {
+		client, err := s3.NewHTTPClient(cfg)
+		Expect(err).To(BeNil())
+
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			Expect(resp.Body.Close()).To(Succeed())
+		}
+		return err
+	}
+
+	It("should verify certificates by default", func() {
+		Expect(get(&configs.S3Config{})).NotTo(Succeed())
+	})
+
+	It("should trust certificates from the CA bundle", func() {
+		Expect(get(&configs.S3Config{CAFile: caFile})).To(Succeed())
+	})
+
+	It("should check the overridden server name", func() {
+		Expect(get(&configs.S3Config{CAFile: caFile, ServerName: "example.com"})).To(Succeed())
+		Expect(get(&configs.S3Config{CAFile: caFile, ServerName: "minio.local"})).NotTo(Succeed())
+	})
+
+	It("should skip verification only when explicitly asked", func() {
+		Expect(get(&configs.S3Config{InsecureSkipVerify: true})).To(Succeed())
+	})
+
+	It("should fail on a CA bundle without certificates", func() {
+		Expect(os.WriteFile(caFile, []byte("garbage"), 0o600)).To(Succeed())
+
+		_, err := s3.NewHTTPClient(&configs.S3Config{CAFile: caFile})
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("S3ClientImpl", func() {
+	It("should upload objects with the content type of their extension", func() {
+		contentTypes := map[string]string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentTypes[r.URL.Path] = r.Header.Get("Content-Type")
+			w.WriteHeader(http.StatusOK)
+		}))
+		DeferCleanup(server.Close)
+
+		client, err := s3.NewClient(&configs.S3Config{
+			Endpoint:        server.URL,
+			Region:          "us-east-1",
+			AccessKeyID:     "key",
+			SecretAccessKey: "secret",
+		})
+		Expect(err).To(BeNil())
+		storage := s3.NewS3Client(client)
+
+		ctx := context.Background()
+		Expect(storage.UploadFile(ctx, "photos", "user/photo.png", strings.NewReader("png"))).To(Succeed())
+		Expect(storage.UploadFile(ctx, "photos", "user/photo.jpg", strings.NewReader("jpeg"))).To(Succeed())
+		Expect(contentTypes).To(HaveKeyWithValue("/photos/user/photo.png", "image/png"))
+		Expect(contentTypes).To(HaveKeyWithValue("/photos/user/photo.jpg", "image/jpeg"))
+	})
+})
@@ -3,9 +3,7 @@ package telegram
 import (
 	"bytes"
 	"context"
-	"fmt"
-	"io"
-	"net/http"
+	"errors"
 
 	"gorm.io/gorm"
 
@@ -15,42 +13,32 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
 )
 
 func (s *TelegramBotService) addItemHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
-	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      update.Message.Chat.ID,
-		Text:        "Пожалуйста, отправьте все фото товара, а затем отдельным сообщением или с подписью артикулы в формате: articul1, articul2, ...\n\nПример: 1.2345, 6.7890",
-		ReplyMarkup: cancelMenu,
-	})
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        tr(ctx).T("upload.prompt"),
+		ReplyMarkup: cancelMenu(ctx),
+	}))
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to send message")
 	}
-	err = s.userRepository.UpdateByTelegramID(
-		update.Message.From.ID,
-		map[string]interface{}{"state": models.TelegramUserStateUploading},
-	)
-	if err != nil {
+	if err := s.setState(update.Message, models.TelegramUserStateUploading); err != nil {
 		log.Error().Err(err).Msg("Failed to update user state")
 	}
 }
 
 func (s *TelegramBotService) photoMessageHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
-	user, err := s.userRepository.GetByTelegramID(update.Message.From.ID)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to get user")
-		_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:      update.Message.Chat.ID,
-			Text:        "Произошла ошибка. Пожалуйста, попробуйте снова.",
-			ReplyMarkup: mainMenu,
-		})
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to send message")
-		}
-		return
-	}
+	// The user in the context carries the workspace of a bound group
+	user := userFromContext(ctx)
 
-	var file *tgmodels.File
+	var (
+		file *tgmodels.File
+		err  error
+		// codes are barcodes and QR codes found on the photo
+		codes []string
+	)
 	if len(update.Message.Photo) > 0 {
 		photo := update.Message.Photo[len(update.Message.Photo)-1]
 		file, err = b.GetFile(ctx, &bot.GetFileParams{
@@ -58,11 +46,10 @@ func (s *TelegramBotService) photoMessageHandler(ctx context.Context, b *bot.Bot
 		})
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to get file from Telegram")
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        "Не удалось получить файл из Telegram. Пожалуйста, попробуйте снова.",
-				ReplyMarkup: mainMenu,
-			})
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("upload.file_failed"),
+				ReplyMarkup: mainMenu(ctx),
+			}))
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to send message")
 			}
@@ -75,11 +62,10 @@ func (s *TelegramBotService) photoMessageHandler(ctx context.Context, b *bot.Bot
 		})
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to get document file from Telegram")
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        "Не удалось получить файл из Telegram. Пожалуйста, попробуйте снова.",
-				ReplyMarkup: mainMenu,
-			})
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("upload.file_failed"),
+				ReplyMarkup: mainMenu(ctx),
+			}))
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to send message")
 			}
@@ -87,59 +73,53 @@ func (s *TelegramBotService) photoMessageHandler(ctx context.Context, b *bot.Bot
 		}
 	}
 	if file != nil {
-		fileURL := fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", s.config.Token, file.FilePath)
-		resp, err := http.Get(fileURL)
+		limit := s.imageProcessor.MaxFileSize()
+		if limit > 0 && file.FileSize > limit {
+			s.rejectImage(ctx, b, update, imaging.ErrFileTooLarge)
+			return
+		}
+		photoData, err := s.downloadFile(ctx, b, file, limit)
+		if errors.Is(err, imaging.ErrFileTooLarge) {
+			s.rejectImage(ctx, b, update, err)
+			return
+		}
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to download file from Telegram")
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        "Не удалось загрузить файл из Telegram. Пожалуйста, попробуйте снова.",
-				ReplyMarkup: mainMenu,
-			})
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("upload.download_failed"),
+				ReplyMarkup: mainMenu(ctx),
+			}))
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to send message")
 			}
 			return
 		}
-		defer func() {
-			if err := resp.Body.Close(); err != nil {
-				log.Error().Err(err).Msg("Failed to close response body")
-			}
-		}()
-
-		// Read photo data to generate hash
-		photoData, err := io.ReadAll(resp.Body)
+		// Photos are stored upright, without metadata and within the configured size
+		photoData, format, err := s.imageProcessor.Process(photoData)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to read photo data for hashing")
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        "Не удалось обработать фото. Пожалуйста, попробуйте снова.",
-				ReplyMarkup: mainMenu,
-			})
-			if err != nil {
-				log.Error().Err(err).Msg("Failed to send message")
-			}
+			s.rejectImage(ctx, b, update, err)
 			return
 		}
 
-		resp.Body = io.NopCloser(bytes.NewReader(photoData))
-
 		photoModel := &models.Photo{
-			UserID: user.ID,
-			State:  models.PhotoNotApplied,
+			UserID:      user.ID,
+			State:       models.PhotoNotApplied,
+			WorkspaceID: user.ActiveWorkspaceID,
+			ChatID:      update.Message.Chat.ID,
+			ContentHash: models.PhotoContentHash(photoData),
+			Format:      format,
 		}
 
 		s3Key := uuid.New()
 		photoModel.S3Key = s3Key
 
-		err = s.photoRepository.CreatePhoto(photoModel)
+		err = s.photoRepository.WithActor(user).CreatePhoto(photoModel)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to save photo to database")
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        "Не удалось сохранить фото в базе данных. Пожалуйста, попробуйте снова.",
-				ReplyMarkup: mainMenu,
-			})
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("upload.save_failed"),
+				ReplyMarkup: mainMenu(ctx),
+			}))
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to send message")
 			}
@@ -150,32 +130,33 @@ func (s *TelegramBotService) photoMessageHandler(ctx context.Context, b *bot.Bot
 			ctx,
 			user.ID,
 			s3Key,
+			format,
 			s.s3Config.Bucket,
-			resp.Body,
+			bytes.NewReader(photoData),
 		); err != nil {
 			log.Error().Err(err).Msg("Failed to upload file to S3")
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        "Не удалось загрузить фото в хранилище. Пожалуйста, попробуйте снова.",
-				ReplyMarkup: mainMenu,
-			})
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("upload.storage_failed"),
+				ReplyMarkup: mainMenu(ctx),
+			}))
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to send message")
 			}
 			return
 		}
-		_, err = b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:      update.Message.Chat.ID,
-			Text:        "Фото успешно сохранено!",
-			ReplyMarkup: cancelMenu,
-		})
+		_, err = b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+			Text:        tr(ctx).T("upload.saved"),
+			ReplyMarkup: cancelMenu(ctx),
+		}))
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to send message")
 		}
+		// Stored photos passed the pixel limit of the processor already
+		codes, _ = decodeBarcodes(photoData)
 	}
 	if update.Message.Text != "" || update.Message.Caption != "" {
 		var articleNumbers []string
-		if update.Message.Text == cancelText || update.Message.Caption == cancelText {
+		if isButton(update.Message.Text, "menu.cancel") || isButton(update.Message.Caption, "menu.cancel") {
 			s.cancelAddPhotos(ctx, user.ID, update, b)
 			return
 		}
@@ -185,24 +166,48 @@ func (s *TelegramBotService) photoMessageHandler(ctx context.Context, b *bot.Bot
 			articleNumbers = parseArticleNumbers(update.Message.Caption)
 		}
 		if len(articleNumbers) == 0 {
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        "Не удалось найти артикулы в сообщении. Пожалуйста, попробуйте снова.",
-				ReplyMarkup: mainMenu,
-			})
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("upload.no_articles"),
+				ReplyMarkup: mainMenu(ctx),
+			}))
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to send message")
 			}
 			return
 		}
-		s.applyPhotos(ctx, articleNumbers, user.ID, update, b)
+		s.applyPhotos(ctx, articleNumbers, user, update, b)
 		return
 	}
-	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      update.Message.Chat.ID,
-		Text:        "Отправьте еще фото или текст с артикулами. Или фото с подписью",
-		ReplyMarkup: cancelMenu,
-	})
+	params := &bot.SendMessageParams{
+		Text:        tr(ctx).T("upload.more"),
+		ReplyMarkup: cancelMenu(ctx),
+	}
+	// Codes of warehouse labels are proposed as the article numbers
+	if len(codes) > 0 {
+		params.Text = tr(ctx).T("barcode.upload")
+		params.ReplyMarkup = barcodeKeyboard(ctx, barcodeUploadCallbackPrefix, codes)
+	}
+	if _, err = b.SendMessage(ctx, inChat(update.Message, params)); err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+// rejectImage tells the user why the file was not accepted, they may send another one
+func (s *TelegramBotService) rejectImage(ctx context.Context, b *bot.Bot, update *tgmodels.Update, err error) {
+	text := tr(ctx).T("upload.not_image")
+	switch {
+	case errors.Is(err, imaging.ErrFileTooLarge):
+		text = tr(ctx).T("upload.too_large", s.imageProcessor.MaxFileSize()>>20)
+	case errors.Is(err, imaging.ErrTooManyPixels):
+		text = tr(ctx).T("upload.too_many_pixels", imaging.MaxPixels/1_000_000)
+	case !errors.Is(err, imaging.ErrNotImage):
+		log.Error().Err(err).Msg("Failed to process image")
+		text = tr(ctx).T("upload.save_failed")
+	}
+	_, err = b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        text,
+		ReplyMarkup: cancelMenu(ctx),
+	}))
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to send message")
 	}
@@ -211,7 +216,7 @@ func (s *TelegramBotService) photoMessageHandler(ctx context.Context, b *bot.Bot
 func (s *TelegramBotService) applyPhotos(
 	ctx context.Context,
 	articleNumbers []string,
-	userID uuid.UUID,
+	user *models.TelegramUser,
 	update *tgmodels.Update,
 	b *bot.Bot,
 ) {
@@ -219,18 +224,28 @@ func (s *TelegramBotService) applyPhotos(
 
 	var articleNumberModels []models.ArticleNumber
 	for _, articleNumberStr := range articleNumbers {
-		articleNumberModel, err := s.articleRepository.GetOrCreateArticleNumber(articleNumberStr)
+		articleNumberModel, err := s.articleRepository.WithActor(user).GetOrCreateArticleNumber(user.ActiveWorkspaceID, articleNumberStr)
+		if errors.Is(err, models.ErrArticleNumberInTrash) {
+			// The photos stay pending, so the user can restore the number or send another one
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("upload.article_in_trash", articleNumberStr),
+				ReplyMarkup: cancelMenu(ctx),
+			}))
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to send message")
+			}
+			return
+		}
 		if err != nil {
 			log.Error().
 				Err(err).
 				Str("article_number", articleNumberStr).
 				Msg("Failed to get or create article number")
 
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        fmt.Sprintf("Ошибка обработки артикула '%s'. Пожалуйста, попробуйте снова.", articleNumberStr),
-				ReplyMarkup: mainMenu,
-			})
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("upload.article_failed", articleNumberStr),
+				ReplyMarkup: mainMenu(ctx),
+			}))
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to send message")
 			}
@@ -240,29 +255,29 @@ func (s *TelegramBotService) applyPhotos(
 	}
 
 	// Process all photos in state NotApplied
-	photos, err := s.photoRepository.GetUsersPhotosByState(userID, models.PhotoNotApplied)
+	photos, err := s.pendingPhotos(user.ID, update.Message.Chat.ID)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get photos from database")
-		_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:      update.Message.Chat.ID,
-			Text:        "Не удалось загрузить фото. Пожалуйста, попробуйте снова.",
-			ReplyMarkup: mainMenu,
-		})
+		_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+			Text:        tr(ctx).T("upload.photos_failed"),
+			ReplyMarkup: mainMenu(ctx),
+		}))
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to send message")
 		}
 		return
 	}
 
+	photoRepository := s.photoRepository.WithActor(user)
 	for _, photo := range photos {
 		photo.State = models.PhotoApplied
-		err := s.photoRepository.UpdatePhoto(photo)
+		err := photoRepository.UpdatePhoto(photo)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to update photo in database")
 			continue
 		}
 		for _, articleNumber := range articleNumberModels {
-			err := s.photoRepository.AddArticleNumberToPhoto(photo.ID, articleNumber.ID)
+			err := photoRepository.AddArticleNumberToPhoto(photo.ID, articleNumber.ID)
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to add article number to photo")
 				continue
@@ -272,26 +287,21 @@ func (s *TelegramBotService) applyPhotos(
 	}
 
 	if successfulApplies != len(photos) {
-		_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:      update.Message.Chat.ID,
-			Text:        fmt.Sprintf("Не удалось загрузить %d фото. Пожалуйста, попробуйте снова.", len(photos)-successfulApplies),
-			ReplyMarkup: mainMenu,
-		})
+		_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+			Text:        tr(ctx).N("upload.apply_failed", len(photos)-successfulApplies, len(photos)-successfulApplies),
+			ReplyMarkup: mainMenu(ctx),
+		}))
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to send message")
 		}
 	}
-	err = s.userRepository.UpdateByTelegramID(update.Message.From.ID, map[string]interface{}{
-		"state": models.TelegramUserStateDefault,
-	})
-	if err != nil {
+	if err := s.setState(update.Message, models.TelegramUserStateDefault); err != nil {
 		log.Error().Err(err).Msg("Failed to update user")
 	}
-	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      update.Message.Chat.ID,
-		Text:        fmt.Sprintf("Успешно загружено %d фото!", successfulApplies),
-		ReplyMarkup: mainMenu,
-	})
+	_, err = b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        tr(ctx).N("upload.done", successfulApplies, successfulApplies),
+		ReplyMarkup: mainMenu(ctx),
+	}))
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to send message")
 	}
@@ -304,31 +314,45 @@ func (s *TelegramBotService) cancelAddPhotos(
 	b *bot.Bot,
 ) {
 
-	photos, err := s.photoRepository.GetUsersPhotosByState(userID, models.PhotoNotApplied)
+	photos, err := s.pendingPhotos(userID, update.Message.Chat.ID)
 	if err != nil && err != gorm.ErrRecordNotFound {
 		log.Error().Err(err).Msg("Failed to get photos for cleanup")
 		return
 	} else {
+		photoRepository := s.photoRepository.WithActor(userFromContext(ctx))
 		for _, photo := range photos {
-			if err := s.photoRepository.DeletePhoto(photo.ID, s.s3Config.Bucket); err != nil {
+			if err := photoRepository.DiscardPhoto(photo.ID, s.s3Config.Bucket); err != nil {
 				log.Error().Err(err).Str("photo_id", photo.ID.String()).Msg("Failed to delete photo")
 			}
 		}
 	}
 
-	if err := s.userRepository.UpdateByTelegramID(update.Message.From.ID, map[string]interface{}{
-		"state": models.TelegramUserStateDefault,
-	}); err != nil {
+	if err := s.setState(update.Message, models.TelegramUserStateDefault); err != nil {
 		log.Error().Err(err).Msg("Failed to reset user state")
 		return
 	}
 
-	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      update.Message.Chat.ID,
-		Text:        "Добавление фото отменено",
-		ReplyMarkup: mainMenu,
-	})
+	_, err = b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        tr(ctx).T("upload.cancelled"),
+		ReplyMarkup: mainMenu(ctx),
+	}))
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to send cancellation message")
 	}
 }
+
+// pendingPhotos returns photos the user sent to the chat which have no article numbers yet
+func (s *TelegramBotService) pendingPhotos(userID uuid.UUID, chatID int64) ([]*models.Photo, error) {
+	photos, err := s.photoRepository.GetUsersPhotosByState(userID, models.PhotoNotApplied)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := photos[:0]
+	for _, photo := range photos {
+		if photo.ChatID == chatID {
+			pending = append(pending, photo)
+		}
+	}
+	return pending, nil
+}
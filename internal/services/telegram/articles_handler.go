@@ -0,0 +1,264 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+)
+
+const (
+	renameCommand  = "rename"
+	mergeCommand   = "merge"
+	moveCommand    = "move"
+	aliasCommand   = "alias"
+	unaliasCommand = "unalias"
+)
+
+// renameHandler fixes a typo in an article number of the active workspace
+func (s *TelegramBotService) renameHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+	_, args := splitCommand(update.Message.Text)
+	if len(args) != 2 {
+		s.sendText(ctx, b, update, t.T("common.usage", t.T("usage.rename")))
+		return
+	}
+
+	articleNumber, ok := s.findArticleNumber(ctx, b, update, args[0])
+	if !ok {
+		return
+	}
+	_, err := s.articleRepository.WithActor(user).RenameArticleNumber(articleNumber.ID, args[1])
+	switch {
+	case errors.Is(err, appmodels.ErrArticleNumberTaken):
+		s.sendText(ctx, b, update, t.T("articles.taken", args[1], args[0], args[1]))
+	case errors.Is(err, appmodels.ErrArticleNumberInTrash):
+		s.sendText(ctx, b, update, t.T("articles.in_trash", args[1]))
+	case err != nil:
+		log.Error().Err(err).Str("article_number", args[0]).Msg("Failed to rename article number")
+		s.sendText(ctx, b, update, t.T("common.error"))
+	default:
+		s.sendText(ctx, b, update, t.T("articles.renamed", args[0], args[1]))
+	}
+}
+
+// mergeHandler moves all photos of an article number to another one and moves the first to the trash
+func (s *TelegramBotService) mergeHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+	_, args := splitCommand(update.Message.Text)
+	if len(args) != 2 {
+		s.sendText(ctx, b, update, t.T("common.usage", t.T("usage.merge")))
+		return
+	}
+
+	source, target, ok := s.findArticleNumberPair(ctx, b, update, args[0], args[1])
+	if !ok {
+		return
+	}
+	moved, err := s.articleRepository.WithActor(user).MergeArticleNumbers(source.ID, target.ID)
+	if err != nil {
+		log.Error().Err(err).Str("article_number", source.Number).Msg("Failed to merge article numbers")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	s.sendText(ctx, b, update, t.N("articles.merged", moved, source.Number, target.Number, moved))
+}
+
+// moveHandler moves photos picked by their positions or IDs from an article number to another one.
+// Without photos it lists the photos of the first article number to pick from.
+func (s *TelegramBotService) moveHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+	_, args := splitCommand(update.Message.Text)
+	if len(args) < 2 {
+		s.sendText(ctx, b, update, t.T("common.usage", t.T("usage.move")))
+		return
+	}
+
+	source, target, ok := s.findArticleNumberPair(ctx, b, update, args[0], args[1])
+	if !ok {
+		return
+	}
+	withPhotos, err := s.articleRepository.GetArticleNumberWithPhotos(source.ID)
+	if err != nil {
+		log.Error().Err(err).Str("article_number", source.Number).Msg("Failed to get photos for article number")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	var photos []appmodels.Photo
+	for _, photo := range withPhotos.Photos {
+		if photo.State == appmodels.PhotoApplied {
+			photos = append(photos, photo)
+		}
+	}
+	if len(photos) == 0 {
+		s.sendText(ctx, b, update, t.T("search.no_photos", source.Number))
+		return
+	}
+
+	if len(args) == 2 {
+		text := t.T("articles.photos", source.Number)
+		for i := range photos {
+			text += t.T("articles.photo", i+1, shortID(photos[i].ID), describePhotoArticles(t, &photos[i]))
+		}
+		text += t.T("articles.pick", source.Number, target.Number)
+		s.sendText(ctx, b, update, text)
+		return
+	}
+
+	photoIDs, err := catalog.SelectPhotos(photos, args[2:])
+	if err != nil {
+		s.sendText(ctx, b, update, t.T("articles.invalid_photos", err.Error()))
+		return
+	}
+	moved, err := s.articleRepository.WithActor(user).MoveArticleNumberPhotos(source.ID, target.ID, photoIDs)
+	if err != nil {
+		log.Error().Err(err).Str("article_number", source.Number).Msg("Failed to move photos")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	s.sendText(ctx, b, update, t.N("articles.moved", moved, moved, source.Number, target.Number))
+}
+
+// aliasHandler lists the aliases of an article number or adds the given ones.
+// Aliases are supplier codes, EANs or old numbers resolving to the article number in search.
+func (s *TelegramBotService) aliasHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+	_, args := splitCommand(update.Message.Text)
+	if len(args) == 0 {
+		s.sendText(ctx, b, update, t.T("common.usage", t.T("usage.alias")))
+		return
+	}
+
+	articleNumber, ok := s.findArticleNumber(ctx, b, update, args[0])
+	if !ok {
+		return
+	}
+
+	aliases := parseArticleNumbers(strings.Join(args[1:], ","))
+	if len(aliases) == 0 {
+		existing, err := s.articleRepository.GetArticleAliases(articleNumber.ID)
+		if err != nil {
+			log.Error().Err(err).Str("article_number", articleNumber.Number).Msg("Failed to get aliases")
+			s.sendText(ctx, b, update, t.T("common.error"))
+			return
+		}
+		if len(existing) == 0 {
+			s.sendText(ctx, b, update, t.T("aliases.none", articleNumber.Number, articleNumber.Number))
+			return
+		}
+		var list strings.Builder
+		for _, alias := range existing {
+			list.WriteString(t.T("aliases.item", alias.Alias))
+		}
+		s.sendText(ctx, b, update, t.T("aliases.list", articleNumber.Number, list.String()))
+		return
+	}
+
+	// Aliases are added together, so a taken one leaves the article number as it was
+	_, err := s.articleRepository.WithActor(user).AddArticleAliases(articleNumber.ID, aliases)
+	var numberErr *appmodels.ArticleNumberError
+	switch {
+	case errors.As(err, &numberErr) && errors.Is(numberErr, appmodels.ErrArticleNumberTaken):
+		s.sendText(ctx, b, update, t.T("aliases.taken", numberErr.Number))
+		return
+	case errors.As(err, &numberErr) && errors.Is(numberErr, appmodels.ErrArticleNumberInTrash):
+		s.sendText(ctx, b, update, t.T("aliases.in_trash", numberErr.Number))
+		return
+	case err != nil:
+		log.Error().Err(err).Str("article_number", articleNumber.Number).Strs("aliases", aliases).Msg("Failed to add aliases")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	s.sendText(ctx, b, update, t.N("aliases.added", len(aliases), articleNumber.Number, len(aliases)))
+}
+
+// unaliasHandler removes aliases of the active workspace
+func (s *TelegramBotService) unaliasHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+	_, args := splitCommand(update.Message.Text)
+	aliases := parseArticleNumbers(strings.Join(args, ","))
+	if len(aliases) == 0 {
+		s.sendText(ctx, b, update, t.T("common.usage", t.T("usage.unalias")))
+		return
+	}
+
+	var text strings.Builder
+	for _, alias := range aliases {
+		if text.Len() > 0 {
+			text.WriteString("\n")
+		}
+		articleNumber, err := s.articleRepository.WithActor(user).RemoveArticleAlias(user.ActiveWorkspaceID, alias)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			text.WriteString(t.T("aliases.not_found", alias))
+		case err != nil:
+			log.Error().Err(err).Str("alias", alias).Msg("Failed to remove alias")
+			text.WriteString(t.T("common.error"))
+		default:
+			text.WriteString(t.T("aliases.removed", alias, articleNumber.Number))
+		}
+	}
+	s.sendText(ctx, b, update, text.String())
+}
+
+// findArticleNumberPair looks up two different article numbers of the active workspace,
+// telling the user what is wrong otherwise
+func (s *TelegramBotService) findArticleNumberPair(
+	ctx context.Context,
+	b *bot.Bot,
+	update *tgmodels.Update,
+	sourceNumber, targetNumber string,
+) (*appmodels.ArticleNumber, *appmodels.ArticleNumber, bool) {
+	if sourceNumber == targetNumber {
+		s.sendText(ctx, b, update, tr(ctx).T("articles.same"))
+		return nil, nil, false
+	}
+	source, ok := s.findArticleNumber(ctx, b, update, sourceNumber)
+	if !ok {
+		return nil, nil, false
+	}
+	target, ok := s.findArticleNumber(ctx, b, update, targetNumber)
+	if !ok {
+		return nil, nil, false
+	}
+	// Different aliases may name the same article number
+	if source.ID == target.ID {
+		s.sendText(ctx, b, update, tr(ctx).T("articles.same"))
+		return nil, nil, false
+	}
+	return source, target, true
+}
+
+// findArticleNumber looks up an article number of the active workspace by its number or alias,
+// telling the user if there is none
+func (s *TelegramBotService) findArticleNumber(
+	ctx context.Context,
+	b *bot.Bot,
+	update *tgmodels.Update,
+	number string,
+) (*appmodels.ArticleNumber, bool) {
+	articleNumber, err := s.articleRepository.GetByNumber(userFromContext(ctx).ActiveWorkspaceID, number)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.sendText(ctx, b, update, tr(ctx).T("search.not_found", number))
+		return nil, false
+	}
+	if err != nil {
+		log.Error().Err(err).Str("article_number", number).Msg("Failed to find article number")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return nil, false
+	}
+	return articleNumber, true
+}
@@ -0,0 +1,155 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/Conty111/AlfredoBot/internal/i18n"
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+)
+
+const auditCommand = "audit"
+
+// auditLimit caps the number of changes shown by /audit
+const auditLimit = 20
+
+const auditTimeFormat = "02.01.2006 15:04"
+
+// auditHandler shows the latest catalog changes. The changes may be narrowed down to
+// an author given as @username or @telegram_id, an action and an article number of
+// the active workspace, in any order.
+func (s *TelegramBotService) auditHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+	_, args := splitCommand(update.Message.Text)
+
+	filter := appmodels.AuditLogFilter{Limit: auditLimit}
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "@") && filter.ActorTelegramID == 0:
+			actor, err := s.findUser(strings.TrimPrefix(arg, "@"))
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				s.sendText(ctx, b, update, t.T("common.user_not_found", arg))
+				return
+			}
+			if err != nil {
+				log.Error().Err(err).Str("user", arg).Msg("Failed to find user")
+				s.sendText(ctx, b, update, t.T("common.error"))
+				return
+			}
+			filter.ActorTelegramID = actor.TelegramID
+		case appmodels.IsValidAuditAction(arg) && filter.Action == "":
+			filter.Action = arg
+		case filter.TargetID == uuid.Nil:
+			articleNumber, err := s.articleRepository.GetByNumber(user.ActiveWorkspaceID, arg)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				s.sendText(ctx, b, update, t.T("search.not_found", arg))
+				return
+			}
+			if err != nil {
+				log.Error().Err(err).Str("article_number", arg).Msg("Failed to find article number")
+				s.sendText(ctx, b, update, t.T("common.error"))
+				return
+			}
+			filter.TargetID = articleNumber.ID
+		default:
+			s.sendText(ctx, b, update, t.T("common.usage", t.T("usage.audit")))
+			return
+		}
+	}
+
+	entries, err := s.auditRepository.GetAuditLogs(filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get audit log")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	if len(entries) == 0 {
+		s.sendText(ctx, b, update, t.T("audit.empty"))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(t.T("audit.title"))
+	for _, entry := range entries {
+		text.WriteString(t.T("audit.item",
+			entry.CreatedAt.Format(auditTimeFormat),
+			describeAuditActor(t, entry),
+			t.T("audit.actions."+entry.Action),
+			s.describeAuditTarget(t, entry)))
+	}
+	s.sendText(ctx, b, update, text.String())
+}
+
+func describeAuditActor(t *i18n.Localizer, entry *appmodels.AuditLog) string {
+	switch {
+	case entry.ActorID == uuid.Nil:
+		return t.T("audit.cli")
+	case entry.ActorUsername != "":
+		return "@" + entry.ActorUsername
+	}
+	return strconv.FormatInt(entry.ActorTelegramID, 10)
+}
+
+// describeAuditTarget names what was changed using the snapshots of the entry
+func (s *TelegramBotService) describeAuditTarget(t *i18n.Localizer, entry *appmodels.AuditLog) string {
+	switch {
+	case entry.UserID != uuid.Nil:
+		var before, after appmodels.RoleSnapshot
+		decodeSnapshot(entry.Before, &before)
+		decodeSnapshot(entry.After, &after)
+
+		name := shortID(entry.UserID)
+		if user, err := s.userRepository.GetByID(entry.UserID); err == nil {
+			name = displayUser(user)
+		}
+		return t.T("audit.role", name, before.Role, after.Role)
+	case entry.ArticleNumberID != uuid.Nil && entry.PhotoID != uuid.Nil:
+		var link appmodels.LinkSnapshot
+		decodeSnapshot(entry.Before, &link)
+		decodeSnapshot(entry.After, &link)
+		return t.T("audit.link", link.Number, shortID(entry.PhotoID))
+	case entry.Action == appmodels.AuditAliasAdded || entry.Action == appmodels.AuditAliasRemoved:
+		var alias appmodels.AliasSnapshot
+		decodeSnapshot(entry.Before, &alias)
+		decodeSnapshot(entry.After, &alias)
+		return t.T("audit.alias", alias.Number, alias.Alias)
+	case entry.ArticleNumberID != uuid.Nil:
+		var before, after appmodels.ArticleNumberSnapshot
+		decodeSnapshot(entry.Before, &before)
+		decodeSnapshot(entry.After, &after)
+		if before.Number != "" && after.Number != "" && before.Number != after.Number {
+			return t.T("audit.renamed", before.Number, after.Number)
+		}
+		if after.Number != "" {
+			return after.Number
+		}
+		return before.Number
+	}
+	return t.T("audit.photo", shortID(entry.PhotoID))
+}
+
+// decodeSnapshot reads a JSON snapshot of the audit log, empty snapshots leave v as is
+func decodeSnapshot(snapshot string, v interface{}) {
+	if snapshot == "" {
+		return
+	}
+	if err := json.Unmarshal([]byte(snapshot), v); err != nil {
+		log.Warn().Err(err).Msg("Failed to decode audit snapshot")
+	}
+}
+
+// shortID is the beginning of a UUID, enough to tell records apart in a chat
+func shortID(id uuid.UUID) string {
+	return id.String()[:8]
+}
@@ -0,0 +1,157 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/barcode"
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
+)
+
+const (
+	barcodeCallbackPrefix       = "barcode:"
+	barcodeUploadCallbackPrefix = barcodeCallbackPrefix + "upload:"
+	barcodeSearchCallbackPrefix = barcodeCallbackPrefix + "search:"
+	// maxCallbackData is the limit of Telegram for the data of an inline button
+	maxCallbackData = 64
+)
+
+// decodeBarcodes reads the codes of a photo which fit into the data of an inline button,
+// longer codes are not article numbers anyway. Only images too large to decode are an error.
+func decodeBarcodes(data []byte) ([]string, error) {
+	codes, err := barcode.Decode(data)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return nil, err
+	}
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to decode barcodes")
+		return nil, nil
+	}
+
+	var fitting []string
+	for _, code := range codes {
+		if len(barcodeUploadCallbackPrefix)+len(code) <= maxCallbackData && !strings.ContainsAny(code, "\r\n") {
+			fitting = append(fitting, code)
+		}
+	}
+	return fitting, nil
+}
+
+// barcodeKeyboard has a button confirming each code
+func barcodeKeyboard(ctx context.Context, prefix string, codes []string) *tgmodels.InlineKeyboardMarkup {
+	keyboard := make([][]tgmodels.InlineKeyboardButton, 0, len(codes))
+	for _, code := range codes {
+		keyboard = append(keyboard, []tgmodels.InlineKeyboardButton{
+			{Text: tr(ctx).T("barcode.use_button", code), CallbackData: prefix + code},
+		})
+	}
+	return &tgmodels.InlineKeyboardMarkup{InlineKeyboard: keyboard}
+}
+
+// searchByBarcode proposes the codes found on a photo sent in the search mode as search queries
+func (s *TelegramBotService) searchByBarcode(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	data, err := s.downloadMessagePhoto(ctx, b, update.Message)
+	if errors.Is(err, imaging.ErrFileTooLarge) {
+		s.rejectImage(ctx, b, update, err)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to download photo for barcode search")
+		_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+			Text:        tr(ctx).T("upload.download_failed"),
+			ReplyMarkup: cancelMenu(ctx),
+		}))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to send message")
+		}
+		return
+	}
+
+	params := &bot.SendMessageParams{
+		Text:        tr(ctx).T("barcode.not_found"),
+		ReplyMarkup: cancelMenu(ctx),
+	}
+	codes, err := decodeBarcodes(data)
+	if err != nil {
+		s.rejectImage(ctx, b, update, err)
+		return
+	}
+	if len(codes) > 0 {
+		params.Text = tr(ctx).T("barcode.search")
+		params.ReplyMarkup = barcodeKeyboard(ctx, barcodeSearchCallbackPrefix, codes)
+	}
+	if _, err := b.SendMessage(ctx, inChat(update.Message, params)); err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+// barcodeCallbackHandler uses a confirmed code as if the user typed it: as the article number
+// of the photos being uploaded or as a search query
+func (s *TelegramBotService) barcodeCallbackHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	query := update.CallbackQuery
+	user := userFromContext(ctx)
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to answer callback query")
+	}
+	if query.Message.Message == nil {
+		return
+	}
+
+	// The button answers the message of the bot, the code goes on as a message of the user
+	message := *query.Message.Message
+	message.From = &query.From
+	message.Photo = nil
+	message.Document = nil
+	message.Caption = ""
+
+	switch {
+	case strings.HasPrefix(query.Data, barcodeUploadCallbackPrefix) && user.State == appmodels.TelegramUserStateUploading:
+		message.Text = strings.TrimPrefix(query.Data, barcodeUploadCallbackPrefix)
+		s.photoMessageHandler(ctx, b, &tgmodels.Update{Message: &message})
+	case strings.HasPrefix(query.Data, barcodeSearchCallbackPrefix) && user.State == appmodels.TelegramUserStateSearching:
+		message.Text = strings.TrimPrefix(query.Data, barcodeSearchCallbackPrefix)
+		s.handleArticleNumberSearch(ctx, b, &tgmodels.Update{Message: &message})
+	default:
+		// The upload or the search is already over
+		_, err := b.SendMessage(ctx, inCallbackChat(query, &bot.SendMessageParams{
+			Text:        tr(ctx).T("barcode.expired"),
+			ReplyMarkup: mainMenu(ctx),
+		}))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to send message")
+		}
+	}
+}
+
+// downloadMessagePhoto downloads the largest size of the photo or the image document of the message
+func (s *TelegramBotService) downloadMessagePhoto(ctx context.Context, b *bot.Bot, message *tgmodels.Message) ([]byte, error) {
+	var fileID string
+	switch {
+	case len(message.Photo) > 0:
+		fileID = message.Photo[len(message.Photo)-1].FileID
+	case message.Document != nil:
+		fileID = message.Document.FileID
+	default:
+		return nil, fmt.Errorf("message has no photo")
+	}
+
+	file, err := b.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	limit := s.imageProcessor.MaxFileSize()
+	if limit > 0 && file.FileSize > limit {
+		return nil, imaging.ErrFileTooLarge
+	}
+	return s.downloadFile(ctx, b, file, limit)
+}
@@ -0,0 +1,136 @@
+package telegram
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+)
+
+const startCommand = "start"
+const addCommand = "add"
+const searchCommand = "search"
+const cancelCommand = "cancel"
+const helpCommand = "help"
+const myCommand = "my"
+
+// myArticlesLimit caps the number of article numbers listed by /my
+const myArticlesLimit = 50
+
+// menuCommands are shown in the command menu of Telegram clients, described in their language
+var menuCommands = []string{startCommand, addCommand, searchCommand, myCommand, cancelCommand, languageCommand, helpCommand}
+
+// registerCommands publishes menuCommands with SetMyCommands for every language.
+// The default language is also registered for clients in languages without translations.
+// Failures are only logged, the commands work without the menu as well.
+func (s *TelegramBotService) registerCommands(ctx context.Context) {
+	languageCodes := append([]string{""}, messages.Locales()...)
+	for _, languageCode := range languageCodes {
+		t := messages.Localizer(languageCode)
+		commands := make([]tgmodels.BotCommand, 0, len(menuCommands))
+		for _, command := range menuCommands {
+			commands = append(commands, tgmodels.BotCommand{
+				Command:     command,
+				Description: t.T("commands." + command),
+			})
+		}
+
+		_, err := s.bot.SetMyCommands(ctx, &bot.SetMyCommandsParams{
+			Commands:     commands,
+			LanguageCode: languageCode,
+		})
+		if err != nil {
+			log.Warn().Err(err).Str("language_code", languageCode).Msg("Failed to register bot commands")
+		}
+	}
+}
+
+// isCommand reports whether the message starts with a /command
+func isCommand(message *tgmodels.Message) bool {
+	return len(message.Entities) > 0 &&
+		message.Entities[0].Type == tgmodels.MessageEntityTypeBotCommand &&
+		message.Entities[0].Offset == 0
+}
+
+// searchCommandHandler searches right away for article numbers given as arguments,
+// without arguments it asks for them like the search button
+func (s *TelegramBotService) searchCommandHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	_, args := splitCommand(update.Message.Text)
+	articleNumbers := parseArticleNumbers(strings.Join(args, " "))
+	if len(articleNumbers) == 0 {
+		s.searchByArticleNumberHandler(ctx, b, update)
+		return
+	}
+
+	// A one-shot search keeps the dialog the user is in
+	menu := mainMenu(ctx)
+	if user := userFromContext(ctx); user.State != appmodels.TelegramUserStateDefault {
+		menu = cancelMenu(ctx)
+	}
+	s.searchArticles(ctx, b, update, articleNumbers, menu)
+}
+
+func (s *TelegramBotService) cancelHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	user := userFromContext(ctx)
+	switch user.State {
+	case appmodels.TelegramUserStateUploading:
+		s.cancelAddPhotos(ctx, user.ID, update, b)
+	case appmodels.TelegramUserStateSearching:
+		s.cancelSearchPhotos(ctx, update, b)
+	case appmodels.TelegramUserStateSupport:
+		s.leaveSupport(ctx, b, update)
+	case appmodels.TelegramUserStateImporting:
+		s.leaveImport(ctx, b, update, tr(ctx).T("import.cancelled"))
+	default:
+		s.sendText(ctx, b, update, tr(ctx).T("cancel.nothing"))
+	}
+}
+
+// myHandler lists article numbers of photos the user uploaded to the active workspace
+func (s *TelegramBotService) myHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	user := userFromContext(ctx)
+	photos, err := s.photoRepository.GetUsersPhotosByState(user.ID, appmodels.PhotoApplied)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to get user photos")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+
+	photoCounts := map[string]int{}
+	for _, photo := range photos {
+		if photo.WorkspaceID != user.ActiveWorkspaceID {
+			continue
+		}
+		for _, articleNumber := range photo.ArticleNumbers {
+			photoCounts[articleNumber.Number]++
+		}
+	}
+	if len(photoCounts) == 0 {
+		s.sendText(ctx, b, update, tr(ctx).T("my.empty"))
+		return
+	}
+
+	numbers := make([]string, 0, len(photoCounts))
+	for number := range photoCounts {
+		numbers = append(numbers, number)
+	}
+	sort.Strings(numbers)
+
+	var text strings.Builder
+	text.WriteString(tr(ctx).T("my.title", len(numbers)))
+	for i, number := range numbers {
+		if i == myArticlesLimit {
+			rest := len(numbers) - myArticlesLimit
+			text.WriteString(tr(ctx).N("my.more", rest, rest))
+			break
+		}
+		count := photoCounts[number]
+		text.WriteString(tr(ctx).N("my.item", count, number, count))
+	}
+	s.sendText(ctx, b, update, text.String())
+}
@@ -0,0 +1,217 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+const reloadContentCommand = "reload_content"
+
+// Menu buttons which may be placed in the menu layout
+const (
+	searchButton    = "search"
+	addButton       = "add"
+	workspaceButton = "workspace"
+	helpButton      = "help"
+	supportButton   = "support"
+	usersButton     = "users"
+)
+
+var menuButtons = []string{searchButton, addButton, workspaceButton, helpButton, supportButton, usersButton}
+
+// defaultMenuLayout is used when the content file has no menu
+var defaultMenuLayout = [][]string{
+	{searchButton, addButton},
+	{workspaceButton},
+	{helpButton, supportButton},
+	{usersButton},
+}
+
+// contentFile is the YAML file operators use to customize the bot
+type contentFile struct {
+	// Menu lists rows of the main menu, buttons the user may not use are hidden
+	Menu [][]string `yaml:"menu"`
+	// Help and Support are templates by locale
+	Help    map[string]string `yaml:"help"`
+	Support map[string]string `yaml:"support"`
+}
+
+// content is the parsed and validated content file
+type content struct {
+	menu    [][]string
+	help    map[string]*template.Template
+	support map[string]*template.Template
+}
+
+// contentData is available to help and support templates
+type contentData struct {
+	FirstName   string
+	Username    string
+	BotName     string
+	BotUsername string
+	// Commands lists menu buttons and commands available to the user, only filled for help
+	Commands string
+}
+
+var defaultContent = &content{menu: defaultMenuLayout}
+
+// loadContent reads the content file, an empty path gives the built-in content
+func loadContent(path string) (*content, error) {
+	if path == "" {
+		return defaultContent, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content file: %w", err)
+	}
+
+	var file contentFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse content file %s: %w", path, err)
+	}
+
+	c, err := newContent(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func newContent(file contentFile) (*content, error) {
+	c := &content{menu: file.Menu}
+	if len(c.menu) == 0 {
+		c.menu = defaultMenuLayout
+	}
+
+	seen := map[string]bool{}
+	for i, row := range c.menu {
+		if len(row) == 0 {
+			return nil, fmt.Errorf("menu row %d is empty", i+1)
+		}
+		for _, button := range row {
+			if !slices.Contains(menuButtons, button) {
+				return nil, fmt.Errorf("unknown menu button %q, expected one of: %s", button, strings.Join(menuButtons, ", "))
+			}
+			if seen[button] {
+				return nil, fmt.Errorf("menu button %q is used twice", button)
+			}
+			seen[button] = true
+		}
+	}
+
+	var err error
+	if c.help, err = parseTemplates("help", file.Help); err != nil {
+		return nil, err
+	}
+	if c.support, err = parseTemplates("support", file.Support); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseTemplates compiles texts by locale and checks them against sample data,
+// so typos in field names are reported at startup instead of to users
+func parseTemplates(name string, texts map[string]string) (map[string]*template.Template, error) {
+	templates := map[string]*template.Template{}
+	for locale, text := range texts {
+		if !slices.Contains(messages.Locales(), locale) {
+			return nil, fmt.Errorf("%s: unknown locale %q, expected one of: %s",
+				name, locale, strings.Join(messages.Locales(), ", "))
+		}
+		tmpl, err := template.New(name + "." + locale).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := tmpl.Execute(&bytes.Buffer{}, contentData{}); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		templates[locale] = tmpl
+	}
+	return templates, nil
+}
+
+// render executes the template of the locale, falling back to the default locale.
+// It reports false when the content has no such text and the built-in one should be used.
+func render(templates map[string]*template.Template, locale string, data contentData) (string, bool) {
+	tmpl, ok := templates[locale]
+	if !ok {
+		tmpl, ok = templates[messages.DefaultLocale()]
+	}
+	if !ok {
+		return "", false
+	}
+
+	var text strings.Builder
+	if err := tmpl.Execute(&text, data); err != nil {
+		log.Error().Err(err).Str("template", tmpl.Name()).Msg("Failed to render content")
+		return "", false
+	}
+	return strings.TrimSpace(text.String()), true
+}
+
+// ReloadContent reads the content file again.
+// Invalid content is rejected and the bot keeps the previous one.
+func (s *TelegramBotService) ReloadContent() error {
+	c, err := loadContent(s.contentConfig.File)
+	if err != nil {
+		return err
+	}
+	s.content.Store(c)
+	log.Info().Str("file", s.contentConfig.File).Msg("Content loaded")
+	return nil
+}
+
+// contentMiddleware pins the content for the whole update, so a reload in the middle
+// of handling does not mix menus of the old and the new layout
+func (s *TelegramBotService) contentMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		next(context.WithValue(ctx, contentContextKey, s.content.Load()), b, update)
+	}
+}
+
+// contentFromContext returns the content pinned by contentMiddleware or the built-in one
+func contentFromContext(ctx context.Context) *content {
+	if c, ok := ctx.Value(contentContextKey).(*content); ok && c != nil {
+		return c
+	}
+	return defaultContent
+}
+
+// contentDataFor fills template data with the sender of the message and the bot
+func (s *TelegramBotService) contentDataFor(message *tgmodels.Message) contentData {
+	data := contentData{
+		BotName:     s.botUser.FirstName,
+		BotUsername: s.botUser.Username,
+	}
+	if message.From != nil {
+		data.FirstName = message.From.FirstName
+		data.Username = message.From.Username
+	}
+	return data
+}
+
+func (s *TelegramBotService) reloadContentHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if err := s.ReloadContent(); err != nil {
+		log.Error().Err(err).Msg("Failed to reload content")
+		s.sendText(ctx, b, update, tr(ctx).T("content.reload_failed", err.Error()))
+		return
+	}
+	// The reply already uses the new menu
+	ctx = context.WithValue(ctx, contentContextKey, s.content.Load())
+	s.sendText(ctx, b, update, tr(ctx).T("content.reloaded"))
+}
@@ -0,0 +1,1364 @@
+package telegram_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/repositories/memory"
+	"github.com/Conty111/AlfredoBot/internal/services/barcode"
+	"github.com/Conty111/AlfredoBot/internal/services/telegram"
+	"github.com/Conty111/AlfredoBot/internal/services/telegram/telegramtest"
+)
+
+const replyTimeout = 5 * time.Second
+
+var mainMenuKeyboard = [][]string{
+	{"Поиск по артикулу 🔎", "Добавить товар ®️"},
+	{"Рабочее пространство 🏬"},
+	{"Help ❓", "Support 🆘"},
+}
+
+var cancelKeyboard = [][]string{
+	{"Отмена"},
+}
+
+// conversation drives the bot through the fake Bot API server on behalf of a user.
+// Messages go to the private chat with the user unless a group chat is set.
+type conversation struct {
+	server   *telegramtest.Server
+	user     tgmodels.User
+	chat     *tgmodels.Chat
+	threadID int
+}
+
+// send pushes an update and waits for the given number of bot replies to the conversation's chat
+func (c *conversation) send(update *tgmodels.Update, replies int) []telegramtest.Request {
+	GinkgoHelper()
+
+	seen := len(c.replies())
+	c.server.PushUpdate(update)
+	Eventually(func() int { return len(c.replies()) }, replyTimeout).
+		Should(BeNumerically(">=", seen+replies))
+
+	// Give the handler a moment to misbehave with unexpected extra replies
+	Consistently(c.replies, 100*time.Millisecond).Should(HaveLen(seen + replies))
+
+	return c.replies()[seen:]
+}
+
+// replies returns all messages the bot sent to the conversation's chat
+func (c *conversation) replies() []telegramtest.Request {
+	chatID := c.user.ID
+	if c.chat != nil {
+		chatID = c.chat.ID
+	}
+
+	var replies []telegramtest.Request
+	for _, request := range c.server.Sent() {
+		if request.ChatID() == chatID {
+			replies = append(replies, request)
+		}
+	}
+	return replies
+}
+
+func (c *conversation) say(text string, replies int) []telegramtest.Request {
+	GinkgoHelper()
+	return c.send(c.inChat(telegramtest.NewTextUpdate(c.user, text)), replies)
+}
+
+func (c *conversation) sendPhoto(fileID, caption string, replies int) []telegramtest.Request {
+	GinkgoHelper()
+	return c.send(c.inChat(telegramtest.NewPhotoUpdate(c.user, fileID, caption)), replies)
+}
+
+// reply answers a message the bot sent with the request
+func (c *conversation) reply(to telegramtest.Request, text string, replies int) []telegramtest.Request {
+	GinkgoHelper()
+	return c.send(c.server.ReplyTo(c.inChat(telegramtest.NewTextUpdate(c.user, text)), to), replies)
+}
+
+// as builds the same conversation on behalf of another member of the group
+func (c *conversation) as(user tgmodels.User) *conversation {
+	return &conversation{server: c.server, user: user, chat: c.chat, threadID: c.threadID}
+}
+
+func (c *conversation) inChat(update *tgmodels.Update) *tgmodels.Update {
+	if c.chat == nil {
+		return update
+	}
+	return telegramtest.InChat(update, *c.chat, c.threadID)
+}
+
+// testPhoto encodes a small JPEG whose pixels are drawn from the seed, so seeds give distinct photos
+func testPhoto(seed string) []byte {
+	GinkgoHelper()
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = seed[i%len(seed)]
+	}
+	var buf bytes.Buffer
+	Expect(jpeg.Encode(&buf, img, nil)).To(Succeed())
+	return buf.Bytes()
+}
+
+func mustParseURL(rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	Expect(err).To(BeNil())
+	return u
+}
+
+var _ = Describe("Conversation", func() {
+	var (
+		server     *telegramtest.Server
+		tgConfig   *configs.TelegramConfig
+		access     *configs.AccessConfig
+		content    *configs.ContentConfig
+		support    *configs.SupportConfig
+		watermark  *configs.WatermarkConfig
+		images     *configs.ImagesConfig
+		s3Client   *memory.S3Client
+		users      *memory.TelegramUserRepository
+		workspaces *memory.WorkspaceRepository
+		photos     *memory.PhotoRepository
+		articles   *memory.ArticleNumberRepository
+		chats      *memory.ChatRepository
+		service    *telegram.TelegramBotService
+		alice      *conversation
+	)
+
+	BeforeEach(func() {
+		server = telegramtest.NewServer()
+		DeferCleanup(server.Close)
+
+		tgConfig = &configs.TelegramConfig{Token: server.Token, APIURL: server.URL}
+		access = &configs.AccessConfig{}
+		content = &configs.ContentConfig{}
+		support = &configs.SupportConfig{}
+		watermark = nil
+		images = nil
+	})
+
+	JustBeforeEach(func() {
+		db := memory.NewDatabase()
+		s3Client = memory.NewS3Client()
+		users = memory.NewTelegramUserRepository(db)
+		workspaces = memory.NewWorkspaceRepository(db)
+		photos = memory.NewPhotoRepository(db, s3Client)
+		articles = memory.NewArticleNumberRepository(db)
+		chats = memory.NewChatRepository(db)
+
+		var err error
+		service, err = telegram.NewTelegramBotService(
+			&configs.Configuration{
+				Telegram:  tgConfig,
+				S3:        &configs.S3Config{Bucket: "test-bucket"},
+				Access:    access,
+				Workspace: &configs.WorkspaceConfig{Default: "default"},
+				Content:   content,
+				Support:   support,
+				Watermark: watermark,
+				Images:    images,
+			},
+			users,
+			photos,
+			articles,
+			memory.NewInviteCodeRepository(db),
+			workspaces,
+			chats,
+			memory.NewSupportTicketRepository(db),
+			memory.NewAuditLogRepository(db),
+			s3Client,
+		)
+		Expect(err).To(BeNil())
+
+		Expect(service.Start(context.Background())).To(Succeed())
+		DeferCleanup(service.Stop)
+
+		alice = &conversation{
+			server: server,
+			user:   tgmodels.User{ID: 1001, FirstName: "Alice", Username: "alice", LanguageCode: "ru"},
+		}
+	})
+
+	// withRole pre-registers the user in the default workspace the way the role CLI does
+	withRole := func(c *conversation, role string) {
+		user := &models.TelegramUser{
+			TelegramID: c.user.ID,
+			Username:   c.user.Username,
+			Role:       role,
+		}
+		Expect(users.CreateUser(user)).To(Succeed())
+
+		workspace, err := workspaces.GetOrCreateWorkspace("default")
+		Expect(err).To(BeNil())
+		Expect(workspaces.AddMember(workspace.ID, user.ID)).To(Succeed())
+	}
+
+	It("should greet the user with the main menu", func() {
+		replies := alice.say("/start", 1)
+
+		Expect(replies[0].Method).To(Equal("sendMessage"))
+		Expect(replies[0].ChatID()).To(Equal(alice.user.ID))
+		Expect(replies[0].Text()).To(Equal("Привет, Alice! 👋"))
+		Expect(replies[0].ReplyKeyboard()).To(Equal(mainMenuKeyboard))
+	})
+
+	It("should show help", func() {
+		replies := alice.say("Help ❓", 1)
+
+		Expect(replies[0].Text()).To(ContainSubstring("Доступные команды"))
+		Expect(replies[0].ReplyKeyboard()).To(Equal(mainMenuKeyboard))
+	})
+
+	It("should register the command menu", func() {
+		Eventually(func() []string {
+			var languages []string
+			for _, request := range server.Requests() {
+				if request.Method == "setMyCommands" {
+					languages = append(languages, request.Params["language_code"])
+				}
+			}
+			return languages
+		}, replyTimeout).Should(ContainElement("en"))
+	})
+
+	Describe("languages", func() {
+		englishMenuKeyboard := [][]string{
+			{"Search by article 🔎", "Add item ®️"},
+			{"Workspace 🏬"},
+			{"Help ❓", "Support 🆘"},
+		}
+
+		It("should talk in the language of the Telegram client", func() {
+			alice.user.LanguageCode = "en-GB"
+
+			replies := alice.say("/start", 1)
+			Expect(replies[0].Text()).To(Equal("Hi, Alice! 👋"))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(englishMenuKeyboard))
+
+			replies = alice.say("Search by article 🔎", 1)
+			Expect(replies[0].Text()).To(Equal("Please enter the article number to search for:"))
+			Expect(replies[0].ReplyKeyboard()).To(Equal([][]string{{"Cancel"}}))
+			Expect(alice.say("Cancel", 1)[0].Text()).To(Equal("Search cancelled"))
+		})
+
+		It("should keep the language picked with /language", func() {
+			replies := alice.say("/language", 1)
+			Expect(replies[0].Text()).To(Equal("Выберите язык:"))
+			englishButton := replies[0].InlineKeyboard()[2][0]
+			Expect(englishButton.Text).To(Equal("English"))
+
+			replies = alice.send(telegramtest.NewCallbackUpdate(alice.user, englishButton.CallbackData), 1)
+			Expect(replies[0].Text()).To(Equal("Language: English"))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(englishMenuKeyboard))
+			Expect(alice.say("/start", 1)[0].Text()).To(Equal("Hi, Alice! 👋"))
+
+			// Buttons of keyboards sent before the change keep working
+			Expect(alice.say("Поиск по артикулу 🔎", 1)[0].Text()).To(Equal("Please enter the article number to search for:"))
+			Expect(alice.say("Отмена", 1)[0].Text()).To(Equal("Search cancelled"))
+
+			autoButton := alice.say("/language", 1)[0].InlineKeyboard()[0][0]
+			replies = alice.send(telegramtest.NewCallbackUpdate(alice.user, autoButton.CallbackData), 1)
+			Expect(replies[0].Text()).To(Equal("Язык выбирается по настройкам Telegram."))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(mainMenuKeyboard))
+		})
+	})
+
+	Describe("content file", func() {
+		const customContent = `
+menu:
+  - [help, search]
+  - [support]
+help:
+  ru: "{{.FirstName}}, я {{.BotName}} (@{{.BotUsername}}).\n{{.Commands}}"
+support:
+  en: "Write to help@example.com"
+`
+
+		BeforeEach(func() {
+			content.File = filepath.Join(GinkgoT().TempDir(), "content.yaml")
+			Expect(os.WriteFile(content.File, []byte(customContent), 0o600)).To(Succeed())
+		})
+
+		It("should use the menu layout and templates of the file", func() {
+			replies := alice.say("/start", 1)
+			Expect(replies[0].ReplyKeyboard()).To(Equal([][]string{
+				{"Help ❓", "Поиск по артикулу 🔎"},
+				{"Support 🆘"},
+			}))
+
+			replies = alice.say("Help ❓", 1)
+			Expect(replies[0].Text()).To(Equal("Alice, я Alfredo (@alfredo_test_bot).\n" +
+				"- Help ❓ - показать справку\n" +
+				"- Поиск по артикулу 🔎 - найти товар по его артикулу\n" +
+				"- Support 🆘 - связаться с поддержкой\n\n" +
+				"Команды:\n" +
+				"- /add - добавить фото товара\n" +
+				"- /labels [pdf|png] артикул1, артикул2 - распечатать этикетки с QR-кодами, открывающими товары в боте\n" +
+				"- /search артикул1, артикул2 - сразу найти товары по артикулам\n" +
+				"- /my - мои артикулы\n" +
+				"- /cancel - отменить текущее действие\n" +
+				"- /language - выбрать язык"))
+
+			// Locales without a text fall back to the default one, then to the built-in text
+			Expect(alice.say("Support 🆘", 1)[0].Text()).To(HavePrefix("Свяжитесь с нашей поддержкой"))
+			alice.user.LanguageCode = "en"
+			Expect(alice.say("Support 🆘", 1)[0].Text()).To(Equal("Write to help@example.com"))
+		})
+
+		It("should let admins reload the file and keep the previous content when it is invalid", func() {
+			withRole(alice, models.TelegramUserRoleAdmin)
+
+			Expect(os.WriteFile(content.File, []byte("menu:\n  - [search, users]\n"), 0o600)).To(Succeed())
+			replies := alice.say("/reload_content", 1)
+			Expect(replies[0].Text()).To(Equal("Справка, контакты поддержки и меню обновлены."))
+			Expect(replies[0].ReplyKeyboard()).To(Equal([][]string{{"Поиск по артикулу 🔎", "Пользователи 👥"}}))
+
+			Expect(os.WriteFile(content.File, []byte("menu:\n  - [search, shop]\n"), 0o600)).To(Succeed())
+			replies = alice.say("/reload_content", 1)
+			Expect(replies[0].Text()).To(HavePrefix("Не удалось обновить содержимое, оставлено прежнее: "))
+			Expect(replies[0].Text()).To(ContainSubstring(`unknown menu button "shop"`))
+			Expect(replies[0].ReplyKeyboard()).To(Equal([][]string{{"Поиск по артикулу 🔎", "Пользователи 👥"}}))
+		})
+
+		It("should refuse reloads by non-admins", func() {
+			Expect(alice.say("/reload_content", 1)[0].Text()).To(Equal("Недостаточно прав для этого действия."))
+		})
+	})
+
+	It("should refuse to start with an invalid content file", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "content.yaml")
+		Expect(os.WriteFile(file, []byte("help:\n  ru: \"{{.Name}}\"\n"), 0o600)).To(Succeed())
+
+		_, err := telegram.NewTelegramBotService(
+			&configs.Configuration{
+				Telegram: tgConfig,
+				S3:       &configs.S3Config{Bucket: "test-bucket"},
+				Content:  &configs.ContentConfig{File: file},
+			},
+			users,
+			nil, nil, nil, workspaces, nil, nil, nil, s3Client,
+		)
+		Expect(err).To(MatchError(ContainSubstring("can't evaluate field Name")))
+	})
+
+	It("should show support contacts", func() {
+		replies := alice.say("Support 🆘", 1)
+		Expect(replies[0].Text()).To(HavePrefix("Свяжитесь с нашей поддержкой"))
+		Expect(replies[0].ReplyKeyboard()).To(Equal(mainMenuKeyboard))
+	})
+
+	Describe("support tickets", func() {
+		var staff *conversation
+
+		BeforeEach(func() {
+			support.ChatID = -2001
+		})
+
+		JustBeforeEach(func() {
+			staffChat := telegramtest.NewGroupChat(-2001, "Staff", false)
+			staff = &conversation{
+				server: server,
+				user:   tgmodels.User{ID: 1002, FirstName: "Bob", Username: "bob", LanguageCode: "ru"},
+				chat:   &staffChat,
+			}
+		})
+
+		It("should relay messages to the staff chat and answers back", func() {
+			Expect(server.AddFile("photo-1", testPhoto("jpeg-data"))).To(Succeed())
+
+			replies := alice.say("Support 🆘", 1)
+			Expect(replies[0].Text()).To(ContainSubstring("Сотрудники поддержки ответят здесь же"))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(cancelKeyboard))
+
+			replies = alice.say("Где мой заказ?", 1)
+			Expect(replies[0].Text()).To(Equal("Обращение #1 создано. Сотрудники поддержки ответят здесь же."))
+			Eventually(staff.replies, replyTimeout).Should(HaveLen(1))
+			question := staff.replies()[0]
+			Expect(question.Text()).To(Equal("🆕 Новое обращение #1 от Alice (@alice, 1001):\nГде мой заказ?"))
+
+			alice.sendPhoto("photo-1", "Вот коробка", 0)
+			Eventually(staff.replies, replyTimeout).Should(HaveLen(2))
+			photo := staff.replies()[1]
+			Expect(photo.Method).To(Equal("sendPhoto"))
+			Expect(photo.Params["photo"]).To(Equal("photo-1"))
+			Expect(photo.Text()).To(Equal("📩 #1 Alice (@alice, 1001):\nВот коробка"))
+
+			// Staff replies go back to the user, staff messages without a reply stay in the group
+			staff.say("Кто возьмет?", 0)
+			staff.reply(photo, "Заказ в пути", 0)
+			Eventually(alice.replies, replyTimeout).Should(HaveLen(3))
+			Expect(alice.replies()[2].Text()).To(Equal("💬 Поддержка, обращение #1:\nЗаказ в пути"))
+
+			replies = alice.say("Отмена", 1)
+			Expect(replies[0].Text()).To(HavePrefix("Вы вышли из режима поддержки."))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(mainMenuKeyboard))
+
+			Expect(staff.say("/tickets", 1)[0].Text()).To(HavePrefix("Открытые обращения:\n#1 - @alice, обновлено "))
+			history := staff.say("/ticket #1", 1)[0].Text()
+			Expect(history).To(HavePrefix("Обращение #1 от @alice, открыто:"))
+			Expect(history).To(ContainSubstring("@alice:\nГде мой заказ?"))
+			Expect(history).To(ContainSubstring("@alice:\n📷 фото Вот коробка"))
+			Expect(history).To(ContainSubstring("Поддержка (@bob):\nЗаказ в пути"))
+
+			replies = staff.reply(question, "/close", 1)
+			Expect(replies[0].Text()).To(Equal("Обращение #1 закрыто."))
+			Eventually(alice.replies, replyTimeout).Should(HaveLen(5))
+			Expect(alice.replies()[4].Text()).To(HavePrefix("Обращение #1 закрыто."))
+
+			replies = staff.reply(question, "Что-то еще?", 1)
+			Expect(replies[0].Text()).To(Equal("Обращение #1 закрыто, ответ не отправлен."))
+			Expect(staff.say("/tickets", 1)[0].Text()).To(Equal("Открытых обращений нет."))
+
+			// The next question opens a new ticket
+			alice.say("Support 🆘", 1)
+			Expect(alice.say("Еще вопрос", 1)[0].Text()).To(HavePrefix("Обращение #2 создано."))
+		})
+
+		It("should accept staff commands in the staff chat only", func() {
+			Expect(alice.say("/tickets", 1)[0].Text()).To(Equal("Команда работает только в чате поддержки."))
+			Expect(staff.say("/close", 1)[0].Text()).To(HavePrefix("Использование: /close номер"))
+			Expect(staff.say("/ticket 7", 1)[0].Text()).To(Equal("Обращение #7 не найдено."))
+		})
+	})
+
+	Describe("adding and searching items", func() {
+		JustBeforeEach(func() {
+			Expect(server.AddFile("photo-1", testPhoto("jpeg-data"))).To(Succeed())
+
+			replies := alice.say("Добавить товар ®️", 1)
+			Expect(replies[0].Text()).To(HavePrefix("Пожалуйста, отправьте все фото товара"))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(cancelKeyboard))
+
+			replies = alice.sendPhoto("photo-1", "", 2)
+			Expect(replies[0].Text()).To(Equal("Фото успешно сохранено!"))
+			Expect(replies[1].Text()).To(HavePrefix("Отправьте еще фото"))
+			Expect(replies[1].ReplyKeyboard()).To(Equal(cancelKeyboard))
+		})
+
+		It("should store photos and find them by any of their article numbers", func() {
+			replies := alice.say("1.2345, 6.7890", 1)
+			Expect(replies[0].Text()).To(Equal("Успешно загружено 1 фото!"))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(mainMenuKeyboard))
+			Expect(s3Client.Objects()).To(HaveLen(1))
+
+			replies = alice.say("Поиск по артикулу 🔎", 1)
+			Expect(replies[0].Text()).To(Equal("Пожалуйста, введите артикул товара для поиска:"))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(cancelKeyboard))
+
+			replies = alice.say("6.7890", 3)
+			Expect(replies[0].Method).To(Equal("sendPhoto"))
+			Expect(replies[0].Text()).To(Equal("1.2345, 6.7890"))
+			Expect(replies[0].Files["photo"]).To(Equal(testPhoto("jpeg-data")))
+			Expect(replies[1].Text()).To(Equal("Артикул '6.7890': найдено фото - 1"))
+			Expect(replies[2].Text()).To(Equal("Поиск завершен!"))
+			Expect(replies[2].ReplyKeyboard()).To(Equal(mainMenuKeyboard))
+		})
+
+		It("should share presigned links to found photos", func() {
+			alice.say("1.2345", 1)
+			alice.say("Поиск по артикулу 🔎", 1)
+			results := alice.say("1.2345", 3)
+
+			photoButton := results[0].InlineKeyboard()[0][0]
+			Expect(photoButton.Text).To(Equal("🔗 Поделиться"))
+			replies := alice.send(telegramtest.NewCallbackUpdate(alice.user, photoButton.CallbackData), 1)
+			Expect(replies[0].Text()).To(HavePrefix("🔗 Ссылка на фото"))
+			Expect(replies[0].Text()).To(ContainSubstring("memory://test-bucket/"))
+
+			articleButton := results[1].InlineKeyboard()[0][0]
+			Expect(articleButton.Text).To(Equal("🔗 Поделиться всеми фото"))
+			replies = alice.send(telegramtest.NewCallbackUpdate(alice.user, articleButton.CallbackData), 1)
+			Expect(replies[0].Text()).To(HavePrefix("🔗 Ссылки на фото артикула '1.2345'"))
+			Expect(replies[0].Text()).To(ContainSubstring("1. memory://test-bucket/"))
+		})
+
+		Context("with a watermark", func() {
+			BeforeEach(func() {
+				logo := image.NewRGBA(image.Rect(0, 0, 40, 20))
+				draw.Draw(logo, logo.Bounds(), image.Black, image.Point{}, draw.Src)
+				var data bytes.Buffer
+				Expect(png.Encode(&data, logo)).To(Succeed())
+				path := filepath.Join(GinkgoT().TempDir(), "logo.png")
+				Expect(os.WriteFile(path, data.Bytes(), 0o644)).To(Succeed())
+
+				watermark = &configs.WatermarkConfig{
+					Image:    path,
+					Position: configs.WatermarkBottomRight,
+					Opacity:  0.5,
+					Scale:    0.5,
+					Roles:    []string{models.TelegramUserRoleViewer},
+				}
+			})
+
+			It("should send watermarked photos to the configured roles and originals to others", func() {
+				alice.say("1.2345", 1)
+				vera := &conversation{
+					server: server,
+					user:   tgmodels.User{ID: 1003, FirstName: "Vera", Username: "vera", LanguageCode: "ru"},
+				}
+				withRole(vera, models.TelegramUserRoleViewer)
+
+				vera.say("Поиск по артикулу 🔎", 1)
+				replies := vera.say("1.2345", 3)
+				Expect(replies[0].Method).To(Equal("sendPhoto"))
+				Expect(replies[0].Files["photo"]).NotTo(Equal(testPhoto("jpeg-data")))
+				Expect(replies[0].Files["photo"]).To(ContainSubstring("alfredo-watermark:"))
+				Expect(s3Client.Objects()).To(HaveLen(2))
+
+				// The watermarked photo is made once and shared from the storage
+				shareButton := replies[0].InlineKeyboard()[0][0]
+				replies = vera.send(telegramtest.NewCallbackUpdate(vera.user, shareButton.CallbackData), 1)
+				link, err := url.Parse(strings.Split(replies[0].Text(), "\n")[1])
+				Expect(err).To(BeNil())
+				Expect(s3Client.Objects()).To(HaveLen(2))
+				Expect(s3Client.Objects()).To(ContainElement(link.Host + link.Path))
+				shared, err := s3Client.DownloadFile(context.Background(), link.Host, strings.TrimPrefix(link.Path, "/"))
+				Expect(err).To(BeNil())
+				Expect(io.ReadAll(shared)).To(ContainSubstring("alfredo-watermark:"))
+
+				alice.say("Поиск по артикулу 🔎", 1)
+				replies = alice.say("1.2345", 3)
+				Expect(replies[0].Files["photo"]).To(Equal(testPhoto("jpeg-data")))
+			})
+
+			It("should send watermarked photos to groups whatever the role of the sender", func() {
+				alice.say("1.2345", 1)
+				workspace, err := workspaces.GetOrCreateWorkspace("default")
+				Expect(err).To(BeNil())
+				groupChat := telegramtest.NewGroupChat(-1001, "Shop", false)
+				Expect(chats.BindGroupChat(&models.GroupChat{ChatID: groupChat.ID, Title: groupChat.Title, WorkspaceID: workspace.ID})).
+					To(Succeed())
+				group := &conversation{server: server, user: alice.user, chat: &groupChat}
+
+				replies := group.say("/search 1.2345", 3)
+				Expect(replies[0].Method).To(Equal("sendPhoto"))
+				Expect(replies[0].Files["photo"]).To(ContainSubstring("alfredo-watermark:"))
+
+				shareButton := replies[0].InlineKeyboard()[0][0]
+				replies = group.send(group.inChat(telegramtest.NewCallbackUpdate(alice.user, shareButton.CallbackData)), 1)
+				link, err := url.Parse(strings.Split(replies[0].Text(), "\n")[1])
+				Expect(err).To(BeNil())
+				shared, err := s3Client.DownloadFile(context.Background(), link.Host, strings.TrimPrefix(link.Path, "/"))
+				Expect(err).To(BeNil())
+				Expect(io.ReadAll(shared)).To(ContainSubstring("alfredo-watermark:"))
+			})
+		})
+
+		It("should propose barcodes of photos as article numbers and search queries", func() {
+			matrix, err := oned.NewEAN13Writer().Encode("4006381333931", gozxing.BarcodeFormat_EAN_13, 400, 150, nil)
+			Expect(err).To(BeNil())
+			label := image.NewGray(image.Rect(0, 0, 600, 350))
+			draw.Draw(label, label.Bounds(), image.White, image.Point{}, draw.Src)
+			draw.Draw(label, image.Rect(100, 100, 500, 250), matrix, image.Point{}, draw.Src)
+			var data bytes.Buffer
+			Expect(jpeg.Encode(&data, label, nil)).To(Succeed())
+			Expect(server.AddFile("label-1", data.Bytes())).To(Succeed())
+
+			replies := alice.sendPhoto("label-1", "", 2)
+			Expect(replies[1].Text()).To(HavePrefix("На фото найден код"))
+			codeButton := replies[1].InlineKeyboard()[0][0]
+			Expect(codeButton.Text).To(Equal("✅ 4006381333931"))
+			replies = alice.send(telegramtest.NewCallbackUpdate(alice.user, codeButton.CallbackData), 1)
+			Expect(replies[0].Text()).To(Equal("Успешно загружено 2 фото!"))
+
+			alice.say("Поиск по артикулу 🔎", 1)
+			replies = alice.sendPhoto("photo-1", "", 1)
+			Expect(replies[0].Text()).To(HavePrefix("На фото не найдено штрихкодов"))
+			replies = alice.sendPhoto("label-1", "", 1)
+			Expect(replies[0].Text()).To(Equal("На фото найден код. Нажмите на него, чтобы найти товар."))
+			codeButton = replies[0].InlineKeyboard()[0][0]
+			replies = alice.send(telegramtest.NewCallbackUpdate(alice.user, codeButton.CallbackData), 4)
+			Expect(replies[2].Text()).To(Equal("Артикул '4006381333931': найдено фото - 2"))
+
+			replies = alice.send(telegramtest.NewCallbackUpdate(alice.user, codeButton.CallbackData), 1)
+			Expect(replies[0].Text()).To(Equal("Это действие уже завершено."))
+		})
+
+		Context("with photos stored as PNG", func() {
+			BeforeEach(func() {
+				images = &configs.ImagesConfig{Format: configs.ImageFormatPNG}
+			})
+
+			It("should name stored and sent photos after their format", func() {
+				Expect(alice.say("1.2345", 1)[0].Text()).To(Equal("Успешно загружено 1 фото!"))
+				Expect(s3Client.Objects()).To(HaveLen(1))
+				Expect(s3Client.Objects()[0]).To(HaveSuffix(".png"))
+
+				alice.say("Поиск по артикулу 🔎", 1)
+				replies := alice.say("1.2345", 3)
+				Expect(replies[0].Method).To(Equal("sendPhoto"))
+				Expect(replies[0].FileNames["photo"]).To(HaveSuffix(".png"))
+				Expect(replies[0].Files["photo"]).To(HavePrefix("\x89PNG"))
+			})
+		})
+
+		Context("with a file size limit", func() {
+			BeforeEach(func() {
+				images = &configs.ImagesConfig{MaxFileSize: 1 << 20}
+			})
+
+			It("should refuse large files and images before searching their barcodes", func() {
+				Expect(server.AddFile("large-1", make([]byte, 2<<20))).To(Succeed())
+				// A PNG header of a 100000x100000 image, decoding it would take 40 GB
+				header := binary.BigEndian.AppendUint32(nil, 100_000)
+				header = binary.BigEndian.AppendUint32(header, 100_000)
+				header = append(header, 8, 0, 0, 0, 0)
+				chunk := append([]byte("IHDR"), header...)
+				bomb := append([]byte("\x89PNG\r\n\x1a\n"), binary.BigEndian.AppendUint32(nil, uint32(len(header)))...)
+				bomb = append(bomb, chunk...)
+				bomb = binary.BigEndian.AppendUint32(bomb, crc32.ChecksumIEEE(chunk))
+				Expect(server.AddFile("bomb-1", bomb)).To(Succeed())
+
+				alice.say("Отмена", 1)
+				alice.say("Поиск по артикулу 🔎", 1)
+				replies := alice.sendPhoto("large-1", "", 1)
+				Expect(replies[0].Text()).To(HavePrefix("Файл больше 1 МБ"))
+				replies = alice.sendPhoto("bomb-1", "", 1)
+				Expect(replies[0].Text()).To(HavePrefix("Изображение больше 100 мегапикселей"))
+			})
+
+			It("should refuse large files while downloading when Telegram omits their sizes", func() {
+				server.HideFileSizes()
+				Expect(server.AddFile("large-1", make([]byte, 2<<20))).To(Succeed())
+
+				replies := alice.sendPhoto("large-1", "", 1)
+				Expect(replies[0].Text()).To(HavePrefix("Файл больше 1 МБ"))
+				Expect(s3Client.Objects()).To(HaveLen(1))
+
+				alice.say("Отмена", 1)
+				alice.say("Поиск по артикулу 🔎", 1)
+				replies = alice.sendPhoto("large-1", "", 1)
+				Expect(replies[0].Text()).To(HavePrefix("Файл больше 1 МБ"))
+			})
+		})
+
+		It("should drop pending photos on cancel", func() {
+			replies := alice.say("Отмена", 1)
+			Expect(replies[0].Text()).To(Equal("Добавление фото отменено"))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(mainMenuKeyboard))
+			Expect(s3Client.Objects()).To(BeEmpty())
+		})
+
+		It("should ask to restore article numbers in the trash before uploading with them", func() {
+			workspace, err := workspaces.GetByName("default")
+			Expect(err).To(BeNil())
+			articleNumber, err := articles.GetOrCreateArticleNumber(workspace.ID, "1.2345")
+			Expect(err).To(BeNil())
+			Expect(articles.DeleteArticleNumber(articleNumber.ID)).To(Succeed())
+
+			replies := alice.say("1.2345, 6.7890", 1)
+			Expect(replies[0].Text()).To(Equal("Артикул 1.2345 в корзине. Восстановите его через /trash или отправьте другие артикулы."))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(cancelKeyboard))
+
+			Expect(articles.RestoreArticleNumber(articleNumber.ID)).To(Succeed())
+			replies = alice.say("1.2345, 6.7890", 1)
+			Expect(replies[0].Text()).To(Equal("Успешно загружено 1 фото!"))
+			found, err := articles.GetArticleNumberWithPhotos(articleNumber.ID)
+			Expect(err).To(BeNil())
+			Expect(found.Photos).To(HaveLen(1))
+		})
+
+		It("should refuse files which are not images", func() {
+			Expect(server.AddFile("notes-1", []byte("not a photo"))).To(Succeed())
+			replies := alice.send(telegramtest.NewDocumentUpdate(alice.user, "notes-1", "notes.txt", "text/plain"), 1)
+			Expect(replies[0].Text()).To(HavePrefix("Файл не является изображением."))
+			Expect(replies[0].ReplyKeyboard()).To(Equal(cancelKeyboard))
+			Expect(s3Client.Objects()).To(HaveLen(1))
+		})
+
+		It("should search right away with /search and list article numbers with /my", func() {
+			alice.say("1.2345", 1)
+
+			replies := alice.say("/search 1.2345", 3)
+			Expect(replies[0].Method).To(Equal("sendPhoto"))
+			Expect(replies[1].Text()).To(Equal("Артикул '1.2345': найдено фото - 1"))
+			Expect(replies[2].Text()).To(Equal("Поиск завершен!"))
+			Expect(replies[2].ReplyKeyboard()).To(Equal(mainMenuKeyboard))
+
+			replies = alice.say("/my", 1)
+			Expect(replies[0].Text()).To(Equal("Ваши артикулы (1):\n- 1.2345: фото - 1"))
+		})
+
+		It("should cancel dialogs with /cancel", func() {
+			replies := alice.say("/cancel", 1)
+			Expect(replies[0].Text()).To(Equal("Добавление фото отменено"))
+
+			alice.say("/search", 1)
+			replies = alice.say("/cancel", 1)
+			Expect(replies[0].Text()).To(Equal("Поиск отменен"))
+
+			replies = alice.say("/cancel", 1)
+			Expect(replies[0].Text()).To(Equal("Нечего отменять."))
+		})
+
+		Context("with a local Bot API server", func() {
+			BeforeEach(func() {
+				server.UseLocalMode(GinkgoT().TempDir())
+				tgConfig.LocalMode = true
+			})
+
+			It("should read uploaded files from the local disk", func() {
+				Expect(s3Client.Objects()).To(HaveLen(1))
+			})
+		})
+
+		Context("with a separate file server", func() {
+			var fileRequests atomic.Int32
+
+			BeforeEach(func() {
+				fileRequests.Store(0)
+				fileServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					fileRequests.Add(1)
+					httputil.NewSingleHostReverseProxy(mustParseURL(server.URL)).ServeHTTP(w, r)
+				}))
+				DeferCleanup(fileServer.Close)
+
+				tgConfig.FileURL = fileServer.URL
+			})
+
+			It("should download files from the file URL", func() {
+				Expect(s3Client.Objects()).To(HaveLen(1))
+				Expect(fileRequests.Load()).To(Equal(int32(1)))
+			})
+		})
+	})
+
+	Describe("roles", func() {
+		var bob *conversation
+
+		JustBeforeEach(func() {
+			bob = &conversation{
+				server: server,
+				user:   tgmodels.User{ID: 1002, FirstName: "Bob", Username: "bob", LanguageCode: "ru"},
+			}
+		})
+
+		It("should hide and refuse uploads for viewers", func() {
+			withRole(alice, models.TelegramUserRoleViewer)
+
+			replies := alice.say("/start", 1)
+			Expect(replies[0].ReplyKeyboard()).To(Equal([][]string{
+				{"Поиск по артикулу 🔎"},
+				{"Рабочее пространство 🏬"},
+				{"Help ❓", "Support 🆘"},
+			}))
+
+			replies = alice.say("Добавить товар ®️", 1)
+			Expect(replies[0].Text()).To(Equal("Недостаточно прав для этого действия."))
+		})
+
+		It("should block banned users", func() {
+			withRole(alice, models.TelegramUserRoleBanned)
+
+			replies := alice.say("Поиск по артикулу 🔎", 1)
+			Expect(replies[0].Text()).To(Equal("Доступ к боту заблокирован."))
+			Expect(replies[0].Params["reply_markup"]).To(ContainSubstring("remove_keyboard"))
+		})
+
+		It("should let admins grant and revoke roles", func() {
+			withRole(alice, models.TelegramUserRoleAdmin)
+			bob.say("/start", 1)
+
+			replies := alice.say("/grant @bob banned", 1)
+			Expect(replies[0].Text()).To(Equal("Пользователю @bob назначена роль banned."))
+			Expect(replies[0].ReplyKeyboard()).To(ContainElement([]string{"Пользователи 👥"}))
+			Expect(bob.say("/start", 1)[0].Text()).To(Equal("Доступ к боту заблокирован."))
+
+			replies = alice.say("/revoke 1002", 1)
+			Expect(replies[0].Text()).To(Equal("Пользователю @bob назначена роль viewer."))
+
+			replies = alice.say("Пользователи 👥", 1)
+			Expect(replies[0].Text()).To(ContainSubstring("admin: @alice"))
+			Expect(replies[0].Text()).To(ContainSubstring("viewer: @bob"))
+		})
+
+		It("should show admins who changed the catalog and roles", func() {
+			withRole(alice, models.TelegramUserRoleAdmin)
+			Expect(server.AddFile("photo-1", testPhoto("jpeg-data"))).To(Succeed())
+			bob.say("Добавить товар ®️", 1)
+			bob.sendPhoto("photo-1", "", 2)
+			bob.say("1.2345", 1)
+			alice.say("/grant @bob viewer", 1)
+
+			replies := alice.say("/audit", 1)
+			lines := strings.Split(replies[0].Text(), "\n")
+			Expect(lines).To(HaveLen(6))
+			Expect(lines[0]).To(Equal("Последние изменения:"))
+			Expect(lines[1]).To(HaveSuffix(" @alice - роль изменена: @bob: uploader → viewer"))
+			Expect(lines[2]).To(MatchRegexp(` @bob - артикул привязан: 1\.2345 ↔ фото [0-9a-f]{8}$`))
+			Expect(lines[3]).To(MatchRegexp(` @bob - фото сохранено: фото [0-9a-f]{8}$`))
+			Expect(lines[4]).To(HaveSuffix(" @bob - артикул создан: 1.2345"))
+			Expect(lines[5]).To(MatchRegexp(` @bob - фото загружено: фото [0-9a-f]{8}$`))
+
+			replies = alice.say("/audit 1.2345 @bob", 1)
+			Expect(strings.Split(replies[0].Text(), "\n")).To(HaveLen(3))
+
+			replies = alice.say("/audit photo.deleted", 1)
+			Expect(replies[0].Text()).To(Equal("Изменений не найдено."))
+
+			replies = alice.say("/audit 9.9999", 1)
+			Expect(replies[0].Text()).To(Equal("Артикул '9.9999' не найден в базе данных."))
+
+			replies = bob.say("/audit", 1)
+			Expect(replies[0].Text()).To(Equal("Недостаточно прав для этого действия."))
+		})
+
+		It("should let admins restore deleted photos and article numbers", func() {
+			withRole(alice, models.TelegramUserRoleAdmin)
+			Expect(server.AddFile("photo-1", testPhoto("jpeg-data"))).To(Succeed())
+			bob.say("Добавить товар ®️", 1)
+			bob.sendPhoto("photo-1", "", 2)
+			bob.say("1.2345", 1)
+			Expect(alice.say("/trash", 1)[0].Text()).To(Equal("Корзина пуста."))
+
+			workspace, err := workspaces.GetByName("default")
+			Expect(err).To(BeNil())
+			articleNumber, err := articles.GetByNumber(workspace.ID, "1.2345")
+			Expect(err).To(BeNil())
+			found, err := articles.GetArticleNumberWithPhotos(articleNumber.ID)
+			Expect(err).To(BeNil())
+			Expect(photos.DeletePhoto(found.Photos[0].ID)).To(Succeed())
+			Expect(articles.DeleteArticleNumber(articleNumber.ID)).To(Succeed())
+
+			replies := alice.say("/trash", 1)
+			lines := strings.Split(replies[0].Text(), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[0]).To(Equal("Корзина:"))
+			Expect(lines[1]).To(MatchRegexp(`^🖼 фото [0-9a-f]{8} \(без артикулов\), удалено `))
+			Expect(lines[2]).To(HavePrefix("🔢 артикул 1.2345, удален "))
+			buttons := replies[0].InlineKeyboard()
+			Expect(buttons).To(HaveLen(2))
+			Expect(buttons[1][0].Text).To(Equal("♻️ 1.2345"))
+
+			Expect(bob.send(telegramtest.NewCallbackUpdate(bob.user, buttons[1][0].CallbackData), 0)).To(BeEmpty())
+			Expect(s3Client.Objects()).To(HaveLen(1))
+
+			replies = alice.send(telegramtest.NewCallbackUpdate(alice.user, buttons[1][0].CallbackData), 1)
+			Expect(replies[0].Text()).To(Equal("Артикул 1.2345 восстановлен."))
+			replies = alice.send(telegramtest.NewCallbackUpdate(alice.user, buttons[0][0].CallbackData), 1)
+			Expect(replies[0].Text()).To(MatchRegexp(`^Фото [0-9a-f]{8} восстановлено\.$`))
+			replies = alice.send(telegramtest.NewCallbackUpdate(alice.user, buttons[0][0].CallbackData), 1)
+			Expect(replies[0].Text()).To(Equal("Запись уже восстановлена или удалена окончательно."))
+
+			Expect(alice.say("/trash", 1)[0].Text()).To(Equal("Корзина пуста."))
+			bob.say("Поиск по артикулу 🔎", 1)
+			Expect(bob.say("1.2345", 3)[0].Method).To(Equal("sendPhoto"))
+		})
+
+		It("should let admins import a catalog from a ZIP archive", func() {
+			var archive bytes.Buffer
+			writer := zip.NewWriter(&archive)
+			for name, data := range map[string]string{
+				"catalog/manifest.csv": "file,article_numbers\nred.jpg,\"1.2345, 6.7890\"\nmissing.jpg,1.2345\n",
+				"catalog/red.jpg":      string(testPhoto("red")),
+			} {
+				file, err := writer.Create(name)
+				Expect(err).To(BeNil())
+				_, err = file.Write([]byte(data))
+				Expect(err).To(BeNil())
+			}
+			Expect(writer.Close()).To(Succeed())
+			Expect(server.AddFile("catalog-1", archive.Bytes())).To(Succeed())
+			upload := func(c *conversation) []telegramtest.Request {
+				return c.send(telegramtest.NewDocumentUpdate(c.user, "catalog-1", "catalog.zip", "application/zip"), 2)
+			}
+
+			Expect(bob.say("/import", 1)[0].Text()).To(Equal("Недостаточно прав для этого действия."))
+
+			withRole(alice, models.TelegramUserRoleAdmin)
+			Expect(alice.say("/import", 1)[0].Text()).To(HavePrefix("Отправьте ZIP-архив"))
+			Expect(alice.say("catalog", 1)[0].Text()).To(Equal("Отправьте ZIP-архив файлом или нажмите «Отмена»."))
+
+			replies := upload(alice)
+			Expect(replies[0].Text()).To(Equal("Архив получен, импортирую 2 строки…"))
+			Expect(replies[1].Method).To(Equal("sendDocument"))
+			Expect(replies[1].Text()).To(HavePrefix("Импорт завершен. Строк: 2, новых фото: 1, дополнено артикулами: 0, без изменений: 0, с ошибками: 1."))
+			Expect(replies[1].ReplyKeyboard()).To(ContainElement([]string{"Пользователи 👥"}))
+			Expect(string(replies[1].Files["document"])).To(ContainSubstring("missing.jpg,1.2345,failed,,file not found"))
+			Expect(s3Client.Objects()).To(HaveLen(1))
+
+			bob.say("Поиск по артикулу 🔎", 1)
+			Expect(bob.say("6.7890", 3)[0].Method).To(Equal("sendPhoto"))
+
+			alice.say("/import", 1)
+			replies = upload(alice)
+			Expect(replies[1].Text()).To(HavePrefix("Импорт завершен. Строк: 2, новых фото: 0, дополнено артикулами: 0, без изменений: 1, с ошибками: 1."))
+			Expect(s3Client.Objects()).To(HaveLen(1))
+		})
+
+		It("should let admins export photos to a ZIP archive", func() {
+			Expect(server.AddFile("photo-1", testPhoto("jpeg-data"))).To(Succeed())
+			bob.say("Добавить товар ®️", 1)
+			bob.sendPhoto("photo-1", "", 2)
+			bob.say("1.2345", 1)
+			Expect(bob.say("/export", 1)[0].Text()).To(Equal("Недостаточно прав для этого действия."))
+
+			withRole(alice, models.TelegramUserRoleAdmin)
+			replies := alice.say("/export @bob", 2)
+			Expect(replies[0].Text()).To(Equal("Собираю архив…"))
+			Expect(replies[1].Method).To(Equal("sendDocument"))
+			Expect(replies[1].Text()).To(Equal("Выгружено 1 фото."))
+
+			data := replies[1].Files["document"]
+			archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			Expect(err).To(BeNil())
+			Expect(archive.File).To(HaveLen(3))
+			Expect(archive.File[0].Name).To(Equal("photos/1.2345.jpg"))
+
+			replies = alice.say("/export 01.01.2020 31.12.2020", 2)
+			Expect(replies[1].Text()).To(Equal("Нет фото для выгрузки."))
+			replies = alice.say("/export someday", 1)
+			Expect(replies[0].Text()).To(ContainSubstring("/export [@username|@ID]"))
+		})
+
+		It("should let admins print a PDF lookbook", func() {
+			photo := image.NewGray(image.Rect(0, 0, 320, 240))
+			draw.Draw(photo, photo.Bounds(), image.White, image.Point{}, draw.Src)
+			var data bytes.Buffer
+			Expect(jpeg.Encode(&data, photo, nil)).To(Succeed())
+			Expect(server.AddFile("photo-1", data.Bytes())).To(Succeed())
+			bob.say("Добавить товар ®️", 1)
+			bob.sendPhoto("photo-1", "", 2)
+			bob.say("1.2345, 6.7890", 1)
+			Expect(bob.say("/lookbook", 1)[0].Text()).To(Equal("Недостаточно прав для этого действия."))
+
+			withRole(alice, models.TelegramUserRoleAdmin)
+			replies := alice.say("/lookbook 1.2345 @bob +aliases +date", 2)
+			Expect(replies[0].Text()).To(Equal("Собираю каталог…"))
+			Expect(replies[1].Method).To(Equal("sendDocument"))
+			Expect(replies[1].Text()).To(Equal("Каталог из 1 артикула."))
+			Expect(string(replies[1].Files["document"])).To(HavePrefix("%PDF"))
+
+			Expect(alice.say("/lookbook", 2)[1].Text()).To(Equal("Каталог из 2 артикулов."))
+			Expect(alice.say("/lookbook 01.01.2020 31.12.2020", 2)[1].Text()).To(Equal("Нет фото для каталога."))
+			Expect(alice.say("/lookbook NOPE", 1)[0].Text()).To(Equal("Артикул 'NOPE' не найден в базе данных."))
+			Expect(alice.say("/lookbook +price", 1)[0].Text()).To(ContainSubstring("/lookbook [артикул1, артикул2]"))
+		})
+
+		It("should refuse exports and lookbooks larger than the public Bot API accepts", func() {
+			withRole(alice, models.TelegramUserRoleAdmin)
+			uploader, err := users.GetByTelegramID(alice.user.ID)
+			Expect(err).To(BeNil())
+			workspace, err := workspaces.GetOrCreateWorkspace("default")
+			Expect(err).To(BeNil())
+			articleNumber, err := articles.GetOrCreateArticleNumber(workspace.ID, "1.2345")
+			Expect(err).To(BeNil())
+
+			// Noise does not compress, each photo takes about a megabyte in archives and lookbooks.
+			// Lookbooks embed identical images once, so every photo gets its own noise.
+			noise := image.NewGray(image.Rect(0, 0, 1200, 1200))
+			random := rand.New(rand.NewSource(1))
+			for size := 0; size <= 55<<20; {
+				random.Read(noise.Pix)
+				var data bytes.Buffer
+				Expect(jpeg.Encode(&data, noise, &jpeg.Options{Quality: 85})).To(Succeed())
+				size += data.Len()
+
+				photo := &models.Photo{UserID: uploader.ID, S3Key: uuid.New(), State: models.PhotoApplied, WorkspaceID: workspace.ID}
+				Expect(photos.CreatePhoto(photo)).To(Succeed())
+				Expect(photos.AddArticleNumberToPhoto(photo.ID, articleNumber.ID)).To(Succeed())
+				Expect(photos.UploadPhotoToS3(context.Background(), uploader.ID, photo.S3Key, photo.Format, "test-bucket", &data)).To(Succeed())
+			}
+
+			// The default config talks to a Bot API server which is not in the local mode
+			replies := alice.say("/export", 2)
+			Expect(replies[1].Method).To(Equal("sendMessage"))
+			Expect(replies[1].Text()).To(MatchRegexp(`^Архив занимает \d+ МБ, а бот может отправить файл не больше 50 МБ`))
+			// Photos are scaled for lookbooks one by one, which takes longer than a reply
+			seen := len(alice.replies())
+			Expect(alice.say("/lookbook", 1)[0].Text()).To(Equal("Собираю каталог…"))
+			Eventually(alice.replies, time.Minute).Should(HaveLen(seen + 2))
+			reply := alice.replies()[seen+1]
+			Expect(reply.Method).To(Equal("sendMessage"))
+			Expect(reply.Text()).To(MatchRegexp(`^Каталог занимает \d+ МБ, а бот может отправить файл не больше 50 МБ`))
+		})
+
+		It("should let admins rename, merge and move article numbers", func() {
+			for i, number := range []string{"1.2354", "1.2354", "1.2345"} {
+				fileID := fmt.Sprintf("photo-%d", i+1)
+				Expect(server.AddFile(fileID, testPhoto(fileID))).To(Succeed())
+				bob.say("Добавить товар ®️", 1)
+				bob.sendPhoto(fileID, "", 2)
+				bob.say(number, 1)
+			}
+			Expect(bob.say("/merge 1.2354 1.2345", 1)[0].Text()).To(Equal("Недостаточно прав для этого действия."))
+
+			withRole(alice, models.TelegramUserRoleAdmin)
+			Expect(alice.say("/rename 1.2354 1.2345", 1)[0].Text()).
+				To(Equal("Артикул 1.2345 уже есть. Чтобы объединить их, отправьте /merge 1.2354 1.2345"))
+
+			lines := strings.Split(alice.say("/move 1.2354 1.2345", 1)[0].Text(), "\n")
+			Expect(lines).To(HaveLen(5))
+			Expect(lines[0]).To(Equal("Фото артикула 1.2354:"))
+			Expect(lines[2]).To(MatchRegexp(`^2\. фото [0-9a-f]{8} \(1\.2354\)$`))
+			Expect(alice.say("/move 1.2354 1.2345 2", 1)[0].Text()).To(Equal("1 фото перенесено из 1.2354 в 1.2345."))
+			Expect(alice.say("/move 1.2354 1.2345 7", 1)[0].Text()).To(Equal("Не удалось выбрать фото: no photo 7"))
+
+			Expect(alice.say("/merge 1.2354 1.2345", 1)[0].Text()).To(Equal("Артикул 1.2354 объединен с 1.2345, перенесено 1 фото."))
+			Expect(alice.say("/rename 1.2345 1.2346", 1)[0].Text()).To(Equal("Артикул 1.2345 переименован в 1.2346."))
+			Expect(alice.say("/rename 1.2346 1.2354", 1)[0].Text()).To(HavePrefix("Артикул 1.2354 в корзине."))
+
+			bob.say("Поиск по артикулу 🔎", 1)
+			replies := bob.say("1.2346", 5)
+			Expect(replies[3].Text()).To(ContainSubstring("1.2346"))
+			for _, reply := range replies[:3] {
+				Expect(reply.Text()).To(Equal("1.2346"))
+			}
+
+			// The merged-away number still works for uploads and searches
+			Expect(server.AddFile("photo-4", testPhoto("photo-4"))).To(Succeed())
+			bob.say("Добавить товар ®️", 1)
+			bob.sendPhoto("photo-4", "", 2)
+			Expect(bob.say("1.2354", 1)[0].Text()).To(Equal("Успешно загружено 1 фото!"))
+			bob.say("Поиск по артикулу 🔎", 1)
+			replies = bob.say("1.2354", 6)
+			Expect(replies[4].Text()).To(ContainSubstring("1.2346"))
+			for _, reply := range replies[:4] {
+				Expect(reply.Text()).To(Equal("1.2346"))
+			}
+		})
+
+		It("should let admins add aliases found by search", func() {
+			Expect(server.AddFile("photo-1", testPhoto("photo-1"))).To(Succeed())
+			Expect(server.AddFile("photo-2", testPhoto("photo-2"))).To(Succeed())
+			bob.say("Добавить товар ®️", 1)
+			bob.sendPhoto("photo-1", "", 2)
+			bob.say("1.2345", 1)
+			Expect(bob.say("/alias 1.2345 SUP-77", 1)[0].Text()).To(Equal("Недостаточно прав для этого действия."))
+
+			withRole(alice, models.TelegramUserRoleAdmin)
+			Expect(alice.say("/alias 1.2345", 1)[0].Text()).
+				To(Equal("У артикула 1.2345 нет кодов. Добавьте их: /alias 1.2345 код1 код2"))
+			Expect(alice.say("/alias 1.2345 SUP-77, 4006381333931", 1)[0].Text()).
+				To(Equal("Артикулу 1.2345 добавлено 2 кода. Поиск по ним найдет фото артикула."))
+			Expect(alice.say("/alias 1.2345 SUP-88, 1.2345", 1)[0].Text()).
+				To(Equal("1.2345 уже используется как артикул или код другого артикула."))
+			Expect(alice.say("/alias SUP-77", 1)[0].Text()).
+				To(Equal("Коды артикула 1.2345:\n- SUP-77\n- 4006381333931"))
+
+			bob.say("Поиск по артикулу 🔎", 1)
+			replies := bob.say("SUP-77, 1.2345", 3)
+			Expect(replies[0].Text()).To(Equal("1.2345"))
+			Expect(replies[1].Text()).To(Equal("Артикул '1.2345' (код 'SUP-77'): найдено фото - 1"))
+
+			// Photos uploaded with an alias get the article number
+			bob.say("Добавить товар ®️", 1)
+			bob.sendPhoto("photo-2", "", 2)
+			bob.say("4006381333931", 1)
+			Expect(alice.say("/unalias SUP-77 NOPE", 1)[0].Text()).
+				To(Equal("Код SUP-77 удален у артикула 1.2345.\nКод NOPE не найден."))
+
+			bob.say("Поиск по артикулу 🔎", 1)
+			replies = bob.say("SUP-77, 4006381333931", 5)
+			Expect(replies[0].Text()).To(Equal("Артикул 'SUP-77' не найден в базе данных."))
+			Expect(replies[3].Text()).To(Equal("Артикул '1.2345' (код '4006381333931'): найдено фото - 2"))
+		})
+
+		Context("with the whitelist policy", func() {
+			BeforeEach(func() {
+				access.Policy = configs.AccessPolicyWhitelist
+				access.Whitelist = []int64{1002}
+			})
+
+			It("should admit only whitelisted and pre-registered users", func() {
+				replies := alice.say("/start", 1)
+				Expect(replies[0].Text()).To(Equal("Доступ к боту ограничен. Обратитесь к администратору."))
+				_, err := users.GetByTelegramID(alice.user.ID)
+				Expect(err).NotTo(BeNil())
+
+				Expect(bob.say("/start", 1)[0].Text()).To(Equal("Привет, Bob! 👋"))
+
+				withRole(alice, models.TelegramUserRoleViewer)
+				Expect(alice.say("/start", 1)[0].Text()).To(Equal("Привет, Alice! 👋"))
+			})
+
+			It("should reject unknown users with valid invite links and keep the invites unused", func() {
+				withRole(alice, models.TelegramUserRoleAdmin)
+				replies := alice.say("/invite viewer", 1)
+				code := mustParseURL(strings.TrimSpace(replies[0].Text()[strings.LastIndex(replies[0].Text(), "\n"):])).Query().Get("start")
+
+				carol := &conversation{server: server, user: tgmodels.User{ID: 1003, FirstName: "Carol"}}
+				replies = carol.say("/start "+code, 1)
+				Expect(replies[0].Text()).To(Equal("Доступ к боту ограничен. Обратитесь к администратору."))
+				_, err := users.GetByTelegramID(carol.user.ID)
+				Expect(err).NotTo(BeNil())
+
+				replies = alice.say("/invites", 1)
+				Expect(replies[0].Text()).To(ContainSubstring(code + " - viewer в рабочее пространство default, использовано 0 из 1"))
+			})
+		})
+
+		Context("with the invite-only policy", func() {
+			BeforeEach(func() {
+				access.Policy = configs.AccessPolicyInviteOnly
+			})
+
+			It("should admit users with invite links and the role of the invite", func() {
+				withRole(alice, models.TelegramUserRoleAdmin)
+
+				replies := alice.say("/invite viewer", 1)
+				Expect(replies[0].Text()).To(HavePrefix("Приглашение с ролью viewer в рабочее пространство default (использовано 0 из 1):"))
+				link := mustParseURL(strings.TrimSpace(replies[0].Text()[strings.LastIndex(replies[0].Text(), "\n"):]))
+				Expect(link.Host).To(Equal("t.me"))
+				Expect(link.Path).To(Equal("/alfredo_test_bot"))
+				code := link.Query().Get("start")
+
+				replies = bob.say("/start "+code, 2)
+				Expect(replies[0].Text()).To(Equal("Приглашение принято! Ваша роль: viewer.\nРабочее пространство: default."))
+				Expect(replies[1].Text()).To(Equal("Привет, Bob! 👋"))
+				user, err := users.GetByTelegramID(bob.user.ID)
+				Expect(err).To(BeNil())
+				Expect(user.Role).To(Equal(models.TelegramUserRoleViewer))
+
+				carol := &conversation{server: server, user: tgmodels.User{ID: 1003, FirstName: "Carol"}}
+				replies = carol.say("/start "+code, 1)
+				Expect(replies[0].Text()).To(Equal("Приглашение недействительно или уже использовано."))
+
+				replies = alice.say("/invites", 1)
+				Expect(replies[0].Text()).To(HavePrefix("Активных приглашений нет."))
+			})
+
+			It("should raise but never lower roles of known users", func() {
+				withRole(alice, models.TelegramUserRoleAdmin)
+				withRole(bob, models.TelegramUserRoleViewer)
+
+				replies := alice.say("/invite uploader 0", 1)
+				code := mustParseURL(strings.TrimSpace(replies[0].Text()[strings.LastIndex(replies[0].Text(), "\n"):])).Query().Get("start")
+
+				Expect(bob.say("/start "+code, 2)[0].Text()).To(Equal("Приглашение принято! Ваша роль: uploader.\nРабочее пространство: default."))
+				Expect(alice.say("/start "+code, 1)[0].Text()).To(Equal("Привет, Alice! 👋"))
+
+				admin, err := users.GetByTelegramID(alice.user.ID)
+				Expect(err).To(BeNil())
+				Expect(admin.Role).To(Equal(models.TelegramUserRoleAdmin))
+			})
+		})
+
+		Describe("workspaces", func() {
+			JustBeforeEach(func() {
+				Expect(server.AddFile("photo-1", testPhoto("jpeg-data"))).To(Succeed())
+				withRole(alice, models.TelegramUserRoleAdmin)
+				bob.say("/start", 1)
+
+				replies := alice.say("/workspace_create north", 1)
+				Expect(replies[0].Text()).To(Equal("Рабочее пространство north создано."))
+
+				replies = alice.say("Рабочее пространство 🏬", 1)
+				Expect(replies[0].Text()).To(HavePrefix("Текущее рабочее пространство: default"))
+				buttons := replies[0].InlineKeyboard()
+				Expect(buttons).To(HaveLen(2))
+				Expect(buttons[1][0].Text).To(Equal("north"))
+
+				replies = alice.send(telegramtest.NewCallbackUpdate(alice.user, buttons[1][0].CallbackData), 1)
+				Expect(replies[0].Text()).To(Equal("Рабочее пространство: north"))
+
+				alice.say("Добавить товар ®️", 1)
+				alice.sendPhoto("photo-1", "", 2)
+				alice.say("1.2345", 1)
+			})
+
+			It("should keep catalogs of workspaces apart", func() {
+				bob.say("Поиск по артикулу 🔎", 1)
+				replies := bob.say("1.2345", 2)
+				Expect(replies[0].Text()).To(Equal("Артикул '1.2345' не найден в базе данных."))
+
+				replies = alice.say("/workspace_add @bob north", 1)
+				Expect(replies[0].Text()).To(Equal("@bob добавлен в рабочее пространство north."))
+
+				replies = bob.say("Рабочее пространство 🏬", 1)
+				northButton := replies[0].InlineKeyboard()[1][0]
+				bob.send(telegramtest.NewCallbackUpdate(bob.user, northButton.CallbackData), 1)
+
+				bob.say("Поиск по артикулу 🔎", 1)
+				replies = bob.say("1.2345", 3)
+				Expect(replies[1].Text()).To(Equal("Артикул '1.2345': найдено фото - 1"))
+			})
+
+			It("should print labels opening article numbers for workspace members", func() {
+				replies := alice.say("/labels 1.2345, NOPE", 2)
+				Expect(replies[0].Text()).To(Equal("Артикул 'NOPE' не найден в базе данных."))
+				Expect(replies[1].Method).To(Equal("sendDocument"))
+				Expect(replies[1].Text()).To(Equal("1 этикетка. QR-код на ней открывает товар в боте."))
+				Expect(string(replies[1].Files["document"])).To(HavePrefix("%PDF"))
+
+				replies = alice.say("/labels png 1.2345", 1)
+				codes, err := barcode.Decode(replies[0].Files["document"])
+				Expect(err).To(BeNil())
+				Expect(codes).To(HaveLen(1))
+				link, err := url.Parse(codes[0])
+				Expect(err).To(BeNil())
+				Expect(link.Host + link.Path).To(Equal("t.me/alfredo_test_bot"))
+				start := "/start " + link.Query().Get("start")
+
+				replies = bob.say(start, 1)
+				Expect(replies[0].Text()).To(Equal("Товар с этой этикетки не найден, возможно, он удален."))
+
+				alice.say("/workspace_add @bob north", 1)
+				replies = bob.say(start, 4)
+				Expect(replies[0].Text()).To(Equal("Рабочее пространство: north"))
+				Expect(replies[1].Method).To(Equal("sendPhoto"))
+				Expect(replies[2].Text()).To(Equal("Артикул '1.2345': найдено фото - 1"))
+			})
+
+			It("should refuse sharing photos of other workspaces", func() {
+				alice.say("Поиск по артикулу 🔎", 1)
+				results := alice.say("1.2345", 3)
+
+				shareButton := results[0].InlineKeyboard()[0][0]
+				replies := bob.send(telegramtest.NewCallbackUpdate(bob.user, shareButton.CallbackData), 1)
+				Expect(replies[0].Text()).To(Equal("Не удалось создать ссылку. Пожалуйста, попробуйте снова."))
+			})
+		})
+
+		It("should not accept role commands from non-admins", func() {
+			bob.say("/start", 1)
+
+			replies := alice.say("/grant @bob admin", 1)
+			Expect(replies[0].Text()).To(Equal("Недостаточно прав для этого действия."))
+
+			user, err := users.GetByTelegramID(bob.user.ID)
+			Expect(err).To(BeNil())
+			Expect(user.Role).To(Equal(models.TelegramUserRoleUploader))
+		})
+	})
+
+	Describe("group chats", func() {
+		var (
+			groupChat tgmodels.Chat
+			group     *conversation
+			bob       tgmodels.User
+		)
+
+		BeforeEach(func() {
+			groupChat = telegramtest.NewGroupChat(-1001, "Shop", true)
+			bob = tgmodels.User{ID: 1002, FirstName: "Bob", Username: "bob", LanguageCode: "ru"}
+		})
+
+		JustBeforeEach(func() {
+			group = &conversation{server: server, user: alice.user, chat: &groupChat, threadID: 7}
+		})
+
+		// bindGroup binds the group to the default workspace the way /bind_workspace does
+		bindGroup := func() {
+			workspace, err := workspaces.GetOrCreateWorkspace("default")
+			Expect(err).To(BeNil())
+			Expect(chats.BindGroupChat(&models.GroupChat{ChatID: groupChat.ID, Title: groupChat.Title, WorkspaceID: workspace.ID})).
+				To(Succeed())
+		}
+
+		It("should answer only commands, mentions and dialogs in the topic", func() {
+			bindGroup()
+			group.say("Всем привет!", 0)
+			group.say("/start@other_bot", 0)
+
+			replies := group.say("/start@alfredo_test_bot", 1)
+			Expect(replies[0].Text()).To(Equal("Привет, Alice! 👋"))
+			Expect(replies[0].ThreadID()).To(Equal(7))
+			Expect(replies[0].Params["reply_markup"]).To(ContainSubstring(`"selective":true`))
+
+			replies = group.say("@alfredo_test_bot Поиск по артикулу 🔎", 1)
+			Expect(replies[0].Text()).To(Equal("Пожалуйста, введите артикул товара для поиска:"))
+
+			group.as(bob).say("9.9999", 0)
+			replies = group.say("9.9999", 2)
+			Expect(replies[0].Text()).To(Equal("Артикул '9.9999' не найден в базе данных."))
+			Expect(replies[1].ThreadID()).To(Equal(7))
+
+			group.say("9.9999", 0)
+		})
+
+		It("should keep dialogs in groups apart from private chats", func() {
+			bindGroup()
+			group.say("/start", 1)
+			group.say("@alfredo_test_bot Поиск по артикулу 🔎", 1)
+
+			Expect(alice.say("9.9999", 1)[0].Text()).To(Equal("Привет, Alice! 👋"))
+			Expect(group.say("9.9999", 2)[1].Text()).To(Equal("Поиск завершен!"))
+		})
+
+		It("should let admins bind a group to a workspace", func() {
+			Expect(server.AddFile("photo-1", testPhoto("jpeg-data"))).To(Succeed())
+			withRole(alice, models.TelegramUserRoleAdmin)
+			alice.say("/workspace_create north", 1)
+
+			replies := group.say("/bind_workspace north", 1)
+			Expect(replies[0].Text()).To(Equal("Группа привязана к рабочему пространству north."))
+
+			member := group.as(bob)
+			member.say("@alfredo_test_bot Добавить товар ®️", 1)
+			Expect(member.sendPhoto("photo-1", "1.2345", 2)[1].Text()).To(Equal("Успешно загружено 1 фото!"))
+
+			private := &conversation{server: server, user: bob}
+			private.say("Поиск по артикулу 🔎", 1)
+			Expect(private.say("1.2345", 2)[0].Text()).To(Equal("Артикул '1.2345' не найден в базе данных."))
+
+			member.say("@alfredo_test_bot Поиск по артикулу 🔎", 1)
+			replies = member.say("1.2345", 3)
+			Expect(replies[0].Method).To(Equal("sendPhoto"))
+			Expect(replies[0].ThreadID()).To(Equal(7))
+
+			replies = member.say("/bind_workspace north", 1)
+			Expect(replies[0].Text()).To(Equal("Недостаточно прав для этого действия."))
+		})
+
+		It("should refuse workspace actions in groups not bound to a workspace", func() {
+			Expect(server.AddFile("photo-1", testPhoto("jpeg-data"))).To(Succeed())
+			withRole(alice, models.TelegramUserRoleAdmin)
+			alice.say("Добавить товар ®️", 1)
+			alice.sendPhoto("photo-1", "1.2345", 2)
+
+			notBound := "Группа не привязана к рабочему пространству. Администратор может привязать ее командой /bind_workspace название."
+			Expect(group.say("/start", 1)[0].Text()).To(Equal("Привет, Alice! 👋"))
+			Expect(group.say("@alfredo_test_bot Поиск по артикулу 🔎", 1)[0].Text()).To(Equal(notBound))
+			Expect(group.say("/search 1.2345", 1)[0].Text()).To(Equal(notBound))
+			Expect(group.say("@alfredo_test_bot Добавить товар ®️", 1)[0].Text()).To(Equal(notBound))
+			Expect(group.say("/export", 1)[0].Text()).To(Equal(notBound))
+			Expect(group.say("/trash", 1)[0].Text()).To(Equal(notBound))
+
+			Expect(group.say("/bind_workspace default", 1)[0].Text()).To(Equal("Группа привязана к рабочему пространству default."))
+			group.say("@alfredo_test_bot Поиск по артикулу 🔎", 1)
+			Expect(group.say("1.2345", 3)[0].Method).To(Equal("sendPhoto"))
+
+			Expect(group.say("/unbind_workspace", 1)[0].Text()).To(HavePrefix("Группа отвязана от рабочего пространства."))
+			Expect(group.say("/search 1.2345", 1)[0].Text()).To(Equal(notBound))
+		})
+	})
+
+	It("should report unknown article numbers", func() {
+		alice.say("Поиск по артикулу 🔎", 1)
+
+		replies := alice.say("9.9999", 2)
+		Expect(replies[0].Text()).To(Equal("Артикул '9.9999' не найден в базе данных."))
+		Expect(replies[1].Text()).To(Equal("Поиск завершен!"))
+	})
+})
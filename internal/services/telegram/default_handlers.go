@@ -2,78 +2,167 @@ package telegram
 
 import (
 	"context"
+	"strings"
 
 	"github.com/go-telegram/bot"
 	tgmodels "github.com/go-telegram/bot/models"
 	"github.com/rs/zerolog/log"
+
+	"github.com/Conty111/AlfredoBot/internal/i18n"
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/labels"
 )
 
-const searchByArticleNumberText = "Поиск по артикулу 🔎"
-const addItemText = "Добавить товар ®️"
-const helpText = "Help ❓"
-const supportText = "Support 🆘"
-const cancelText = "Отмена"
+// mainMenu returns the main keyboard with only the buttons the current user may use
+func mainMenu(ctx context.Context) tgmodels.ReplyMarkup {
+	role := appmodels.TelegramUserRoleViewer
+	if user := userFromContext(ctx); user != nil {
+		role = user.Role
+	}
+	return menuForRole(tr(ctx), contentFromContext(ctx).menu, role)
+}
 
-var mainMenu = &tgmodels.ReplyKeyboardMarkup{
-	Keyboard: [][]tgmodels.KeyboardButton{
-		{
-			{Text: searchByArticleNumberText},
-			{Text: addItemText},
-		},
-		{
-			{Text: helpText},
-			{Text: supportText},
-		},
-	},
+// menuForRole lays the buttons out like the layout, dropping buttons the role may not use
+func menuForRole(t *i18n.Localizer, layout [][]string, role string) tgmodels.ReplyMarkup {
+	if (&appmodels.TelegramUser{Role: role}).IsBanned() {
+		return &tgmodels.ReplyKeyboardRemove{RemoveKeyboard: true}
+	}
+
+	var keyboard [][]tgmodels.KeyboardButton
+	for _, row := range visibleLayout(layout, role) {
+		buttons := make([]tgmodels.KeyboardButton, 0, len(row))
+		for _, button := range row {
+			buttons = append(buttons, tgmodels.KeyboardButton{Text: t.T("menu." + button)})
+		}
+		keyboard = append(keyboard, buttons)
+	}
+
+	return &tgmodels.ReplyKeyboardMarkup{Keyboard: keyboard}
 }
 
-var cancelMenu = &tgmodels.ReplyKeyboardMarkup{
-	Keyboard: [][]tgmodels.KeyboardButton{
-		{
-			{Text: cancelText},
+// visibleLayout drops buttons the role may not use and rows left empty
+func visibleLayout(layout [][]string, role string) [][]string {
+	user := &appmodels.TelegramUser{Role: role}
+	var visible [][]string
+	for _, row := range layout {
+		var buttons []string
+		for _, button := range row {
+			if (button == addButton && !user.CanUpload()) || (button == usersButton && !user.IsAdmin()) {
+				continue
+			}
+			buttons = append(buttons, button)
+		}
+		if len(buttons) > 0 {
+			visible = append(visible, buttons)
+		}
+	}
+	return visible
+}
+
+// cancelMenu returns the keyboard shown in the middle of a dialog
+func cancelMenu(ctx context.Context) tgmodels.ReplyMarkup {
+	return &tgmodels.ReplyKeyboardMarkup{
+		Keyboard: [][]tgmodels.KeyboardButton{
+			{
+				{Text: tr(ctx).T("menu.cancel")},
+			},
 		},
-	},
+	}
 }
 
 func defaultHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
-	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      update.Message.Chat.ID,
-		Text:        "Привет, " + update.Message.From.FirstName + "! 👋",
-		ReplyMarkup: mainMenu,
-	})
+	// Service updates, e.g. the bot being added to a group, need no answer
+	if update.Message == nil {
+		return
+	}
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        tr(ctx).T("start.greeting", update.Message.From.FirstName),
+		ReplyMarkup: mainMenu(ctx),
+	}))
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to send default welcome message")
 	}
 }
 
-func helpHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
-	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: update.Message.Chat.ID,
-		Text: `Привет, ` + update.Message.From.FirstName + `.
-
-Доступные команды:
--  ` + addItemText + ` - добавить фото товара с артикулом(-ами)
-- ` + searchByArticleNumberText + ` - найти товар по его артикулу
-- ` + helpText + ` - показать справку
-- ` + supportText + ` - связаться с поддержкой`,
-		ReplyMarkup: mainMenu,
-	})
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to send help message")
+// startHandler greets the user, deep links of labels open their article number right away
+func (s *TelegramBotService) startHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if articleNumberID, ok := labels.ParseStartParameter(startPayload(update)); ok {
+		s.openArticleLink(ctx, b, update, articleNumberID)
+		return
 	}
+	defaultHandler(ctx, b, update)
 }
 
-func supportHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
-	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: update.Message.Chat.ID,
-		Text: `Свяжитесь с нашей поддержкой:
+func (s *TelegramBotService) helpHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	var commands strings.Builder
+	item := func(action, description string) {
+		commands.WriteString(t.T("help.item", action, t.T(description)))
+	}
 
-Email: support@example.com
-Телефон: +7 (123) 456-78-90
-Telegram: @support_bot`,
-		ReplyMarkup: mainMenu,
-	})
+	user := userFromContext(ctx)
+	role := appmodels.TelegramUserRoleViewer
+	if user != nil {
+		role = user.Role
+	}
+	// Buttons are described in the order of the menu, so a custom layout gets matching help
+	for _, row := range visibleLayout(contentFromContext(ctx).menu, role) {
+		for _, button := range row {
+			item(t.T("menu."+button), "help."+button)
+		}
+	}
+	commands.WriteString(t.T("help.commands"))
+	if user != nil && user.CanUpload() {
+		item("/"+addCommand, "help.add_command")
+		item(t.T("usage.labels"), "help.labels")
+	}
+	item(t.T("usage.search"), "help.search_command")
+	item("/"+myCommand, "help.my_command")
+	item("/"+cancelCommand, "help.cancel_command")
+	item("/"+languageCommand, "help.language_command")
+	if user != nil && user.IsAdmin() {
+		item(t.T("usage.grant"), "help.grant")
+		item(t.T("usage.revoke"), "help.revoke")
+		item(t.T("usage.invite"), "help.invite")
+		item("/"+invitesCommand, "help.invites")
+		item(t.T("usage.revoke_invite"), "help.revoke_invite")
+		item("/"+workspacesCommand, "help.workspaces")
+		item(t.T("usage.workspace_create"), "help.workspace_create")
+		item(t.T("usage.workspace_add"), "help.workspace_add")
+		item(t.T("usage.workspace_remove"), "help.workspace_remove")
+		item(t.T("usage.bind_workspace"), "help.bind_workspace")
+		item("/"+unbindWorkspaceCommand, "help.unbind_workspace")
+		item("/"+reloadContentCommand, "help.reload_content")
+		item(t.T("usage.audit"), "help.audit")
+		item("/"+trashCommand, "help.trash")
+		item("/"+importCommand, "help.import")
+		item(t.T("usage.export"), "help.export")
+		item(t.T("usage.lookbook"), "help.lookbook")
+		item(t.T("usage.rename"), "help.rename")
+		item(t.T("usage.merge"), "help.merge")
+		item(t.T("usage.move"), "help.move")
+		item(t.T("usage.alias"), "help.alias")
+		item(t.T("usage.unalias"), "help.unalias")
+	}
+	if s.isStaffChat(update.Message.Chat) {
+		commands.WriteString(t.T("help.support_commands"))
+		item("/"+ticketsCommand, "help.tickets")
+		item(t.T("usage.ticket"), "help.ticket")
+		item(t.T("usage.close"), "help.close")
+	}
+
+	data := s.contentDataFor(update.Message)
+	data.Commands = strings.TrimPrefix(commands.String(), "\n")
+	text, ok := render(contentFromContext(ctx).help, t.Locale(), data)
+	if !ok {
+		text = t.T("help.text", update.Message.From.FirstName, commands.String())
+	}
+
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        text,
+		ReplyMarkup: mainMenu(ctx),
+	}))
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to send support message")
+		log.Error().Err(err).Msg("Failed to send help message")
 	}
 }
@@ -0,0 +1,186 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+)
+
+const exportCommand = "export"
+
+// Limits of documents sent by bots through the public Bot API and a self-hosted server in the local mode
+const (
+	maxDocumentSize           = 50 << 20
+	maxSelfHostedDocumentSize = 2000 << 20
+)
+
+// exportHandler sends an admin a ZIP archive with the applied photos of the active workspace.
+// The photos may be narrowed down to an uploader given as @username or @telegram_id and
+// to the days between two dates, in any order.
+func (s *TelegramBotService) exportHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+	if user.ActiveWorkspaceID == uuid.Nil {
+		s.sendText(ctx, b, update, t.T("workspaces.none"))
+		return
+	}
+
+	filter := appmodels.PhotoFilter{WorkspaceID: user.ActiveWorkspaceID}
+	_, args := splitCommand(update.Message.Text)
+	for _, arg := range args {
+		matched, ok := s.applyFilterArg(ctx, b, update, &filter, arg)
+		if !ok {
+			return
+		}
+		if !matched {
+			s.sendText(ctx, b, update, t.T("common.usage", t.T("usage.export")))
+			return
+		}
+	}
+
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text: t.T("export.started"),
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+
+	// The archive goes to a temporary file, so photos are never held in memory all at once
+	file, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create export file")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	exporter := catalog.NewExporter(s.photoRepository, s.userRepository, s.s3Config.Bucket)
+	report, err := exporter.Export(ctx, file, filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to export photos")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	log.Info().
+		Int64("telegram_id", user.TelegramID).
+		Int("photos", len(report.Photos)).
+		Int("failed", len(report.Failed)).
+		Msg("Catalog exported")
+	if len(report.Photos) == 0 {
+		s.sendText(ctx, b, update, t.T("export.empty"))
+		return
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read export file")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	if limit := s.documentSizeLimit(); size > limit {
+		s.sendText(ctx, b, update, t.T("export.too_large", size>>20, limit>>20))
+		return
+	}
+
+	caption := t.N("export.done", len(report.Photos), len(report.Photos))
+	if len(report.Failed) > 0 {
+		caption += t.N("export.failed", len(report.Failed), len(report.Failed))
+	}
+	s.sendDocument(ctx, b, update, "export-"+time.Now().Format(time.DateOnly)+".zip", file, caption)
+}
+
+// applyFilterArg narrows the filter down to an uploader given as @username or @telegram_id
+// or to the days between two dates. It returns false in matched for other arguments and
+// false in ok when the user was told about an unknown uploader.
+func (s *TelegramBotService) applyFilterArg(
+	ctx context.Context,
+	b *bot.Bot,
+	update *tgmodels.Update,
+	filter *appmodels.PhotoFilter,
+	arg string,
+) (matched, ok bool) {
+	if strings.HasPrefix(arg, "@") && filter.UserID == uuid.Nil {
+		uploader, err := s.findUser(strings.TrimPrefix(arg, "@"))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.sendText(ctx, b, update, tr(ctx).T("common.user_not_found", arg))
+			return true, false
+		}
+		if err != nil {
+			log.Error().Err(err).Str("user", arg).Msg("Failed to find user")
+			s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+			return true, false
+		}
+		filter.UserID = uploader.ID
+		return true, true
+	}
+
+	date, err := catalog.ParseDate(arg)
+	switch {
+	case err == nil && filter.Since.IsZero():
+		filter.Since = date
+	case err == nil && filter.Until.IsZero():
+		// The last day is included
+		filter.Until = date.AddDate(0, 0, 1)
+	default:
+		return false, true
+	}
+	return true, true
+}
+
+// documentSizeLimit is the size of the largest document the bot can send. Self-hosted
+// servers accept larger uploads in the local mode only, whatever their URL.
+func (s *TelegramBotService) documentSizeLimit() int64 {
+	if s.config.LocalMode {
+		return maxSelfHostedDocumentSize
+	}
+	return maxDocumentSize
+}
+
+// sendDocument sends a generated file to the chat of the message
+func (s *TelegramBotService) sendDocument(
+	ctx context.Context,
+	b *bot.Bot,
+	update *tgmodels.Update,
+	filename string,
+	data io.Reader,
+	caption string,
+) {
+	params := &bot.SendDocumentParams{
+		ChatID: update.Message.Chat.ID,
+		Document: &tgmodels.InputFileUpload{
+			Filename: filename,
+			Data:     data,
+		},
+		Caption: caption,
+	}
+	if update.Message.IsTopicMessage {
+		params.MessageThreadID = update.Message.MessageThreadID
+	}
+	// Reply keyboards would pop up for every member of a group
+	if !isGroupChat(update.Message.Chat) {
+		params.ReplyMarkup = mainMenu(ctx)
+	}
+	if _, err := b.SendDocument(ctx, params); err != nil {
+		log.Error().Err(err).Str("filename", filename).Msg("Failed to send document")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+	}
+}
@@ -0,0 +1,294 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+)
+
+const bindWorkspaceCommand = "bind_workspace"
+const unbindWorkspaceCommand = "unbind_workspace"
+
+func isGroupChat(chat tgmodels.Chat) bool {
+	return chat.Type == tgmodels.ChatTypeGroup || chat.Type == tgmodels.ChatTypeSupergroup
+}
+
+// inChat addresses a reply to the chat and forum topic of the message.
+// In groups the reply quotes the message and its reply keyboard is shown only to the sender.
+func inChat(message *tgmodels.Message, params *bot.SendMessageParams) *bot.SendMessageParams {
+	params.ChatID = message.Chat.ID
+	if message.IsTopicMessage {
+		params.MessageThreadID = message.MessageThreadID
+	}
+	if isGroupChat(message.Chat) {
+		params.ReplyParameters = &tgmodels.ReplyParameters{
+			MessageID:                message.ID,
+			AllowSendingWithoutReply: true,
+		}
+		switch markup := params.ReplyMarkup.(type) {
+		case *tgmodels.ReplyKeyboardMarkup:
+			selective := *markup
+			selective.Selective = true
+			params.ReplyMarkup = &selective
+		case *tgmodels.ReplyKeyboardRemove:
+			selective := *markup
+			selective.Selective = true
+			params.ReplyMarkup = &selective
+		}
+	}
+	return params
+}
+
+// callbackChat returns the chat a callback query is answered in. Replies to queries
+// from inaccessible messages go to the private chat with the user.
+func callbackChat(query *tgmodels.CallbackQuery) tgmodels.Chat {
+	if message := query.Message.Message; message != nil {
+		return message.Chat
+	}
+	return tgmodels.Chat{ID: query.From.ID, Type: tgmodels.ChatTypePrivate}
+}
+
+// inCallbackChat addresses an answer to the chat and forum topic of the message with the pressed inline button.
+// Reply keyboards are not sent to groups, they would pop up for every member.
+func inCallbackChat(query *tgmodels.CallbackQuery, params *bot.SendMessageParams) *bot.SendMessageParams {
+	message := query.Message.Message
+	if message == nil {
+		params.ChatID = query.From.ID
+		return params
+	}
+
+	params.ChatID = message.Chat.ID
+	if message.IsTopicMessage {
+		params.MessageThreadID = message.MessageThreadID
+	}
+	if isGroupChat(message.Chat) {
+		if _, inline := params.ReplyMarkup.(*tgmodels.InlineKeyboardMarkup); !inline {
+			params.ReplyMarkup = nil
+		}
+	}
+	return params
+}
+
+// updateChat returns the chat an update came from, or nil if it has none
+func updateChat(update *tgmodels.Update) *tgmodels.Chat {
+	switch {
+	case update.Message != nil:
+		return &update.Message.Chat
+	case update.CallbackQuery != nil && update.CallbackQuery.Message.Message != nil:
+		return &update.CallbackQuery.Message.Message.Chat
+	case update.CallbackQuery != nil:
+		return &tgmodels.Chat{ID: update.CallbackQuery.From.ID, Type: tgmodels.ChatTypePrivate}
+	}
+	return nil
+}
+
+// setState stores the dialog state of the sender in the chat of the message
+func (s *TelegramBotService) setState(message *tgmodels.Message, state string) error {
+	return s.chatRepository.SetState(message.Chat.ID, message.From.ID, state)
+}
+
+// groupMiddleware drops group messages which are not addressed to the bot.
+// The bot answers commands, mentions, replies to its messages and messages
+// of users in the middle of a dialog started in the group.
+func (s *TelegramBotService) groupMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		message := update.Message
+		if message == nil || ctx.Value(normalizedContextKey) != nil {
+			next(ctx, b, update)
+			return
+		}
+
+		text := message.Text
+		addressed := s.stripBotMention(message)
+		if isGroupChat(message.Chat) {
+			if message.From == nil || message.From.IsBot {
+				return
+			}
+			if !addressed && !s.inDialog(message) {
+				return
+			}
+		}
+
+		if message.Text != text {
+			// Handlers were matched against the text with the mention,
+			// so the update is processed again to find the right one
+			b.ProcessUpdate(context.WithValue(ctx, normalizedContextKey, true), update)
+			return
+		}
+		next(ctx, b, update)
+	}
+}
+
+// stripBotMention removes "@bot" from "/command@bot" and a leading "@bot" mention,
+// so handlers match the text as in a private chat. It reports whether the
+// message is a command or mentions the bot.
+func (s *TelegramBotService) stripBotMention(message *tgmodels.Message) bool {
+	mention := "@" + s.botUser.Username
+	text := message.Text
+
+	if strings.HasPrefix(text, "/") {
+		command, rest, _ := strings.Cut(text, " ")
+		name, target, found := strings.Cut(command, "@")
+		if found && !strings.EqualFold("@"+target, mention) {
+			// A command for another bot in the group
+			return false
+		}
+		if found {
+			message.Text = strings.TrimRight(name+" "+rest, " ")
+			message.Entities = commandEntities(message.Text)
+		}
+		return true
+	}
+
+	if len(text) >= len(mention) && strings.EqualFold(text[:len(mention)], mention) {
+		message.Text = strings.TrimSpace(text[len(mention):])
+		message.Entities = commandEntities(message.Text)
+		return true
+	}
+
+	reply := message.ReplyToMessage
+	return reply != nil && reply.From != nil && reply.From.ID == s.botUser.ID
+}
+
+// inDialog reports whether the sender of the message is searching or uploading in its chat
+func (s *TelegramBotService) inDialog(message *tgmodels.Message) bool {
+	state, err := s.chatRepository.GetState(message.Chat.ID, message.From.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("chat_id", message.Chat.ID).Msg("Failed to get chat state")
+		return false
+	}
+	return state != appmodels.TelegramUserStateDefault
+}
+
+// commandEntities marks a leading /command with a bot_command entity like Telegram does
+func commandEntities(text string) []tgmodels.MessageEntity {
+	if !strings.HasPrefix(text, "/") {
+		return nil
+	}
+	command, _, _ := strings.Cut(text, " ")
+	return []tgmodels.MessageEntity{
+		{Type: tgmodels.MessageEntityTypeBotCommand, Offset: 0, Length: len(command)},
+	}
+}
+
+// applyChat loads the dialog state of the user in the chat and the workspace the group is bound to.
+// Unbound groups have no workspace: the active workspace of the sender may be private,
+// and everyone in the group would see its photos.
+func (s *TelegramBotService) applyChat(user *appmodels.TelegramUser, chat *tgmodels.Chat) error {
+	state, err := s.chatRepository.GetState(chat.ID, user.TelegramID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat state: %w", err)
+	}
+	user.State = state
+
+	if !isGroupChat(*chat) {
+		return nil
+	}
+	group, err := s.chatRepository.GetGroupChat(chat.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user.ActiveWorkspaceID = uuid.Nil
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get group chat: %w", err)
+	}
+	user.ActiveWorkspaceID = group.WorkspaceID
+	return nil
+}
+
+// needsWorkspace reports whether the action requested by the update works with the photos
+// or article numbers of the active workspace
+func needsWorkspace(user *appmodels.TelegramUser, required permission, update *tgmodels.Update) bool {
+	switch required {
+	case permissionSearch, permissionUpload:
+		return true
+	case permissionAdmin:
+		// Trash buttons restore photos and article numbers of the workspace
+		if update.CallbackQuery != nil || update.Message == nil {
+			return true
+		}
+	default:
+		return false
+	}
+
+	command, _ := splitCommand(update.Message.Text)
+	switch command {
+	case "":
+		return user.State == appmodels.TelegramUserStateImporting
+	case auditCommand, trashCommand, importCommand, exportCommand, lookbookCommand, inviteCommand,
+		renameCommand, mergeCommand, moveCommand, aliasCommand, unaliasCommand:
+		return true
+	}
+	return false
+}
+
+func (s *TelegramBotService) bindWorkspaceHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if !isGroupChat(update.Message.Chat) {
+		s.sendText(ctx, b, update, tr(ctx).T("groups.only"))
+		return
+	}
+
+	_, args := splitCommand(update.Message.Text)
+	name := strings.Join(args, " ")
+	if name == "" {
+		s.sendText(ctx, b, update, tr(ctx).T("common.usage", tr(ctx).T("usage.bind_workspace")))
+		return
+	}
+
+	workspace, err := s.workspaceRepository.GetByName(name)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Error().Err(err).Msg("Failed to find workspace")
+		}
+		s.sendText(ctx, b, update, tr(ctx).T("common.workspace_not_found", name))
+		return
+	}
+
+	if err := s.chatRepository.BindGroupChat(&appmodels.GroupChat{
+		ChatID:      update.Message.Chat.ID,
+		Title:       update.Message.Chat.Title,
+		WorkspaceID: workspace.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to bind group chat")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+
+	log.Info().
+		Int64("admin_telegram_id", update.Message.From.ID).
+		Int64("chat_id", update.Message.Chat.ID).
+		Str("workspace", workspace.Name).
+		Msg("Group chat bound to workspace")
+
+	s.sendText(ctx, b, update, tr(ctx).T("groups.bound", workspace.Name))
+}
+
+func (s *TelegramBotService) unbindWorkspaceHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if !isGroupChat(update.Message.Chat) {
+		s.sendText(ctx, b, update, tr(ctx).T("groups.only"))
+		return
+	}
+
+	if err := s.chatRepository.UnbindGroupChat(update.Message.Chat.ID); err != nil {
+		log.Error().Err(err).Msg("Failed to unbind group chat")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+
+	log.Info().
+		Int64("admin_telegram_id", update.Message.From.ID).
+		Int64("chat_id", update.Message.Chat.ID).
+		Msg("Group chat unbound from workspace")
+
+	s.sendText(ctx, b, update, tr(ctx).T("groups.unbound"))
+}
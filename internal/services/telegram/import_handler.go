@@ -0,0 +1,155 @@
+package telegram
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"path"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+)
+
+const importCommand = "import"
+
+const importReportName = "import-report.csv"
+
+// importHandler asks an admin for a ZIP archive with the images and the manifest of a catalog
+func (s *TelegramBotService) importHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if userFromContext(ctx).ActiveWorkspaceID == uuid.Nil {
+		s.sendText(ctx, b, update, tr(ctx).T("workspaces.none"))
+		return
+	}
+	if err := s.setState(update.Message, appmodels.TelegramUserStateImporting); err != nil {
+		log.Error().Err(err).Msg("Failed to update user state")
+	}
+	s.sendImportText(ctx, b, update, tr(ctx).T("import.prompt"))
+}
+
+// importMessageHandler imports the catalog of a ZIP archive sent in the import dialog
+// to the active workspace and replies with the report of every manifest row
+func (s *TelegramBotService) importMessageHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	message := update.Message
+	t := tr(ctx)
+	user := userFromContext(ctx)
+	if isButton(message.Text, "menu.cancel") {
+		s.leaveImport(ctx, b, update, t.T("import.cancelled"))
+		return
+	}
+	if !isZipDocument(message.Document) {
+		s.sendImportText(ctx, b, update, t.T("import.not_zip"))
+		return
+	}
+
+	file, err := b.GetFile(ctx, &bot.GetFileParams{FileID: message.Document.FileID})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get document file from Telegram")
+		s.sendImportText(ctx, b, update, t.T("upload.file_failed"))
+		return
+	}
+	data, err := s.downloadFile(ctx, b, file, 0)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to download file from Telegram")
+		s.sendImportText(ctx, b, update, t.T("upload.download_failed"))
+		return
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		s.sendImportText(ctx, b, update, t.T("import.invalid", err.Error()))
+		return
+	}
+	rows, images, err := catalog.OpenCatalog(archive)
+	if err != nil {
+		s.sendImportText(ctx, b, update, t.T("import.invalid", err.Error()))
+		return
+	}
+
+	_, err = b.SendMessage(ctx, inChat(message, &bot.SendMessageParams{
+		Text: t.N("import.started", len(rows), len(rows)),
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+
+	importer := catalog.NewImporter(
+		s.photoRepository.WithActor(user),
+		s.articleRepository.WithActor(user),
+		s.imageProcessor,
+		s.s3Config.Bucket,
+	)
+	report := importer.Import(ctx, images, rows, user.ID, user.ActiveWorkspaceID)
+	log.Info().
+		Int64("telegram_id", user.TelegramID).
+		Int("rows", len(report.Rows)).
+		Int("failed", report.Count(catalog.RowFailed)).
+		Msg("Catalog imported")
+
+	if err := s.setState(message, appmodels.TelegramUserStateDefault); err != nil {
+		log.Error().Err(err).Msg("Failed to reset user state")
+	}
+
+	summary := t.T("import.done",
+		len(report.Rows),
+		report.Count(catalog.RowImported),
+		report.Count(catalog.RowLinked),
+		report.Count(catalog.RowUnchanged),
+		report.Count(catalog.RowFailed))
+	var csv bytes.Buffer
+	if err := report.WriteCSV(&csv); err != nil {
+		log.Error().Err(err).Msg("Failed to write import report")
+		s.sendText(ctx, b, update, summary)
+		return
+	}
+
+	params := &bot.SendDocumentParams{
+		ChatID:   message.Chat.ID,
+		Document: &tgmodels.InputFileUpload{Filename: importReportName, Data: &csv},
+		Caption:  summary,
+	}
+	if message.IsTopicMessage {
+		params.MessageThreadID = message.MessageThreadID
+	}
+	// Reply keyboards would pop up for every member of a group
+	if !isGroupChat(message.Chat) {
+		params.ReplyMarkup = mainMenu(ctx)
+	}
+	if _, err := b.SendDocument(ctx, params); err != nil {
+		log.Error().Err(err).Msg("Failed to send import report")
+	}
+}
+
+func (s *TelegramBotService) leaveImport(ctx context.Context, b *bot.Bot, update *tgmodels.Update, text string) {
+	if err := s.setState(update.Message, appmodels.TelegramUserStateDefault); err != nil {
+		log.Error().Err(err).Msg("Failed to reset user state")
+	}
+	s.sendText(ctx, b, update, text)
+}
+
+// sendImportText answers an admin in the import dialog, keeping the cancel keyboard
+func (s *TelegramBotService) sendImportText(ctx context.Context, b *bot.Bot, update *tgmodels.Update, text string) {
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        text,
+		ReplyMarkup: cancelMenu(ctx),
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+func isZipDocument(document *tgmodels.Document) bool {
+	if document == nil || document.FileID == "" {
+		return false
+	}
+	switch document.MimeType {
+	case "application/zip", "application/x-zip-compressed":
+		return true
+	}
+	return strings.EqualFold(path.Ext(document.FileName), ".zip")
+}
@@ -0,0 +1,329 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/i18n"
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/labels"
+)
+
+const inviteCommand = "invite"
+const invitesCommand = "invites"
+const revokeInviteCommand = "revoke_invite"
+
+// admitUser applies the access policy and invite codes to the sender.
+// It returns false when the update must not be processed any further.
+func (s *TelegramBotService) admitUser(ctx context.Context, b *bot.Bot, update *tgmodels.Update, from *tgmodels.User) bool {
+	code := startPayload(update)
+	// Deep links of labels are not invites
+	if _, ok := labels.ParseStartParameter(code); ok {
+		code = ""
+	}
+	// Unknown users have no language preference yet
+	t := messages.Localizer(from.LanguageCode)
+
+	user, err := s.userRepository.GetByTelegramID(from.ID)
+	if err == nil {
+		if code != "" {
+			s.upgradeByInvite(ctx, b, update, user, code)
+		}
+		return true
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error().Err(err).Int64("telegram_id", from.ID).Msg("Failed to check if Telegram user exists")
+		// The access middleware reports the error to the user
+		return true
+	}
+
+	role := s.accessConfig.DefaultRole
+	// The whitelist policy admits no one by invites, so codes are left unused
+	acceptsInvites := s.accessConfig.Policy == configs.AccessPolicyInviteOnly || s.accessConfig.Policy == configs.AccessPolicyOpen
+	var invite *appmodels.InviteCode
+	if acceptsInvites {
+		if invite, err = s.useInvite(code); err != nil {
+			s.sendAdmissionText(ctx, b, update, t.T("common.error"))
+			return false
+		}
+	}
+	switch {
+	case invite != nil:
+		role = invite.Role
+	case s.accessConfig.Policy == configs.AccessPolicyOpen:
+	case slices.Contains(s.accessConfig.Whitelist, from.ID):
+	default:
+		log.Info().
+			Int64("telegram_id", from.ID).
+			Str("username", from.Username).
+			Str("policy", s.accessConfig.Policy).
+			Msg("Unknown user rejected")
+		text := t.T("access.restricted")
+		if code != "" && acceptsInvites {
+			text = t.T("access.invalid_invite")
+		}
+		s.sendAdmissionText(ctx, b, update, text)
+		return false
+	}
+
+	user = newTelegramUser(from, role)
+	if err := s.userRepository.CreateUser(user); err != nil {
+		log.Error().Err(err).Int64("telegram_id", from.ID).Msg("Failed to create Telegram user")
+		s.sendAdmissionText(ctx, b, update, t.T("common.error"))
+		return false
+	}
+
+	if invite != nil && invite.WorkspaceID != uuid.Nil {
+		err = s.joinWorkspace(user, invite.WorkspaceID)
+	} else {
+		err = s.joinDefaultWorkspace(user)
+	}
+	if err != nil {
+		log.Error().Err(err).Int64("telegram_id", from.ID).Msg("Failed to join workspace")
+	}
+
+	if invite != nil {
+		s.sendAdmissionText(ctx, b, update, s.inviteAcceptedText(t, role, invite.WorkspaceID))
+	}
+	return true
+}
+
+// upgradeByInvite raises the role of a known user or adds them to the workspace of the invite.
+// Invites never lift bans or lower roles.
+func (s *TelegramBotService) upgradeByInvite(ctx context.Context, b *bot.Bot, update *tgmodels.Update, user *appmodels.TelegramUser, code string) {
+	invite, err := s.inviteRepository.GetByCode(code)
+	if err != nil || invite.IsExhausted() || user.IsBanned() {
+		return
+	}
+
+	raises := appmodels.IsRoleHigher(invite.Role, user.Role)
+	joins := false
+	if invite.WorkspaceID != uuid.Nil {
+		member, err := s.workspaceRepository.IsMember(invite.WorkspaceID, user.ID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to check workspace membership")
+			return
+		}
+		joins = !member
+	}
+	if !raises && !joins {
+		return
+	}
+
+	invite, err = s.useInvite(code)
+	if err != nil || invite == nil {
+		return
+	}
+	if raises {
+		if err := s.userRepository.WithActor(user).UpdateByID(user.ID, map[string]interface{}{"role": invite.Role}); err != nil {
+			log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to update user role")
+			return
+		}
+		user.Role = invite.Role
+	}
+	if joins {
+		if err := s.joinWorkspace(user, invite.WorkspaceID); err != nil {
+			log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to join workspace")
+			return
+		}
+	}
+	s.sendAdmissionText(ctx, b, update, s.inviteAcceptedText(localizerFor(user), user.Role, invite.WorkspaceID))
+}
+
+func (s *TelegramBotService) inviteAcceptedText(t *i18n.Localizer, role string, workspaceID uuid.UUID) string {
+	text := t.T("invites.accepted", role)
+	if name := s.workspaceName(workspaceID); name != "" {
+		text += t.T("invites.accepted_workspace", name)
+	}
+	return text
+}
+
+// workspaceName returns the name of a workspace or an empty string if there is none
+func (s *TelegramBotService) workspaceName(workspaceID uuid.UUID) string {
+	if workspaceID == uuid.Nil {
+		return ""
+	}
+	workspace, err := s.workspaceRepository.GetByID(workspaceID)
+	if err != nil {
+		log.Error().Err(err).Str("workspace_id", workspaceID.String()).Msg("Failed to get workspace")
+		return ""
+	}
+	return workspace.Name
+}
+
+// useInvite spends one use of the code, a missing or exhausted code returns nil
+func (s *TelegramBotService) useInvite(code string) (*appmodels.InviteCode, error) {
+	if code == "" {
+		return nil, nil
+	}
+	invite, err := s.inviteRepository.UseInviteCode(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to use invite code")
+		return nil, err
+	}
+	log.Info().
+		Str("code", invite.Code).
+		Str("role", invite.Role).
+		Int("uses", invite.Uses).
+		Msg("Invite code used")
+	return invite, nil
+}
+
+func (s *TelegramBotService) sendAdmissionText(ctx context.Context, b *bot.Bot, update *tgmodels.Update, text string) {
+	if update.Message == nil {
+		return
+	}
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text: text,
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+// startPayload returns the deep link parameter of a "/start <payload>" message
+func startPayload(update *tgmodels.Update) string {
+	if update.Message == nil {
+		return ""
+	}
+	command, args := splitCommand(update.Message.Text)
+	if command != "start" || len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+func (s *TelegramBotService) inviteHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	_, args := splitCommand(update.Message.Text)
+	if len(args) == 0 || len(args) > 2 || !appmodels.IsValidTelegramUserRole(args[0]) ||
+		args[0] == appmodels.TelegramUserRoleBanned {
+		s.sendText(ctx, b, update, tr(ctx).T("common.usage", tr(ctx).T("usage.invite")))
+		return
+	}
+
+	maxUses := 1
+	if len(args) == 2 {
+		var err error
+		if maxUses, err = strconv.Atoi(args[1]); err != nil || maxUses < 0 {
+			s.sendText(ctx, b, update, tr(ctx).T("common.usage", tr(ctx).T("usage.invite")))
+			return
+		}
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate invite code")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+	invite := &appmodels.InviteCode{
+		Code:    code,
+		Role:    args[0],
+		MaxUses: maxUses,
+	}
+	if admin := userFromContext(ctx); admin != nil {
+		invite.CreatedByID = admin.ID
+		invite.WorkspaceID = admin.ActiveWorkspaceID
+	}
+	if err := s.inviteRepository.CreateInviteCode(invite); err != nil {
+		log.Error().Err(err).Msg("Failed to save invite code")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+
+	log.Info().
+		Int64("admin_telegram_id", update.Message.From.ID).
+		Str("code", invite.Code).
+		Str("role", invite.Role).
+		Int("max_uses", invite.MaxUses).
+		Msg("Invite code created")
+
+	s.sendText(ctx, b, update, tr(ctx).T("invites.created",
+		invite.Role, s.describeWorkspace(ctx, invite), describeUses(ctx, invite), s.inviteLink(invite.Code)))
+}
+
+func (s *TelegramBotService) invitesHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	invites, err := s.inviteRepository.GetActiveInviteCodes()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get invite codes")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+	if len(invites) == 0 {
+		s.sendText(ctx, b, update, tr(ctx).T("invites.none", tr(ctx).T("usage.invite")))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(tr(ctx).T("invites.title"))
+	for _, invite := range invites {
+		text.WriteString(tr(ctx).T("invites.item",
+			invite.Code, invite.Role, s.describeWorkspace(ctx, invite), describeUses(ctx, invite), s.inviteLink(invite.Code)))
+	}
+	text.WriteString(tr(ctx).T("invites.revoke_hint", tr(ctx).T("usage.revoke_invite")))
+	s.sendText(ctx, b, update, text.String())
+}
+
+func (s *TelegramBotService) revokeInviteHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	_, args := splitCommand(update.Message.Text)
+	if len(args) != 1 {
+		s.sendText(ctx, b, update, tr(ctx).T("common.usage", tr(ctx).T("usage.revoke_invite")))
+		return
+	}
+
+	if _, err := s.inviteRepository.GetByCode(args[0]); err != nil {
+		s.sendText(ctx, b, update, tr(ctx).T("invites.not_found", args[0]))
+		return
+	}
+	if err := s.inviteRepository.DeleteByCode(args[0]); err != nil {
+		log.Error().Err(err).Msg("Failed to delete invite code")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+	s.sendText(ctx, b, update, tr(ctx).T("invites.revoked", args[0]))
+}
+
+// inviteLink builds a t.me deep link which sends "/start <code>" to the bot
+func (s *TelegramBotService) inviteLink(code string) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s", s.botUser.Username, code)
+}
+
+func (s *TelegramBotService) describeWorkspace(ctx context.Context, invite *appmodels.InviteCode) string {
+	if name := s.workspaceName(invite.WorkspaceID); name != "" {
+		return tr(ctx).T("invites.in_workspace", name)
+	}
+	return ""
+}
+
+func describeUses(ctx context.Context, invite *appmodels.InviteCode) string {
+	if invite.MaxUses == 0 {
+		return tr(ctx).T("invites.uses_unlimited", invite.Uses)
+	}
+	return tr(ctx).T("invites.uses", invite.Uses, invite.MaxUses)
+}
+
+// newInviteCode generates a random code usable as a deep link parameter
+func newInviteCode() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
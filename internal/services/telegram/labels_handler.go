@@ -0,0 +1,134 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/labels"
+)
+
+const labelsCommand = "labels"
+
+// labelsHandler sends a sheet of labels for article numbers of the active workspace.
+// Each label has a QR code of the deep link opening the article number in the bot.
+// The first argument may pick the format of the sheet.
+func (s *TelegramBotService) labelsHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+	if user.ActiveWorkspaceID == uuid.Nil {
+		s.sendText(ctx, b, update, t.T("workspaces.none"))
+		return
+	}
+
+	_, args := splitCommand(update.Message.Text)
+	format := labels.Formats[0]
+	if len(args) > 0 {
+		if parsed, err := labels.ParseFormat(args[0]); err == nil {
+			format = parsed
+			args = args[1:]
+		}
+	}
+	numbers := parseArticleNumbers(strings.Join(args, " "))
+	if len(numbers) == 0 {
+		s.sendText(ctx, b, update, t.T("common.usage", t.T("usage.labels")))
+		return
+	}
+
+	var sheet []labels.Label
+	// Aliases of one article number get a single label
+	seen := make(map[uuid.UUID]bool)
+	for _, number := range numbers {
+		articleNumber, ok := s.findArticleNumber(ctx, b, update, number)
+		if !ok || seen[articleNumber.ID] {
+			continue
+		}
+		seen[articleNumber.ID] = true
+		sheet = append(sheet, labels.Label{
+			Number: articleNumber.Number,
+			Link:   labels.DeepLink(s.botUser.Username, articleNumber.ID),
+		})
+	}
+	if len(sheet) == 0 {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := labels.Write(&buf, format, sheet); err != nil {
+		log.Error().Err(err).Msg("Failed to print labels")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+
+	filename := "labels-" + time.Now().Format(time.DateOnly) + "." + string(format)
+	s.sendDocument(ctx, b, update, filename, &buf, t.N("labels.done", len(sheet), len(sheet)))
+}
+
+// openArticleLink searches for the article number of a label deep link.
+// Members of the workspace of the article number are switched to it first.
+func (s *TelegramBotService) openArticleLink(
+	ctx context.Context,
+	b *bot.Bot,
+	update *tgmodels.Update,
+	articleNumberID uuid.UUID,
+) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+
+	articleNumber, err := s.articleRepository.GetByID(articleNumberID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.sendText(ctx, b, update, t.T("labels.not_found"))
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("article_number_id", articleNumberID.String()).Msg("Failed to get article number")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+
+	if articleNumber.WorkspaceID != user.ActiveWorkspaceID {
+		member, err := s.workspaceRepository.IsMember(articleNumber.WorkspaceID, user.ID)
+		if err != nil {
+			log.Error().Err(err).Str("article_number_id", articleNumberID.String()).Msg("Failed to check workspace member")
+			s.sendText(ctx, b, update, t.T("common.error"))
+			return
+		}
+		// Numbers of foreign workspaces are not revealed, groups stay in their workspace
+		if !member || isGroupChat(update.Message.Chat) {
+			s.sendText(ctx, b, update, t.T("labels.not_found"))
+			return
+		}
+		if user.State != "" && user.State != appmodels.TelegramUserStateDefault {
+			s.sendText(ctx, b, update, t.T("workspaces.finish_first"))
+			return
+		}
+		if err := s.activateWorkspace(user, articleNumber.WorkspaceID); err != nil {
+			log.Error().Err(err).Str("article_number_id", articleNumberID.String()).Msg("Failed to activate workspace")
+			s.sendText(ctx, b, update, t.T("common.error"))
+			return
+		}
+		_, err = b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+			Text: t.T("workspaces.switched", s.workspaceName(articleNumber.WorkspaceID)),
+		}))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to send message")
+		}
+	}
+
+	// A one-shot search keeps the dialog the user is in
+	menu := mainMenu(ctx)
+	if user.State != appmodels.TelegramUserStateDefault {
+		menu = cancelMenu(ctx)
+	}
+	s.searchArticles(ctx, b, update, []string{articleNumber.Number}, menu)
+}
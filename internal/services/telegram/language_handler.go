@@ -0,0 +1,106 @@
+package telegram
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	"github.com/Conty111/AlfredoBot/internal/i18n"
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+)
+
+const languageCommand = "language"
+const languageCallbackPrefix = "language:"
+
+// messages are the texts of the bot in all supported languages
+var messages = i18n.Default()
+
+// tr returns messages in the language of the user who sent the update
+func tr(ctx context.Context) *i18n.Localizer {
+	return localizerFor(userFromContext(ctx))
+}
+
+// localizerFor prefers the language chosen with /language over the one of the Telegram client
+func localizerFor(user *appmodels.TelegramUser) *i18n.Localizer {
+	if user == nil {
+		return messages.Localizer()
+	}
+	return messages.Localizer(user.Locale, user.LanguageCode)
+}
+
+// isButton reports whether the text is the label of a menu button in any language,
+// so keyboards sent before a language change keep working
+func isButton(text, key string) bool {
+	return slices.Contains(messages.All(key), text)
+}
+
+// buttonHandlers registers the handler for the labels of a menu button in all languages
+func buttonHandlers(key string, handler bot.HandlerFunc) []bot.Option {
+	var opts []bot.Option
+	for _, text := range messages.All(key) {
+		opts = append(opts, bot.WithMessageTextHandler(text, bot.MatchTypeExact, handler))
+	}
+	return opts
+}
+
+func (s *TelegramBotService) languageHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	keyboard := [][]tgmodels.InlineKeyboardButton{
+		{{Text: tr(ctx).T("language.auto"), CallbackData: languageCallbackPrefix}},
+	}
+	for _, locale := range messages.Locales() {
+		keyboard = append(keyboard, []tgmodels.InlineKeyboardButton{
+			{Text: messages.Localizer(locale).T("language.name"), CallbackData: languageCallbackPrefix + locale},
+		})
+	}
+
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        tr(ctx).T("language.choose"),
+		ReplyMarkup: &tgmodels.InlineKeyboardMarkup{InlineKeyboard: keyboard},
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+// languageCallbackHandler stores the language picked from the inline keyboard,
+// an empty choice makes the bot follow the language of the Telegram client again
+func (s *TelegramBotService) languageCallbackHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	query := update.CallbackQuery
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to answer callback query")
+	}
+
+	user := userFromContext(ctx)
+	locale := strings.TrimPrefix(query.Data, languageCallbackPrefix)
+	if locale != "" && !slices.Contains(messages.Locales(), locale) {
+		log.Warn().Str("data", query.Data).Msg("Unknown language picked")
+		return
+	}
+
+	var text string
+	if err := s.userRepository.UpdateByID(user.ID, map[string]interface{}{"locale": locale}); err != nil {
+		log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to update user language")
+		text = tr(ctx).T("common.error")
+	} else {
+		user.Locale = locale
+		text = tr(ctx).T("language.set", tr(ctx).T("language.name"))
+		if locale == "" {
+			text = tr(ctx).T("language.auto_set")
+		}
+	}
+
+	_, err := b.SendMessage(ctx, inCallbackChat(query, &bot.SendMessageParams{
+		Text:        text,
+		ReplyMarkup: mainMenu(ctx),
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
@@ -0,0 +1,120 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/catalog"
+)
+
+const lookbookCommand = "lookbook"
+
+// lookbookHandler sends an admin a PDF lookbook of the active workspace with a grid of photos
+// per article number. Arguments narrow it down to article numbers, an uploader and days the
+// same way as for export, and +aliases, +uploader and +date add optional fields.
+func (s *TelegramBotService) lookbookHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+	if user.ActiveWorkspaceID == uuid.Nil {
+		s.sendText(ctx, b, update, t.T("workspaces.none"))
+		return
+	}
+
+	options := catalog.LookbookOptions{
+		Filter:       appmodels.PhotoFilter{WorkspaceID: user.ActiveWorkspaceID},
+		Title:        s.workspaceName(user.ActiveWorkspaceID),
+		AliasesLabel: t.T("lookbook.aliases"),
+	}
+	_, args := splitCommand(update.Message.Text)
+	for _, arg := range args {
+		if name, ok := strings.CutPrefix(arg, "+"); ok {
+			field, err := catalog.ParseLookbookField(name)
+			if err != nil {
+				s.sendText(ctx, b, update, t.T("common.usage", t.T("usage.lookbook")))
+				return
+			}
+			options.Fields = append(options.Fields, field)
+			continue
+		}
+		matched, ok := s.applyFilterArg(ctx, b, update, &options.Filter, arg)
+		if !ok {
+			return
+		}
+		if matched {
+			continue
+		}
+		for _, number := range parseArticleNumbers(arg) {
+			articleNumber, ok := s.findArticleNumber(ctx, b, update, number)
+			if !ok {
+				return
+			}
+			options.ArticleNumberIDs = append(options.ArticleNumberIDs, articleNumber.ID)
+		}
+	}
+
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text: t.T("lookbook.started"),
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+
+	// Lookbooks of large catalogs take tens of megabytes
+	file, err := os.CreateTemp("", "lookbook-*.pdf")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create lookbook file")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	lookbook := catalog.NewLookbook(s.photoRepository, s.articleRepository, s.userRepository, s.s3Config.Bucket)
+	report, err := lookbook.Generate(ctx, file, options)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate lookbook")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	log.Info().
+		Int64("telegram_id", user.TelegramID).
+		Int("article_numbers", report.ArticleNumbers).
+		Int("photos", report.Photos).
+		Int("failed", len(report.Failed)).
+		Msg("Lookbook generated")
+	if report.ArticleNumbers == 0 {
+		s.sendText(ctx, b, update, t.T("lookbook.empty"))
+		return
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read lookbook file")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	if limit := s.documentSizeLimit(); size > limit {
+		s.sendText(ctx, b, update, t.T("lookbook.too_large", size>>20, limit>>20))
+		return
+	}
+
+	caption := t.N("lookbook.done", report.ArticleNumbers, report.ArticleNumbers)
+	if len(report.Failed) > 0 {
+		caption += t.N("export.failed", len(report.Failed), len(report.Failed))
+	}
+	s.sendDocument(ctx, b, update, "lookbook-"+time.Now().Format(time.DateOnly)+".pdf", file, caption)
+}
@@ -2,44 +2,235 @@ package telegram
 
 import (
 	"context"
+	"strings"
 
 	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/labels"
 	"github.com/go-telegram/bot"
 	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
 func (s *TelegramBotService) saveUserMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 	return func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
-		if err := s.SaveUser(ctx, update.Message.From); err != nil {
-			log.Error().Err(err).Msg("failed to save user")
+		if from := updateSender(update); from != nil {
+			if !s.admitUser(ctx, b, update, from) {
+				return
+			}
+			if err := s.SaveUser(ctx, from); err != nil {
+				log.Error().Err(err).Msg("failed to save user")
+			}
 		}
 		next(ctx, b, update)
 	}
 }
 
-func (s *TelegramBotService) routerMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	// normalizedContextKey marks updates processed again after the bot mention was stripped
+	normalizedContextKey
+	contentContextKey
+)
+
+// contextWithUser stores the user who sent the update for the handlers down the chain
+func contextWithUser(ctx context.Context, user *appmodels.TelegramUser) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// userFromContext returns the user stored by accessMiddleware, or nil
+func userFromContext(ctx context.Context) *appmodels.TelegramUser {
+	user, _ := ctx.Value(userContextKey).(*appmodels.TelegramUser)
+	return user
+}
 
+// accessMiddleware checks the sender's role before the update reaches any handler
+func (s *TelegramBotService) accessMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 	return func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
-		if update.Message == nil {
+		from := updateSender(update)
+		if from == nil {
 			next(ctx, b, update)
 			return
 		}
 
-		user, err := s.userRepository.GetByTelegramID(update.Message.From.ID)
+		user, err := s.userRepository.GetByTelegramID(from.ID)
+		if err == nil {
+			err = s.applyChat(user, updateChat(update))
+		}
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to get user")
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        "Произошла ошибка. Пожалуйста, попробуйте снова.",
-				ReplyMarkup: mainMenu,
-			})
-			if err != nil {
-				log.Error().Err(err).Msg("Failed to send message")
+			log.Error().Err(err).Int64("telegram_id", from.ID).Msg("Failed to get user for access check")
+			if update.Message != nil {
+				_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+					Text: messages.Localizer(from.LanguageCode).T("common.error"),
+				}))
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to send message")
+				}
+			}
+			return
+		}
+
+		ctx = contextWithUser(ctx, user)
+		required := requiredPermission(user, update)
+		if !hasPermission(user, required) {
+			text := tr(ctx).T("access.denied")
+			if user.IsBanned() {
+				text = tr(ctx).T("access.banned")
+			}
+			s.denyAccess(ctx, b, update, user, text)
+			return
+		}
+
+		if chat := updateChat(update); isGroupChat(*chat) && user.ActiveWorkspaceID == uuid.Nil {
+			// Unbound groups never fall back to a workspace of the sender
+			if needsWorkspace(user, required, update) {
+				s.denyAccess(ctx, b, update, user, tr(ctx).T("groups.not_bound", tr(ctx).T("usage.bind_workspace")))
+				return
 			}
 			next(ctx, b, update)
 			return
 		}
+		s.ensureActiveWorkspace(user)
+		if (required == permissionSearch || required == permissionUpload) && user.ActiveWorkspaceID == uuid.Nil {
+			s.denyAccess(ctx, b, update, user, tr(ctx).T("workspaces.none"))
+			return
+		}
+		next(ctx, b, update)
+	}
+}
+
+// permission is a class of actions checked against the user's role
+type permission int
+
+const (
+	// permissionUse covers help, support and workspace switching
+	permissionUse permission = iota
+	permissionSearch
+	permissionUpload
+	permissionAdmin
+)
+
+// requiredPermission decides which permission the action requested by the update needs
+func requiredPermission(user *appmodels.TelegramUser, update *tgmodels.Update) permission {
+	if update.CallbackQuery != nil {
+		if strings.HasPrefix(update.CallbackQuery.Data, workspaceCallbackPrefix) ||
+			strings.HasPrefix(update.CallbackQuery.Data, languageCallbackPrefix) {
+			return permissionUse
+		}
+		if strings.HasPrefix(update.CallbackQuery.Data, trashCallbackPrefix) {
+			return permissionAdmin
+		}
+		if strings.HasPrefix(update.CallbackQuery.Data, barcodeUploadCallbackPrefix) {
+			return permissionUpload
+		}
+		return permissionSearch
+	}
+	if update.Message == nil {
+		return permissionUse
+	}
+
+	text := update.Message.Text
+	if command, _ := splitCommand(text); command != "" {
+		// Deep links of labels run a search
+		if _, ok := labels.ParseStartParameter(startPayload(update)); ok {
+			return permissionSearch
+		}
+		return commandPermission(command)
+	}
+	switch {
+	case user.State == appmodels.TelegramUserStateUploading || isButton(text, "menu.add"):
+		return permissionUpload
+	case user.State == appmodels.TelegramUserStateSearching || isButton(text, "menu.search"):
+		return permissionSearch
+	case user.State == appmodels.TelegramUserStateImporting || isButton(text, "menu.users"):
+		return permissionAdmin
+	}
+	return permissionUse
+}
+
+func commandPermission(command string) permission {
+	switch {
+	case command == addCommand || command == labelsCommand:
+		return permissionUpload
+	case command == searchCommand || command == myCommand:
+		return permissionSearch
+	case isAdminCommand("/" + command):
+		return permissionAdmin
+	}
+	return permissionUse
+}
+
+func hasPermission(user *appmodels.TelegramUser, required permission) bool {
+	switch required {
+	case permissionSearch:
+		return user.CanSearch()
+	case permissionUpload:
+		return user.CanUpload()
+	case permissionAdmin:
+		return user.IsAdmin()
+	}
+	return !user.IsBanned()
+}
+
+func (s *TelegramBotService) denyAccess(
+	ctx context.Context,
+	b *bot.Bot,
+	update *tgmodels.Update,
+	user *appmodels.TelegramUser,
+	text string,
+) {
+	log.Debug().
+		Int64("telegram_id", user.TelegramID).
+		Str("role", user.Role).
+		Str("reason", text).
+		Msg("Access denied")
+
+	// The role may have been revoked in the middle of a dialog
+	if user.State != "" && user.State != appmodels.TelegramUserStateDefault {
+		if err := s.chatRepository.SetState(updateChat(update).ID, user.TelegramID, appmodels.TelegramUserStateDefault); err != nil {
+			log.Error().Err(err).Msg("Failed to reset user state")
+		}
+	}
+
+	if update.CallbackQuery != nil {
+		_, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            text,
+			ShowAlert:       true,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to answer callback query")
+		}
+		return
+	}
+
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        text,
+		ReplyMarkup: mainMenu(ctx),
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+// routerMiddleware passes messages of users in the middle of a dialog to the dialog handler
+func (s *TelegramBotService) routerMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+		user := userFromContext(ctx)
+		// Commands work in the middle of a dialog too
+		if update.Message == nil || user == nil || isCommand(update.Message) {
+			next(ctx, b, update)
+			return
+		}
+
+		// Replies in the staff chat go to the users of tickets
+		if s.isStaffChat(update.Message.Chat) && repliedMessage(update.Message) != nil {
+			s.staffReplyHandler(ctx, b, update)
+			return
+		}
+
 		if user.State == appmodels.TelegramUserStateUploading {
 			s.photoMessageHandler(ctx, b, update)
 			return
@@ -48,6 +239,14 @@ func (s *TelegramBotService) routerMiddleware(next bot.HandlerFunc) bot.HandlerF
 			s.handleArticleNumberSearch(ctx, b, update)
 			return
 		}
+		if user.State == appmodels.TelegramUserStateSupport {
+			s.supportMessageHandler(ctx, b, update)
+			return
+		}
+		if user.State == appmodels.TelegramUserStateImporting {
+			s.importMessageHandler(ctx, b, update)
+			return
+		}
 		next(ctx, b, update)
 	}
 }
@@ -0,0 +1,163 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+)
+
+const grantCommand = "grant"
+const revokeCommand = "revoke"
+
+// isAdminCommand reports whether the text is one of the commands reserved for admins
+func isAdminCommand(text string) bool {
+	command, _ := splitCommand(text)
+	switch command {
+	case grantCommand, revokeCommand, inviteCommand, invitesCommand, revokeInviteCommand,
+		workspacesCommand, workspaceCreateCommand, workspaceAddCommand, workspaceRemoveCommand,
+		bindWorkspaceCommand, unbindWorkspaceCommand, reloadContentCommand, auditCommand, trashCommand, importCommand,
+		exportCommand, lookbookCommand, renameCommand, mergeCommand, moveCommand, aliasCommand, unaliasCommand:
+		return true
+	}
+	return false
+}
+
+// splitCommand splits "/command@bot arg1 arg2" into the command name and its arguments
+func splitCommand(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil
+	}
+	command, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "/"), "@")
+	return command, fields[1:]
+}
+
+func (s *TelegramBotService) usersHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	var text strings.Builder
+	text.WriteString(tr(ctx).T("roles.title"))
+	for _, role := range appmodels.TelegramUserRoles {
+		users, err := s.userRepository.GetUsersByRole(role)
+		if err != nil {
+			log.Error().Err(err).Str("role", role).Msg("Failed to get users by role")
+			s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+			return
+		}
+		names := make([]string, 0, len(users))
+		for _, user := range users {
+			names = append(names, displayUser(user))
+		}
+		if len(names) == 0 {
+			names = append(names, "—")
+		}
+		text.WriteString(tr(ctx).T("roles.item", role, strings.Join(names, ", ")))
+	}
+	text.WriteString(tr(ctx).T("roles.footer",
+		tr(ctx).T("usage.grant"), tr(ctx).T("usage.revoke"), strings.Join(appmodels.TelegramUserRoles, ", ")))
+
+	s.sendText(ctx, b, update, text.String())
+}
+
+func (s *TelegramBotService) grantRoleHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	_, args := splitCommand(update.Message.Text)
+	if len(args) != 2 || !appmodels.IsValidTelegramUserRole(args[1]) {
+		s.sendText(ctx, b, update, tr(ctx).T("roles.usage",
+			tr(ctx).T("usage.grant"), strings.Join(appmodels.TelegramUserRoles, ", ")))
+		return
+	}
+	s.setRole(ctx, b, update, args[0], args[1])
+}
+
+// revokeRoleHandler drops the user to the viewer role, banning is done with /grant
+func (s *TelegramBotService) revokeRoleHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	_, args := splitCommand(update.Message.Text)
+	if len(args) != 1 {
+		s.sendText(ctx, b, update, tr(ctx).T("common.usage", tr(ctx).T("usage.revoke")))
+		return
+	}
+	s.setRole(ctx, b, update, args[0], appmodels.TelegramUserRoleViewer)
+}
+
+func (s *TelegramBotService) setRole(ctx context.Context, b *bot.Bot, update *tgmodels.Update, reference, role string) {
+	target, err := s.findUser(reference)
+	if telegramID, idErr := strconv.ParseInt(reference, 10, 64); idErr == nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		// Registering the ID in advance admits the user under the whitelist policy
+		target = &appmodels.TelegramUser{
+			TelegramID: telegramID,
+			State:      appmodels.TelegramUserStateDefault,
+			Role:       role,
+		}
+		if err := s.userRepository.CreateUser(target); err != nil {
+			log.Error().Err(err).Int64("telegram_id", telegramID).Msg("Failed to register user")
+			s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+			return
+		}
+		if err := s.joinDefaultWorkspace(target); err != nil {
+			log.Error().Err(err).Int64("telegram_id", telegramID).Msg("Failed to join default workspace")
+		}
+		s.sendText(ctx, b, update, tr(ctx).T("roles.assigned", displayUser(target), role))
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.sendText(ctx, b, update, tr(ctx).T("roles.not_found", reference))
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("user", reference).Msg("Failed to find user")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+
+	if target.TelegramID == update.Message.From.ID {
+		s.sendText(ctx, b, update, tr(ctx).T("roles.own"))
+		return
+	}
+
+	if err := s.userRepository.WithActor(userFromContext(ctx)).UpdateByID(target.ID, map[string]interface{}{"role": role}); err != nil {
+		log.Error().Err(err).Str("user_id", target.ID.String()).Msg("Failed to update user role")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+
+	log.Info().
+		Int64("admin_telegram_id", update.Message.From.ID).
+		Int64("telegram_id", target.TelegramID).
+		Str("old_role", target.Role).
+		Str("role", role).
+		Msg("User role changed")
+
+	s.sendText(ctx, b, update, tr(ctx).T("roles.assigned", displayUser(target), role))
+}
+
+// findUser looks a user up by "@username" or numeric Telegram ID
+func (s *TelegramBotService) findUser(reference string) (*appmodels.TelegramUser, error) {
+	if telegramID, err := strconv.ParseInt(reference, 10, 64); err == nil {
+		return s.userRepository.GetByTelegramID(telegramID)
+	}
+	return s.userRepository.GetByUsername(strings.TrimPrefix(reference, "@"))
+}
+
+func (s *TelegramBotService) sendText(ctx context.Context, b *bot.Bot, update *tgmodels.Update, text string) {
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        text,
+		ReplyMarkup: mainMenu(ctx),
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+func displayUser(user *appmodels.TelegramUser) string {
+	if user.Username != "" {
+		return "@" + user.Username
+	}
+	return strconv.FormatInt(user.TelegramID, 10)
+}
@@ -2,7 +2,7 @@ package telegram
 
 import (
 	"context"
-	"fmt"
+	"strings"
 
 	"github.com/go-telegram/bot"
 	tgmodels "github.com/go-telegram/bot/models"
@@ -13,18 +13,14 @@ import (
 )
 
 func (s *TelegramBotService) searchByArticleNumberHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
-	err := s.userRepository.UpdateByTelegramID(update.Message.From.ID, map[string]interface{}{
-		"state": appmodels.TelegramUserStateSearching,
-	})
-	if err != nil {
+	if err := s.setState(update.Message, appmodels.TelegramUserStateSearching); err != nil {
 		log.Error().Err(err).Msg("Failed to update user state")
 	}
 
-	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      update.Message.Chat.ID,
-		Text:        "Пожалуйста, введите артикул товара для поиска:",
-		ReplyMarkup: cancelMenu,
-	})
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        tr(ctx).T("search.prompt"),
+		ReplyMarkup: cancelMenu(ctx),
+	}))
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to send message")
 		return
@@ -32,49 +28,74 @@ func (s *TelegramBotService) searchByArticleNumberHandler(ctx context.Context, b
 }
 
 func (s *TelegramBotService) handleArticleNumberSearch(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
-
-	user, err := s.userRepository.GetByTelegramID(update.Message.From.ID)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to get user")
+	if len(update.Message.Photo) > 0 || update.Message.Document != nil {
+		s.searchByBarcode(ctx, b, update)
 		return
 	}
-
 	if update.Message.Text == "" {
-		_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:      update.Message.Chat.ID,
-			Text:        "Пожалуйста, введите артикулы через запятую",
-			ReplyMarkup: cancelMenu,
-		})
+		_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+			Text:        tr(ctx).T("search.empty"),
+			ReplyMarkup: cancelMenu(ctx),
+		}))
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to send message")
 		}
 		return
 	}
-	if update.Message.Text == cancelText {
+	if isButton(update.Message.Text, "menu.cancel") {
 		s.cancelSearchPhotos(ctx, update, b)
 		return
 	}
 
-	articleNumbers := parseArticleNumbers(update.Message.Text)
-	totalPhotos := map[uuid.UUID]string{}
+	// Reset user state
+	if err := s.setState(update.Message, appmodels.TelegramUserStateDefault); err != nil {
+		log.Error().Err(err).Msg("Failed to reset user state")
+	}
+
+	s.searchArticles(ctx, b, update, parseArticleNumbers(update.Message.Text), mainMenu(ctx))
+}
+
+// searchArticles sends photos of the article numbers found in the active workspace.
+// Answers carry the given reply keyboard.
+func (s *TelegramBotService) searchArticles(
+	ctx context.Context,
+	b *bot.Bot,
+	update *tgmodels.Update,
+	articleNumbers []string,
+	menu tgmodels.ReplyMarkup,
+) {
+	user := userFromContext(ctx)
+
+	var (
+		foundPhotos   []appmodels.Photo
+		foundArticles []appmodels.ArticleNumber
+		// foundBy keeps the alias an article number was found by, if it was
+		foundBy      []string
+		seenPhotos   = map[uuid.UUID]bool{}
+		seenArticles = map[uuid.UUID]bool{}
+	)
 
 	for _, article := range articleNumbers {
-		articleNumber, err := s.articleRepository.GetByNumber(article)
+		articleNumber, err := s.articleRepository.GetByNumber(user.ActiveWorkspaceID, article)
 		if err != nil {
 			log.Debug().
 				Err(err).
 				Str("article_number", article).
 				Msg("Article number not found")
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        fmt.Sprintf("Артикул '%s' не найден в базе данных.", article),
-				ReplyMarkup: mainMenu,
-			})
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("search.not_found", article),
+				ReplyMarkup: menu,
+			}))
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to send message")
 			}
 			continue
 		}
+		// Aliases and the number itself may be searched for together
+		if seenArticles[articleNumber.ID] {
+			continue
+		}
+		seenArticles[articleNumber.ID] = true
 
 		// Get photos associated with this article number
 		articleNumberWithPhotos, err := s.articleRepository.GetArticleNumberWithPhotos(articleNumber.ID)
@@ -84,23 +105,39 @@ func (s *TelegramBotService) handleArticleNumberSearch(ctx context.Context, b *b
 				Str("article_number_id", articleNumber.ID.String()).
 				Msg("Failed to get photos for article number")
 
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        "Произошла ошибка при поиске фотографий для артикула " + articleNumber.Number,
-				ReplyMarkup: mainMenu,
-			})
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("search.failed", articleNumber.Number),
+				ReplyMarkup: menu,
+			}))
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to send message")
 			}
 			return
 		}
 
-		if len(articleNumberWithPhotos.Photos) == 0 {
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:      update.Message.Chat.ID,
-				Text:        fmt.Sprintf("Для артикула '%s' не найдено фотографий.", articleNumberWithPhotos.Number),
-				ReplyMarkup: mainMenu,
-			})
+		appliedPhotos := 0
+		for _, photo := range articleNumberWithPhotos.Photos {
+			if photo.State != appmodels.PhotoApplied {
+				continue
+			}
+			if len(photo.ArticleNumbers) == 0 {
+				log.Warn().
+					Str("photo_id", photo.ID.String()).
+					Msg("Photo has no associated article numbers - skipping")
+				continue
+			}
+			appliedPhotos++
+			if !seenPhotos[photo.ID] {
+				seenPhotos[photo.ID] = true
+				foundPhotos = append(foundPhotos, photo)
+			}
+		}
+
+		if appliedPhotos == 0 {
+			_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+				Text:        tr(ctx).T("search.no_photos", articleNumberWithPhotos.Number),
+				ReplyMarkup: menu,
+			}))
 			log.Debug().
 				Str("article_number", articleNumberWithPhotos.Number).
 				Str("id", articleNumber.ID.String()).
@@ -108,85 +145,95 @@ func (s *TelegramBotService) handleArticleNumberSearch(ctx context.Context, b *b
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to send message")
 			}
+			continue
+		}
+		foundArticles = append(foundArticles, *articleNumberWithPhotos)
+		if article == articleNumber.Number {
+			foundBy = append(foundBy, "")
 		} else {
-			// Send each photo
-			for _, photo := range articleNumberWithPhotos.Photos {
-				if photo.State != appmodels.PhotoApplied {
-					continue
-				}
-				photoFull, err := s.photoRepository.GetByID(photo.ID)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("photo_id", photo.ID.String()).
-						Msg("Failed to get photo")
-					continue
-				}
-				photoFull.State = appmodels.PhotoApplied
-				if len(photo.ArticleNumbers) == 0 {
-					log.Warn().
-						Str("photo_id", photo.ID.String()).
-						Msg("Photo has no associated article numbers - skipping")
-					continue
-				}
-
-				articlesStr := ""
-				for _, article := range photo.ArticleNumbers[:len(photo.ArticleNumbers)-1] {
-					articlesStr += article.Number + ", "
-				}
-				articlesStr += photo.ArticleNumbers[len(photo.ArticleNumbers)-1].Number
-				totalPhotos[photo.S3Key] = articlesStr
-			}
+			foundBy = append(foundBy, article)
 		}
 	}
 
-	for s3Key, articlesStr := range totalPhotos {
-		fileReader, err := s.photoRepository.GetPhotoFromS3(ctx, user.ID, s3Key, s.s3Config.Bucket)
-		if err != nil {
-			log.Error().
-				Err(err).
-				Str("s3_key", s3Key.String()).
-				Msg("Failed to download file from S3")
-			continue
+	for _, photo := range foundPhotos {
+		s.sendSearchResultPhoto(ctx, b, update.Message, photo)
+	}
+
+	for i, articleNumber := range foundArticles {
+		photos := countAppliedPhotos(articleNumber.Photos)
+		text := tr(ctx).N("search.found", photos, articleNumber.Number, photos)
+		if foundBy[i] != "" {
+			text = tr(ctx).N("search.found_alias", photos, articleNumber.Number, foundBy[i], photos)
 		}
-		defer func() {
-			if err := fileReader.Close(); err != nil {
-				log.Error().Err(err).Msg("Failed to close file reader")
-			}
-		}()
-
-		// Send photo with articles as caption
-		_, err = b.SendPhoto(ctx, &bot.SendPhotoParams{
-			ChatID: update.Message.Chat.ID,
-			Photo: &tgmodels.InputFileUpload{
-				Data:     fileReader,
-				Filename: s3Key.String() + ".jpg",
-			},
-			Caption: articlesStr,
-		})
+		_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+			Text:        text,
+			ReplyMarkup: shareArticleKeyboard(ctx, articleNumber.ID),
+		}))
 		if err != nil {
-			log.Error().
-				Err(err).
-				Str("s3_key", s3Key.String()).
-				Msg("Failed to send photo")
+			log.Error().Err(err).Msg("Failed to send message")
 		}
 	}
 
-	// Reset user state
-	if err := s.userRepository.UpdateByTelegramID(update.Message.From.ID, map[string]interface{}{
-		"state": appmodels.TelegramUserStateDefault,
-	}); err != nil {
-		log.Error().Err(err).Msg("Failed to reset user state")
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        tr(ctx).T("search.done"),
+		ReplyMarkup: menu,
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message with photos")
 	}
+}
 
-	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      update.Message.Chat.ID,
-		Text:        "Поиск завершен!",
-		ReplyMarkup: mainMenu,
-	})
+// sendSearchResultPhoto sends a found photo captioned with all its article numbers
+func (s *TelegramBotService) sendSearchResultPhoto(ctx context.Context, b *bot.Bot, message *tgmodels.Message, photo appmodels.Photo) {
+	fileReader, format, err := s.openDeliveredPhoto(ctx, message.Chat, userFromContext(ctx), &photo)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to send message with photos")
+		log.Error().
+			Err(err).
+			Str("s3_key", photo.S3Key.String()).
+			Msg("Failed to download file from S3")
+		return
+	}
+	defer func() {
+		if err := fileReader.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close file reader")
+		}
+	}()
+
+	articles := make([]string, 0, len(photo.ArticleNumbers))
+	for _, article := range photo.ArticleNumbers {
+		articles = append(articles, article.Number)
+	}
+
+	// Send photo with articles as caption
+	params := &bot.SendPhotoParams{
+		ChatID: message.Chat.ID,
+		Photo: &tgmodels.InputFileUpload{
+			Data:     fileReader,
+			Filename: photo.S3Key.String() + appmodels.PhotoExtension(format),
+		},
+		Caption:     strings.Join(articles, ", "),
+		ReplyMarkup: sharePhotoKeyboard(ctx, photo.ID),
+	}
+	if message.IsTopicMessage {
+		params.MessageThreadID = message.MessageThreadID
+	}
+	_, err = b.SendPhoto(ctx, params)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("s3_key", photo.S3Key.String()).
+			Msg("Failed to send photo")
+	}
+}
+
+func countAppliedPhotos(photos []appmodels.Photo) int {
+	count := 0
+	for _, photo := range photos {
+		if photo.State == appmodels.PhotoApplied {
+			count++
+		}
 	}
+	return count
 }
 
 func (s *TelegramBotService) cancelSearchPhotos(
@@ -195,18 +242,15 @@ func (s *TelegramBotService) cancelSearchPhotos(
 	b *bot.Bot,
 ) {
 	// Reset user state
-	if err := s.userRepository.UpdateByTelegramID(update.Message.From.ID, map[string]interface{}{
-		"state": appmodels.TelegramUserStateDefault,
-	}); err != nil {
+	if err := s.setState(update.Message, appmodels.TelegramUserStateDefault); err != nil {
 		log.Error().Err(err).Msg("Failed to reset user state")
 	}
 
 	// Send confirmation message
-	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      update.Message.Chat.ID,
-		Text:        "Поиск отменен",
-		ReplyMarkup: mainMenu,
-	})
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        tr(ctx).T("search.cancelled"),
+		ReplyMarkup: mainMenu(ctx),
+	}))
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to send cancellation message")
 	}
@@ -0,0 +1,157 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+)
+
+const (
+	shareCallbackPrefix        = "share:"
+	sharePhotoCallbackPrefix   = shareCallbackPrefix + "photo:"
+	shareArticleCallbackPrefix = shareCallbackPrefix + "article:"
+)
+
+func sharePhotoKeyboard(ctx context.Context, photoID uuid.UUID) *tgmodels.InlineKeyboardMarkup {
+	return &tgmodels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgmodels.InlineKeyboardButton{
+			{
+				{Text: tr(ctx).T("share.photo_button"), CallbackData: sharePhotoCallbackPrefix + photoID.String()},
+			},
+		},
+	}
+}
+
+func shareArticleKeyboard(ctx context.Context, articleNumberID uuid.UUID) *tgmodels.InlineKeyboardMarkup {
+	return &tgmodels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgmodels.InlineKeyboardButton{
+			{
+				{Text: tr(ctx).T("share.article_button"), CallbackData: shareArticleCallbackPrefix + articleNumberID.String()},
+			},
+		},
+	}
+}
+
+// shareCallbackHandler replies with expiring presigned links to a photo or to all photos of an article
+func (s *TelegramBotService) shareCallbackHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	query := update.CallbackQuery
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to answer callback query")
+	}
+
+	var (
+		text string
+		err  error
+	)
+	switch {
+	case strings.HasPrefix(query.Data, sharePhotoCallbackPrefix):
+		text, err = s.sharePhotoText(ctx, callbackChat(query), strings.TrimPrefix(query.Data, sharePhotoCallbackPrefix))
+	case strings.HasPrefix(query.Data, shareArticleCallbackPrefix):
+		text, err = s.shareArticleText(ctx, callbackChat(query), strings.TrimPrefix(query.Data, shareArticleCallbackPrefix))
+	default:
+		err = fmt.Errorf("unknown share callback: %s", query.Data)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("data", query.Data).Msg("Failed to create share links")
+		text = tr(ctx).T("share.failed")
+	}
+
+	_, err = b.SendMessage(ctx, inCallbackChat(query, &bot.SendMessageParams{
+		Text: text,
+		LinkPreviewOptions: &tgmodels.LinkPreviewOptions{
+			IsDisabled: bot.True(),
+		},
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+func (s *TelegramBotService) sharePhotoText(ctx context.Context, chat tgmodels.Chat, rawPhotoID string) (string, error) {
+	photoID, err := uuid.Parse(rawPhotoID)
+	if err != nil {
+		return "", fmt.Errorf("invalid photo id: %w", err)
+	}
+
+	photo, err := s.photoRepository.GetByID(photoID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkWorkspaceAccess(ctx, photo.WorkspaceID); err != nil {
+		return "", err
+	}
+
+	link, err := s.deliveredPhotoURL(ctx, chat, userFromContext(ctx), photo)
+	if err != nil {
+		return "", err
+	}
+
+	return tr(ctx).T("share.photo", s.shareExpiry(), link), nil
+}
+
+func (s *TelegramBotService) shareArticleText(ctx context.Context, chat tgmodels.Chat, rawArticleNumberID string) (string, error) {
+	articleNumberID, err := uuid.Parse(rawArticleNumberID)
+	if err != nil {
+		return "", fmt.Errorf("invalid article number id: %w", err)
+	}
+
+	articleNumber, err := s.articleRepository.GetArticleNumberWithPhotos(articleNumberID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkWorkspaceAccess(ctx, articleNumber.WorkspaceID); err != nil {
+		return "", err
+	}
+
+	var links []string
+	for _, photo := range articleNumber.Photos {
+		if photo.State != appmodels.PhotoApplied {
+			continue
+		}
+		link, err := s.deliveredPhotoURL(ctx, chat, userFromContext(ctx), &photo)
+		if err != nil {
+			return "", err
+		}
+		links = append(links, fmt.Sprintf("%d. %s", len(links)+1, link))
+	}
+	if len(links) == 0 {
+		return tr(ctx).T("search.no_photos", articleNumber.Number), nil
+	}
+
+	return tr(ctx).T("share.article", articleNumber.Number, s.shareExpiry(), strings.Join(links, "\n")), nil
+}
+
+// checkWorkspaceAccess refuses sharing photos of workspaces the user is not a member of.
+// Photos of the workspace a group is bound to may be shared by anyone in the group.
+func (s *TelegramBotService) checkWorkspaceAccess(ctx context.Context, workspaceID uuid.UUID) error {
+	user := userFromContext(ctx)
+	if user == nil {
+		return fmt.Errorf("unknown user")
+	}
+	if workspaceID == user.ActiveWorkspaceID {
+		return nil
+	}
+	member, err := s.workspaceRepository.IsMember(workspaceID, user.ID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return fmt.Errorf("user %s is not a member of workspace %s", user.ID, workspaceID)
+	}
+	return nil
+}
+
+func (s *TelegramBotService) shareExpiry() string {
+	return time.Now().Add(s.shareConfig.LinkTTL).Format("02.01.2006 15:04 MST")
+}
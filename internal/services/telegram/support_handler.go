@@ -0,0 +1,415 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog/log"
+
+	"github.com/Conty111/AlfredoBot/internal/i18n"
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+)
+
+const ticketsCommand = "tickets"
+const ticketCommand = "ticket"
+const closeTicketCommand = "close"
+
+// ticketHistoryLimit caps the number of messages shown by /ticket
+const ticketHistoryLimit = 20
+
+const ticketTimeFormat = "02.01.2006 15:04"
+
+// supportEnabled reports whether messages sent in the chat are relayed to the staff chat.
+// Only private chats are relayed, in groups the support button shows the contacts.
+func (s *TelegramBotService) supportEnabled(chat tgmodels.Chat) bool {
+	return s.supportConfig.ChatID != 0 && chat.Type == tgmodels.ChatTypePrivate
+}
+
+// isStaffChat reports whether the chat is the staff group receiving tickets
+func (s *TelegramBotService) isStaffChat(chat tgmodels.Chat) bool {
+	return s.supportConfig.ChatID != 0 && chat.ID == s.supportConfig.ChatID
+}
+
+// supportHandler shows the support contacts. With a staff chat configured it also
+// starts the support dialog, in which messages of the user become a ticket.
+func (s *TelegramBotService) supportHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	text, ok := render(contentFromContext(ctx).support, t.Locale(), s.contentDataFor(update.Message))
+	if !ok {
+		text = t.T("support.text")
+	}
+
+	if !s.supportEnabled(update.Message.Chat) {
+		s.sendText(ctx, b, update, text)
+		return
+	}
+
+	if err := s.setState(update.Message, appmodels.TelegramUserStateSupport); err != nil {
+		log.Error().Err(err).Msg("Failed to update user state")
+	}
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        text + "\n\n" + t.T("support.prompt"),
+		ReplyMarkup: cancelMenu(ctx),
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send support message")
+	}
+}
+
+// supportMessageHandler relays a message of the user in the support dialog to the staff chat
+func (s *TelegramBotService) supportMessageHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	message := update.Message
+	t := tr(ctx)
+	if isButton(message.Text, "menu.cancel") {
+		s.leaveSupport(ctx, b, update)
+		return
+	}
+
+	text, photoFileID := messageContent(message)
+	if text == "" && photoFileID == "" {
+		s.sendSupportText(ctx, b, update, t.T("support.unsupported"))
+		return
+	}
+
+	ticket, created, err := s.openTicket(message.From.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("telegram_id", message.From.ID).Msg("Failed to open support ticket")
+		s.sendSupportText(ctx, b, update, t.T("support.failed"))
+		return
+	}
+
+	// The staff chat is addressed in the default language, it is shared by the whole team
+	staff := messages.Localizer()
+	staffText := staff.T("support.staff_message", ticket.ID, describeCustomer(message.From), text)
+	if created {
+		staffText = staff.T("support.staff_new", ticket.ID, describeCustomer(message.From), text)
+	}
+	sent, err := s.sendTo(ctx, b, s.supportConfig.ChatID, s.supportConfig.ThreadID, strings.TrimSpace(staffText), photoFileID)
+	if err != nil {
+		log.Error().Err(err).Uint("ticket_id", ticket.ID).Msg("Failed to relay message to the staff chat")
+		s.sendSupportText(ctx, b, update, t.T("support.failed"))
+		return
+	}
+
+	err = s.ticketRepository.AddMessage(&appmodels.SupportMessage{
+		TicketID:       ticket.ID,
+		TelegramID:     message.From.ID,
+		Text:           text,
+		PhotoFileID:    photoFileID,
+		StaffChatID:    sent.Chat.ID,
+		StaffMessageID: sent.ID,
+	})
+	if err != nil {
+		log.Error().Err(err).Uint("ticket_id", ticket.ID).Msg("Failed to save support message")
+	}
+
+	// Further messages of the ticket are relayed silently, like in a chat with a person
+	if created {
+		s.sendSupportText(ctx, b, update, t.T("support.created", ticket.ID))
+	}
+}
+
+// openTicket returns the open ticket of the user or creates a new one
+func (s *TelegramBotService) openTicket(telegramID int64) (*appmodels.SupportTicket, bool, error) {
+	ticket, err := s.ticketRepository.GetOpenTicket(telegramID)
+	if err == nil {
+		return ticket, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	ticket = &appmodels.SupportTicket{TelegramID: telegramID}
+	if err := s.ticketRepository.CreateTicket(ticket); err != nil {
+		return nil, false, err
+	}
+	log.Info().Uint("ticket_id", ticket.ID).Int64("telegram_id", telegramID).Msg("Support ticket opened")
+	return ticket, true, nil
+}
+
+func (s *TelegramBotService) leaveSupport(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	if err := s.setState(update.Message, appmodels.TelegramUserStateDefault); err != nil {
+		log.Error().Err(err).Msg("Failed to reset user state")
+	}
+	s.sendText(ctx, b, update, tr(ctx).T("support.left"))
+}
+
+// sendSupportText answers a user in the support dialog, keeping the cancel keyboard
+func (s *TelegramBotService) sendSupportText(ctx context.Context, b *bot.Bot, update *tgmodels.Update, text string) {
+	_, err := b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        text,
+		ReplyMarkup: cancelMenu(ctx),
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+// staffReplyHandler sends a reply of the staff to a relayed message back to the user of the ticket
+func (s *TelegramBotService) staffReplyHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	message := update.Message
+	t := tr(ctx)
+
+	ticket, err := s.ticketRepository.GetTicketByStaffMessage(message.Chat.ID, repliedMessage(message).ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.sendText(ctx, b, update, t.T("support.not_ticket"))
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find support ticket")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	if !ticket.IsOpen() {
+		s.sendText(ctx, b, update, t.T("support.ticket_closed", ticket.ID))
+		return
+	}
+
+	text, photoFileID := messageContent(message)
+	if text == "" && photoFileID == "" {
+		s.sendText(ctx, b, update, t.T("support.unsupported"))
+		return
+	}
+
+	customer := s.customerLocalizer(ticket.TelegramID)
+	reply := strings.TrimSpace(customer.T("support.reply", ticket.ID, text))
+	if _, err := s.sendTo(ctx, b, ticket.TelegramID, 0, reply, photoFileID); err != nil {
+		log.Error().Err(err).Uint("ticket_id", ticket.ID).Msg("Failed to deliver support reply")
+		s.sendText(ctx, b, update, t.T("support.delivery_failed", ticket.ID))
+		return
+	}
+
+	err = s.ticketRepository.AddMessage(&appmodels.SupportMessage{
+		TicketID:       ticket.ID,
+		FromStaff:      true,
+		TelegramID:     message.From.ID,
+		Text:           text,
+		PhotoFileID:    photoFileID,
+		StaffChatID:    message.Chat.ID,
+		StaffMessageID: message.ID,
+	})
+	if err != nil {
+		log.Error().Err(err).Uint("ticket_id", ticket.ID).Msg("Failed to save support message")
+	}
+}
+
+// closeTicketHandler closes the ticket given by its number or by the replied message
+func (s *TelegramBotService) closeTicketHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	ticket, ok := s.commandTicket(ctx, b, update, "usage.close")
+	if !ok {
+		return
+	}
+	if !ticket.IsOpen() {
+		s.sendText(ctx, b, update, t.T("support.already_closed", ticket.ID))
+		return
+	}
+
+	if err := s.ticketRepository.CloseTicket(ticket.ID); err != nil {
+		log.Error().Err(err).Uint("ticket_id", ticket.ID).Msg("Failed to close support ticket")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	log.Info().
+		Uint("ticket_id", ticket.ID).
+		Int64("staff_telegram_id", update.Message.From.ID).
+		Msg("Support ticket closed")
+
+	customer := s.customerLocalizer(ticket.TelegramID)
+	if _, err := s.sendTo(ctx, b, ticket.TelegramID, 0, customer.T("support.closed_user", ticket.ID), ""); err != nil {
+		log.Error().Err(err).Uint("ticket_id", ticket.ID).Msg("Failed to notify user about closed ticket")
+	}
+	s.sendText(ctx, b, update, t.T("support.closed", ticket.ID))
+}
+
+// ticketsHandler lists open tickets in the staff chat
+func (s *TelegramBotService) ticketsHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	if !s.isStaffChat(update.Message.Chat) {
+		s.sendText(ctx, b, update, t.T("support.staff_only"))
+		return
+	}
+
+	tickets, err := s.ticketRepository.GetOpenTickets()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get open support tickets")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	if len(tickets) == 0 {
+		s.sendText(ctx, b, update, t.T("support.none"))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(t.T("support.title"))
+	for _, ticket := range tickets {
+		text.WriteString(t.T("support.item", ticket.ID, s.describeTelegramID(ticket.TelegramID),
+			ticket.UpdatedAt.Format(ticketTimeFormat)))
+	}
+	s.sendText(ctx, b, update, text.String())
+}
+
+// ticketHandler shows the history of a ticket in the staff chat
+func (s *TelegramBotService) ticketHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	ticket, ok := s.commandTicket(ctx, b, update, "usage.ticket")
+	if !ok {
+		return
+	}
+
+	history, err := s.ticketRepository.GetMessages(ticket.ID)
+	if err != nil {
+		log.Error().Err(err).Uint("ticket_id", ticket.ID).Msg("Failed to get support ticket history")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+
+	customer := s.describeTelegramID(ticket.TelegramID)
+	var text strings.Builder
+	text.WriteString(t.T("support.history", ticket.ID, customer, t.T("support.status."+ticket.Status)))
+	if len(history) > ticketHistoryLimit {
+		earlier := len(history) - ticketHistoryLimit
+		text.WriteString(t.N("support.history_more", earlier, earlier))
+		history = history[earlier:]
+	}
+	for _, message := range history {
+		author := customer
+		if message.FromStaff {
+			author = t.T("support.staff", s.describeTelegramID(message.TelegramID))
+		}
+		body := message.Text
+		if message.PhotoFileID != "" {
+			body = strings.TrimSpace(t.T("support.photo") + " " + body)
+		}
+		text.WriteString(t.T("support.history_item", message.CreatedAt.Format(ticketTimeFormat), author, body))
+	}
+	s.sendText(ctx, b, update, text.String())
+}
+
+// commandTicket finds the ticket of a staff command by the number in its arguments
+// or by the message the command replies to. It answers the staff itself when there is none.
+func (s *TelegramBotService) commandTicket(
+	ctx context.Context,
+	b *bot.Bot,
+	update *tgmodels.Update,
+	usageKey string,
+) (*appmodels.SupportTicket, bool) {
+	t := tr(ctx)
+	message := update.Message
+	if !s.isStaffChat(message.Chat) {
+		s.sendText(ctx, b, update, t.T("support.staff_only"))
+		return nil, false
+	}
+
+	var (
+		ticket *appmodels.SupportTicket
+		err    error
+	)
+	_, args := splitCommand(message.Text)
+	switch {
+	case len(args) > 0:
+		number := strings.TrimPrefix(args[0], "#")
+		id, parseErr := strconv.ParseUint(number, 10, 32)
+		if parseErr != nil {
+			s.sendText(ctx, b, update, t.T("support.not_found", number))
+			return nil, false
+		}
+		ticket, err = s.ticketRepository.GetTicket(uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.sendText(ctx, b, update, t.T("support.not_found", number))
+			return nil, false
+		}
+	case repliedMessage(message) != nil:
+		ticket, err = s.ticketRepository.GetTicketByStaffMessage(message.Chat.ID, repliedMessage(message).ID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.sendText(ctx, b, update, t.T("support.not_ticket"))
+			return nil, false
+		}
+	default:
+		s.sendText(ctx, b, update, t.T("common.usage", t.T(usageKey)))
+		return nil, false
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find support ticket")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return nil, false
+	}
+	return ticket, true
+}
+
+// sendTo sends a text, or a photo with the text as the caption, to a chat the update did not come from
+func (s *TelegramBotService) sendTo(
+	ctx context.Context,
+	b *bot.Bot,
+	chatID int64,
+	threadID int,
+	text string,
+	photoFileID string,
+) (*tgmodels.Message, error) {
+	if photoFileID != "" {
+		return b.SendPhoto(ctx, &bot.SendPhotoParams{
+			ChatID:          chatID,
+			MessageThreadID: threadID,
+			Photo:           &tgmodels.InputFileString{Data: photoFileID},
+			Caption:         text,
+		})
+	}
+	return b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		MessageThreadID: threadID,
+		Text:            text,
+	})
+}
+
+// customerLocalizer returns messages in the language of the user of a ticket
+func (s *TelegramBotService) customerLocalizer(telegramID int64) *i18n.Localizer {
+	user, err := s.userRepository.GetByTelegramID(telegramID)
+	if err != nil {
+		log.Warn().Err(err).Int64("telegram_id", telegramID).Msg("Failed to get user of support ticket")
+		return messages.Localizer()
+	}
+	return localizerFor(user)
+}
+
+// describeTelegramID shows a known user by username, others by Telegram ID
+func (s *TelegramBotService) describeTelegramID(telegramID int64) string {
+	user, err := s.userRepository.GetByTelegramID(telegramID)
+	if err != nil {
+		return strconv.FormatInt(telegramID, 10)
+	}
+	return displayUser(user)
+}
+
+// describeCustomer introduces the author of a ticket message to the staff
+func describeCustomer(from *tgmodels.User) string {
+	name := strings.TrimSpace(from.FirstName + " " + from.LastName)
+	if from.Username != "" {
+		return name + " (@" + from.Username + ", " + strconv.FormatInt(from.ID, 10) + ")"
+	}
+	return name + " (" + strconv.FormatInt(from.ID, 10) + ")"
+}
+
+// repliedMessage returns the message the message explicitly replies to, or nil.
+// In forum topics messages also reply to the service message which created the topic.
+func repliedMessage(message *tgmodels.Message) *tgmodels.Message {
+	reply := message.ReplyToMessage
+	if reply == nil || reply.ForumTopicCreated != nil {
+		return nil
+	}
+	return reply
+}
+
+// messageContent returns the text and the largest photo of a message, the caption is the text of a photo
+func messageContent(message *tgmodels.Message) (string, string) {
+	if len(message.Photo) > 0 {
+		return message.Caption, message.Photo[len(message.Photo)-1].FileID
+	}
+	return message.Text, ""
+}
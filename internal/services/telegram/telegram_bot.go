@@ -3,7 +3,9 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-telegram/bot"
@@ -12,31 +14,55 @@ import (
 
 	"github.com/Conty111/AlfredoBot/internal/configs"
 	"github.com/Conty111/AlfredoBot/internal/interfaces"
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
 )
 
+const defaultShareLinkTTL = 24 * time.Hour
+
 // TelegramBotService is the main service that manages the Telegram bot operations.
 // It handles command registration, message processing, and bot lifecycle management.
 type TelegramBotService struct {
-	bot               *bot.Bot
-	config            *configs.TelegramConfig
-	s3Config          *configs.S3Config
-	userRepository    interfaces.TelegramUserManager
-	photoRepository   interfaces.PhotoManager
-	articleRepository interfaces.ArticleNumberManager
-	wg                sync.WaitGroup
-	stopCh            chan struct{}
-	cancel            context.CancelFunc
-	botUser           *tgmodels.User
+	bot                 *bot.Bot
+	config              *configs.TelegramConfig
+	s3Config            *configs.S3Config
+	shareConfig         *configs.ShareConfig
+	accessConfig        *configs.AccessConfig
+	userRepository      interfaces.TelegramUserManager
+	photoRepository     interfaces.PhotoManager
+	articleRepository   interfaces.ArticleNumberManager
+	inviteRepository    interfaces.InviteCodeManager
+	workspaceRepository interfaces.WorkspaceManager
+	chatRepository      interfaces.ChatManager
+	ticketRepository    interfaces.SupportTicketManager
+	auditRepository     interfaces.AuditLogManager
+	workspaceConfig     *configs.WorkspaceConfig
+	contentConfig       *configs.ContentConfig
+	supportConfig       *configs.SupportConfig
+	trashConfig         *configs.TrashConfig
+	imageProcessor      *imaging.Processor
+	watermarkConfig     *configs.WatermarkConfig
+	watermark           *imaging.Watermark
+	content             atomic.Pointer[content]
+	wg                  sync.WaitGroup
+	stopCh              chan struct{}
+	cancel              context.CancelFunc
+	botUser             *tgmodels.User
 }
 
 func NewTelegramBotService(
-	config *configs.TelegramConfig,
-	s3Config *configs.S3Config,
+	cfg *configs.Configuration,
 	userRepository interfaces.TelegramUserManager,
 	photoRepository interfaces.PhotoManager,
 	articleRepository interfaces.ArticleNumberManager,
+	inviteRepository interfaces.InviteCodeManager,
+	workspaceRepository interfaces.WorkspaceManager,
+	chatRepository interfaces.ChatManager,
+	ticketRepository interfaces.SupportTicketManager,
+	auditRepository interfaces.AuditLogManager,
 	s3Client interfaces.S3Client,
 ) (*TelegramBotService, error) {
+	config := cfg.Telegram
 	if config == nil {
 		return nil, fmt.Errorf("telegram config is nil")
 	}
@@ -44,13 +70,88 @@ func NewTelegramBotService(
 		return nil, fmt.Errorf("telegram bot token is required")
 	}
 
+	shareConfig := cfg.Share
+	if shareConfig == nil {
+		shareConfig = &configs.ShareConfig{}
+	}
+	if shareConfig.LinkTTL <= 0 {
+		shareConfig.LinkTTL = defaultShareLinkTTL
+	}
+
+	accessConfig := cfg.Access
+	if accessConfig == nil {
+		accessConfig = &configs.AccessConfig{}
+	}
+	if accessConfig.DefaultRole == "" {
+		accessConfig.DefaultRole = appmodels.TelegramUserRoleUploader
+	}
+	if accessConfig.Policy == "" {
+		accessConfig.Policy = configs.AccessPolicyOpen
+	}
+	if !appmodels.IsValidTelegramUserRole(accessConfig.DefaultRole) {
+		return nil, fmt.Errorf("unknown access.default_role %q", accessConfig.DefaultRole)
+	}
+
+	workspaceConfig := cfg.Workspace
+	if workspaceConfig == nil {
+		workspaceConfig = &configs.WorkspaceConfig{}
+	}
+
+	contentConfig := cfg.Content
+	if contentConfig == nil {
+		contentConfig = &configs.ContentConfig{}
+	}
+
+	supportConfig := cfg.Support
+	if supportConfig == nil {
+		supportConfig = &configs.SupportConfig{}
+	}
+
+	trashConfig := cfg.Trash
+	if trashConfig == nil {
+		trashConfig = &configs.TrashConfig{}
+	}
+
+	watermarkConfig := cfg.Watermark
+	if watermarkConfig == nil {
+		watermarkConfig = &configs.WatermarkConfig{}
+	}
+	for _, role := range watermarkConfig.Roles {
+		if !appmodels.IsValidTelegramUserRole(role) {
+			return nil, fmt.Errorf("unknown role %q in watermark.roles", role)
+		}
+	}
+	watermark, err := imaging.NewWatermark(watermarkConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	service := &TelegramBotService{
-		config:            config,
-		s3Config:          s3Config,
-		userRepository:    userRepository,
-		photoRepository:   photoRepository,
-		articleRepository: articleRepository,
-		stopCh:            make(chan struct{}),
+		config:              config,
+		s3Config:            cfg.S3,
+		shareConfig:         shareConfig,
+		accessConfig:        accessConfig,
+		userRepository:      userRepository,
+		photoRepository:     photoRepository,
+		articleRepository:   articleRepository,
+		inviteRepository:    inviteRepository,
+		workspaceRepository: workspaceRepository,
+		chatRepository:      chatRepository,
+		ticketRepository:    ticketRepository,
+		auditRepository:     auditRepository,
+		workspaceConfig:     workspaceConfig,
+		contentConfig:       contentConfig,
+		supportConfig:       supportConfig,
+		trashConfig:         trashConfig,
+		imageProcessor:      imaging.NewProcessor(cfg.Images),
+		watermarkConfig:     watermarkConfig,
+		watermark:           watermark,
+		stopCh:              make(chan struct{}),
+	}
+
+	// Broken content fails the startup instead of the first /help
+	if err := service.ReloadContent(); err != nil {
+		return nil, err
 	}
 
 	log.Debug().
@@ -59,6 +160,9 @@ func NewTelegramBotService(
 		Msg("Initializing Telegram bot")
 
 	opts := []bot.Option{}
+	if config.APIURL != "" {
+		opts = append(opts, bot.WithServerURL(strings.TrimSuffix(config.APIURL, "/")))
+	}
 	if config.Debug {
 		opts = append(opts, bot.WithDebug())
 	}
@@ -90,6 +194,8 @@ func (s *TelegramBotService) Start(parentCtx context.Context) error {
 	ctx, cancel := context.WithCancel(parentCtx)
 	s.cancel = cancel
 
+	s.registerCommands(ctx)
+
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
@@ -134,14 +240,56 @@ func (s *TelegramBotService) Stop() error {
 }
 
 func (s *TelegramBotService) RegisterHandlers(opts []bot.Option) []bot.Option {
-	return append(opts,
+	opts = append(opts,
 		[]bot.Option{
-			bot.WithMiddlewares(s.saveUserMiddleware, s.routerMiddleware),
+			bot.WithMiddlewares(s.contentMiddleware, s.groupMiddleware, s.saveUserMiddleware, s.accessMiddleware, s.routerMiddleware),
 			bot.WithDefaultHandler(defaultHandler),
-			bot.WithMessageTextHandler(helpText, bot.MatchTypeExact, helpHandler),
-			bot.WithMessageTextHandler(supportText, bot.MatchTypeExact, supportHandler),
-			bot.WithMessageTextHandler(searchByArticleNumberText, bot.MatchTypeExact, s.searchByArticleNumberHandler),
-			bot.WithMessageTextHandler(addItemText, bot.MatchTypeExact, s.addItemHandler),
+			bot.WithMessageTextHandler(startCommand, bot.MatchTypeCommandStartOnly, s.startHandler),
+			bot.WithMessageTextHandler(addCommand, bot.MatchTypeCommandStartOnly, s.addItemHandler),
+			bot.WithMessageTextHandler(searchCommand, bot.MatchTypeCommandStartOnly, s.searchCommandHandler),
+			bot.WithMessageTextHandler(cancelCommand, bot.MatchTypeCommandStartOnly, s.cancelHandler),
+			bot.WithMessageTextHandler(helpCommand, bot.MatchTypeCommandStartOnly, s.helpHandler),
+			bot.WithMessageTextHandler(myCommand, bot.MatchTypeCommandStartOnly, s.myHandler),
+			bot.WithMessageTextHandler(languageCommand, bot.MatchTypeCommandStartOnly, s.languageHandler),
+			bot.WithMessageTextHandler(grantCommand, bot.MatchTypeCommandStartOnly, s.grantRoleHandler),
+			bot.WithMessageTextHandler(revokeCommand, bot.MatchTypeCommandStartOnly, s.revokeRoleHandler),
+			bot.WithMessageTextHandler(inviteCommand, bot.MatchTypeCommandStartOnly, s.inviteHandler),
+			bot.WithMessageTextHandler(invitesCommand, bot.MatchTypeCommandStartOnly, s.invitesHandler),
+			bot.WithMessageTextHandler(revokeInviteCommand, bot.MatchTypeCommandStartOnly, s.revokeInviteHandler),
+			bot.WithMessageTextHandler(workspacesCommand, bot.MatchTypeCommandStartOnly, s.workspacesHandler),
+			bot.WithMessageTextHandler(workspaceCreateCommand, bot.MatchTypeCommandStartOnly, s.workspaceCreateHandler),
+			bot.WithMessageTextHandler(workspaceAddCommand, bot.MatchTypeCommandStartOnly, s.workspaceAddHandler),
+			bot.WithMessageTextHandler(workspaceRemoveCommand, bot.MatchTypeCommandStartOnly, s.workspaceRemoveHandler),
+			bot.WithMessageTextHandler(bindWorkspaceCommand, bot.MatchTypeCommandStartOnly, s.bindWorkspaceHandler),
+			bot.WithMessageTextHandler(unbindWorkspaceCommand, bot.MatchTypeCommandStartOnly, s.unbindWorkspaceHandler),
+			bot.WithMessageTextHandler(reloadContentCommand, bot.MatchTypeCommandStartOnly, s.reloadContentHandler),
+			bot.WithMessageTextHandler(auditCommand, bot.MatchTypeCommandStartOnly, s.auditHandler),
+			bot.WithMessageTextHandler(trashCommand, bot.MatchTypeCommandStartOnly, s.trashHandler),
+			bot.WithMessageTextHandler(importCommand, bot.MatchTypeCommandStartOnly, s.importHandler),
+			bot.WithMessageTextHandler(exportCommand, bot.MatchTypeCommandStartOnly, s.exportHandler),
+			bot.WithMessageTextHandler(lookbookCommand, bot.MatchTypeCommandStartOnly, s.lookbookHandler),
+			bot.WithMessageTextHandler(renameCommand, bot.MatchTypeCommandStartOnly, s.renameHandler),
+			bot.WithMessageTextHandler(mergeCommand, bot.MatchTypeCommandStartOnly, s.mergeHandler),
+			bot.WithMessageTextHandler(moveCommand, bot.MatchTypeCommandStartOnly, s.moveHandler),
+			bot.WithMessageTextHandler(aliasCommand, bot.MatchTypeCommandStartOnly, s.aliasHandler),
+			bot.WithMessageTextHandler(unaliasCommand, bot.MatchTypeCommandStartOnly, s.unaliasHandler),
+			bot.WithMessageTextHandler(labelsCommand, bot.MatchTypeCommandStartOnly, s.labelsHandler),
+			bot.WithMessageTextHandler(ticketsCommand, bot.MatchTypeCommandStartOnly, s.ticketsHandler),
+			bot.WithMessageTextHandler(ticketCommand, bot.MatchTypeCommandStartOnly, s.ticketHandler),
+			bot.WithMessageTextHandler(closeTicketCommand, bot.MatchTypeCommandStartOnly, s.closeTicketHandler),
+			bot.WithCallbackQueryDataHandler(workspaceCallbackPrefix, bot.MatchTypePrefix, s.workspaceCallbackHandler),
+			bot.WithCallbackQueryDataHandler(shareCallbackPrefix, bot.MatchTypePrefix, s.shareCallbackHandler),
+			bot.WithCallbackQueryDataHandler(languageCallbackPrefix, bot.MatchTypePrefix, s.languageCallbackHandler),
+			bot.WithCallbackQueryDataHandler(trashCallbackPrefix, bot.MatchTypePrefix, s.trashCallbackHandler),
+			bot.WithCallbackQueryDataHandler(barcodeCallbackPrefix, bot.MatchTypePrefix, s.barcodeCallbackHandler),
 		}...,
 	)
+	// Menu buttons are matched by their labels in every language
+	opts = append(opts, buttonHandlers("menu.help", s.helpHandler)...)
+	opts = append(opts, buttonHandlers("menu.support", s.supportHandler)...)
+	opts = append(opts, buttonHandlers("menu.search", s.searchByArticleNumberHandler)...)
+	opts = append(opts, buttonHandlers("menu.add", s.addItemHandler)...)
+	opts = append(opts, buttonHandlers("menu.users", s.usersHandler)...)
+	opts = append(opts, buttonHandlers("menu.workspace", s.workspaceHandler)...)
+	return opts
 }
@@ -0,0 +1,424 @@
+// Package telegramtest provides a fake Telegram Bot API server for tests.
+//
+// The server implements the subset of the Bot API used by the bot: getMe,
+// getUpdates (long polling), sendMessage, sendPhoto, sendDocument, getFile
+// and file downloads. Every other method is recorded and answered with true.
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+// DefaultToken is a well-formed bot token accepted by the fake server
+const DefaultToken = "123456:TEST-TOKEN"
+
+// Request is a Bot API call made by the bot under test
+type Request struct {
+	Method string
+	// Params contains plain form values, JSON-encoded values are kept as is
+	Params map[string]string
+	// Files contains uploaded files by form field name
+	Files map[string][]byte
+	// FileNames contains names of uploaded files by form field name
+	FileNames map[string]string
+	// MessageID is the ID of the message created by a sending method
+	MessageID int
+}
+
+// ChatID returns the chat_id parameter of the request
+func (r Request) ChatID() int64 {
+	id, _ := strconv.ParseInt(r.Params["chat_id"], 10, 64)
+	return id
+}
+
+// ThreadID returns the forum topic the message was sent to
+func (r Request) ThreadID() int {
+	id, _ := strconv.Atoi(r.Params["message_thread_id"])
+	return id
+}
+
+// Text returns text of a message or caption of a media message
+func (r Request) Text() string {
+	if text, ok := r.Params["text"]; ok {
+		return text
+	}
+	return r.Params["caption"]
+}
+
+// ReplyKeyboard returns button texts of a reply keyboard attached to the request
+func (r Request) ReplyKeyboard() [][]string {
+	var markup tgmodels.ReplyKeyboardMarkup
+	if err := json.Unmarshal([]byte(r.Params["reply_markup"]), &markup); err != nil {
+		return nil
+	}
+
+	rows := make([][]string, 0, len(markup.Keyboard))
+	for _, row := range markup.Keyboard {
+		texts := make([]string, 0, len(row))
+		for _, button := range row {
+			texts = append(texts, button.Text)
+		}
+		rows = append(rows, texts)
+	}
+	return rows
+}
+
+// InlineKeyboard returns buttons of an inline keyboard attached to the request
+func (r Request) InlineKeyboard() [][]tgmodels.InlineKeyboardButton {
+	var markup tgmodels.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(r.Params["reply_markup"]), &markup); err != nil {
+		return nil
+	}
+	return markup.InlineKeyboard
+}
+
+// Server is a fake Telegram Bot API server
+type Server struct {
+	*httptest.Server
+
+	Token string
+	Bot   tgmodels.User
+
+	mu            sync.Mutex
+	updates       []*tgmodels.Update
+	nextUpdateID  int64
+	nextMessageID int
+	requests      []Request
+	files         map[string]fakeFile
+	localDir      string
+	hideSizes     bool
+	changed       chan struct{}
+}
+
+type fakeFile struct {
+	path string
+	data []byte
+}
+
+// NewServer starts a new fake Bot API server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		Token: DefaultToken,
+		Bot: tgmodels.User{
+			ID:        123456,
+			IsBot:     true,
+			FirstName: "Alfredo",
+			Username:  "alfredo_test_bot",
+		},
+		nextUpdateID:  1,
+		nextMessageID: 1,
+		files:         map[string]fakeFile{},
+		changed:       make(chan struct{}),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// PushUpdate queues an update for the bot, assigning its update ID
+func (s *Server) PushUpdate(update *tgmodels.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update.ID = s.nextUpdateID
+	s.nextUpdateID++
+	if update.Message != nil && update.Message.ID == 0 {
+		update.Message.ID = s.newMessageID()
+		update.Message.Date = int(time.Now().Unix())
+	}
+	s.updates = append(s.updates, update)
+	s.notify()
+}
+
+// UseLocalMode makes the server behave like telegram-bot-api started with
+// --local: files added afterwards are written to dir, getFile returns their
+// absolute paths and they can not be downloaded over HTTP
+func (s *Server) UseLocalMode(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.localDir = dir
+}
+
+// HideFileSizes makes getFile leave file sizes empty, as Telegram may do
+func (s *Server) HideFileSizes() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hideSizes = true
+}
+
+// AddFile registers a file which can be referenced by its file ID in updates
+func (s *Server) AddFile(fileID string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file := fakeFile{
+		path: "photos/" + fileID + ".jpg",
+		data: data,
+	}
+	if s.localDir != "" {
+		file.path = filepath.Join(s.localDir, fileID+".jpg")
+		if err := os.WriteFile(file.path, data, 0o600); err != nil {
+			return err
+		}
+		file.data = nil
+	}
+	s.files[fileID] = file
+	return nil
+}
+
+// Requests returns all Bot API calls made so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Sent returns calls of sending methods (sendMessage, sendPhoto, ...) made so far
+func (s *Server) Sent() []Request {
+	var sent []Request
+	for _, request := range s.Requests() {
+		if strings.HasPrefix(request.Method, "send") {
+			sent = append(sent, request)
+		}
+	}
+	return sent
+}
+
+// WaitSent waits until the bot made at least n sending calls and returns all of them
+func (s *Server) WaitSent(n int, timeout time.Duration) ([]Request, error) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+
+		if sent := s.Sent(); len(sent) >= n {
+			return sent, nil
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			sent := s.Sent()
+			return sent, fmt.Errorf("expected %d sent messages, got %d", n, len(sent))
+		}
+	}
+}
+
+// notify wakes up waiters, must be called with mu held
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) newMessageID() int {
+	id := s.nextMessageID
+	s.nextMessageID++
+	return id
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	filePrefix := "/file/bot" + s.Token + "/"
+	if strings.HasPrefix(r.URL.Path, filePrefix) {
+		s.handleFileDownload(w, strings.TrimPrefix(r.URL.Path, filePrefix))
+		return
+	}
+
+	botPrefix := "/bot" + s.Token + "/"
+	if !strings.HasPrefix(r.URL.Path, botPrefix) {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	request, err := parseRequest(strings.TrimPrefix(r.URL.Path, botPrefix), r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch request.Method {
+	case "getMe":
+		writeResult(w, s.Bot)
+	case "getUpdates":
+		s.handleGetUpdates(w, r, request)
+	case "getFile":
+		s.handleGetFile(w, request)
+	default:
+		if strings.HasPrefix(request.Method, "send") {
+			message := s.messageFor(request)
+			request.MessageID = message.ID
+			s.record(request)
+			writeResult(w, message)
+			return
+		}
+		s.record(request)
+		writeResult(w, true)
+	}
+}
+
+func (s *Server) record(request Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, request)
+	s.notify()
+}
+
+func (s *Server) messageFor(request Request) tgmodels.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	threadID, _ := strconv.Atoi(request.Params["message_thread_id"])
+	return tgmodels.Message{
+		ID:              s.newMessageID(),
+		MessageThreadID: threadID,
+		Date:            int(time.Now().Unix()),
+		Chat:            tgmodels.Chat{ID: request.ChatID()},
+		From:            &s.Bot,
+		Text:            request.Params["text"],
+		Caption:         request.Params["caption"],
+	}
+}
+
+func (s *Server) handleGetUpdates(w http.ResponseWriter, r *http.Request, request Request) {
+	offset, _ := strconv.ParseInt(request.Params["offset"], 10, 64)
+	timeout, _ := strconv.Atoi(request.Params["timeout"])
+	deadline := time.After(time.Duration(timeout) * time.Second)
+
+	for {
+		s.mu.Lock()
+		// Updates below the offset are confirmed and can be forgotten
+		pending := s.updates[:0]
+		for _, update := range s.updates {
+			if update.ID >= offset {
+				pending = append(pending, update)
+			}
+		}
+		s.updates = pending
+		updates := append([]*tgmodels.Update(nil), pending...)
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(updates) > 0 {
+			writeResult(w, updates)
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			writeResult(w, []*tgmodels.Update{})
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) handleGetFile(w http.ResponseWriter, request Request) {
+	s.mu.Lock()
+	file, ok := s.files[request.Params["file_id"]]
+	hideSizes := s.hideSizes
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Bad Request: invalid file_id")
+		return
+	}
+
+	result := tgmodels.File{
+		FileID:   request.Params["file_id"],
+		FileSize: int64(len(file.data)),
+		FilePath: file.path,
+	}
+	if hideSizes {
+		result.FileSize = 0
+	}
+	writeResult(w, result)
+}
+
+func (s *Server) handleFileDownload(w http.ResponseWriter, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, file := range s.files {
+		if file.data != nil && file.path == path {
+			_, _ = w.Write(file.data)
+			return
+		}
+	}
+	http.NotFound(w, nil)
+}
+
+func parseRequest(method string, r *http.Request) (Request, error) {
+	request := Request{
+		Method:    method,
+		Params:    map[string]string{},
+		Files:     map[string][]byte{},
+		FileNames: map[string]string{},
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return request, nil
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return request, fmt.Errorf("failed to parse form: %w", err)
+	}
+	for name, values := range r.MultipartForm.Value {
+		request.Params[name] = values[0]
+	}
+	for name, headers := range r.MultipartForm.File {
+		data, err := readFormFile(headers[0])
+		if err != nil {
+			return request, err
+		}
+		request.Files[name] = data
+		request.FileNames[name] = headers[0].Filename
+	}
+	return request, nil
+}
+
+func readFormFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return io.ReadAll(file)
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     true,
+		"result": result,
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":          false,
+		"error_code":  code,
+		"description": description,
+	})
+}
@@ -0,0 +1,129 @@
+package telegramtest
+
+import (
+	"strings"
+
+	tgmodels "github.com/go-telegram/bot/models"
+)
+
+// NewTextUpdate builds an update with a text message sent by the user in a private chat.
+// A leading /command is marked with a bot_command entity like Telegram does.
+func NewTextUpdate(from tgmodels.User, text string) *tgmodels.Update {
+	return &tgmodels.Update{
+		Message: &tgmodels.Message{
+			From:     &from,
+			Chat:     privateChat(from),
+			Text:     text,
+			Entities: commandEntities(text),
+		},
+	}
+}
+
+func commandEntities(text string) []tgmodels.MessageEntity {
+	if !strings.HasPrefix(text, "/") {
+		return nil
+	}
+	command, _, _ := strings.Cut(text, " ")
+	return []tgmodels.MessageEntity{
+		{Type: tgmodels.MessageEntityTypeBotCommand, Offset: 0, Length: len(command)},
+	}
+}
+
+// NewPhotoUpdate builds an update with a photo sent by the user in a private chat.
+// The file must be registered with Server.AddFile.
+func NewPhotoUpdate(from tgmodels.User, fileID, caption string) *tgmodels.Update {
+	return &tgmodels.Update{
+		Message: &tgmodels.Message{
+			From: &from,
+			Chat: privateChat(from),
+			Photo: []tgmodels.PhotoSize{
+				{FileID: fileID + "-small", FileUniqueID: fileID + "-small", Width: 90, Height: 90},
+				{FileID: fileID, FileUniqueID: fileID, Width: 1280, Height: 1280},
+			},
+			Caption: caption,
+		},
+	}
+}
+
+// NewDocumentUpdate builds an update with a file sent by the user in a private chat.
+// The file must be registered with Server.AddFile.
+func NewDocumentUpdate(from tgmodels.User, fileID, fileName, mimeType string) *tgmodels.Update {
+	return &tgmodels.Update{
+		Message: &tgmodels.Message{
+			From: &from,
+			Chat: privateChat(from),
+			Document: &tgmodels.Document{
+				FileID:       fileID,
+				FileUniqueID: fileID,
+				FileName:     fileName,
+				MimeType:     mimeType,
+			},
+		},
+	}
+}
+
+func privateChat(user tgmodels.User) tgmodels.Chat {
+	return tgmodels.Chat{
+		ID:        user.ID,
+		Type:      tgmodels.ChatTypePrivate,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}
+}
+
+// NewCallbackUpdate builds an update with an inline button press on a message the bot sent to the user
+func NewCallbackUpdate(from tgmodels.User, data string) *tgmodels.Update {
+	return &tgmodels.Update{
+		CallbackQuery: &tgmodels.CallbackQuery{
+			ID:   data,
+			From: from,
+			Message: tgmodels.MaybeInaccessibleMessage{
+				Type: tgmodels.MaybeInaccessibleMessageTypeMessage,
+				Message: &tgmodels.Message{
+					ID:   1,
+					Date: 1,
+					Chat: privateChat(from),
+				},
+			},
+			Data: data,
+		},
+	}
+}
+
+// NewGroupChat builds a supergroup chat, forum topics are enabled when forum is true
+func NewGroupChat(id int64, title string, forum bool) tgmodels.Chat {
+	return tgmodels.Chat{
+		ID:      id,
+		Type:    tgmodels.ChatTypeSupergroup,
+		Title:   title,
+		IsForum: forum,
+	}
+}
+
+// InChat moves a message or the message with a pressed inline button into the chat.
+// A non-zero threadID puts the message into that forum topic.
+func InChat(update *tgmodels.Update, chat tgmodels.Chat, threadID int) *tgmodels.Update {
+	message := update.Message
+	if update.CallbackQuery != nil {
+		message = update.CallbackQuery.Message.Message
+	}
+	message.Chat = chat
+	if threadID != 0 {
+		message.MessageThreadID = threadID
+		message.IsTopicMessage = true
+	}
+	return update
+}
+
+// ReplyTo makes the message of the update a reply to a message the bot sent with the request
+func (s *Server) ReplyTo(update *tgmodels.Update, request Request) *tgmodels.Update {
+	update.Message.ReplyToMessage = &tgmodels.Message{
+		ID:      request.MessageID,
+		From:    &s.Bot,
+		Chat:    tgmodels.Chat{ID: request.ChatID()},
+		Text:    request.Params["text"],
+		Caption: request.Params["caption"],
+	}
+	return update
+}
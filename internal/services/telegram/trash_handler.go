@@ -0,0 +1,172 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/Conty111/AlfredoBot/internal/i18n"
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+)
+
+const trashCommand = "trash"
+
+const (
+	trashCallbackPrefix        = "trash:"
+	trashPhotoCallbackPrefix   = trashCallbackPrefix + "photo:"
+	trashArticleCallbackPrefix = trashCallbackPrefix + "article:"
+)
+
+// trashLimit caps the number of photos and of article numbers listed by /trash
+const trashLimit = 20
+
+// trashHandler lists photos and article numbers of the active workspace in the trash
+// with buttons restoring them
+func (s *TelegramBotService) trashHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	t := tr(ctx)
+	user := userFromContext(ctx)
+
+	photos, err := s.photoRepository.GetDeletedPhotos(user.ActiveWorkspaceID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get deleted photos")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	articleNumbers, err := s.articleRepository.GetDeletedArticleNumbers(user.ActiveWorkspaceID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get deleted article numbers")
+		s.sendText(ctx, b, update, t.T("common.error"))
+		return
+	}
+	if len(photos) == 0 && len(articleNumbers) == 0 {
+		s.sendText(ctx, b, update, t.T("trash.empty"))
+		return
+	}
+	if len(photos) > trashLimit {
+		photos = photos[:trashLimit]
+	}
+	if len(articleNumbers) > trashLimit {
+		articleNumbers = articleNumbers[:trashLimit]
+	}
+
+	var (
+		text     strings.Builder
+		keyboard [][]tgmodels.InlineKeyboardButton
+	)
+	text.WriteString(t.T("trash.title"))
+	for _, photo := range photos {
+		text.WriteString(t.T("trash.photo",
+			shortID(photo.ID),
+			describePhotoArticles(t, photo),
+			photo.DeletedAt.Time.Format(auditTimeFormat)))
+		keyboard = append(keyboard, []tgmodels.InlineKeyboardButton{{
+			Text:         t.T("trash.photo_button", shortID(photo.ID)),
+			CallbackData: trashPhotoCallbackPrefix + photo.ID.String(),
+		}})
+	}
+	for _, articleNumber := range articleNumbers {
+		text.WriteString(t.T("trash.article",
+			articleNumber.Number,
+			articleNumber.DeletedAt.Time.Format(auditTimeFormat)))
+		keyboard = append(keyboard, []tgmodels.InlineKeyboardButton{{
+			Text:         t.T("trash.article_button", articleNumber.Number),
+			CallbackData: trashArticleCallbackPrefix + articleNumber.ID.String(),
+		}})
+	}
+	if days := int(s.trashConfig.Retention.Hours() / 24); days > 0 {
+		text.WriteString(t.N("trash.retention", days, days))
+	}
+
+	_, err = b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        text.String(),
+		ReplyMarkup: &tgmodels.InlineKeyboardMarkup{InlineKeyboard: keyboard},
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+func describePhotoArticles(t *i18n.Localizer, photo *appmodels.Photo) string {
+	if len(photo.ArticleNumbers) == 0 {
+		return t.T("trash.no_articles")
+	}
+	numbers := make([]string, 0, len(photo.ArticleNumbers))
+	for _, articleNumber := range photo.ArticleNumbers {
+		numbers = append(numbers, articleNumber.Number)
+	}
+	return strings.Join(numbers, ", ")
+}
+
+// trashCallbackHandler restores a photo or an article number from the trash
+func (s *TelegramBotService) trashCallbackHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	query := update.CallbackQuery
+	t := tr(ctx)
+	user := userFromContext(ctx)
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to answer callback query")
+	}
+
+	var (
+		text string
+		err  error
+	)
+	switch {
+	case strings.HasPrefix(query.Data, trashPhotoCallbackPrefix):
+		text, err = s.restorePhoto(t, user, strings.TrimPrefix(query.Data, trashPhotoCallbackPrefix))
+	case strings.HasPrefix(query.Data, trashArticleCallbackPrefix):
+		text, err = s.restoreArticleNumber(t, user, strings.TrimPrefix(query.Data, trashArticleCallbackPrefix))
+	default:
+		err = fmt.Errorf("unknown trash callback: %s", query.Data)
+	}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		text = t.T("trash.not_found")
+	case err != nil:
+		log.Error().Err(err).Str("data", query.Data).Msg("Failed to restore from trash")
+		text = t.T("common.error")
+	}
+
+	_, err = b.SendMessage(ctx, inCallbackChat(query, &bot.SendMessageParams{
+		Text: text,
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+func (s *TelegramBotService) restorePhoto(t *i18n.Localizer, user *appmodels.TelegramUser, id string) (string, error) {
+	photoID, err := uuid.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("invalid photo ID %q: %w", id, err)
+	}
+	if err := s.photoRepository.WithActor(user).RestorePhoto(photoID); err != nil {
+		return "", err
+	}
+	return t.T("trash.restored_photo", shortID(photoID)), nil
+}
+
+func (s *TelegramBotService) restoreArticleNumber(t *i18n.Localizer, user *appmodels.TelegramUser, id string) (string, error) {
+	articleNumberID, err := uuid.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("invalid article number ID %q: %w", id, err)
+	}
+	if err := s.articleRepository.WithActor(user).RestoreArticleNumber(articleNumberID); err != nil {
+		return "", err
+	}
+	articleNumber, err := s.articleRepository.GetByID(articleNumberID)
+	if err != nil {
+		return "", err
+	}
+	return t.T("trash.restored_article", articleNumber.Number), nil
+}
@@ -3,14 +3,20 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"gorm.io/gorm"
 
+	"github.com/go-telegram/bot"
 	tgmodels "github.com/go-telegram/bot/models"
 	"github.com/rs/zerolog/log"
 
 	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/services/imaging"
 )
 
 func (s *TelegramBotService) SaveUser(ctx context.Context, tgUser *tgmodels.User) error {
@@ -18,14 +24,7 @@ func (s *TelegramBotService) SaveUser(ctx context.Context, tgUser *tgmodels.User
 		return fmt.Errorf("user cannot be nil")
 	}
 
-	user := &models.TelegramUser{
-		TelegramID:   tgUser.ID,
-		Username:     tgUser.Username,
-		FirstName:    tgUser.FirstName,
-		LastName:     tgUser.LastName,
-		LanguageCode: tgUser.LanguageCode,
-		IsBot:        tgUser.IsBot,
-	}
+	user := newTelegramUser(tgUser, s.accessConfig.DefaultRole)
 
 	existingUser, err := s.userRepository.GetByTelegramID(tgUser.ID)
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -75,6 +74,29 @@ func (s *TelegramBotService) SaveUser(ctx context.Context, tgUser *tgmodels.User
 	return nil
 }
 
+func newTelegramUser(tgUser *tgmodels.User, role string) *models.TelegramUser {
+	return &models.TelegramUser{
+		TelegramID:   tgUser.ID,
+		Username:     tgUser.Username,
+		FirstName:    tgUser.FirstName,
+		LastName:     tgUser.LastName,
+		LanguageCode: tgUser.LanguageCode,
+		IsBot:        tgUser.IsBot,
+		Role:         role,
+	}
+}
+
+// updateSender returns the user who sent a message or pressed an inline button
+func updateSender(update *tgmodels.Update) *tgmodels.User {
+	switch {
+	case update.Message != nil:
+		return update.Message.From
+	case update.CallbackQuery != nil:
+		return &update.CallbackQuery.From
+	}
+	return nil
+}
+
 func parseArticleNumbers(caption string) []string {
 	// Split by comma and trim whitespace
 	parts := strings.Split(caption, ",")
@@ -89,3 +111,64 @@ func parseArticleNumbers(caption string) []string {
 
 	return articleNumbers
 }
+
+// downloadFile downloads a file previously resolved with GetFile.
+// A local Bot API server started with --local returns absolute paths on its
+// own disk instead of download paths, such files are read directly.
+// Files longer than the limit fail with imaging.ErrFileTooLarge, 0 allows any size.
+// Telegram may leave File.FileSize empty, so the limit is applied while reading.
+func (s *TelegramBotService) downloadFile(ctx context.Context, b *bot.Bot, file *tgmodels.File, limit int64) ([]byte, error) {
+	if s.config.LocalMode && filepath.IsAbs(file.FilePath) {
+		local, err := os.Open(file.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := local.Close(); err != nil {
+				log.Error().Err(err).Msg("Failed to close file")
+			}
+		}()
+		return readLimited(local, limit)
+	}
+
+	link := b.FileDownloadLink(file)
+	if s.config.FileURL != "" {
+		link = fmt.Sprintf("%s/file/bot%s/%s", strings.TrimSuffix(s.config.FileURL, "/"), b.Token(), file.FilePath)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close response body")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return readLimited(resp.Body, limit)
+}
+
+// readLimited reads at most limit bytes, 0 reads everything
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, imaging.ErrFileTooLarge
+	}
+	return data, nil
+}
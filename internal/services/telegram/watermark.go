@@ -0,0 +1,106 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+)
+
+// watermarked tells whether photos are sent to the chat with the watermark. Anyone in a group
+// may see them, so groups get watermarked photos whenever some role is watermarked,
+// private chats get them by the role of the user.
+func (s *TelegramBotService) watermarked(chat tgmodels.Chat, user *appmodels.TelegramUser) bool {
+	if s.watermark == nil {
+		return false
+	}
+	if isGroupChat(chat) {
+		return len(s.watermarkConfig.Roles) > 0
+	}
+	return user != nil && slices.Contains(s.watermarkConfig.Roles, user.Role)
+}
+
+// openDeliveredPhoto opens the photo the chat gets, the original or its watermarked rendition,
+// and returns its image format
+func (s *TelegramBotService) openDeliveredPhoto(
+	ctx context.Context,
+	chat tgmodels.Chat,
+	user *appmodels.TelegramUser,
+	photo *appmodels.Photo,
+) (io.ReadCloser, string, error) {
+	if !s.watermarked(chat, user) {
+		reader, err := s.photoRepository.GetPhotoFromS3(ctx, photo.UserID, photo.S3Key, photo.Format, s.s3Config.Bucket)
+		return reader, photo.Format, err
+	}
+	data, err := s.watermarkedPhoto(ctx, photo)
+	if err != nil {
+		return nil, "", err
+	}
+	return &photoReader{Reader: bytes.NewReader(data)}, appmodels.PhotoRenditionFormat, nil
+}
+
+// photoReader reads a photo made in memory. Uploads need a pointer, they check readers for nil
+type photoReader struct {
+	*bytes.Reader
+}
+
+func (r *photoReader) Close() error {
+	return nil
+}
+
+// deliveredPhotoURL returns an expiring link to the photo the chat gets
+func (s *TelegramBotService) deliveredPhotoURL(
+	ctx context.Context,
+	chat tgmodels.Chat,
+	user *appmodels.TelegramUser,
+	photo *appmodels.Photo,
+) (string, error) {
+	key, format := photo.S3Key, photo.Format
+	if s.watermarked(chat, user) {
+		if _, err := s.watermarkedPhoto(ctx, photo); err != nil {
+			return "", err
+		}
+		key, format = photo.RenditionKey(appmodels.PhotoRenditionWatermarked), appmodels.PhotoRenditionFormat
+	}
+	return s.photoRepository.GetPhotoURL(ctx, photo.UserID, key, format, s.s3Config.Bucket, s.shareConfig.LinkTTL)
+}
+
+// watermarkedPhoto returns the watermarked rendition of the photo. It is cached in S3
+// and made again when it is missing or was made with another watermark.
+func (s *TelegramBotService) watermarkedPhoto(ctx context.Context, photo *appmodels.Photo) ([]byte, error) {
+	key := photo.RenditionKey(appmodels.PhotoRenditionWatermarked)
+	cached, err := s.readObject(ctx, photo.UserID, key, appmodels.PhotoRenditionFormat)
+	if err == nil && s.watermark.IsCurrent(cached) {
+		return cached, nil
+	}
+
+	original, err := s.readObject(ctx, photo.UserID, photo.S3Key, photo.Format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download photo: %w", err)
+	}
+	data, err := s.watermark.Apply(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watermark photo: %w", err)
+	}
+	err = s.photoRepository.UploadPhotoToS3(ctx, photo.UserID, key, appmodels.PhotoRenditionFormat, s.s3Config.Bucket, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store watermarked photo: %w", err)
+	}
+	return data, nil
+}
+
+// readObject downloads an object of the photo storage
+func (s *TelegramBotService) readObject(ctx context.Context, userID, key uuid.UUID, format string) ([]byte, error) {
+	reader, err := s.photoRepository.GetPhotoFromS3(ctx, userID, key, format, s.s3Config.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,304 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/go-telegram/bot"
+	tgmodels "github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	appmodels "github.com/Conty111/AlfredoBot/internal/models"
+)
+
+const workspaceCallbackPrefix = "workspace:"
+
+const workspacesCommand = "workspaces"
+const workspaceCreateCommand = "workspace_create"
+const workspaceAddCommand = "workspace_add"
+const workspaceRemoveCommand = "workspace_remove"
+
+// ensureActiveWorkspace activates the first workspace of users who have none active
+func (s *TelegramBotService) ensureActiveWorkspace(user *appmodels.TelegramUser) {
+	if user.ActiveWorkspaceID != uuid.Nil || user.IsBanned() {
+		return
+	}
+
+	workspaces, err := s.workspaceRepository.GetUserWorkspaces(user.ID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to get user workspaces")
+		return
+	}
+	if len(workspaces) == 0 {
+		return
+	}
+	if err := s.activateWorkspace(user, workspaces[0].ID); err != nil {
+		log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to activate workspace")
+	}
+}
+
+func (s *TelegramBotService) activateWorkspace(user *appmodels.TelegramUser, workspaceID uuid.UUID) error {
+	if err := s.userRepository.UpdateByID(user.ID, map[string]interface{}{
+		"active_workspace_id": workspaceID,
+	}); err != nil {
+		return err
+	}
+	user.ActiveWorkspaceID = workspaceID
+	return nil
+}
+
+// joinWorkspace adds a user to the workspace and makes it active
+func (s *TelegramBotService) joinWorkspace(user *appmodels.TelegramUser, workspaceID uuid.UUID) error {
+	if err := s.workspaceRepository.AddMember(workspaceID, user.ID); err != nil {
+		return err
+	}
+	return s.activateWorkspace(user, workspaceID)
+}
+
+// joinDefaultWorkspace adds a newly registered user to the configured default workspace
+func (s *TelegramBotService) joinDefaultWorkspace(user *appmodels.TelegramUser) error {
+	if s.workspaceConfig.Default == "" {
+		return nil
+	}
+	workspace, err := s.workspaceRepository.GetOrCreateWorkspace(s.workspaceConfig.Default)
+	if err != nil {
+		return err
+	}
+	return s.joinWorkspace(user, workspace.ID)
+}
+
+func (s *TelegramBotService) workspaceHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	user := userFromContext(ctx)
+	workspaces, err := s.workspaceRepository.GetUserWorkspaces(user.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get user workspaces")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+	if len(workspaces) == 0 {
+		s.sendText(ctx, b, update, tr(ctx).T("workspaces.none"))
+		return
+	}
+
+	active := "-"
+	keyboard := make([][]tgmodels.InlineKeyboardButton, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		name := workspace.Name
+		if workspace.ID == user.ActiveWorkspaceID {
+			active = workspace.Name
+			name = "✅ " + name
+		}
+		keyboard = append(keyboard, []tgmodels.InlineKeyboardButton{
+			{Text: name, CallbackData: workspaceCallbackPrefix + workspace.ID.String()},
+		})
+	}
+
+	_, err = b.SendMessage(ctx, inChat(update.Message, &bot.SendMessageParams{
+		Text:        tr(ctx).T("workspaces.current", active),
+		ReplyMarkup: &tgmodels.InlineKeyboardMarkup{InlineKeyboard: keyboard},
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+// workspaceCallbackHandler switches the active workspace picked from the inline keyboard
+func (s *TelegramBotService) workspaceCallbackHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	query := update.CallbackQuery
+
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to answer callback query")
+	}
+
+	text, err := s.switchWorkspace(ctx, updateChat(update), strings.TrimPrefix(query.Data, workspaceCallbackPrefix))
+	if err != nil {
+		log.Error().Err(err).Str("data", query.Data).Msg("Failed to switch workspace")
+		text = tr(ctx).T("workspaces.switch_failed")
+	}
+
+	_, err = b.SendMessage(ctx, inCallbackChat(query, &bot.SendMessageParams{
+		Text:        text,
+		ReplyMarkup: mainMenu(ctx),
+	}))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+	}
+}
+
+func (s *TelegramBotService) switchWorkspace(ctx context.Context, chat *tgmodels.Chat, rawWorkspaceID string) (string, error) {
+	user := userFromContext(ctx)
+	if user.State != "" && user.State != appmodels.TelegramUserStateDefault {
+		return tr(ctx).T("workspaces.finish_first"), nil
+	}
+	if isGroupChat(*chat) {
+		group, err := s.chatRepository.GetGroupChat(chat.ID)
+		if err == nil {
+			return tr(ctx).T("groups.bound", s.workspaceName(group.WorkspaceID)), nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+	}
+
+	workspaceID, err := uuid.Parse(rawWorkspaceID)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace id: %w", err)
+	}
+	member, err := s.workspaceRepository.IsMember(workspaceID, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if !member {
+		return tr(ctx).T("workspaces.not_member"), nil
+	}
+
+	workspace, err := s.workspaceRepository.GetByID(workspaceID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.activateWorkspace(user, workspace.ID); err != nil {
+		return "", err
+	}
+	return tr(ctx).T("workspaces.switched", workspace.Name), nil
+}
+
+func (s *TelegramBotService) workspacesHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	workspaces, err := s.workspaceRepository.GetAll()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get workspaces")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(tr(ctx).T("workspaces.title"))
+	for _, workspace := range workspaces {
+		members, err := s.workspaceRepository.GetMembers(workspace.ID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get workspace members")
+			s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+			return
+		}
+		names := make([]string, 0, len(members))
+		for _, member := range members {
+			names = append(names, displayUser(member))
+		}
+		if len(names) == 0 {
+			names = append(names, "—")
+		}
+		text.WriteString(tr(ctx).T("workspaces.item", workspace.Name, strings.Join(names, ", ")))
+	}
+	text.WriteString(tr(ctx).T("workspaces.footer",
+		tr(ctx).T("usage.workspace_create"), tr(ctx).T("usage.workspace_add"), tr(ctx).T("usage.workspace_remove")))
+
+	s.sendText(ctx, b, update, text.String())
+}
+
+func (s *TelegramBotService) workspaceCreateHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	_, args := splitCommand(update.Message.Text)
+	name := strings.Join(args, " ")
+	if name == "" {
+		s.sendText(ctx, b, update, tr(ctx).T("common.usage", tr(ctx).T("usage.workspace_create")))
+		return
+	}
+
+	if _, err := s.workspaceRepository.GetByName(name); err == nil {
+		s.sendText(ctx, b, update, tr(ctx).T("workspaces.exists", name))
+		return
+	}
+
+	workspace := &appmodels.Workspace{Name: name}
+	if err := s.workspaceRepository.CreateWorkspace(workspace); err != nil {
+		log.Error().Err(err).Str("workspace", name).Msg("Failed to create workspace")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+	if err := s.workspaceRepository.AddMember(workspace.ID, userFromContext(ctx).ID); err != nil {
+		log.Error().Err(err).Str("workspace", name).Msg("Failed to add workspace creator")
+	}
+
+	log.Info().
+		Int64("admin_telegram_id", update.Message.From.ID).
+		Str("workspace", name).
+		Msg("Workspace created")
+
+	s.sendText(ctx, b, update, tr(ctx).T("workspaces.created", name))
+}
+
+func (s *TelegramBotService) workspaceAddHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	target, workspace, ok := s.workspaceMemberArgs(ctx, b, update, "usage.workspace_add")
+	if !ok {
+		return
+	}
+
+	if err := s.workspaceRepository.AddMember(workspace.ID, target.ID); err != nil {
+		log.Error().Err(err).Msg("Failed to add workspace member")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+	s.sendText(ctx, b, update, tr(ctx).T("workspaces.member_added", displayUser(target), workspace.Name))
+}
+
+func (s *TelegramBotService) workspaceRemoveHandler(ctx context.Context, b *bot.Bot, update *tgmodels.Update) {
+	target, workspace, ok := s.workspaceMemberArgs(ctx, b, update, "usage.workspace_remove")
+	if !ok {
+		return
+	}
+
+	if err := s.workspaceRepository.RemoveMember(workspace.ID, target.ID); err != nil {
+		log.Error().Err(err).Msg("Failed to remove workspace member")
+		s.sendText(ctx, b, update, tr(ctx).T("common.error"))
+		return
+	}
+	// The next update activates another workspace of the user, if any
+	if target.ActiveWorkspaceID == workspace.ID {
+		if err := s.userRepository.UpdateByID(target.ID, map[string]interface{}{
+			"active_workspace_id": uuid.Nil,
+		}); err != nil {
+			log.Error().Err(err).Msg("Failed to reset active workspace")
+		}
+	}
+	s.sendText(ctx, b, update, tr(ctx).T("workspaces.member_removed", displayUser(target), workspace.Name))
+}
+
+// workspaceMemberArgs resolves the "<user> <workspace name>" arguments of membership commands.
+// usageKey is the message with the usage of the command.
+func (s *TelegramBotService) workspaceMemberArgs(
+	ctx context.Context,
+	b *bot.Bot,
+	update *tgmodels.Update,
+	usageKey string,
+) (*appmodels.TelegramUser, *appmodels.Workspace, bool) {
+	_, args := splitCommand(update.Message.Text)
+	if len(args) < 2 {
+		s.sendText(ctx, b, update, tr(ctx).T("common.usage", tr(ctx).T(usageKey)))
+		return nil, nil, false
+	}
+
+	target, err := s.findUser(args[0])
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Error().Err(err).Msg("Failed to find user")
+		}
+		s.sendText(ctx, b, update, tr(ctx).T("common.user_not_found", args[0]))
+		return nil, nil, false
+	}
+
+	name := strings.Join(args[1:], " ")
+	workspace, err := s.workspaceRepository.GetByName(name)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Error().Err(err).Msg("Failed to find workspace")
+		}
+		s.sendText(ctx, b, update, tr(ctx).T("common.workspace_not_found", name))
+		return nil, nil, false
+	}
+	return target, workspace, true
+}
@@ -0,0 +1,102 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/interfaces"
+)
+
+// Purger permanently deletes photos and article numbers which stayed in the trash
+// longer than the retention, together with the objects of the photos
+type Purger struct {
+	config            *configs.TrashConfig
+	bucket            string
+	photoRepository   interfaces.PhotoManager
+	articleRepository interfaces.ArticleNumberManager
+	cancel            context.CancelFunc
+	wg                sync.WaitGroup
+}
+
+// NewPurger creates a new Purger, the trash is kept forever without the trash config
+func NewPurger(
+	cfg *configs.Configuration,
+	photoRepository interfaces.PhotoManager,
+	articleRepository interfaces.ArticleNumberManager,
+) *Purger {
+	config := cfg.Trash
+	if config == nil {
+		config = &configs.TrashConfig{}
+	}
+	var bucket string
+	if cfg.S3 != nil {
+		bucket = cfg.S3.Bucket
+	}
+	return &Purger{
+		config:            config,
+		bucket:            bucket,
+		photoRepository:   photoRepository,
+		articleRepository: articleRepository,
+	}
+}
+
+// Start purges the trash right away and then every purge interval until Stop is called
+func (p *Purger) Start(parentCtx context.Context) {
+	if p.config.Retention <= 0 || p.config.PurgeInterval <= 0 {
+		log.Info().Msg("Trash purge is disabled, deleted items are kept forever")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(parentCtx)
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.config.PurgeInterval)
+		defer ticker.Stop()
+		for {
+			p.purgeExpired()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduled purges and waits for the running one
+func (p *Purger) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+}
+
+func (p *Purger) purgeExpired() {
+	photos, articleNumbers, err := p.Purge(time.Now().Add(-p.config.Retention))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to purge the trash")
+	}
+	if photos > 0 || articleNumbers > 0 {
+		log.Info().
+			Int("photos", photos).
+			Int("article_numbers", articleNumbers).
+			Msg("Purged the trash")
+	}
+}
+
+// Purge permanently deletes photos and article numbers deleted before the time.
+// Items failing to purge stay in the trash, the counts cover the purged ones.
+func (p *Purger) Purge(deletedBefore time.Time) (photos, articleNumbers int, err error) {
+	photos, photosErr := p.photoRepository.PurgeDeletedPhotos(deletedBefore, p.bucket)
+	articleNumbers, articlesErr := p.articleRepository.PurgeDeletedArticleNumbers(deletedBefore)
+	return photos, articleNumbers, errors.Join(photosErr, articlesErr)
+}
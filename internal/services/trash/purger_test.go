@@ -0,0 +1,87 @@
+package trash_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Conty111/AlfredoBot/internal/configs"
+	"github.com/Conty111/AlfredoBot/internal/models"
+	"github.com/Conty111/AlfredoBot/internal/repositories/memory"
+	"github.com/Conty111/AlfredoBot/internal/services/trash"
+)
+
+var _ = Describe("Purger", func() {
+	const bucket = "test-bucket"
+
+	var (
+		s3Client      *memory.S3Client
+		photos        *memory.PhotoRepository
+		articles      *memory.ArticleNumberRepository
+		photo         *models.Photo
+		articleNumber *models.ArticleNumber
+		cfg           *configs.Configuration
+	)
+
+	BeforeEach(func() {
+		db := memory.NewDatabase()
+		s3Client = memory.NewS3Client()
+		photos = memory.NewPhotoRepository(db, s3Client)
+		articles = memory.NewArticleNumberRepository(db)
+		cfg = &configs.Configuration{
+			S3:    &configs.S3Config{Bucket: bucket},
+			Trash: &configs.TrashConfig{Retention: time.Hour, PurgeInterval: time.Hour},
+		}
+
+		photo = &models.Photo{UserID: uuid.New(), S3Key: uuid.New(), State: models.PhotoApplied}
+		Expect(photos.CreatePhoto(photo)).To(Succeed())
+		Expect(photos.UploadPhotoToS3(context.Background(), photo.UserID, photo.S3Key, photo.Format, bucket, strings.NewReader("jpeg"))).To(Succeed())
+		var err error
+		articleNumber, err = articles.GetOrCreateArticleNumber(uuid.Nil, "1.2345")
+		Expect(err).To(BeNil())
+
+		Expect(photos.DeletePhoto(photo.ID)).To(Succeed())
+		Expect(articles.DeleteArticleNumber(articleNumber.ID)).To(Succeed())
+	})
+
+	It("should purge items deleted before the time with the objects of photos", func() {
+		purger := trash.NewPurger(cfg, photos, articles)
+
+		purgedPhotos, purgedArticles, err := purger.Purge(time.Now().Add(-time.Minute))
+		Expect(err).To(BeNil())
+		Expect(purgedPhotos).To(BeZero())
+		Expect(purgedArticles).To(BeZero())
+		Expect(s3Client.Objects()).To(HaveLen(1))
+
+		purgedPhotos, purgedArticles, err = purger.Purge(time.Now().Add(time.Minute))
+		Expect(err).To(BeNil())
+		Expect(purgedPhotos).To(Equal(1))
+		Expect(purgedArticles).To(Equal(1))
+		Expect(s3Client.Objects()).To(BeEmpty())
+	})
+
+	It("should purge expired items on start", func() {
+		cfg.Trash.Retention = time.Nanosecond
+		purger := trash.NewPurger(cfg, photos, articles)
+		purger.Start(context.Background())
+		DeferCleanup(purger.Stop)
+
+		Eventually(s3Client.Objects).Should(BeEmpty())
+		Eventually(func() ([]*models.ArticleNumber, error) {
+			return articles.GetDeletedArticleNumbers(uuid.Nil)
+		}).Should(BeEmpty())
+	})
+
+	It("should keep the trash forever without retention", func() {
+		cfg.Trash = nil
+		purger := trash.NewPurger(cfg, photos, articles)
+		purger.Start(context.Background())
+		purger.Stop()
+
+		Expect(s3Client.Objects()).To(HaveLen(1))
+	})
+})
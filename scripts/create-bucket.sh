@@ -11,7 +11,6 @@ done
 if ! mc ls minio/$MINIO_BUCKET --insecure >/dev/null 2>&1; then
   echo "Creating bucket $MINIO_BUCKET"
   mc mb minio/$MINIO_BUCKET --insecure
-  mc anonymous set download minio/$MINIO_BUCKET --insecure
 else
   echo "Bucket $MINIO_BUCKET already exists"
 fi